@@ -1,15 +1,21 @@
 module github.com/polyfloyd/trollibox
 
 require (
-	github.com/antage/eventsource v0.0.0-20190412115600-84b661236871
+	github.com/dhowden/tag v0.0.0-20240417053706-3d75831295e8
+	github.com/eclipse/paho.mqtt.golang v1.3.5
 	github.com/fhs/gompd v2.0.0+incompatible
+	github.com/fsnotify/fsnotify v1.4.9
 	github.com/go-chi/chi v4.0.3+incompatible
 	github.com/golang/gddo v0.0.0-20181116215533-9bd4a3295021 // indirect
+	github.com/golang/protobuf v1.4.1
 	github.com/sirupsen/logrus v1.4.2
 	github.com/tdewolff/minify/v2 v2.7.2 // indirect
 	github.com/tmthrgd/go-bindata v0.0.0-20180829002824-c8d03665bae9
-	golang.org/x/net v0.0.0-20190227160552-c95aed5357e7 // indirect
-	golang.org/x/text v0.3.0 // indirect
+	go.uber.org/goleak v1.1.10
+	golang.org/x/image v0.0.0-20200927104501-e162460cd6b5
+	golang.org/x/text v0.3.0
+	google.golang.org/grpc v1.33.2
+	google.golang.org/protobuf v1.25.0
 	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c
 )
 
@@ -1,67 +1,206 @@
 package api
 
 import (
+	"context"
+	"crypto/rand"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
 	"time"
 
-	"github.com/antage/eventsource"
 	"github.com/go-chi/chi"
 	log "github.com/sirupsen/logrus"
 
-	"github.com/polyfloyd/trollibox/src/filter"
 	"github.com/polyfloyd/trollibox/src/jukebox"
-	"github.com/polyfloyd/trollibox/src/library"
 	"github.com/polyfloyd/trollibox/src/player"
 	"github.com/polyfloyd/trollibox/src/util"
 )
 
+// DefaultTimeout is the maximum amount of time a request may take to
+// complete if no other timeout is configured.
+const DefaultTimeout = time.Second * 8
+
+// ErrReadOnly is returned for requests that would change state through a
+// read-only API.
+var ErrReadOnly = errors.New("this API is read-only")
+
+// ErrNotAdmin is returned for requests that require the client to be an
+// admin.
+var ErrNotAdmin = errors.New("this operation requires an admin")
+
+// ErrNoAdminToken is returned when enabling party mode while no admin token is
+// configured. Everyone would be an admin, so party mode would have no effect.
+var ErrNoAdminToken = errors.New("party mode requires an admin token")
+
 // InitRouter attaches all API routes to the specified router.
-func InitRouter(r chi.Router, jukebox *jukebox.Jukebox) {
-	api := API{jukebox: jukebox}
+//
+// Requests are aborted with a 504 Gateway Timeout if they take longer than
+// the specified timeout. A zero timeout selects the DefaultTimeout. Event
+// streams are exempt.
+//
+// The returned API should be closed when the server shuts down to disconnect
+// clients listening for events.
+func InitRouter(r chi.Router, jukebox *jukebox.Jukebox, timeout time.Duration) *API {
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	api := &API{
+		jukebox:    jukebox,
+		timeout:    timeout,
+		closing:    make(chan struct{}),
+		thumbnails: util.NewLRU(thumbnailCacheSize),
+		clientKey:  make([]byte, 32),
+	}
+	if _, err := rand.Read(api.clientKey); err != nil {
+		panic(err)
+	}
+	r.Use(api.clientIdentity)
+
+	r.Route("/client", func(r chi.Router) {
+		r.Use(jsonCtx)
+		r.Get("/", api.clientGet)
+		r.Put("/", api.clientSetNickname)
+	})
+	r.With(jsonCtx).Get("/nowplaying", api.nowPlaying)
+	r.With(jsonCtx).Get("/time/now", api.timeNow)
 	r.Route("/player/{playerName}", func(r chi.Router) {
 		r.Use(jsonCtx)
-		r.Route("/playlist", func(r chi.Router) {
-			r.Get("/", api.playlistContents)
-			r.Put("/", api.playlistInsert)
-			r.Patch("/", api.playlistMove)
-			r.Delete("/", api.playlistRemove)
-			r.Post("/appendraw", api.rawTrackAdd)
-			r.Post("/appendnet", api.netTrackAdd)
+		r.Group(func(r chi.Router) {
+			r.Use(timeoutCtx(timeout))
+			r.Route("/playlist", func(r chi.Router) {
+				r.Get("/", api.playlistContents)
+				r.Put("/", api.playlistInsert)
+				r.Patch("/", api.playlistMove)
+				r.Delete("/", api.playlistRemove)
+				r.Post("/play", api.playlistPlay)
+				r.Post("/priority", api.playlistSetPriority)
+				r.Post("/nextalbum", api.playlistNextAlbum)
+				r.Post("/appendraw", api.rawTrackAdd)
+				r.Post("/appendnet", api.netTrackAdd)
+				r.Post("/appendsilence", api.silenceAdd)
+			})
+			r.Route("/savedqueues", func(r chi.Router) {
+				r.Get("/", api.savedQueueList)
+				r.Put("/{name}", api.savedQueueSave)
+				r.Post("/{name}/load", api.savedQueueLoad)
+				r.Delete("/{name}", api.savedQueueRemove)
+			})
+			r.Get("/pins", api.pinsList)
+			r.Post("/pins", api.pinsAdd)
+			r.Delete("/pins", api.pinsRemove)
+			r.Get("/storedplaylists", api.storedPlaylistList)
+			r.Post("/storedplaylists", api.storedPlaylistAppend)
+			r.Get("/partymode", api.partyModeGet)
+			r.With(api.requireAdmin).Post("/partymode", api.partyModeSet)
+			r.Post("/voteskip", api.voteSkip)
+			r.Get("/lock", api.queueLockGet)
+			r.With(api.requireAdmin).Post("/lock", api.queueLockSet)
+			r.With(api.requireAdmin).Get("/audit", api.auditList)
+			r.Get("/settings", api.settingsGet)
+			r.Get("/restore", api.restoreOffer)
+			r.Post("/restore", api.restore)
+			r.With(api.requireAdmin).Patch("/settings", api.settingsUpdate)
+			r.Post("/current", api.playerSetCurrent)
+			r.Post("/next", api.playerNext) // Deprecated
+			r.Get("/time", api.playerGetTime)
+			r.Post("/time", api.playerSetTime)
+			r.Get("/playstate", api.playerGetPlaystate)
+			r.Post("/playstate", api.playerSetPlaystate)
+			r.Get("/volume", api.playerGetVolume)
+			r.Post("/volume", api.playerSetVolume)
+			r.Get("/tracks", api.playerTracks)
+			r.Get("/tracks/search", api.playerTrackSearch)
+			r.Get("/tracks/mostplayed", api.playerMostPlayed)
+			r.Get("/tracks/mostskipped", api.playerMostSkipped)
+			r.Get("/tracks/trending", api.playerTrending)
+			r.Post("/tracks/info", api.playerTrackInfo)
+			r.Get("/tracks/art", api.playerTrackArt)
+			r.Post("/tracks/art/status", api.playerTrackArtStatus)
+			r.Get("/tracks/art/palette", api.playerTrackArtPalette)
+			r.Get("/server/stats", api.playerServerStats)
+			r.Get("/debug/connections", api.playerDebugConnections)
+			r.Get("/capabilities", api.playerCapabilities)
 		})
-		r.Post("/current", api.playerSetCurrent)
-		r.Post("/next", api.playerNext) // Deprecated
-		r.Get("/time", api.playerGetTime)
-		r.Post("/time", api.playerSetTime)
-		r.Get("/playstate", api.playerGetPlaystate)
-		r.Post("/playstate", api.playerSetPlaystate)
-		r.Get("/volume", api.playerGetVolume)
-		r.Post("/volume", api.playerSetVolume)
-		r.Get("/tracks", api.playerTracks)
-		r.Get("/tracks/search", api.playerTrackSearch)
-		r.Get("/tracks/art", api.playerTrackArt)
 		r.Mount("/events", api.playerEvents())
 	})
 
+	r.Route("/library", func(r chi.Router) {
+		r.Use(jsonCtx)
+		r.With(timeoutCtx(timeout)).Get("/", api.libraryList)
+		r.Route("/{libraryName}", func(r chi.Router) {
+			r.Group(func(r chi.Router) {
+				r.Use(timeoutCtx(timeout))
+				r.Get("/tracks", api.libraryTracks)
+				r.Get("/tracks/search", api.libraryTrackSearch)
+			})
+			r.Mount("/events", api.libraryEvents())
+		})
+	})
+
 	r.Route("/filters/", func(r chi.Router) {
-		r.Get("/", api.filterList)
-		r.Route("/{name}", func(r chi.Router) {
-			r.Get("/", api.filterGet)
-			r.Delete("/", api.filterRemove)
-			r.Put("/", api.filterSet)
+		r.Group(func(r chi.Router) {
+			r.Use(timeoutCtx(timeout))
+			r.Get("/", api.filterList)
+			r.Post("/test", api.filterTest)
+			r.Route("/{name}", func(r chi.Router) {
+				r.Get("/", api.filterGet)
+				r.Delete("/", api.filterRemove)
+				r.Put("/", api.filterSet)
+				r.Post("/test", api.filterTest)
+			})
 		})
-		r.Mount("/events", htEvents(&jukebox.FilterDB().Emitter))
+		r.Mount("/events", api.htEvents(&jukebox.FilterDB().Emitter, nil))
 	})
 
 	r.Route("/streams", func(r chi.Router) {
-		r.Get("/", api.streamsList)
-		r.Post("/", api.streamsAdd)
-		r.Delete("/", api.streamsRemove)
-		r.Mount("/events", htEvents(&jukebox.StreamDB().Emitter))
+		r.Group(func(r chi.Router) {
+			r.Use(timeoutCtx(timeout))
+			r.Get("/", api.streamsList)
+			r.Post("/", api.streamsAdd)
+			r.Delete("/", api.streamsRemove)
+		})
+		r.Mount("/events", api.htEvents(&jukebox.StreamDB().Emitter, nil))
 	})
 
+	r.With(jsonCtx, timeoutCtx(timeout)).Post("/jsonrpc", api.jsonRPC)
+
 	r.Mount("/raw", jukebox.RawServer())
+	if sv := jukebox.SilenceServer(); sv != nil {
+		r.Mount("/silence", sv)
+	}
+	return api
+}
+
+// InitReadOnlyRouter is like InitRouter, but refuses all requests that could
+// change state with a 403 Forbidden. Everything can still be observed,
+// including the event streams. This is intended for untrusted devices like
+// wall mounted displays.
+func InitReadOnlyRouter(r chi.Router, jukebox *jukebox.Jukebox, timeout time.Duration) *API {
+	r.Use(readOnly)
+	return InitRouter(r, jukebox, timeout)
+}
+
+// readOnly is middleware that only allows requests with safe methods.
+func readOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+		default:
+			WriteError(w, r, ErrReadOnly)
+		}
+	})
+}
+
+// Close disconnects all event stream clients and waits for their handlers to
+// return. Event streams requested after closing are refused.
+func (api *API) Close() {
+	api.closeOnce.Do(func() {
+		api.eventStreamsLock.Lock()
+		close(api.closing)
+		api.eventStreamsLock.Unlock()
+	})
+	api.eventStreams.Wait()
 }
 
 // WriteError writes an error to the client or an empty object if err is nil.
@@ -69,7 +208,19 @@ func InitRouter(r chi.Router, jukebox *jukebox.Jukebox) {
 // An attempt is made to tune the response format to the requestor.
 func WriteError(w http.ResponseWriter, r *http.Request, err error) {
 	log.Errorf("Error serving %s: %v", r.RemoteAddr, err)
-	w.WriteHeader(http.StatusBadRequest)
+	if errors.Is(err, context.DeadlineExceeded) {
+		w.WriteHeader(http.StatusGatewayTimeout)
+	} else if errors.Is(err, player.ErrUnseekable) || errors.Is(err, jukebox.ErrNoNextAlbum) || errors.Is(err, ErrNoAdminToken) || errors.Is(err, jukebox.ErrSkipVoting) {
+		w.WriteHeader(http.StatusConflict)
+	} else if errors.Is(err, ErrReadOnly) || errors.Is(err, ErrNotAdmin) || errors.Is(err, jukebox.ErrPartyMode) || errors.Is(err, jukebox.ErrBlocked) {
+		w.WriteHeader(http.StatusForbidden)
+	} else if errors.Is(err, jukebox.ErrQueueCap) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	} else if errors.Is(err, jukebox.ErrQueueLocked) {
+		w.WriteHeader(http.StatusLocked)
+	} else {
+		w.WriteHeader(http.StatusBadRequest)
+	}
 
 	if r.Header.Get("X-Requested-With") == "" {
 		w.Write([]byte(err.Error()))
@@ -86,66 +237,16 @@ func WriteError(w http.ResponseWriter, r *http.Request, err error) {
 	})
 }
 
-func htEvents(emitter *util.Emitter) http.Handler {
-	conf := eventsource.DefaultSettings()
-	events := eventsource.New(conf, func(r *http.Request) [][]byte {
-		return [][]byte{
-			[]byte("X-Accel-Buffering: no"),
-		}
-	})
-
-	ch := emitter.Listen()
-	go func() {
-		id := 0
-		for event := range ch {
-			id++
-
-			// TODO: All these events should not all be combined in here.
-			var eventStr string
-			var eventObj interface{}
-			switch t := event.(type) {
-			case player.PlaylistEvent:
-				eventStr, eventObj = "playlist", map[string]interface{}{
-					"index": t.Index,
-				}
-			case player.PlayStateEvent:
-				eventStr, eventObj = "playstate", map[string]interface{}{
-					"state": t.State,
-				}
-			case player.TimeEvent:
-				eventStr, eventObj = "time", map[string]interface{}{
-					"time": int(t.Time / time.Second),
-				}
-			case player.VolumeEvent:
-				eventStr, eventObj = "volume", map[string]interface{}{
-					"volume": float32(t.Volume) / 100.0,
-				}
-			case player.ListEvent:
-				eventStr, eventObj = "list", struct{}{}
-			case player.AvailabilityEvent:
-				eventStr, eventObj = "availability", map[string]interface{}{
-					"available": t.Available,
-				}
-			case library.UpdateEvent:
-				eventStr, eventObj = "library:tracks", struct{}{}
-			case filter.UpdateEvent:
-				eventStr, eventObj = "filter:update", map[string]interface{}{
-					"filter": t.Filter,
-				}
-			default:
-				log.Debugf("Unmapped event %#v", event)
-				continue
-			}
-
-			eventMsg, err := json.Marshal(eventObj)
-			if err != nil {
-				log.Error(err)
-				continue
-			}
-			events.SendEventMessage(string(eventMsg), eventStr, fmt.Sprintf("%d", id))
-		}
-	}()
-	return events
+// timeoutCtx creates middleware that limits the time a request may take by
+// setting a deadline on the request's context.
+func timeoutCtx(timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
 }
 
 func jsonCtx(next http.Handler) http.Handler {
@@ -0,0 +1,385 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"go.uber.org/goleak"
+
+	"github.com/polyfloyd/trollibox/src/filter"
+	"github.com/polyfloyd/trollibox/src/jukebox"
+	"github.com/polyfloyd/trollibox/src/library/stream"
+	"github.com/polyfloyd/trollibox/src/player"
+	"github.com/polyfloyd/trollibox/src/util"
+)
+
+// readEvent reads the next Server-Sent Event from the stream and returns its
+// name and data.
+func readEvent(t *testing.T, rd *bufio.Reader) (name, data string) {
+	t.Helper()
+	for {
+		line, err := rd.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Error reading event: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "":
+			return
+		case strings.HasPrefix(line, "event: "):
+			name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestEventSnapshot(t *testing.T) {
+	api := &API{timeout: DefaultTimeout, closing: make(chan struct{})}
+	defer api.Close()
+	var emitter util.Emitter
+	snapshots := 0
+	snapshot := func(ctx context.Context, r *http.Request) ([]event, error) {
+		snapshots++
+		ev, _ := mapEvent(player.VolumeEvent{Volume: snapshots})
+		return []event{ev}, nil
+	}
+	server := httptest.NewServer(api.htEvents(&emitter, snapshot))
+	defer server.Close()
+
+	respA, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer respA.Body.Close()
+	rdA := bufio.NewReader(respA.Body)
+	if name, data := readEvent(t, rdA); name != "volume" || data != `{"volume":0.01}` {
+		t.Fatalf("Unexpected snapshot event: %q %q", name, data)
+	}
+
+	respB, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer respB.Body.Close()
+	if name, data := readEvent(t, bufio.NewReader(respB.Body)); name != "volume" || data != `{"volume":0.02}` {
+		t.Fatalf("Unexpected snapshot event: %q %q", name, data)
+	}
+
+	// The first client should not receive the snapshot of the second.
+	emitter.Emit(player.PlayStateEvent{State: player.PlayStatePlaying})
+	if name, _ := readEvent(t, rdA); name != "playstate" {
+		t.Fatalf("Unexpected event: %q", name)
+	}
+}
+
+func TestShutdownDisconnectsEvents(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	dir, err := ioutil.TempDir("", "trollibox-api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filterdb, err := filter.NewDB(path.Join(dir, "filters"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	streamdb, err := stream.NewDB(path.Join(dir, "streams"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jb := jukebox.NewJukebox(player.SimpleList{}, nil, filterdb, streamdb, nil)
+	router := chi.NewRouter()
+	api := InitRouter(router, jb, 0)
+	server := httptest.NewServer(router)
+
+	client := &http.Client{Transport: &http.Transport{}}
+	resp, err := client.Get(server.URL + "/streams/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	server.Config.RegisterOnShutdown(api.Close)
+	server.Config.Shutdown(context.Background())
+	server.Close()
+	client.CloseIdleConnections()
+
+	// Reading should end now that the server has disconnected the client.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		rd := bufio.NewReader(resp.Body)
+		for {
+			if _, err := rd.ReadString('\n'); err != nil {
+				return
+			}
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second * 5):
+		t.Fatal("Event stream was not disconnected")
+	}
+
+	if _, err := client.Get(server.URL + "/streams/events"); err == nil {
+		t.Fatal("Expected an error when connecting after shutdown")
+	}
+}
+
+func TestEventStreamOutlivesWriteTimeout(t *testing.T) {
+	api := &API{timeout: DefaultTimeout, closing: make(chan struct{})}
+	defer api.Close()
+	var emitter util.Emitter
+	router := chi.NewRouter()
+	router.Use(util.ControlHandler)
+	router.Use(middleware.DefaultCompress)
+	router.Handle("/", api.htEvents(&emitter, nil))
+	server := httptest.NewUnstartedServer(router)
+	server.Config.WriteTimeout = time.Millisecond * 100
+	server.Start()
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	time.Sleep(server.Config.WriteTimeout * 3)
+	emitter.Emit(player.VolumeEvent{Volume: 50})
+	if name, _ := readEvent(t, bufio.NewReader(resp.Body)); name != "volume" {
+		t.Fatalf("Unexpected event: %q", name)
+	}
+}
+
+func TestEventFilter(t *testing.T) {
+	api := &API{timeout: DefaultTimeout, closing: make(chan struct{})}
+	defer api.Close()
+	var emitter util.Emitter
+	server := httptest.NewServer(api.htEvents(&emitter, nil))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "?events=volume,playstate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	rd := bufio.NewReader(resp.Body)
+
+	emitter.Emit(player.TimeEvent{Time: time.Second})
+	emitter.Emit(player.PlaylistEvent{Index: 1})
+	emitter.Emit(player.VolumeEvent{Volume: 50})
+	emitter.Emit(player.PlayStateEvent{State: player.PlayStatePaused})
+	if name, _ := readEvent(t, rd); name != "volume" {
+		t.Fatalf("Unexpected event: %q", name)
+	}
+	if name, _ := readEvent(t, rd); name != "playstate" {
+		t.Fatalf("Unexpected event: %q", name)
+	}
+}
+
+func TestEventMsgpack(t *testing.T) {
+	api := &API{timeout: DefaultTimeout, closing: make(chan struct{})}
+	defer api.Close()
+	var emitter util.Emitter
+	server := httptest.NewServer(api.htEvents(&emitter, nil))
+	defer server.Close()
+
+	req, err := http.NewRequest("GET", server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept", "application/msgpack")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "application/msgpack" {
+		t.Fatalf("Unexpected content type: %q", ct)
+	}
+
+	emitter.Emit(player.VolumeEvent{Volume: 50})
+	expected := []byte{
+		0x83,
+		0xa4, 'd', 'a', 't', 'a',
+		0x81, 0xa6, 'v', 'o', 'l', 'u', 'm', 'e', 0xca, 0x3f, 0, 0, 0,
+		0xa5, 'e', 'v', 'e', 'n', 't',
+		0xa6, 'v', 'o', 'l', 'u', 'm', 'e',
+		0xa2, 'i', 'd',
+	}
+	expected = append(expected, byte(0xa0|len(formatEventID(1))))
+	expected = append(expected, formatEventID(1)...)
+	packed := make([]byte, len(expected))
+	if _, err := io.ReadFull(resp.Body, packed); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(packed, expected) {
+		t.Fatalf("Unexpected event: % x", packed)
+	}
+}
+
+func TestEventReplay(t *testing.T) {
+	api := &API{timeout: DefaultTimeout, closing: make(chan struct{})}
+	defer api.Close()
+	var emitter util.Emitter
+	snapshot := func(ctx context.Context, r *http.Request) ([]event, error) {
+		ev, _ := mapEvent(player.PlayStateEvent{State: player.PlayStateStopped})
+		return []event{ev}, nil
+	}
+	server := httptest.NewServer(api.htEvents(&emitter, snapshot))
+	defer server.Close()
+
+	var responses []*http.Response
+	defer func() {
+		for _, resp := range responses {
+			resp.Body.Close()
+		}
+	}()
+	connect := func(lastEventID string) *bufio.Reader {
+		t.Helper()
+		req, _ := http.NewRequest("GET", server.URL, nil)
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		responses = append(responses, resp)
+		return bufio.NewReader(resp.Body)
+	}
+	expect := func(rd *bufio.Reader, expectName, expectData string) {
+		t.Helper()
+		if name, data := readEvent(t, rd); name != expectName || (expectData != "" && data != expectData) {
+			t.Fatalf("Unexpected event: %q %q, expected %q %q", name, data, expectName, expectData)
+		}
+	}
+
+	rdA := connect("")
+	expect(rdA, "playstate", "")
+	for i := 1; i <= 3; i++ {
+		emitter.Emit(player.VolumeEvent{Volume: i})
+		expect(rdA, "volume", "")
+	}
+
+	// Missed events are replayed instead of the snapshot.
+	rdB := connect(formatEventID(1))
+	expect(rdB, "volume", `{"volume":0.02}`)
+	expect(rdB, "volume", `{"volume":0.03}`)
+	emitter.Emit(player.VolumeEvent{Volume: 4})
+	expect(rdA, "volume", "")
+	expect(rdB, "volume", `{"volume":0.04}`)
+
+	// IDs that were never issued require a resync, as do IDs of another
+	// process, which restarted its sequence.
+	for _, id := range []string{formatEventID(1000), "1", "x-1", eventEpoch + "-x"} {
+		rdC := connect(id)
+		expect(rdC, "resync", "")
+		expect(rdC, "playstate", "")
+	}
+
+	for i := 0; i < eventHistorySize; i++ {
+		emitter.Emit(player.VolumeEvent{Volume: i})
+		expect(rdA, "volume", "")
+	}
+	rdD := connect(formatEventID(2))
+	expect(rdD, "resync", "")
+	expect(rdD, "playstate", "")
+}
+
+func BenchmarkEventFanOut(b *testing.B) {
+	const numListeners = 50
+	const numEvents = 10000
+
+	api := &API{timeout: DefaultTimeout, closing: make(chan struct{})}
+	defer api.Close()
+	var emitter util.Emitter
+	hub := api.eventHub(&emitter)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var wg sync.WaitGroup
+		wg.Add(numListeners)
+		for l := 0; l < numListeners; l++ {
+			listener := hub.Subscribe(nil)
+			go func() {
+				defer wg.Done()
+				defer listener.Close()
+				// The last event is a list event, events in between may be
+				// dropped if the listener falls behind.
+				for e := range listener.C {
+					if e.(*encodedEvent).name == "list" {
+						return
+					}
+				}
+			}()
+		}
+		for e := 0; e < numEvents-1; e++ {
+			emitter.Emit(player.TimeEvent{Time: time.Duration(e) * time.Second})
+		}
+		emitter.Emit(player.ListEvent{})
+		wg.Wait()
+	}
+}
+
+func TestReadOnlyRouter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trollibox-api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filterdb, err := filter.NewDB(path.Join(dir, "filters"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	streamdb, err := stream.NewDB(path.Join(dir, "streams"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pl := player.NewDummyPlayer()
+	jb := jukebox.NewJukebox(player.SimpleList{"dummy": pl}, nil, filterdb, streamdb, nil)
+	router := chi.NewRouter()
+	api := InitReadOnlyRouter(router, jb, 0)
+	server := httptest.NewServer(router)
+	defer server.Close()
+	defer api.Close()
+
+	resp, err := http.Get(server.URL + "/player/dummy/playstate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Unexpected status: %s", resp.Status)
+	}
+
+	resp, err = http.Post(server.URL+"/player/dummy/playstate", "application/json", strings.NewReader(`{"playstate":"playing"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("Unexpected status: %s", resp.Status)
+	}
+	if state, _ := pl.State(); state != player.PlayStateStopped {
+		t.Fatalf("The state was changed: %v", state)
+	}
+}
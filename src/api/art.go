@@ -0,0 +1,350 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+	// Register the WebP decoder.
+	_ "golang.org/x/image/webp"
+
+	"github.com/polyfloyd/trollibox/src/library"
+)
+
+const (
+	// The largest size thumbnails can be requested at.
+	maxThumbnailSize = 1024
+	// The maximum number of bytes used by cached thumbnails and palettes.
+	thumbnailCacheSize = 32 << 20
+)
+
+// thumbnail scales the image down so it fits within a square with sides of
+// the specified size. The aspect ratio is preserved. Images that already fit
+// are returned as is.
+//
+// Thumbnails of PNG images are PNG encoded to keep transparency, all others
+// are encoded as JPEG.
+func (api *API) thumbnail(data []byte, size int) ([]byte, string, error) {
+	if size <= 0 || size > maxThumbnailSize {
+		return nil, "", fmt.Errorf("thumbnail size out of range: %d (max %d)", size, maxThumbnailSize)
+	}
+	key := fmt.Sprintf("%x:%d", sha1.Sum(data), size)
+	if thumb, ok := api.thumbnails.Get(key); ok {
+		return thumb, http.DetectContentType(thumb), nil
+	}
+
+	src, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to decode image: %v", err)
+	}
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w <= size && h <= size {
+		return data, http.DetectContentType(data), nil
+	}
+	if w > h {
+		w, h = size, h*size/w
+	} else {
+		w, h = w*size/h, size
+	}
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Src, nil)
+
+	var buf bytes.Buffer
+	mime := "image/jpeg"
+	if format == "png" {
+		mime = "image/png"
+		err = png.Encode(&buf, dst)
+	} else {
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85})
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	api.thumbnails.Put(key, buf.Bytes())
+	return buf.Bytes(), mime, nil
+}
+
+// Kinds of placeholders that can be served for tracks without art.
+const (
+	// placeholderNone responds with a 404 Not Found.
+	placeholderNone = "none"
+	// placeholderGeneric serves the configured placeholder image.
+	placeholderGeneric = "true"
+	// placeholderGenerated serves an image with the initials of the artist.
+	placeholderGenerated = "generated"
+)
+
+// The size of placeholders if no size is requested.
+const defaultPlaceholderSize = 256
+
+// placeholderColors are the backgrounds of generated placeholders. The color
+// of a placeholder is derived from the name it shows.
+var placeholderColors = []color.NRGBA{
+	{0x8e, 0x44, 0x43, 0xff},
+	{0x9a, 0x6b, 0x2f, 0xff},
+	{0x5e, 0x7d, 0x3a, 0xff},
+	{0x2f, 0x7a, 0x6b, 0xff},
+	{0x33, 0x63, 0x8c, 0xff},
+	{0x5b, 0x4b, 0x8a, 0xff},
+	{0x86, 0x45, 0x78, 0xff},
+	{0x55, 0x5b, 0x63, 0xff},
+}
+
+var (
+	placeholderFont     *opentype.Font
+	placeholderFontErr  error
+	placeholderFontOnce sync.Once
+)
+
+// SetArtPlaceholder sets the image that is served for tracks without art if a
+// generic placeholder is requested. A plain gray square is served by default.
+func (api *API) SetArtPlaceholder(data []byte) error {
+	if _, _, err := image.DecodeConfig(bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("unable to decode art placeholder: %v", err)
+	}
+	api.placeholderLock.Lock()
+	defer api.placeholderLock.Unlock()
+	api.placeholder = data
+	return nil
+}
+
+// placeholderArt returns an image that can be shown in place of the art of
+// the track. Size is a hint for the dimensions of the image, zero selects the
+// default.
+func (api *API) placeholderArt(kind string, track library.Track, size int) ([]byte, string, error) {
+	if size <= 0 || size > maxThumbnailSize {
+		size = defaultPlaceholderSize
+	}
+	if kind == placeholderGeneric {
+		api.placeholderLock.Lock()
+		data := api.placeholder
+		api.placeholderLock.Unlock()
+		if data != nil {
+			return data, http.DetectContentType(data), nil
+		}
+		return api.renderPlaceholder("", color.NRGBA{0x80, 0x80, 0x80, 0xff}, size)
+	}
+
+	name := track.Artist
+	if name == "" {
+		name = track.Title
+	}
+	if name == "" {
+		name = path.Base(track.URI)
+	}
+	hash := fnv.New32a()
+	hash.Write([]byte(strings.ToLower(name)))
+	background := placeholderColors[hash.Sum32()%uint32(len(placeholderColors))]
+	return api.renderPlaceholder(initials(name), background, size)
+}
+
+// initials returns the uppercased first letters of the first two words of
+// the name.
+func initials(name string) string {
+	var letters []rune
+	for _, word := range strings.Fields(name) {
+		for _, r := range word {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				letters = append(letters, unicode.ToUpper(r))
+				break
+			}
+		}
+		if len(letters) == 2 {
+			break
+		}
+	}
+	return string(letters)
+}
+
+// renderPlaceholder draws the text centered on a square of the background
+// color and encodes it as PNG.
+func (api *API) renderPlaceholder(text string, background color.NRGBA, size int) ([]byte, string, error) {
+	key := fmt.Sprintf("placeholder:%s:%v:%d", text, background, size)
+	if cached, ok := api.thumbnails.Get(key); ok {
+		return cached, "image/png", nil
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+	if text != "" {
+		placeholderFontOnce.Do(func() {
+			placeholderFont, placeholderFontErr = opentype.Parse(gobold.TTF)
+		})
+		if placeholderFontErr != nil {
+			return nil, "", placeholderFontErr
+		}
+		face, err := opentype.NewFace(placeholderFont, &opentype.FaceOptions{
+			Size:    float64(size) * 0.4,
+			DPI:     72,
+			Hinting: font.HintingFull,
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		defer face.Close()
+		drawer := font.Drawer{
+			Dst:  img,
+			Src:  image.NewUniform(color.NRGBA{0xff, 0xff, 0xff, 0xe0}),
+			Face: face,
+		}
+		metrics := face.Metrics()
+		width := drawer.MeasureString(text)
+		drawer.Dot = fixed.Point26_6{
+			X: (fixed.I(size) - width) / 2,
+			Y: (fixed.I(size) + metrics.Ascent - metrics.Descent) / 2,
+		}
+		drawer.DrawString(text)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, "", err
+	}
+	api.thumbnails.Put(key, buf.Bytes())
+	return buf.Bytes(), "image/png", nil
+}
+
+const (
+	// The number of colors in a palette.
+	paletteSize = 5
+	// Images are scaled down to fit a square of this size before their
+	// palette is computed.
+	paletteSampleSize = 64
+)
+
+// defaultPalette is returned for tracks without art.
+var defaultPalette = []string{"#808080"}
+
+// palette computes the dominant colors of an image using median cut. The
+// colors are formatted as hex strings and ordered from most to least
+// dominant.
+func (api *API) palette(data []byte) ([]string, error) {
+	key := fmt.Sprintf("palette:%x", sha1.Sum(data))
+	if cached, ok := api.thumbnails.Get(key); ok {
+		return strings.Split(string(cached), ","), nil
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode image: %v", err)
+	}
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w > paletteSampleSize || h > paletteSampleSize {
+		if w > h {
+			w, h = paletteSampleSize, h*paletteSampleSize/w
+		} else {
+			w, h = w*paletteSampleSize/h, paletteSampleSize
+		}
+	}
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+	sample := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.ApproxBiLinear.Scale(sample, sample.Bounds(), src, bounds, draw.Src, nil)
+
+	pixels := make([]color.NRGBA, 0, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			// Mostly transparent pixels do not contribute to the
+			// visible color.
+			if c := sample.NRGBAAt(x, y); c.A >= 128 {
+				pixels = append(pixels, c)
+			}
+		}
+	}
+	colors := medianCut(pixels, paletteSize)
+	if len(colors) == 0 {
+		colors = defaultPalette
+	}
+	api.thumbnails.Put(key, []byte(strings.Join(colors, ",")))
+	return colors, nil
+}
+
+// medianCut repeatedly splits the box of pixels with the largest range in
+// any channel at the median of that channel until n boxes exist. The average
+// colors of the boxes are returned ordered by the number of pixels they hold.
+func medianCut(pixels []color.NRGBA, n int) []string {
+	if len(pixels) == 0 {
+		return nil
+	}
+	channel := func(c color.NRGBA, i int) uint8 {
+		return [3]uint8{c.R, c.G, c.B}[i]
+	}
+	widest := func(box []color.NRGBA) (ch, width int) {
+		for i := 0; i < 3; i++ {
+			min, max := uint8(255), uint8(0)
+			for _, c := range box {
+				if v := channel(c, i); v < min {
+					min = v
+				}
+				if v := channel(c, i); v > max {
+					max = v
+				}
+			}
+			if w := int(max) - int(min); w > width {
+				ch, width = i, w
+			}
+		}
+		return
+	}
+
+	boxes := [][]color.NRGBA{pixels}
+	for len(boxes) < n {
+		split, splitCh, splitWidth := -1, 0, 0
+		for i, box := range boxes {
+			if ch, width := widest(box); width > splitWidth {
+				split, splitCh, splitWidth = i, ch, width
+			}
+		}
+		if split == -1 {
+			break
+		}
+		box := boxes[split]
+		sort.Slice(box, func(a, b int) bool {
+			return channel(box[a], splitCh) < channel(box[b], splitCh)
+		})
+		mid := len(box) / 2
+		boxes[split] = box[:mid]
+		boxes = append(boxes, box[mid:])
+	}
+
+	sort.SliceStable(boxes, func(a, b int) bool {
+		return len(boxes[a]) > len(boxes[b])
+	})
+	colors := make([]string, len(boxes))
+	for i, box := range boxes {
+		var r, g, b int
+		for _, c := range box {
+			r, g, b = r+int(c.R), g+int(c.G), b+int(c.B)
+		}
+		colors[i] = fmt.Sprintf("#%02x%02x%02x", r/len(box), g/len(box), b/len(box))
+	}
+	return colors
+}
@@ -0,0 +1,181 @@
+package api
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/polyfloyd/trollibox/src/library"
+	"github.com/polyfloyd/trollibox/src/util"
+)
+
+func TestThumbnail(t *testing.T) {
+	api := &API{thumbnails: util.NewLRU(thumbnailCacheSize)}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 200, 100))); err != nil {
+		t.Fatal(err)
+	}
+	original := buf.Bytes()
+
+	thumb, mime, err := api.thumbnail(original, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mime != "image/png" {
+		t.Fatalf("Unexpected MIME type: %q", mime)
+	}
+	img, err := png.Decode(bytes.NewReader(thumb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := img.Bounds().Size(); size != image.Pt(50, 25) {
+		t.Fatalf("Unexpected thumbnail size: %v", size)
+	}
+
+	// Images should never be upscaled.
+	if large, _, err := api.thumbnail(original, 400); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(large, original) {
+		t.Fatal("The original image was not returned")
+	}
+
+	if _, _, err := api.thumbnail(original, maxThumbnailSize+1); err == nil {
+		t.Fatal("Expected an error for a too large size")
+	}
+}
+
+func TestPalette(t *testing.T) {
+	api := &API{thumbnails: util.NewLRU(thumbnailCacheSize)}
+
+	img := image.NewRGBA(image.Rect(0, 0, 300, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 300; x++ {
+			if x < 200 {
+				img.Set(x, y, color.RGBA{R: 0xff, A: 0xff})
+			} else {
+				img.Set(x, y, color.RGBA{B: 0xff, A: 0xff})
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	colors, err := api.palette(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(colors) < 2 || colors[0] != "#ff0000" {
+		t.Fatalf("Unexpected palette: %v", colors)
+	}
+	if cached, err := api.palette(buf.Bytes()); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(cached, colors) {
+		t.Fatalf("The cached palette differs: %v != %v", cached, colors)
+	}
+
+	if _, err := api.palette([]byte("not an image")); err == nil {
+		t.Fatal("Expected an error for invalid image data")
+	}
+}
+
+func TestInitials(t *testing.T) {
+	tests := map[string]string{
+		"the beatles":        "TB",
+		"Daft Punk & Co":     "DP",
+		"  (Ólafur) Arnalds": "ÓA",
+		"Björk":              "B",
+		"":                   "",
+	}
+	for name, expected := range tests {
+		if actual := initials(name); actual != expected {
+			t.Fatalf("Unexpected initials of %q: %q", name, actual)
+		}
+	}
+}
+
+func TestPlaceholderArt(t *testing.T) {
+	api := &API{thumbnails: util.NewLRU(thumbnailCacheSize)}
+
+	data, mime, err := api.placeholderArt(placeholderGenerated, library.Track{Artist: "Foo Fighters"}, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mime != "image/png" {
+		t.Fatalf("Unexpected MIME type: %q", mime)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 64 || b.Dy() != 64 {
+		t.Fatalf("Unexpected size: %v", b)
+	}
+	// The corner shows the background while the initials are drawn over the
+	// center.
+	corner := color.NRGBAModel.Convert(img.At(0, 0))
+	found := false
+	for x := 16; x < 48 && !found; x++ {
+		found = color.NRGBAModel.Convert(img.At(x, 32)) != corner
+	}
+	if !found {
+		t.Fatalf("No text was drawn")
+	}
+
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 4)))
+	if err := api.SetArtPlaceholder(buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if data, _, _ := api.placeholderArt(placeholderGeneric, library.Track{}, 0); !bytes.Equal(data, buf.Bytes()) {
+		t.Fatalf("The configured placeholder was not used")
+	}
+	if err := api.SetArtPlaceholder([]byte("not an image")); err == nil {
+		t.Fatalf("Invalid placeholders should be rejected")
+	}
+}
+
+func TestTrackArtPlaceholder(t *testing.T) {
+	server, _, cleanup := newTestServer(t, library.Track{URI: "a", Artist: "Foo"})
+	defer cleanup()
+
+	resp, err := http.Get(server.URL + "/player/dummy/tracks/art?track=a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("Unexpected status: %s", resp.Status)
+	}
+
+	resp, err = http.Get(server.URL + "/player/dummy/tracks/art?track=a&placeholder=generated&size=32")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Unexpected status: %s", resp.Status)
+	}
+	if h := resp.Header.Get("X-Art-Placeholder"); h != "generated" {
+		t.Fatalf("Unexpected placeholder header: %q", h)
+	}
+	if h := resp.Header.Get("Cache-Control"); h != "no-cache" {
+		t.Fatalf("Unexpected cache header: %q", h)
+	}
+	if h := resp.Header.Get("Last-Modified"); h != "" {
+		t.Fatalf("Unexpected Last-Modified: %q", h)
+	}
+	img, err := png.Decode(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 32 {
+		t.Fatalf("Unexpected size: %v", b)
+	}
+}
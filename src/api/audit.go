@@ -0,0 +1,56 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+)
+
+// The number of audit entries returned if no limit is requested.
+const defaultAuditLimit = 50
+
+// auditList serves the most recent changes to the playlist, newest first. The
+// "before" parameter selects the entries preceding the entry with that ID, so
+// clients can page back using the ID of the last entry they received.
+func (api *API) auditList(w http.ResponseWriter, r *http.Request) {
+	before := -1
+	if s := r.FormValue("before"); s != "" {
+		var err error
+		if before, err = strconv.Atoi(s); err != nil || before < 0 {
+			WriteError(w, r, fmt.Errorf("invalid before: %q", s))
+			return
+		}
+	}
+	limit := defaultAuditLimit
+	if s := r.FormValue("limit"); s != "" {
+		var err error
+		if limit, err = strconv.Atoi(s); err != nil || limit <= 0 {
+			WriteError(w, r, fmt.Errorf("invalid limit: %q", s))
+			return
+		}
+	}
+
+	entries, err := api.jukebox.AuditEntries(r.Context(), chi.URLParam(r, "playerName"), before, limit)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	entriesJSON := make([]interface{}, len(entries))
+	for i, entry := range entries {
+		entriesJSON[i] = map[string]interface{}{
+			"id":       entry.ID,
+			"time":     unixMillis(entry.Time),
+			"client":   entry.Client,
+			"nickname": entry.Nickname,
+			"action":   entry.Action,
+			"uris":     api.externalURIs(entry.URIs),
+			"detail":   entry.Detail,
+		}
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"entries": entriesJSON,
+	})
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/polyfloyd/trollibox/src/jukebox"
+	"github.com/polyfloyd/trollibox/src/library"
+)
+
+func TestAudit(t *testing.T) {
+	server, jb, cleanup := newTestServer(t, library.Track{URI: "a"}, library.Track{URI: "b"})
+	defer cleanup()
+	dir, err := ioutil.TempDir("", "trollibox-audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	auditLog, err := jukebox.NewAuditLog(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jb.SetAuditLog(auditLog)
+
+	do := func(method, url, body string) {
+		t.Helper()
+		req, err := http.NewRequest(method, server.URL+url, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set(NicknameHeader, "Bob")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s %s: unexpected status: %s", method, url, resp.Status)
+		}
+	}
+	do("PUT", "/player/dummy/playlist", `{"position":-1,"tracks":["a","b"]}`)
+	do("PATCH", "/player/dummy/playlist", `{"from":1,"to":0}`)
+	do("DELETE", "/player/dummy/playlist", `{"positions":[1]}`)
+
+	type auditResponse struct {
+		Entries []struct {
+			ID       int      `json:"id"`
+			Nickname string   `json:"nickname"`
+			Action   string   `json:"action"`
+			URIs     []string `json:"uris"`
+		} `json:"entries"`
+	}
+	var audit auditResponse
+	getJSON(t, server.URL+"/player/dummy/audit?limit=2", &audit)
+	if len(audit.Entries) != 2 {
+		t.Fatalf("Unexpected entries: %+v", audit.Entries)
+	}
+	if e := audit.Entries[0]; e.ID != 2 || e.Action != "remove" || e.Nickname != "Bob" || len(e.URIs) != 1 || e.URIs[0] != "a" {
+		t.Fatalf("Unexpected newest entry: %+v", e)
+	}
+	if e := audit.Entries[1]; e.ID != 1 || e.Action != "move" || len(e.URIs) != 1 || e.URIs[0] != "b" {
+		t.Fatalf("Unexpected second entry: %+v", e)
+	}
+
+	var older auditResponse
+	getJSON(t, server.URL+"/player/dummy/audit?before=1", &older)
+	if len(older.Entries) != 1 || older.Entries[0].Action != "insert" || len(older.Entries[0].URIs) != 2 {
+		t.Fatalf("Unexpected older entries: %+v", older.Entries)
+	}
+}
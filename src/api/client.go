@@ -0,0 +1,212 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/polyfloyd/trollibox/src/jukebox"
+)
+
+const (
+	// The cookie holding the signed anonymous ID of a client.
+	clientIDCookie = "trollibox_client"
+	// The cookie holding the nickname of a client.
+	nicknameCookie = "trollibox_nickname"
+	// NicknameHeader may be set by clients that do not keep cookies to
+	// name themselves. As they can not be recognized, such clients get a new
+	// ID for each request.
+	NicknameHeader = "X-Trollibox-Nickname"
+	// AdminTokenHeader is set by clients to authenticate as an admin.
+	AdminTokenHeader = "X-Trollibox-Admin-Token"
+
+	maxNicknameLength = 32
+	clientCookieAge   = time.Hour * 24 * 365
+)
+
+var validClientID = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// SetClientKey sets the secret with which the IDs of clients are signed and
+// from which their public IDs are derived. IDs that were signed with another
+// key are not accepted, so the key should be kept across restarts. A random
+// key is used until this is called.
+func (api *API) SetClientKey(key []byte) {
+	api.clientKeyLock.Lock()
+	defer api.clientKeyLock.Unlock()
+	api.clientKey = key
+}
+
+// clientMAC authenticates the client ID for the specified purpose.
+func (api *API) clientMAC(purpose, id string) string {
+	api.clientKeyLock.RLock()
+	mac := hmac.New(sha256.New, api.clientKey)
+	api.clientKeyLock.RUnlock()
+	mac.Write([]byte(purpose + ":" + id))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// signClientID returns the value of the cookie that holds the client ID.
+func (api *API) signClientID(id string) string {
+	return id + "." + api.clientMAC("cookie", id)
+}
+
+// verifyClientID returns the client ID held by a cookie if it was signed with
+// the current key.
+func (api *API) verifyClientID(value string) (string, bool) {
+	i := strings.IndexByte(value, '.')
+	if i < 0 || !validClientID.MatchString(value[:i]) {
+		return "", false
+	}
+	id := value[:i]
+	return id, hmac.Equal([]byte(value[i+1:]), []byte(api.clientMAC("cookie", id)))
+}
+
+// publicClientID derives the ID of a client that may be shown to others. The
+// client ID can not be recovered from it.
+func (api *API) publicClientID(id string) string {
+	return api.clientMAC("public", id)
+}
+
+// SetAdminToken sets the secret that clients present in the AdminTokenHeader
+// to authenticate as an admin. An empty token disables authentication, which
+// leaves admin only operations open to everyone.
+func (api *API) SetAdminToken(token string) {
+	api.adminTokenLock.Lock()
+	defer api.adminTokenLock.Unlock()
+	api.adminToken = token
+}
+
+func (api *API) hasAdminToken() bool {
+	api.adminTokenLock.RLock()
+	defer api.adminTokenLock.RUnlock()
+	return api.adminToken != ""
+}
+
+// requireAdmin is middleware that refuses requests of clients that are not
+// admins. All clients are admins if no admin token is configured.
+func (api *API) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client, _ := jukebox.ClientFromContext(r.Context())
+		if api.hasAdminToken() && !client.Admin {
+			WriteError(w, r, ErrNotAdmin)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isAdmin checks whether the request carries the admin token.
+func (api *API) isAdmin(r *http.Request) bool {
+	api.adminTokenLock.RLock()
+	token := api.adminToken
+	api.adminTokenLock.RUnlock()
+	given := r.Header.Get(AdminTokenHeader)
+	return token != "" && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+// clientIdentity is middleware that attaches the identity of the requesting
+// client to the request's context. Clients that have not been seen before are
+// assigned a random anonymous ID, which is stored in a signed cookie.
+func (api *API) clientIdentity(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client := jukebox.Client{Admin: api.isAdmin(r)}
+		if nickname, err := cleanNickname(r.Header.Get(NicknameHeader)); err == nil {
+			client.Nickname = nickname
+		} else if cookie, err := r.Cookie(nicknameCookie); err == nil {
+			client.Nickname, _ = cleanNickname(cookie.Value)
+		}
+
+		if cookie, err := r.Cookie(clientIDCookie); err == nil {
+			client.ID, _ = api.verifyClientID(cookie.Value)
+		}
+		if client.ID == "" {
+			var id [16]byte
+			if _, err := rand.Read(id[:]); err != nil {
+				WriteError(w, r, err)
+				return
+			}
+			client.ID = hex.EncodeToString(id[:])
+			setClientCookie(w, clientIDCookie, api.signClientID(client.ID))
+		}
+		client.PublicID = api.publicClientID(client.ID)
+		next.ServeHTTP(w, r.WithContext(jukebox.WithClient(r.Context(), client)))
+	})
+}
+
+func setClientCookie(w http.ResponseWriter, name, value string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   int(clientCookieAge / time.Second),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// cleanNickname trims the nickname and checks whether it is acceptable.
+func cleanNickname(nickname string) (string, error) {
+	nickname = strings.TrimSpace(nickname)
+	if nickname == "" {
+		return "", fmt.Errorf("empty nickname")
+	}
+	if len([]rune(nickname)) > maxNicknameLength {
+		return "", fmt.Errorf("nickname is longer than %d characters", maxNicknameLength)
+	}
+	for _, r := range nickname {
+		if unicode.IsControl(r) {
+			return "", fmt.Errorf("nickname contains control characters")
+		}
+	}
+	return nickname, nil
+}
+
+func (api *API) clientGet(w http.ResponseWriter, r *http.Request) {
+	client, _ := jukebox.ClientFromContext(r.Context())
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":       client.PublicID,
+		"nickname": client.Nickname,
+		"admin":    client.Admin,
+	})
+}
+
+// clientSetNickname sets or, if it is empty, clears the nickname of the
+// client.
+func (api *API) clientSetNickname(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		Nickname string `json:"nickname"`
+	}
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	client, _ := jukebox.ClientFromContext(r.Context())
+	if strings.TrimSpace(data.Nickname) == "" {
+		client.Nickname = ""
+		http.SetCookie(w, &http.Cookie{Name: nicknameCookie, Path: "/", MaxAge: -1})
+	} else {
+		nickname, err := cleanNickname(data.Nickname)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+		client.Nickname = nickname
+		setClientCookie(w, nicknameCookie, nickname)
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":       client.PublicID,
+		"nickname": client.Nickname,
+		"admin":    client.Admin,
+	})
+}
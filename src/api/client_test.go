@@ -0,0 +1,104 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
+	"testing"
+
+	"github.com/polyfloyd/trollibox/src/library"
+)
+
+func TestClientIdentity(t *testing.T) {
+	server, _, cleanup := newTestServer(t, library.Track{URI: "a"})
+	defer cleanup()
+
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar}
+	do := func(method, url, body string, v interface{}) {
+		t.Helper()
+		req, err := http.NewRequest(method, server.URL+url, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Unexpected status: %s", resp.Status)
+		}
+		if v != nil {
+			if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	var first, second struct {
+		ID       string `json:"id"`
+		Nickname string `json:"nickname"`
+	}
+	do("GET", "/client/", "", &first)
+	do("PUT", "/client/", `{"nickname":" DJ Bob "}`, &second)
+	if first.ID == "" || first.ID != second.ID {
+		t.Fatalf("The client ID is not stable: %q != %q", first.ID, second.ID)
+	}
+	if second.Nickname != "DJ Bob" {
+		t.Fatalf("Unexpected nickname: %q", second.Nickname)
+	}
+
+	do("PUT", "/player/dummy/playlist", `{"position":-1,"tracks":["a"]}`, nil)
+	var plist struct {
+		Tracks []struct {
+			QueuedBy     string `json:"queuedby"`
+			QueuedByName string `json:"queuedbyname"`
+		} `json:"tracks"`
+	}
+	do("GET", "/player/dummy/playlist", "", &plist)
+	if len(plist.Tracks) != 1 || plist.Tracks[0].QueuedBy != first.ID || plist.Tracks[0].QueuedByName != "DJ Bob" {
+		t.Fatalf("Unexpected playlist: %+v", plist)
+	}
+
+	// Clients without cookies can not be recognized, so anyone using the
+	// same nickname does not get the same ID.
+	ids := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest("GET", server.URL+"/client/", nil)
+		req.Header.Set(NicknameHeader, "Alice")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var c struct {
+			ID string `json:"id"`
+		}
+		json.NewDecoder(resp.Body).Decode(&c)
+		resp.Body.Close()
+		ids[c.ID] = true
+	}
+	if len(ids) != 2 {
+		t.Fatalf("The ID is derived from the nickname: %v", ids)
+	}
+
+	// Public IDs and cookies that are not signed by the server are not
+	// accepted as identities.
+	for _, forged := range []string{first.ID, first.ID + "." + first.ID} {
+		req, _ := http.NewRequest("GET", server.URL+"/client/", nil)
+		req.AddCookie(&http.Cookie{Name: "trollibox_client", Value: forged})
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var c struct {
+			ID string `json:"id"`
+		}
+		json.NewDecoder(resp.Body).Decode(&c)
+		resp.Body.Close()
+		if c.ID == first.ID {
+			t.Fatalf("A forged cookie was accepted: %q", forged)
+		}
+	}
+}
@@ -0,0 +1,529 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/polyfloyd/trollibox/src/filter"
+	"github.com/polyfloyd/trollibox/src/jukebox"
+	"github.com/polyfloyd/trollibox/src/library"
+	"github.com/polyfloyd/trollibox/src/player"
+	"github.com/polyfloyd/trollibox/src/util"
+)
+
+const (
+	// The maximum amount of time writing a single event to a client may take
+	// before the client is disconnected.
+	eventWriteTimeout = time.Second * 2
+	// The number of recent events that are kept to be replayed to clients
+	// that reconnect.
+	eventHistorySize = 100
+)
+
+// resyncEvent is sent to reconnecting clients that missed events which can no
+// longer be replayed. Clients should reload all state when receiving it.
+var resyncEvent = event{"resync", struct{}{}}
+
+// An event is a named message that is sent to event stream clients.
+type event struct {
+	name string
+	data interface{}
+}
+
+// A snapshotFunc produces events describing the current state of whatever is
+// being listened to. These are sent to new clients only.
+type snapshotFunc func(ctx context.Context, r *http.Request) ([]event, error)
+
+// mapEvent maps an event emitted by an util.Emitter to its wire format. False
+// is returned for events that are not sent to clients.
+func mapEvent(ev interface{}) (event, bool) {
+	// TODO: All these events should not all be combined in here.
+	switch t := ev.(type) {
+	case player.PlaylistEvent:
+		return event{"playlist", map[string]interface{}{
+			"index": t.Index,
+		}}, true
+	case player.PlayStateEvent:
+		return event{"playstate", map[string]interface{}{
+			"state": t.State,
+		}}, true
+	case player.TimeEvent:
+		at := t.At
+		if at.IsZero() {
+			at = time.Now()
+		}
+		return event{"time", map[string]interface{}{
+			"time": t.Time.Seconds(),
+			// The server time in milliseconds at which the offset was
+			// sampled, comparable with /time/now.
+			"at": unixMillis(at),
+		}}, true
+	case player.VolumeEvent:
+		return event{"volume", map[string]interface{}{
+			"volume": float32(t.Volume) / 100.0,
+		}}, true
+	case player.ListEvent:
+		return event{"list", struct{}{}}, true
+	case player.AvailabilityEvent:
+		data := map[string]interface{}{
+			"available": t.Available,
+		}
+		if !t.Available && t.Reason != "" {
+			data["reason"] = t.Reason
+		}
+		return event{"availability", data}, true
+	case player.QueueExhaustedEvent:
+		return event{"queue-exhausted", struct{}{}}, true
+	case player.TrackStartedEvent:
+		return event{"track-started", map[string]interface{}{
+			"uri": t.URI,
+		}}, true
+	case player.TrackMetadataEvent:
+		return event{"track-metadata", map[string]interface{}{
+			"uri":   t.URI,
+			"title": t.Title,
+		}}, true
+	case player.StreamReconnectEvent:
+		return event{"stream-reconnect", map[string]interface{}{
+			"uri":     t.URI,
+			"attempt": t.Attempt,
+			"max":     t.MaxAttempts,
+			"gaveup":  t.GaveUp,
+		}}, true
+	case jukebox.QueueLockEvent:
+		return event{"lock", map[string]interface{}{
+			"locked": t.Locked,
+			"by":     t.By,
+		}}, true
+	case jukebox.PartyModeEvent:
+		return event{"partymode", map[string]interface{}{
+			"enabled": t.Enabled,
+		}}, true
+	case jukebox.SkipVoteEvent:
+		return event{"skipvote", map[string]interface{}{
+			"votes":  t.Votes,
+			"needed": t.Needed,
+		}}, true
+	case jukebox.RestoreOfferEvent:
+		return event{"restore-offer", restoreOfferJSON(t)}, true
+	case jukebox.PinsEvent:
+		return event{"pins", map[string]interface{}{
+			"uris": t.URIs,
+		}}, true
+	case jukebox.SettingsEvent:
+		return event{"settings", map[string]interface{}{
+			"settings": t.Settings,
+		}}, true
+	case library.UpdateEvent:
+		return event{"library:tracks", struct{}{}}, true
+	case library.TrackArtEvent:
+		return event{"track-art-updated", map[string]interface{}{
+			"uri": t.URI,
+		}}, true
+	case filter.UpdateEvent:
+		return event{"filter:update", map[string]interface{}{
+			"filter": t.Filter,
+		}}, true
+	}
+	return event{}, false
+}
+
+// htEvents serves the events of the emitter as Server-Sent Events, or as a
+// stream of MessagePack maps.
+//
+// If snapshot is not nil, the events it returns are sent to each new client
+// before any emitted events. Other clients do not receive them.
+//
+// Clients may restrict the events they receive by listing their names in the
+// comma separated "events" query parameter.
+//
+// Clients may request MessagePack with "encoding=msgpack" or an Accept header
+// of application/msgpack. The response is then a chunked application/msgpack
+// body in which each event is a map holding its "event" name, "data" and
+// "id", which is left out like it is for Server-Sent Events.
+//
+// Clients that reconnect with a Last-Event-ID header are sent the events they
+// missed instead of the snapshot. If the missed events are no longer
+// available or the ID was issued by another process, a resync event is sent
+// followed by the snapshot.
+func (api *API) htEvents(emitter *util.Emitter, snapshot snapshotFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !api.beginEventStream() {
+			http.Error(w, "server is shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		defer api.eventStreams.Done()
+
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		// Listen before taking the snapshot so no events are missed in
+		// between. The listener is reclaimed when the handler exits, even if
+		// it does so abnormally.
+		hub := api.eventHub(emitter)
+		listener := hub.Subscribe(ctx.Done())
+		defer listener.Close()
+
+		var replay []*encodedEvent
+		resync, replaying := false, false
+		if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" {
+			if lastID, ok := parseEventID(lastEventID); ok {
+				replay, replaying = hub.since(lastID)
+			}
+			resync = !replaying
+		}
+
+		var initial []event
+		if resync {
+			initial = append(initial, resyncEvent)
+		}
+		if snapshot != nil && !replaying {
+			snapCtx, snapCancel := context.WithTimeout(ctx, api.timeout)
+			snap, err := snapshot(snapCtx, r)
+			snapCancel()
+			if err != nil {
+				WriteError(w, r, err)
+				return
+			}
+			initial = append(initial, snap...)
+		}
+
+		stream, err := openEventStream(w, r, requestedEventEncoding(r))
+		if err != nil {
+			log.Errorf("Could not open event stream for %s: %v", r.RemoteAddr, err)
+			return
+		}
+		if names := r.FormValue("events"); names != "" {
+			stream.only = map[string]bool{}
+			for _, name := range strings.Split(names, ",") {
+				stream.only[strings.TrimSpace(name)] = true
+			}
+		}
+
+		for _, ev := range initial {
+			frame, err := ev.encode(0)
+			if err != nil {
+				log.Error(err)
+				continue
+			}
+			if err := stream.send(frame); err != nil {
+				return
+			}
+		}
+		// Events may have been emitted after subscribing but before the
+		// replay was taken, these must not be sent twice.
+		lastSent := 0
+		for _, enc := range replay {
+			if err := stream.send(enc); err != nil {
+				return
+			}
+			lastSent = enc.id
+		}
+		for {
+			select {
+			case e, ok := <-listener.C:
+				if !ok {
+					return
+				}
+				enc := e.(*encodedEvent)
+				if enc.id <= lastSent {
+					continue
+				}
+				if err := stream.send(enc); err != nil {
+					log.Debugf("Disconnecting event stream client %s: %v", r.RemoteAddr, err)
+					return
+				}
+			case <-ctx.Done():
+				return
+			case <-api.closing:
+				return
+			}
+		}
+	})
+}
+
+// eventsByName creates a handler that serves the events of the emitter which
+// is looked up using the named URL parameter.
+func (api *API) eventsByName(param string, snapshot snapshotFunc, lookup func(context.Context, string) (*util.Emitter, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), api.timeout)
+		emitter, err := lookup(ctx, chi.URLParam(r, param))
+		cancel()
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+		api.htEvents(emitter, snapshot).ServeHTTP(w, r)
+	})
+}
+
+// The encodings of events.
+type eventEncoding int
+
+const (
+	// Server-Sent Events with JSON data.
+	encodingJSON eventEncoding = iota
+	// Consecutive MessagePack maps.
+	encodingMsgpack
+	numEventEncodings
+)
+
+// contentType returns the media type of a stream of events in the encoding.
+func (encoding eventEncoding) contentType() string {
+	if encoding == encodingMsgpack {
+		return "application/msgpack"
+	}
+	return "text/event-stream"
+}
+
+// requestedEventEncoding returns the encoding requested by the client, either
+// with the "encoding" query parameter or the Accept header.
+func requestedEventEncoding(r *http.Request) eventEncoding {
+	if r.FormValue("encoding") == "msgpack" {
+		return encodingMsgpack
+	}
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType := strings.TrimSpace(strings.SplitN(accept, ";", 2)[0])
+		if mediaType == "application/msgpack" || mediaType == "application/x-msgpack" {
+			return encodingMsgpack
+		}
+	}
+	return encodingJSON
+}
+
+// An encodedEvent is an event that is ready to be written to clients. The
+// JSON frame is encoded up front, other encodings are encoded from the data
+// once they are first needed.
+type encodedEvent struct {
+	id     int
+	name   string
+	data   interface{}
+	json   []byte
+	frames [numEventEncodings]struct {
+		once  sync.Once
+		frame []byte
+		err   error
+	}
+}
+
+// encode serializes the event into a Server-Sent Event frame. The id is
+// omitted if it is zero.
+func (ev event) encode(id int) (*encodedEvent, error) {
+	data, err := json.Marshal(ev.data)
+	if err != nil {
+		return nil, err
+	}
+	enc := &encodedEvent{id: id, name: ev.name, data: ev.data, json: data}
+	if _, err := enc.frame(encodingJSON); err != nil {
+		return nil, err
+	}
+	return enc, nil
+}
+
+// frame returns the event as it is written to streams of the specified
+// encoding.
+func (enc *encodedEvent) frame(encoding eventEncoding) ([]byte, error) {
+	f := &enc.frames[encoding]
+	f.once.Do(func() {
+		if encoding == encodingMsgpack {
+			f.frame, f.err = enc.msgpackFrame()
+			return
+		}
+		var buf bytes.Buffer
+		if enc.id != 0 {
+			fmt.Fprintf(&buf, "id: %s\n", formatEventID(enc.id))
+		}
+		fmt.Fprintf(&buf, "event: %s\ndata: %s\n\n", enc.name, enc.json)
+		f.frame = buf.Bytes()
+	})
+	return f.frame, f.err
+}
+
+func (enc *encodedEvent) msgpackFrame() ([]byte, error) {
+	frame := struct {
+		Data  interface{} `json:"data"`
+		Event string      `json:"event"`
+		ID    string      `json:"id,omitempty"`
+	}{Data: enc.data, Event: enc.name}
+	if enc.id != 0 {
+		frame.ID = formatEventID(enc.id)
+	}
+	return marshalMsgpack(frame)
+}
+
+// eventEpoch identifies this process in event IDs. Sequence numbers restart
+// with every process, so IDs issued by another process can not be replayed.
+var eventEpoch = strconv.FormatInt(time.Now().UnixNano(), 36)
+
+// formatEventID formats the sequence number of an event as the ID that is
+// sent to clients, "<epoch>-<seq>".
+func formatEventID(id int) string {
+	return eventEpoch + "-" + strconv.Itoa(id)
+}
+
+// parseEventID parses an ID formatted by formatEventID. False is returned if
+// the ID is malformed or was issued by another process.
+func parseEventID(s string) (int, bool) {
+	i := strings.LastIndexByte(s, '-')
+	if i < 0 || s[:i] != eventEpoch {
+		return 0, false
+	}
+	id, err := strconv.Atoi(s[i+1:])
+	if err != nil || id <= 0 {
+		return 0, false
+	}
+	return id, true
+}
+
+// An eventHub encodes the events of a single emitter once and redistributes
+// the results to all clients listening to that emitter.
+type eventHub struct {
+	// Emits *encodedEvent.
+	util.Emitter
+	listener *util.Listener
+	// Applied to events before they are mapped, see API.rewriteEvent.
+	rewrite func(interface{}) interface{}
+
+	// The most recent events, used to replay events to clients that
+	// reconnect.
+	history     []*encodedEvent
+	lastID      int
+	historyLock sync.Mutex
+}
+
+// eventHub returns the hub for the specified emitter, it is created if it does
+// not exist yet. Hubs live until the API is closed.
+func (api *API) eventHub(emitter *util.Emitter) *eventHub {
+	api.eventHubsLock.Lock()
+	defer api.eventHubsLock.Unlock()
+	if hub, ok := api.eventHubs[emitter]; ok {
+		return hub
+	}
+	hub := &eventHub{listener: emitter.Subscribe(api.closing), rewrite: api.rewriteEvent}
+	go hub.run()
+	if api.eventHubs == nil {
+		api.eventHubs = map[*util.Emitter]*eventHub{}
+	}
+	api.eventHubs[emitter] = hub
+	return hub
+}
+
+func (hub *eventHub) run() {
+	defer hub.Close()
+	for e := range hub.listener.C {
+		ev, ok := mapEvent(hub.rewrite(e))
+		if !ok {
+			log.Debugf("Unmapped event %#v", e)
+			continue
+		}
+		hub.historyLock.Lock()
+		enc, err := ev.encode(hub.lastID + 1)
+		if err != nil {
+			hub.historyLock.Unlock()
+			log.Error(err)
+			continue
+		}
+		hub.lastID = enc.id
+		if len(hub.history) == eventHistorySize {
+			copy(hub.history, hub.history[1:])
+			hub.history = hub.history[:eventHistorySize-1]
+		}
+		hub.history = append(hub.history, enc)
+		hub.historyLock.Unlock()
+		hub.Emit(enc)
+	}
+}
+
+// since returns the events emitted after the event with the specified id.
+// False is returned if some of those events are no longer available.
+func (hub *eventHub) since(id int) ([]*encodedEvent, bool) {
+	hub.historyLock.Lock()
+	defer hub.historyLock.Unlock()
+	if id > hub.lastID {
+		// The ID was never issued.
+		return nil, false
+	} else if id == hub.lastID {
+		return nil, true
+	}
+	if len(hub.history) == 0 || hub.history[0].id > id+1 {
+		return nil, false
+	}
+	var events []*encodedEvent
+	for _, enc := range hub.history {
+		if enc.id > id {
+			events = append(events, enc)
+		}
+	}
+	return events, true
+}
+
+// beginEventStream registers a new event stream. False is returned if the API
+// has been closed.
+func (api *API) beginEventStream() bool {
+	api.eventStreamsLock.Lock()
+	defer api.eventStreamsLock.Unlock()
+	select {
+	case <-api.closing:
+		return false
+	default:
+	}
+	api.eventStreams.Add(1)
+	return true
+}
+
+// An eventStream is a response streaming events to a single client.
+type eventStream struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+	// The names of the events the client is interested in, nil if the client
+	// wants all events.
+	only map[string]bool
+	// The encoding of the events.
+	encoding eventEncoding
+}
+
+// openEventStream writes the headers of an event stream.
+//
+// The write timeout of the server is lifted for the response, each write is
+// instead given eventWriteTimeout to complete.
+func openEventStream(w http.ResponseWriter, r *http.Request, encoding eventEncoding) (*eventStream, error) {
+	stream := &eventStream{w: w, rc: util.ResponseController(w, r), encoding: encoding}
+	if err := stream.rc.SetWriteDeadline(time.Now().Add(eventWriteTimeout)); err != nil {
+		return nil, fmt.Errorf("connection does not support event streams: %v", err)
+	}
+	w.Header().Set("Content-Type", encoding.contentType())
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := stream.rc.Flush(); err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
+
+func (stream *eventStream) send(ev *encodedEvent) error {
+	if stream.only != nil && !stream.only[ev.name] && ev.name != resyncEvent.name {
+		return nil
+	}
+	frame, err := ev.frame(stream.encoding)
+	if err != nil {
+		log.Errorf("Could not encode the %s event: %v", ev.name, err)
+		return nil
+	}
+	if err := stream.rc.SetWriteDeadline(time.Now().Add(eventWriteTimeout)); err != nil {
+		return err
+	}
+	if _, err := stream.w.Write(frame); err != nil {
+		return err
+	}
+	return stream.rc.Flush()
+}
@@ -9,6 +9,8 @@ import (
 	"github.com/polyfloyd/trollibox/src/filter"
 	"github.com/polyfloyd/trollibox/src/filter/keyed"
 	"github.com/polyfloyd/trollibox/src/filter/ruled"
+	"github.com/polyfloyd/trollibox/src/library"
+	"github.com/polyfloyd/trollibox/src/util"
 )
 
 func (api *API) filterList(w http.ResponseWriter, r *http.Request) {
@@ -73,18 +75,8 @@ func (api *API) filterSet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var filter filter.Filter
-	switch data.Filter.Type {
-	case "ruled":
-		filter = &ruled.RuleFilter{}
-	case "keyed":
-		filter = &keyed.Query{}
-	default:
-		WriteError(w, r, fmt.Errorf("unknown filter type %q", data.Filter.Type))
-		return
-	}
-
-	if err := json.Unmarshal([]byte(data.Filter.Value), filter); err != nil {
+	filter, err := decodeFilter(data.Filter.Type, data.Filter.Value)
+	if err != nil {
 		WriteError(w, r, err)
 		return
 	}
@@ -95,3 +87,83 @@ func (api *API) filterSet(w http.ResponseWriter, r *http.Request) {
 	}
 	w.Write([]byte("{}"))
 }
+
+// filterTest evaluates a filter against a single track without searching a
+// whole library. The filter is either stored under the name in the URL or
+// sent along in the request.
+func (api *API) filterTest(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		Filter *struct {
+			Type  string          `json:"type"`
+			Value json.RawMessage `json:"value"`
+		} `json:"filter"`
+		// The library or player to look the track up in.
+		Library string `json:"library"`
+		URI     string `json:"uri"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	var ft filter.Filter
+	var err error
+	if name := chi.URLParam(r, "name"); name != "" {
+		if ft, err = api.jukebox.FilterDB().Get(name); err == nil && ft == nil {
+			err = fmt.Errorf("no such filter: %q", name)
+		}
+	} else if data.Filter != nil {
+		ft, err = decodeFilter(data.Filter.Type, data.Filter.Value)
+	} else {
+		err = fmt.Errorf("no filter specified")
+	}
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	lib, err := api.jukebox.Library(r.Context(), data.Library)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	var tracks []library.Track
+	err = util.WithContext(r.Context(), func() (err error) {
+		tracks, err = lib.TrackInfo(api.internalURI(data.URI))
+		return
+	})
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	if len(tracks) == 0 || tracks[0].URI == "" {
+		WriteError(w, r, fmt.Errorf("no such track: %q", data.URI))
+		return
+	}
+
+	// The response has the same shape whether the track matches or not.
+	result, ok := ft.Filter(tracks[0])
+	if !ok {
+		result = filter.SearchResult{Track: tracks[0], Matches: map[string][]filter.SearchMatch{}}
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"match":  ok,
+		"result": api.searchResultJSON(result, 0),
+	})
+}
+
+func decodeFilter(typ string, value json.RawMessage) (filter.Filter, error) {
+	var ft filter.Filter
+	switch typ {
+	case "ruled":
+		ft = &ruled.RuleFilter{}
+	case "keyed":
+		ft = &keyed.Query{}
+	default:
+		return nil, fmt.Errorf("unknown filter type %q", typ)
+	}
+	if err := json.Unmarshal([]byte(value), ft); err != nil {
+		return nil, err
+	}
+	return ft, nil
+}
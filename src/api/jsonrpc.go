@@ -0,0 +1,273 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/polyfloyd/trollibox/src/jukebox"
+	"github.com/polyfloyd/trollibox/src/library"
+	"github.com/polyfloyd/trollibox/src/player"
+)
+
+// Error codes defined by the JSON-RPC 2.0 specification.
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcServerError    = -32000
+)
+
+type rpcRequest struct {
+	Version string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+	// The ID is nil for notifications, which are not answered.
+	ID json.RawMessage `json:"id"`
+}
+
+type rpcResponse struct {
+	Version string          `json:"jsonrpc"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (err *rpcError) Error() string {
+	return err.Message
+}
+
+// rpcMethods maps the names of JSON-RPC methods to their implementations. The
+// results share their format with the equivalent REST endpoints.
+var rpcMethods = map[string]func(api *API, ctx context.Context, params json.RawMessage) (interface{}, error){
+	"getState":    (*API).rpcGetState,
+	"setState":    (*API).rpcSetState,
+	"getVolume":   (*API).rpcGetVolume,
+	"setVolume":   (*API).rpcSetVolume,
+	"getPlaylist": (*API).rpcGetPlaylist,
+	"setPlaylist": (*API).rpcSetPlaylist,
+	"search":      (*API).rpcSearch,
+}
+
+// jsonRPC serves the JSON-RPC 2.0 interface. Batched calls are executed in
+// order.
+func (api *API) jsonRPC(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	var response interface{}
+	if body = bytes.TrimSpace(body); len(body) > 0 && body[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(body, &batch); err != nil {
+			response = rpcErrorResponse(nil, rpcParseError, err.Error())
+		} else if len(batch) == 0 {
+			response = rpcErrorResponse(nil, rpcInvalidRequest, "empty batch")
+		} else {
+			var responses []*rpcResponse
+			for _, call := range batch {
+				if res := api.rpcCall(r.Context(), call); res != nil {
+					responses = append(responses, res)
+				}
+			}
+			if responses != nil {
+				response = responses
+			}
+		}
+	} else if res := api.rpcCall(r.Context(), body); res != nil {
+		response = res
+	}
+
+	if response == nil {
+		// Only notifications were sent.
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// rpcCall executes a single call. Nil is returned for notifications.
+func (api *API) rpcCall(ctx context.Context, data json.RawMessage) *rpcResponse {
+	var req rpcRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		if _, ok := err.(*json.SyntaxError); ok {
+			return rpcErrorResponse(nil, rpcParseError, err.Error())
+		}
+		return rpcErrorResponse(nil, rpcInvalidRequest, err.Error())
+	}
+	if req.Version != "2.0" || req.Method == "" {
+		return rpcErrorResponse(req.ID, rpcInvalidRequest, "not a JSON-RPC 2.0 request")
+	}
+
+	var res *rpcResponse
+	if method, ok := rpcMethods[req.Method]; !ok {
+		res = rpcErrorResponse(req.ID, rpcMethodNotFound, fmt.Sprintf("no such method: %q", req.Method))
+	} else if result, err := method(api, ctx, req.Params); err != nil {
+		if rerr, ok := err.(*rpcError); ok {
+			res = rpcErrorResponse(req.ID, rerr.Code, rerr.Message)
+		} else {
+			res = rpcErrorResponse(req.ID, rpcServerError, err.Error())
+		}
+	} else {
+		if result == nil {
+			result = struct{}{}
+		}
+		res = &rpcResponse{Version: "2.0", Result: result, ID: req.ID}
+	}
+	if req.ID == nil {
+		return nil
+	}
+	return res
+}
+
+func rpcErrorResponse(id json.RawMessage, code int, message string) *rpcResponse {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return &rpcResponse{
+		Version: "2.0",
+		Error:   &rpcError{Code: code, Message: message},
+		ID:      id,
+	}
+}
+
+// rpcParams decodes the parameters of a call into v.
+func rpcParams(params json.RawMessage, v interface{}) error {
+	if len(params) == 0 {
+		return &rpcError{Code: rpcInvalidParams, Message: "missing params"}
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+type rpcPlayerParams struct {
+	Player string `json:"player"`
+}
+
+func (api *API) rpcGetState(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p rpcPlayerParams
+	if err := rpcParams(params, &p); err != nil {
+		return nil, err
+	}
+	playstate, err := api.jukebox.PlayerState(ctx, p.Player)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"playstate": playstate}, nil
+}
+
+func (api *API) rpcSetState(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p struct {
+		rpcPlayerParams
+		State string `json:"playstate"`
+	}
+	if err := rpcParams(params, &p); err != nil {
+		return nil, err
+	}
+	return nil, api.jukebox.SetPlayerState(ctx, p.Player, player.PlayState(p.State))
+}
+
+func (api *API) rpcGetVolume(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p rpcPlayerParams
+	if err := rpcParams(params, &p); err != nil {
+		return nil, err
+	}
+	volume, err := api.jukebox.PlayerVolume(ctx, p.Player)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"volume": float32(volume) / 100.0}, nil
+}
+
+func (api *API) rpcSetVolume(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p struct {
+		rpcPlayerParams
+		Volume float32 `json:"volume"`
+	}
+	if err := rpcParams(params, &p); err != nil {
+		return nil, err
+	}
+	return nil, api.jukebox.SetPlayerVolume(ctx, p.Player, int(p.Volume*100))
+}
+
+func (api *API) rpcGetPlaylist(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p struct {
+		rpcPlayerParams
+		QueuedBy string `json:"queuedby"`
+	}
+	if err := rpcParams(params, &p); err != nil {
+		return nil, err
+	}
+	return api.playlistJSON(ctx, p.Player, p.QueuedBy)
+}
+
+// rpcSetPlaylist replaces the contents of the playlist with the specified
+// tracks. Tracks that are already in the playlist are kept.
+//
+// If "dryrun" is set, the playlist is left as is and the operations that
+// would have been performed are returned instead.
+func (api *API) rpcSetPlaylist(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p struct {
+		rpcPlayerParams
+		Tracks []string `json:"tracks"`
+		DryRun bool     `json:"dryrun"`
+	}
+	if err := rpcParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	tracks := make([]library.Track, len(p.Tracks))
+	meta := make([]player.TrackMeta, len(p.Tracks))
+	for i, uri := range p.Tracks {
+		tracks[i].URI = api.internalURI(uri)
+		meta[i] = jukebox.UserTrackMeta(ctx)
+	}
+	ops, err := api.jukebox.SetPlaylist(ctx, p.Player, tracks, meta, p.DryRun)
+	if err != nil || !p.DryRun {
+		return nil, err
+	}
+	if ops == nil {
+		ops = []player.PlaylistOp{}
+	}
+	for i := range ops {
+		ops[i].URIs = api.externalURIs(ops[i].URIs)
+	}
+	return map[string]interface{}{"operations": ops}, nil
+}
+
+func (api *API) rpcSearch(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p struct {
+		Library  string `json:"library"`
+		Query    string `json:"query"`
+		Untagged string `json:"untagged"`
+		Snippet  int    `json:"snippet"`
+		Offset   int    `json:"offset"`
+		Limit    int    `json:"limit"`
+	}
+	if err := rpcParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Offset < 0 || p.Limit < 0 {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "the offset and limit can not be negative"}
+	}
+	results, err := api.jukebox.SearchTracks(ctx, p.Library, p.Query, strings.Split(p.Untagged, ","))
+	if err != nil {
+		return nil, err
+	}
+	return api.searchResultsJSON(results, p.Snippet, api.newPage(p.Offset, p.Limit)), nil
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+
+	"github.com/polyfloyd/trollibox/src/filter"
+	"github.com/polyfloyd/trollibox/src/jukebox"
+	"github.com/polyfloyd/trollibox/src/library/stream"
+	"github.com/polyfloyd/trollibox/src/player"
+)
+
+func TestJSONRPC(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trollibox-api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filterdb, err := filter.NewDB(path.Join(dir, "filters"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	streamdb, err := stream.NewDB(path.Join(dir, "streams"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	jb := jukebox.NewJukebox(player.SimpleList{}, nil, filterdb, streamdb, nil)
+	router := chi.NewRouter()
+	api := InitRouter(router, jb, 0)
+	defer api.Close()
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	call := func(body string) (int, string) {
+		t.Helper()
+		resp, err := http.Post(server.URL+"/jsonrpc", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		data, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, strings.TrimSpace(string(data))
+	}
+	errorCode := func(body string) int {
+		t.Helper()
+		var res struct {
+			Error *rpcError `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(body), &res); err != nil {
+			t.Fatal(err)
+		}
+		if res.Error == nil {
+			t.Fatalf("Expected an error: %s", body)
+		}
+		return res.Error.Code
+	}
+
+	if _, body := call(`{"jsonrpc":"2.0","method":"getState",`); errorCode(body) != rpcParseError {
+		t.Fatalf("Expected a parse error: %s", body)
+	}
+	if _, body := call(`{"jsonrpc":"1.0","method":"getState","id":1}`); errorCode(body) != rpcInvalidRequest {
+		t.Fatalf("Expected an invalid request error: %s", body)
+	}
+	if _, body := call(`{"jsonrpc":"2.0","method":"rewind","id":1}`); errorCode(body) != rpcMethodNotFound {
+		t.Fatalf("Expected a method not found error: %s", body)
+	}
+	if _, body := call(`{"jsonrpc":"2.0","method":"getState","params":{"player":3},"id":1}`); errorCode(body) != rpcInvalidParams {
+		t.Fatalf("Expected an invalid params error: %s", body)
+	}
+	if _, body := call(`[]`); errorCode(body) != rpcInvalidRequest {
+		t.Fatalf("Expected an invalid request error: %s", body)
+	}
+	if status, body := call(`{"jsonrpc":"2.0","method":"getState","params":{"player":"x"}}`); status != http.StatusNoContent || body != "" {
+		t.Fatalf("Notifications should not be answered: %d %q", status, body)
+	}
+
+	_, body := call(`[
+		{"jsonrpc":"2.0","method":"getState","params":{"player":"nope"},"id":"a"},
+		{"jsonrpc":"2.0","method":"setVolume","params":{"player":"nope","volume":0.5}},
+		{"jsonrpc":"2.0","method":"search","params":{"library":"nope","query":"x"},"id":2}
+	]`)
+	var batch []struct {
+		ID    json.RawMessage `json:"id"`
+		Error *rpcError       `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(body), &batch); err != nil {
+		t.Fatal(err)
+	}
+	if len(batch) != 2 {
+		t.Fatalf("Expected 2 responses, got %s", body)
+	}
+	if string(batch[0].ID) != `"a"` || string(batch[1].ID) != "2" {
+		t.Fatalf("Responses are out of order: %s", body)
+	}
+	for _, res := range batch {
+		if res.Error == nil || res.Error.Code != rpcServerError {
+			t.Fatalf("Expected a server error for an unknown player: %s", body)
+		}
+	}
+}
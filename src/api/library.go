@@ -0,0 +1,201 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi"
+
+	"github.com/polyfloyd/trollibox/src/filter"
+	"github.com/polyfloyd/trollibox/src/library"
+	"github.com/polyfloyd/trollibox/src/util"
+)
+
+func (api *API) libraryList(w http.ResponseWriter, r *http.Request) {
+	names, err := api.jukebox.Libraries(r.Context())
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"libraries": names,
+	})
+}
+
+func (api *API) libraryTracks(w http.ResponseWriter, r *http.Request) {
+	lib, err := api.jukebox.Library(r.Context(), chi.URLParam(r, "libraryName"))
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	api.writeTracks(w, r, lib)
+}
+
+func (api *API) libraryTrackSearch(w http.ResponseWriter, r *http.Request) {
+	untaggedFields := strings.Split(r.FormValue("untagged"), ",")
+	results, err := api.jukebox.SearchTracks(r.Context(), chi.URLParam(r, "libraryName"), r.FormValue("query"), untaggedFields)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	api.writeSearchResults(w, r, results)
+}
+
+func (api *API) libraryEvents() http.Handler {
+	return api.eventsByName("libraryName", nil, func(ctx context.Context, name string) (*util.Emitter, error) {
+		lib, err := api.jukebox.Library(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		return lib.Events(), nil
+	})
+}
+
+func (api *API) writeTracks(w http.ResponseWriter, r *http.Request, lib library.Library) {
+	pg, err := api.requestPage(r)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	tracks, err := lib.Tracks()
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	start, end := pg.bounds(len(tracks))
+	resp := map[string]interface{}{
+		"tracks": api.trackJSONList(tracks[start:end]),
+	}
+	pg.annotate(resp, len(tracks))
+	json.NewEncoder(w).Encode(resp)
+}
+
+// writeSearchResults responds with the search results. If the snippet form
+// value is set, a snippet of each matched property is included which extends
+// that number of bytes around the first match.
+func (api *API) writeSearchResults(w http.ResponseWriter, r *http.Request, results []filter.SearchResult) {
+	pg, err := api.requestPage(r)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	var snippetRadius int
+	if s := r.FormValue("snippet"); s != "" {
+		var err error
+		if snippetRadius, err = strconv.Atoi(s); err != nil || snippetRadius <= 0 {
+			WriteError(w, r, fmt.Errorf("invalid snippet radius: %q", s))
+			return
+		}
+	}
+	json.NewEncoder(w).Encode(api.searchResultsJSON(results, snippetRadius, pg))
+}
+
+func (api *API) searchResultsJSON(results []filter.SearchResult, snippetRadius int, pg page) interface{} {
+	start, end := pg.bounds(len(results))
+	mappedResults := make([]interface{}, 0, end-start)
+	for _, res := range results[start:end] {
+		mappedResults = append(mappedResults, api.searchResultJSON(res, snippetRadius))
+	}
+	resp := map[string]interface{}{
+		"tracks": mappedResults,
+	}
+	pg.annotate(resp, len(results))
+	return resp
+}
+
+// A page selects a range of a list of results.
+type page struct {
+	offset int
+	// The maximum number of results, 0 for no limit.
+	limit int
+	// Whether the limit was lowered to the maximum configured for the API.
+	clamped bool
+}
+
+// SetMaxResults limits the number of tracks that are listed by a single
+// request, regardless of the limit requested by the client. Zero, the
+// default, does not limit the number of tracks.
+func (api *API) SetMaxResults(max int) {
+	api.maxResultsLock.Lock()
+	defer api.maxResultsLock.Unlock()
+	api.maxResults = max
+}
+
+// newPage selects the results starting at offset, limited to limit results
+// and the maximum of the API.
+func (api *API) newPage(offset, limit int) page {
+	api.maxResultsLock.RLock()
+	max := api.maxResults
+	api.maxResultsLock.RUnlock()
+	pg := page{offset: offset, limit: limit}
+	if max > 0 && (limit <= 0 || limit > max) {
+		pg.limit, pg.clamped = max, true
+	}
+	return pg
+}
+
+// requestPage reads the page selected by the "offset" and "limit" parameters
+// of the request.
+func (api *API) requestPage(r *http.Request) (page, error) {
+	var offset, limit int
+	if s := r.FormValue("offset"); s != "" {
+		var err error
+		if offset, err = strconv.Atoi(s); err != nil || offset < 0 {
+			return page{}, fmt.Errorf("invalid offset: %q", s)
+		}
+	}
+	if s := r.FormValue("limit"); s != "" {
+		var err error
+		if limit, err = strconv.Atoi(s); err != nil || limit <= 0 {
+			return page{}, fmt.Errorf("invalid limit: %q", s)
+		}
+	}
+	return api.newPage(offset, limit), nil
+}
+
+// bounds returns the range of a list of n results that is selected.
+func (pg page) bounds(n int) (int, int) {
+	start := pg.offset
+	if start > n {
+		start = n
+	}
+	end := n
+	if pg.limit > 0 && start+pg.limit < end {
+		end = start + pg.limit
+	}
+	return start, end
+}
+
+// annotate adds the total number of results and the applied limit to a
+// response. Capped is set if results were left out because of the maximum of
+// the API rather than the limit requested by the client.
+func (pg page) annotate(resp map[string]interface{}, total int) {
+	resp["total"] = total
+	if pg.limit > 0 {
+		resp["limit"] = pg.limit
+	}
+	_, end := pg.bounds(total)
+	resp["capped"] = pg.clamped && end < total
+}
+
+func (api *API) searchResultJSON(res filter.SearchResult, snippetRadius int) interface{} {
+	result := map[string]interface{}{
+		"matches": res.Matches,
+		"track":   api.trackJSON(&res.Track, nil),
+	}
+	if best, ok := res.BestMatch(); ok {
+		result["bestmatch"] = best
+	}
+	if snippetRadius > 0 {
+		snippets := make(map[string]string, len(res.Matches))
+		for property := range res.Matches {
+			snippets[property] = res.Snippet(property, snippetRadius)
+		}
+		result["snippets"] = snippets
+	}
+	return result
+}
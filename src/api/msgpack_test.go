@@ -0,0 +1,81 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestJSONToMsgpack(t *testing.T) {
+	tests := []struct {
+		json     string
+		expected []byte
+	}{
+		{`null`, []byte{0xc0}},
+		{`true`, []byte{0xc3}},
+		{`5`, []byte{0x05}},
+		{`-3`, []byte{0xfd}},
+		{`200`, []byte{0xcc, 0xc8}},
+		{`-200`, []byte{0xd1, 0xff, 0x38}},
+		{`70000`, []byte{0xce, 0x00, 0x01, 0x11, 0x70}},
+		{`0.5`, []byte{0xcb, 0x3f, 0xe0, 0, 0, 0, 0, 0, 0}},
+		{`"abc"`, []byte{0xa3, 'a', 'b', 'c'}},
+		{`[1,2]`, []byte{0x92, 0x01, 0x02}},
+		{`{"b":1,"a":false}`, []byte{0x82, 0xa1, 'a', 0xc2, 0xa1, 'b', 0x01}},
+	}
+	for _, test := range tests {
+		packed, err := jsonToMsgpack([]byte(test.json))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(packed, test.expected) {
+			t.Errorf("Unexpected encoding of %s: % x", test.json, packed)
+		}
+	}
+
+	long := bytes.Repeat([]byte("x"), 40)
+	packed, err := jsonToMsgpack([]byte(`"` + string(long) + `"`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(packed, append([]byte{0xd9, 40}, long...)) {
+		t.Errorf("Unexpected encoding of a long string: % x", packed)
+	}
+}
+
+func TestMarshalMsgpack(t *testing.T) {
+	type embedded struct {
+		C bool `json:"c"`
+	}
+	value := struct {
+		embedded
+		A      string          `json:"a"`
+		B      []int           `json:"b,omitempty"`
+		Raw    json.RawMessage `json:"raw"`
+		Skip   int             `json:"-"`
+		hidden int
+		Bytes  []byte `json:"bytes"`
+		Float  float32
+	}{
+		embedded: embedded{C: true},
+		A:        "x",
+		Raw:      json.RawMessage(`{"n":1}`),
+		Bytes:    []byte{1, 2},
+		Float:    0.5,
+	}
+	packed, err := marshalMsgpack(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []byte{
+		0x85,
+		0xa1, 'c', 0xc3,
+		0xa1, 'a', 0xa1, 'x',
+		0xa3, 'r', 'a', 'w', 0x81, 0xa1, 'n', 0x01,
+		0xa5, 'b', 'y', 't', 'e', 's', 0xc4, 0x02, 0x01, 0x02,
+		0xa5, 'F', 'l', 'o', 'a', 't', 0xca, 0x3f, 0, 0, 0,
+	}
+	if !bytes.Equal(packed, expected) {
+		t.Fatalf("Unexpected encoding: % x", packed)
+	}
+}
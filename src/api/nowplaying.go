@@ -0,0 +1,61 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/polyfloyd/trollibox/src/player"
+)
+
+// nowPlaying serves the most recent snapshot of what is playing without
+// communicating with any player. This is intended for displays that poll
+// frequently.
+func (api *API) nowPlaying(w http.ResponseWriter, r *http.Request) {
+	np, ok := api.jukebox.NowPlaying()
+	if !ok {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"player": nil,
+			"state":  player.PlayStateStopped,
+			"time":   0,
+			"track":  nil,
+			"art":    nil,
+
+			"remaining":        0,
+			"remainingunknown": 0,
+		})
+		return
+	}
+	var art interface{}
+	if np.Track != nil && np.Track.HasArt {
+		art = "player/" + url.PathEscape(np.Player) + "/tracks/art?track=" + url.QueryEscape(api.externalURI(np.Track.URI))
+	}
+	now := time.Now()
+	remaining, unknown := np.Remaining(now)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"player": np.Player,
+		"state":  np.State,
+		"time":   np.Progress(now).Seconds(),
+		"track":  api.trackJSON(np.Track, np.Meta),
+		"art":    art,
+
+		// The remaining time of the current track and the queue. This is a
+		// lower bound if the duration of some tracks is unknown.
+		"remaining":        remaining.Seconds(),
+		"remainingunknown": unknown,
+	})
+}
+
+// timeNow serves the current time of the server in milliseconds. Clients can
+// use it to compensate for the difference between their clock and that of the
+// server when extrapolating the playback offset from time events.
+func (api *API) timeNow(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"now": unixMillis(time.Now()),
+	})
+}
+
+func unixMillis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi"
+)
+
+func (api *API) partyModeGet(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"enabled": api.jukebox.PartyMode(chi.URLParam(r, "playerName")),
+	})
+}
+
+// partyModeSet enables or disables party mode. Party mode can only be enabled
+// if an admin token is configured.
+func (api *API) partyModeSet(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		Enabled bool `json:"enabled"`
+	}
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	if data.Enabled && !api.hasAdminToken() {
+		WriteError(w, r, ErrNoAdminToken)
+		return
+	}
+
+	playerName := chi.URLParam(r, "playerName")
+	if err := api.jukebox.SetPartyMode(r.Context(), playerName, data.Enabled); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"enabled": api.jukebox.PartyMode(playerName),
+	})
+}
+
+// voteSkip casts a vote to skip the current track, see jukebox.VoteSkip.
+func (api *API) voteSkip(w http.ResponseWriter, r *http.Request) {
+	votes, needed, err := api.jukebox.VoteSkip(r.Context(), chi.URLParam(r, "playerName"))
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"votes":  votes,
+		"needed": needed,
+	})
+}
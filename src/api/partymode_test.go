@@ -0,0 +1,69 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/polyfloyd/trollibox/src/library"
+)
+
+func TestPartyMode(t *testing.T) {
+	server, api, jb, cleanup := newTestAPI(t, library.Track{URI: "a"}, library.Track{URI: "b"})
+	defer cleanup()
+
+	do := func(admin bool, method, url, body string, expected int) {
+		t.Helper()
+		req, err := http.NewRequest(method, server.URL+url, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set(NicknameHeader, "Guest")
+		if admin {
+			req.Header.Set(AdminTokenHeader, "secret")
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != expected {
+			t.Fatalf("%s %s (admin: %v): unexpected status: %s", method, url, admin, resp.Status)
+		}
+	}
+	insert := `{"position":-1,"tracks":["a","b"]}`
+	remove := `{"positions":[0]}`
+
+	// Without an admin token everyone is an admin, so party mode would
+	// have no effect.
+	do(false, "POST", "/player/dummy/partymode", `{"enabled":true}`, http.StatusConflict)
+	if jb.PartyMode("dummy") {
+		t.Fatalf("Party mode was enabled without an admin token")
+	}
+	api.SetAdminToken("secret")
+
+	do(false, "POST", "/player/dummy/partymode", `{"enabled":true}`, http.StatusForbidden)
+	do(true, "POST", "/player/dummy/partymode", `{"enabled":true}`, http.StatusOK)
+	if !jb.PartyMode("dummy") {
+		t.Fatalf("Party mode is not enabled")
+	}
+
+	do(false, "PUT", "/player/dummy/playlist", insert, http.StatusOK)
+	do(false, "POST", "/player/dummy/playstate", `{"playstate":"playing"}`, http.StatusOK)
+	do(false, "POST", "/player/dummy/next", "", http.StatusForbidden)
+	do(false, "POST", "/player/dummy/current", `{"current":1,"relative":true}`, http.StatusForbidden)
+	do(false, "POST", "/player/dummy/voteskip", "", http.StatusOK)
+	do(true, "POST", "/player/dummy/next", "", http.StatusOK)
+	do(false, "POST", "/player/dummy/playstate", `{"playstate":"stopped"}`, http.StatusForbidden)
+	do(false, "DELETE", "/player/dummy/playlist", remove, http.StatusForbidden)
+	do(true, "DELETE", "/player/dummy/playlist", remove, http.StatusOK)
+	do(true, "POST", "/player/dummy/playstate", `{"playstate":"stopped"}`, http.StatusOK)
+	do(false, "POST", "/player/dummy/pins", `{"uri":"b"}`, http.StatusForbidden)
+	do(true, "POST", "/player/dummy/pins", `{"uri":"b"}`, http.StatusOK)
+	do(false, "POST", "/player/dummy/playlist/priority", `{"position":1,"priority":200}`, http.StatusForbidden)
+
+	do(true, "POST", "/player/dummy/partymode", `{"enabled":false}`, http.StatusOK)
+	do(false, "DELETE", "/player/dummy/playlist", remove, http.StatusOK)
+	do(false, "POST", "/player/dummy/playstate", `{"playstate":"stopped"}`, http.StatusOK)
+	do(false, "POST", "/player/dummy/voteskip", "", http.StatusConflict)
+}
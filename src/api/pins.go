@@ -0,0 +1,51 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi"
+)
+
+// pinsList lists the URIs of the tracks that are pinned to the front of the
+// queue.
+func (api *API) pinsList(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"uris": api.externalURIs(api.jukebox.PinnedTracks(chi.URLParam(r, "playerName"))),
+	})
+}
+
+// pinsAdd pins a track to the front of the queue, inserting it if it is not
+// queued yet.
+func (api *API) pinsAdd(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		URI string `json:"uri"`
+	}
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	if err := api.jukebox.PinTrack(r.Context(), chi.URLParam(r, "playerName"), api.internalURI(data.URI)); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	w.Write([]byte("{}"))
+}
+
+// pinsRemove unpins a track, leaving it queued where it is.
+func (api *API) pinsRemove(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		URI string `json:"uri"`
+	}
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	if err := api.jukebox.UnpinTrack(r.Context(), chi.URLParam(r, "playerName"), api.internalURI(data.URI)); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	w.Write([]byte("{}"))
+}
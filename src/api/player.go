@@ -4,46 +4,74 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"path"
-	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/go-chi/chi"
 
-	"github.com/polyfloyd/trollibox/src/filter"
-	"github.com/polyfloyd/trollibox/src/filter/keyed"
 	"github.com/polyfloyd/trollibox/src/jukebox"
 	"github.com/polyfloyd/trollibox/src/library"
 	"github.com/polyfloyd/trollibox/src/player"
+	"github.com/polyfloyd/trollibox/src/util"
 )
 
 var httpCacheSince = time.Now()
 
 type playerContextType struct{}
 
-func trackJSON(tr *library.Track, meta *player.TrackMeta) interface{} {
+func (api *API) trackJSON(tr *library.Track, meta *player.TrackMeta) interface{} {
 	if tr == nil {
 		return nil
 	}
-	var struc struct {
-		URI         string `json:"uri"`
-		Artist      string `json:"artist,omitempty"`
-		Title       string `json:"title,omitempty"`
-		Genre       string `json:"genre,omitempty"`
-		Album       string `json:"album,omitempty"`
-		AlbumArtist string `json:"albumartist,omitempty"`
-		AlbumTrack  string `json:"albumtrack,omitempty"`
-		AlbumDisc   string `json:"albumdisc,omitempty"`
-		Duration    int    `json:"duration"`
-		HasArt      bool   `json:"hasart"`
-
-		QueuedBy string `json:"queuedby,omitempty"`
-	}
-	struc.URI = tr.URI
+	return api.newJSONTrack(tr, meta)
+}
+
+// jsonTrack is the wire format of a track.
+type jsonTrack struct {
+	URI         string  `json:"uri"`
+	Artist      string  `json:"artist,omitempty"`
+	Title       string  `json:"title,omitempty"`
+	Genre       string  `json:"genre,omitempty"`
+	Album       string  `json:"album,omitempty"`
+	AlbumArtist string  `json:"albumartist,omitempty"`
+	AlbumTrack  string  `json:"albumtrack,omitempty"`
+	AlbumDisc   string  `json:"albumdisc,omitempty"`
+	TrackNumber int     `json:"tracknumber,omitempty"`
+	TrackTotal  int     `json:"tracktotal,omitempty"`
+	DiscNumber  int     `json:"discnumber,omitempty"`
+	DiscTotal   int     `json:"disctotal,omitempty"`
+	Duration    float64 `json:"duration"`
+	HasArt      bool    `json:"hasart"`
+	IsStream    bool    `json:"isstream"`
+
+	ReplayGain     *float64 `json:"replaygain,omitempty"`
+	ReplayGainPeak *float64 `json:"replaygainpeak,omitempty"`
+	Rating         int      `json:"rating,omitempty"`
+	PlayCount      int      `json:"playcount,omitempty"`
+
+	Artists      []string `json:"artists,omitempty"`
+	Genres       []string `json:"genres,omitempty"`
+	AlbumArtists []string `json:"albumartists,omitempty"`
+
+	Source string            `json:"source,omitempty"`
+	Tags   map[string]string `json:"tags,omitempty"`
+
+	QueuedBy     string `json:"queuedby,omitempty"`
+	QueuedByName string `json:"queuedbyname,omitempty"`
+	// The priority of the entry in the playlist, see player.Prioritizer.
+	Priority int `json:"priority,omitempty"`
+}
+
+func (api *API) newJSONTrack(tr *library.Track, meta *player.TrackMeta) jsonTrack {
+	var struc jsonTrack
+	struc.URI = api.externalURI(tr.URI)
 	struc.Artist = tr.Artist
 	struc.Title = tr.Title
 	struc.Genre = tr.Genre
@@ -51,23 +79,44 @@ func trackJSON(tr *library.Track, meta *player.TrackMeta) interface{} {
 	struc.AlbumArtist = tr.AlbumArtist
 	struc.AlbumTrack = tr.AlbumTrack
 	struc.AlbumDisc = tr.AlbumDisc
-	struc.Duration = int(tr.Duration / time.Second)
+	struc.TrackNumber = tr.TrackNumber
+	struc.TrackTotal = tr.TrackTotal
+	struc.DiscNumber = tr.DiscNumber
+	struc.DiscTotal = tr.DiscTotal
+	struc.Duration = tr.Duration.Seconds()
 	struc.HasArt = tr.HasArt
+	struc.IsStream = tr.IsStream
+	struc.ReplayGain = tr.ReplayGain
+	struc.ReplayGainPeak = tr.ReplayGainPeak
+	struc.Rating = tr.Rating
+	struc.PlayCount = tr.PlayCount
+	struc.Artists = tr.Artists
+	struc.Genres = tr.Genres
+	struc.AlbumArtists = tr.AlbumArtists
+	struc.Source = tr.Source
+	struc.Tags = tr.Tags
 	if meta != nil {
 		struc.QueuedBy = meta.QueuedBy
+		struc.QueuedByName = meta.QueuedByName
+		if struc.Title == "" {
+			struc.Title = meta.Title
+		}
 	}
 	return struc
 }
 
-func trackJSONList(inList []library.Track) (outList []interface{}) {
+func (api *API) trackJSONList(inList []library.Track) (outList []interface{}) {
 	outList = make([]interface{}, len(inList))
 	for i, tr := range inList {
-		outList[i] = trackJSON(&tr, nil)
+		outList[i] = api.trackJSON(&tr, nil)
 	}
 	return
 }
 
-func plTrackJSONList(inList []library.Track, meta []player.TrackMeta, libs []library.Library, trackIndex int) ([]interface{}, error) {
+// plTrackJSONList describes the tracks of a playlist. The priorities of the
+// entries are included if not nil. The info of the tracks as found in the
+// libraries is also returned.
+func (api *API) plTrackJSONList(inList []library.Track, meta []player.TrackMeta, priorities []int, libs []library.Library, trackIndex int) ([]interface{}, []library.Track, error) {
 	outList := make([]interface{}, len(inList))
 	uris := make([]string, len(inList))
 	for i, tr := range inList {
@@ -75,7 +124,7 @@ func plTrackJSONList(inList []library.Track, meta []player.TrackMeta, libs []lib
 	}
 	tracks, err := library.AllTrackInfo(libs, uris...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if trackIndex >= 0 && trackIndex < len(inList) {
@@ -94,14 +143,43 @@ func plTrackJSONList(inList []library.Track, meta []player.TrackMeta, libs []lib
 	}
 
 	for i, tr := range tracks {
-		outList[i] = trackJSON(&tr, &meta[i])
+		track := api.newJSONTrack(&tr, &meta[i])
+		if i < len(priorities) {
+			track.Priority = priorities[i]
+		}
+		outList[i] = track
 	}
-	return outList, nil
+	return outList, tracks, nil
 }
 
 // API contains the state that is accessible over the Trollibox REST API.
 type API struct {
 	jukebox *jukebox.Jukebox
+	timeout time.Duration
+
+	thumbnails *util.LRU
+
+	placeholder     []byte
+	placeholderLock sync.Mutex
+
+	adminToken     string
+	adminTokenLock sync.RWMutex
+
+	clientKey     []byte
+	clientKeyLock sync.RWMutex
+
+	maxResults     int
+	maxResultsLock sync.RWMutex
+
+	uriRewriter     URIRewriter
+	uriRewriterLock sync.RWMutex
+
+	closing          chan struct{}
+	closeOnce        sync.Once
+	eventStreams     sync.WaitGroup
+	eventStreamsLock sync.Mutex
+	eventHubs        map[*util.Emitter]*eventHub
+	eventHubsLock    sync.Mutex
 }
 
 // Deprecated, use setCurrent instead.
@@ -131,9 +209,65 @@ func (api *API) playerSetCurrent(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("{}"))
 }
 
+func (api *API) playlistPlay(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		Index         int  `json:"index"`
+		DropPreceding bool `json:"droppreceding"`
+	}
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	if err := api.jukebox.PlayQueueIndex(r.Context(), chi.URLParam(r, "playerName"), data.Index, data.DropPreceding); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	w.Write([]byte("{}"))
+}
+
+func (api *API) playlistSetPriority(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		Pos      int `json:"position"`
+		Priority int `json:"priority"`
+	}
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	if err := api.jukebox.SetTrackPriority(r.Context(), chi.URLParam(r, "playerName"), data.Pos, data.Priority); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	w.Write([]byte("{}"))
+}
+
+func (api *API) playlistNextAlbum(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		DropPreceding bool `json:"droppreceding"`
+	}
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil && err != io.EOF {
+		WriteError(w, r, err)
+		return
+	}
+
+	index, err := api.jukebox.PlayNextAlbum(r.Context(), chi.URLParam(r, "playerName"), data.DropPreceding)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"index": index,
+	})
+}
+
 func (api *API) playerSetTime(w http.ResponseWriter, r *http.Request) {
 	var data struct {
-		Time int `json:"time"`
+		Time float64 `json:"time"`
 	}
 	defer r.Body.Close()
 	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
@@ -141,7 +275,7 @@ func (api *API) playerSetTime(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := api.jukebox.SetPlayerTime(r.Context(), chi.URLParam(r, "playerName"), time.Duration(data.Time)*time.Second); err != nil {
+	if err := api.jukebox.SetPlayerTime(r.Context(), chi.URLParam(r, "playerName"), time.Duration(data.Time*float64(time.Second))); err != nil {
 		WriteError(w, r, err)
 		return
 	}
@@ -155,7 +289,7 @@ func (api *API) playerGetTime(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"time": int(tim / time.Second),
+		"time": tim.Seconds(),
 	})
 }
 
@@ -215,53 +349,148 @@ func (api *API) playerSetVolume(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("{}"))
 }
 
-func (api *API) playlistContents(w http.ResponseWriter, r *http.Request) {
-	playerName := chi.URLParam(r, "playerName")
-	plist, err := api.jukebox.PlayerPlaylist(r.Context(), playerName)
+func (api *API) playerServerStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := api.jukebox.PlayerServerStats(r.Context(), chi.URLParam(r, "playerName"))
 	if err != nil {
 		WriteError(w, r, err)
 		return
 	}
-	tracks, err := plist.Tracks()
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"uptime":      int(stats.Uptime / time.Second),
+		"playtime":    int(stats.Playtime / time.Second),
+		"db_playtime": int(stats.DBPlaytime / time.Second),
+		"db_update":   stats.DBUpdate.Unix(),
+		"artists":     stats.Artists,
+		"albums":      stats.Albums,
+		"tracks":      stats.Tracks,
+	})
+}
+
+func (api *API) playerCapabilities(w http.ResponseWriter, r *http.Request) {
+	caps, err := api.jukebox.PlayerCapabilities(r.Context(), chi.URLParam(r, "playerName"))
 	if err != nil {
 		WriteError(w, r, err)
 		return
 	}
-	meta, err := plist.Meta()
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"capabilities": caps,
+	})
+}
+
+func (api *API) playerDebugConnections(w http.ResponseWriter, r *http.Request) {
+	stats, err := api.jukebox.PlayerPoolStats(r.Context(), chi.URLParam(r, "playerName"))
 	if err != nil {
 		WriteError(w, r, err)
 		return
 	}
-	trackIndex, err := api.jukebox.PlayerTrackIndex(r.Context(), playerName)
+	unixOrZero := func(t time.Time) int64 {
+		if t.IsZero() {
+			return 0
+		}
+		return t.Unix()
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"size":                  stats.Size,
+		"alive":                 stats.Alive,
+		"checked_out":           stats.CheckedOut,
+		"waiting":               stats.Waiting,
+		"timeouts":              stats.Timeouts,
+		"last_ping_error":       stats.LastPingError,
+		"last_ping_error_time":  unixOrZero(stats.LastPingErrorTime),
+		"events_connected":      stats.EventsConnected,
+		"events_connected_time": unixOrZero(stats.EventsConnectedTime),
+	})
+}
+
+func (api *API) playlistContents(w http.ResponseWriter, r *http.Request) {
+	contents, err := api.playlistJSON(r.Context(), chi.URLParam(r, "playerName"), r.FormValue("queuedby"))
 	if err != nil {
 		WriteError(w, r, err)
 		return
 	}
-	tim, err := api.jukebox.PlayerTime(r.Context(), playerName)
-	if err != nil {
+	if err := json.NewEncoder(w).Encode(contents); err != nil {
 		WriteError(w, r, err)
 		return
 	}
-	libs, err := api.jukebox.PlayerLibraries(r.Context(), playerName)
+}
+
+// playlistJSON describes the playlist of the named player along with the
+// current track and playback time.
+//
+// If queuedBy is not empty, only the tracks queued by that entity are
+// included. Their positions in the playlist are listed separately, the
+// current index keeps referring to the complete playlist.
+func (api *API) playlistJSON(ctx context.Context, playerName, queuedBy string) (map[string]interface{}, error) {
+	plist, err := api.jukebox.PlayerPlaylist(ctx, playerName)
 	if err != nil {
-		WriteError(w, r, err)
-		return
+		return nil, err
 	}
-	trJSON, err := plTrackJSONList(tracks, meta, libs, trackIndex)
+	var tracks []library.Track
+	var meta []player.TrackMeta
+	err = util.WithContext(ctx, func() (err error) {
+		if tracks, err = plist.Tracks(); err != nil {
+			return err
+		}
+		meta, err = plist.Meta()
+		return err
+	})
 	if err != nil {
-		WriteError(w, r, err)
-		return
+		return nil, err
 	}
-
-	err = json.NewEncoder(w).Encode(map[string]interface{}{
-		"time":    int(tim / time.Second),
+	trackIndex, err := api.jukebox.PlayerTrackIndex(ctx, playerName)
+	if err != nil {
+		return nil, err
+	}
+	if trackIndex < 0 || trackIndex >= len(tracks) {
+		// The playlist may have changed in between the calls, make sure the
+		// index always refers to a track in the response.
+		trackIndex = -1
+	}
+	tim, err := api.jukebox.PlayerTime(ctx, playerName)
+	if err != nil {
+		return nil, err
+	}
+	libs, err := api.jukebox.PlayerLibraries(ctx, playerName)
+	if err != nil {
+		return nil, err
+	}
+	priorities, err := api.jukebox.TrackPriorities(ctx, playerName)
+	if err == jukebox.ErrUnsupported {
+		priorities = nil
+	} else if err != nil {
+		return nil, err
+	} else if len(priorities) != len(tracks) {
+		// The playlist has changed in between the calls.
+		priorities = nil
+	}
+	trJSON, info, err := api.plTrackJSONList(tracks, meta, priorities, libs, trackIndex)
+	if err != nil {
+		return nil, err
+	}
+	// The playlist itself may not know the duration of tracks from other
+	// libraries, so the duration is computed from the info of the tracks.
+	remaining, unknown := player.QueueDuration(info, trackIndex, tim)
+	contents := map[string]interface{}{
+		"time":    tim.Seconds(),
 		"current": trackIndex,
 		"tracks":  trJSON,
-	})
-	if err != nil {
-		WriteError(w, r, err)
-		return
+
+		"remaining":        remaining.Seconds(),
+		"remainingunknown": unknown,
 	}
+	if queuedBy != "" {
+		filtered := []interface{}{}
+		positions := []int{}
+		for i, tr := range trJSON {
+			if meta[i].QueuedBy == queuedBy {
+				filtered = append(filtered, tr)
+				positions = append(positions, i)
+			}
+		}
+		contents["tracks"] = filtered
+		contents["positions"] = positions
+	}
+	return contents, nil
 }
 
 func (api *API) playlistInsert(w http.ResponseWriter, r *http.Request) {
@@ -269,6 +498,11 @@ func (api *API) playlistInsert(w http.ResponseWriter, r *http.Request) {
 	var data struct {
 		Pos    int      `json:"position"`
 		Tracks []string `json:"tracks"`
+		// An optional key generated by the client. Requests that repeat a
+		// recently used key are only performed once.
+		IdempotencyKey string `json:"idempotencykey"`
+		// Skip tracks that are already queued to be played.
+		IfAbsent bool `json:"ifabsent"`
 	}
 	defer r.Body.Close()
 	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
@@ -278,22 +512,22 @@ func (api *API) playlistInsert(w http.ResponseWriter, r *http.Request) {
 
 	tracks := make([]library.Track, len(data.Tracks))
 	for i, uri := range data.Tracks {
-		tracks[i].URI = uri
+		tracks[i].URI = api.internalURI(uri)
 	}
 	meta := make([]player.TrackMeta, len(data.Tracks))
 	for i := range data.Tracks {
-		meta[i].QueuedBy = "user"
+		meta[i] = jukebox.UserTrackMeta(r.Context())
 	}
-	plist, err := api.jukebox.PlayerPlaylist(r.Context(), playerName)
+	result, err := api.jukebox.InsertTracksOnce(r.Context(), playerName, data.IdempotencyKey, data.Pos, tracks, meta, data.IfAbsent)
 	if err != nil {
 		WriteError(w, r, err)
 		return
 	}
-	if err := plist.InsertWithMeta(data.Pos, tracks, meta); err != nil {
-		WriteError(w, r, err)
-		return
-	}
-	w.Write([]byte("{}"))
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"positions": result.Positions,
+		"ids":       result.IDs,
+		"existing":  result.Existing,
+	})
 }
 
 func (api *API) playlistMove(w http.ResponseWriter, r *http.Request) {
@@ -301,19 +535,19 @@ func (api *API) playlistMove(w http.ResponseWriter, r *http.Request) {
 	var data struct {
 		From int `json:"from"`
 		To   int `json:"to"`
+		// The number of consecutive tracks to move, defaults to 1.
+		Count int `json:"count"`
 	}
 	defer r.Body.Close()
 	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
 		WriteError(w, r, err)
 		return
 	}
-
-	plist, err := api.jukebox.PlayerPlaylist(r.Context(), playerName)
-	if err != nil {
-		WriteError(w, r, err)
-		return
+	if data.Count == 0 {
+		data.Count = 1
 	}
-	if err := plist.Move(data.From, data.To); err != nil {
+
+	if err := api.jukebox.MoveTracks(r.Context(), playerName, data.From, data.To, data.Count); err != nil {
 		WriteError(w, r, err)
 		return
 	}
@@ -324,106 +558,369 @@ func (api *API) playlistRemove(w http.ResponseWriter, r *http.Request) {
 	playerName := chi.URLParam(r, "playerName")
 	var data struct {
 		Positions []int `json:"positions"`
+		// URIs and IDs select tracks independently of their positions, which
+		// may have changed since the client last saw the playlist.
+		URIs []string `json:"uris"`
+		IDs  []string `json:"ids"`
 	}
 	defer r.Body.Close()
 	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
 		WriteError(w, r, err)
 		return
 	}
+	if len(data.URIs) == 0 && len(data.IDs) == 0 {
+		if err := api.jukebox.RemoveTracks(r.Context(), playerName, data.Positions); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+		w.Write([]byte("{}"))
+		return
+	}
 
-	plist, err := api.jukebox.PlayerPlaylist(r.Context(), playerName)
+	removed, err := api.jukebox.RemoveTracksSelectively(r.Context(), playerName, api.internalURIs(data.URIs), data.IDs)
 	if err != nil {
 		WriteError(w, r, err)
 		return
 	}
-	if err := plist.Remove(data.Positions...); err != nil {
-		WriteError(w, r, err)
-		return
+	removedJSON := make([]interface{}, len(removed))
+	for i, rm := range removed {
+		removedJSON[i] = map[string]interface{}{
+			"position": rm.Position,
+			"uri":      api.externalURI(rm.Track.URI),
+		}
 	}
-	w.Write([]byte("{}"))
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"removed": removedJSON,
+	})
 }
 
 func (api *API) playerTracks(w http.ResponseWriter, r *http.Request) {
-	playerName := chi.URLParam(r, "playerName")
-	lib, err := api.jukebox.PlayerLibrary(r.Context(), playerName)
+	lib, err := api.jukebox.PlayerLibrary(r.Context(), chi.URLParam(r, "playerName"))
 	if err != nil {
 		WriteError(w, r, err)
 		return
 	}
-	tracks, err := lib.Tracks()
+	api.writeTracks(w, r, lib)
+}
+
+// playerMostPlayed lists the tracks that were played most often along with
+// their play count. The number of tracks can be limited with the "limit"
+// parameter.
+func (api *API) playerMostPlayed(w http.ResponseWriter, r *http.Request) {
+	var limit int
+	if s := r.FormValue("limit"); s != "" {
+		var err error
+		if limit, err = strconv.Atoi(s); err != nil || limit <= 0 {
+			WriteError(w, r, fmt.Errorf("invalid limit: %q", s))
+			return
+		}
+	}
+	mostPlayed, err := api.jukebox.MostPlayed(r.Context(), chi.URLParam(r, "playerName"), limit)
 	if err != nil {
 		WriteError(w, r, err)
 		return
 	}
+	tracks := make([]interface{}, len(mostPlayed))
+	for i, pc := range mostPlayed {
+		tracks[i] = map[string]interface{}{
+			"track":     api.trackJSON(&pc.Track, nil),
+			"playcount": pc.Count,
+		}
+	}
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"tracks": trackJSONList(tracks),
+		"tracks": tracks,
 	})
 }
 
-func (api *API) playerTrackArt(w http.ResponseWriter, r *http.Request) {
-	playerName := chi.URLParam(r, "playerName")
-	uri := r.FormValue("track")
+// playerMostSkipped lists the tracks that were skipped most often along with
+// their skip and play count. The number of tracks can be limited with the
+// "limit" parameter.
+func (api *API) playerMostSkipped(w http.ResponseWriter, r *http.Request) {
+	var limit int
+	if s := r.FormValue("limit"); s != "" {
+		var err error
+		if limit, err = strconv.Atoi(s); err != nil || limit <= 0 {
+			WriteError(w, r, fmt.Errorf("invalid limit: %q", s))
+			return
+		}
+	}
+	mostSkipped, err := api.jukebox.MostSkipped(r.Context(), chi.URLParam(r, "playerName"), limit)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	tracks := make([]interface{}, len(mostSkipped))
+	for i, sc := range mostSkipped {
+		tracks[i] = map[string]interface{}{
+			"track":     api.trackJSON(&sc.Track, nil),
+			"skipcount": sc.Skips,
+			"playcount": sc.Plays,
+		}
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tracks": tracks,
+	})
+}
 
-	libs, err := api.jukebox.PlayerLibraries(r.Context(), playerName)
+func (api *API) playerTrending(w http.ResponseWriter, r *http.Request) {
+	var limit int
+	if s := r.FormValue("limit"); s != "" {
+		var err error
+		if limit, err = strconv.Atoi(s); err != nil || limit <= 0 {
+			WriteError(w, r, fmt.Errorf("invalid limit: %q", s))
+			return
+		}
+	}
+	trending, err := api.jukebox.Trending(r.Context(), chi.URLParam(r, "playerName"), limit)
 	if err != nil {
 		WriteError(w, r, err)
 		return
 	}
+	tracks := make([]interface{}, len(trending))
+	for i, tt := range trending {
+		tracks[i] = map[string]interface{}{
+			"track": api.trackJSON(&tt.Track, nil),
+			"score": tt.Score,
+		}
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tracks": tracks,
+	})
+}
 
-	var image io.ReadCloser
-	var mime string
-	for _, lib := range libs {
-		if image, mime = lib.TrackArt(uri); image != nil {
-			break
+// playerTrackArt serves the art of a track, scaled down to the optional
+// "size" parameter.
+//
+// For tracks without art, the "placeholder" parameter selects what is served
+// instead: "none" responds with a 404 Not Found, which is the default, "true"
+// serves a generic placeholder image and "generated" serves an image showing
+// the initials of the artist. Placeholders are marked by the X-Art-Placeholder header and must be
+// revalidated by caches, since the track may gain art later.
+func (api *API) playerTrackArt(w http.ResponseWriter, r *http.Request) {
+	uri := api.internalURI(r.FormValue("track"))
+	var size int
+	if sizeStr := r.FormValue("size"); sizeStr != "" {
+		var err error
+		if size, err = strconv.Atoi(sizeStr); err != nil {
+			WriteError(w, r, err)
+			return
 		}
 	}
-	if image == nil {
-		http.NotFound(w, r)
+	placeholder := r.FormValue("placeholder")
+	switch placeholder {
+	case "", placeholderNone, placeholderGeneric, placeholderGenerated:
+	default:
+		WriteError(w, r, fmt.Errorf("invalid placeholder: %q", placeholder))
 		return
 	}
-	defer image.Close()
 
+	playerName := chi.URLParam(r, "playerName")
+	data, mime, err := api.trackArt(r.Context(), playerName, uri)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	modTime := httpCacheSince
+	if data == nil {
+		if placeholder == "" || placeholder == placeholderNone {
+			http.NotFound(w, r)
+			return
+		}
+		var track library.Track
+		if libs, err := api.jukebox.PlayerLibraries(r.Context(), playerName); err == nil {
+			if tracks, err := library.AllTrackInfo(libs, uri); err == nil {
+				track = tracks[0]
+			}
+		}
+		track.URI = uri
+		if data, mime, err = api.placeholderArt(placeholder, track, size); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+		// Omitting the modification time prevents Not Modified responses
+		// from hiding art that was added later.
+		modTime = time.Time{}
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Art-Placeholder", placeholder)
+	}
+	if size != 0 {
+		if data, mime, err = api.thumbnail(data, size); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+	}
 	w.Header().Set("Content-Type", mime)
-	var buf bytes.Buffer
-	// Copy to a buffer so seeking is supported.
-	io.Copy(&buf, image)
-	http.ServeContent(w, r, path.Base(uri), httpCacheSince, bytes.NewReader(buf.Bytes()))
+	http.ServeContent(w, r, path.Base(uri), modTime, bytes.NewReader(data))
 }
 
-func (api *API) playerTrackSearch(w http.ResponseWriter, r *http.Request) {
-	playerName := chi.URLParam(r, "playerName")
-	lib, err := api.jukebox.PlayerLibrary(r.Context(), playerName)
+// trackArt reads the art of a track from the first library of the player
+// that has it. Nil is returned if there is no art.
+func (api *API) trackArt(ctx context.Context, playerName, uri string) ([]byte, string, error) {
+	libs, err := api.jukebox.PlayerLibraries(ctx, playerName)
 	if err != nil {
+		return nil, "", err
+	}
+	for _, lib := range libs {
+		image, mime := lib.TrackArt(uri)
+		if image == nil {
+			continue
+		}
+		defer image.Close()
+		// Read into memory so seeking is supported.
+		data, err := ioutil.ReadAll(image)
+		if err != nil {
+			return nil, "", err
+		}
+		return data, mime, nil
+	}
+	return nil, "", nil
+}
+
+// playerTrackArtPalette responds with the dominant colors of the art of a
+// track, which can be used for theming. A neutral color is returned for tracks
+// without art. Like for the art itself, the track is selected with the
+// "track" parameter.
+func (api *API) playerTrackArtPalette(w http.ResponseWriter, r *http.Request) {
+	uri := api.internalURI(r.FormValue("track"))
+	data, _, err := api.trackArt(r.Context(), chi.URLParam(r, "playerName"), uri)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	colors := defaultPalette
+	if data != nil {
+		if colors, err = api.palette(data); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"colors": colors,
+	})
+}
+
+// The maximum number of tracks of which the art status is reported by a
+// single response. The status of more tracks is paginated with the "offset"
+// and "limit" parameters.
+const maxArtStatusTracks = 1000
+
+// playerTrackArtStatus reports which of the tracks of which the URIs are
+// POSTed have art.
+func (api *API) playerTrackArtStatus(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		Tracks []string `json:"tracks"`
+	}
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
 		WriteError(w, r, err)
 		return
 	}
-	tracks, err := lib.Tracks()
+	pg, err := api.requestPage(r)
 	if err != nil {
 		WriteError(w, r, err)
 		return
 	}
+	if pg.limit <= 0 || pg.limit > maxArtStatusTracks {
+		pg.limit, pg.clamped = maxArtStatusTracks, true
+	}
+	start, end := pg.bounds(len(data.Tracks))
+	pageTracks := data.Tracks[start:end]
 
-	untaggedFields := strings.Split(r.FormValue("untagged"), ",")
-	compiledQuery, err := keyed.CompileQuery(r.FormValue("query"), untaggedFields)
+	libs, err := api.jukebox.PlayerLibraries(r.Context(), chi.URLParam(r, "playerName"))
 	if err != nil {
 		WriteError(w, r, err)
 		return
 	}
-	wults := filter.Tracks(compiledQuery, tracks)
-	sort.Sort(filter.ByNumMatches(wults))
+	hasArt := make(map[string]bool, len(pageTracks))
+	for _, uri := range pageTracks {
+		hasArt[uri] = false
+	}
+	uris := api.internalURIs(pageTracks)
+	err = util.WithContext(r.Context(), func() error {
+		// Art may be provided by any of the libraries, not just the one that
+		// has precedence for the other track info.
+		for _, lib := range libs {
+			tracks, err := lib.TrackInfo(uris...)
+			if err != nil {
+				return err
+			}
+			for i, track := range tracks {
+				if track.HasArt {
+					hasArt[pageTracks[i]] = true
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	resp := map[string]interface{}{
+		"hasart": hasArt,
+	}
+	pg.annotate(resp, len(data.Tracks))
+	json.NewEncoder(w).Encode(resp)
+}
 
-	mappedResults := make([]interface{}, len(wults))
-	for i, w := range wults {
-		mappedResults[i] = map[string]interface{}{
-			"matches": w.Matches,
-			"track":   trackJSON(&w.Track, nil),
+// The maximum number of tracks of which the info can be requested at once.
+const maxTrackInfoTracks = 10000
+
+// playerTrackInfo responds with the info of the tracks of which the URIs are
+// POSTed as a JSON array. The tracks are listed in the same order, tracks that
+// could not be found are null. Unlike listings, the info includes the
+// ReplayGain of tracks of players that read it from files.
+func (api *API) playerTrackInfo(w http.ResponseWriter, r *http.Request) {
+	var uris []string
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&uris); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	if len(uris) > maxTrackInfoTracks {
+		WriteError(w, r, fmt.Errorf("too many tracks, at most %d may be requested at once", maxTrackInfoTracks))
+		return
+	}
+
+	libs, err := api.jukebox.PlayerLibraries(r.Context(), chi.URLParam(r, "playerName"))
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	var tracks []library.Track
+	err = util.WithContext(r.Context(), func() (err error) {
+		tracks, err = library.AllTrackInfo(libs, api.internalURIs(uris)...)
+		return
+	})
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	if err := api.jukebox.ReadReplayGain(r.Context(), chi.URLParam(r, "playerName"), tracks); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	list := make([]interface{}, len(tracks))
+	for i := range tracks {
+		if tracks[i].URI != "" {
+			list[i] = api.trackJSON(&tracks[i], nil)
 		}
 	}
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"tracks": mappedResults,
+		"tracks": list,
 	})
 }
 
+func (api *API) playerTrackSearch(w http.ResponseWriter, r *http.Request) {
+	untaggedFields := strings.Split(r.FormValue("untagged"), ",")
+	results, err := api.jukebox.SearchTracks(r.Context(), chi.URLParam(r, "playerName"), r.FormValue("query"), untaggedFields)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	api.writeSearchResults(w, r, results)
+}
+
 func (api *API) rawTrackAdd(w http.ResponseWriter, r *http.Request) {
 	playerName := chi.URLParam(r, "playerName")
 
@@ -454,7 +951,8 @@ func (api *API) netTrackAdd(w http.ResponseWriter, r *http.Request) {
 	playerName := chi.URLParam(r, "playerName")
 
 	var data struct {
-		URL string `json:"url"`
+		URL   string `json:"url"`
+		Title string `json:"title"`
 	}
 	defer r.Body.Close()
 	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
@@ -462,33 +960,94 @@ func (api *API) netTrackAdd(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := api.jukebox.AppendNetFile(r.Context(), playerName, data.URL); err != nil {
+	if err := api.jukebox.AppendNetFile(r.Context(), playerName, data.URL, data.Title); err != nil {
 		WriteError(w, r, err)
 		return
 	}
 	w.Write([]byte("{}"))
 }
 
-func (api *API) playerEvents() http.Handler {
-	var eventSourcesLock sync.Mutex
-	eventSources := map[string]http.Handler{}
+func (api *API) silenceAdd(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		// The duration of the silence in seconds.
+		Duration float64 `json:"duration"`
+	}
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		WriteError(w, r, err)
+		return
+	}
 
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		playerName := chi.URLParam(r, "playerName")
+	duration := time.Duration(data.Duration * float64(time.Second))
+	if err := api.jukebox.AppendSilence(r.Context(), chi.URLParam(r, "playerName"), duration); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	w.Write([]byte("{}"))
+}
 
-		eventSourcesLock.Lock()
-		ev, ok := eventSources[playerName]
-		if !ok {
-			emitter, err := api.jukebox.PlayerEvents(context.Background(), playerName)
-			if err != nil {
-				WriteError(w, r, err)
-				return
-			}
-			ev = htEvents(emitter)
-			eventSources[playerName] = ev
-		}
-		eventSourcesLock.Unlock()
+func (api *API) playerEvents() http.Handler {
+	return api.eventsByName("playerName", api.playerSnapshot, api.jukebox.PlayerEvents)
+}
 
-		ev.ServeHTTP(w, r)
+// playerSnapshot describes the current state of a player as events.
+func (api *API) playerSnapshot(ctx context.Context, r *http.Request) ([]event, error) {
+	name := chi.URLParam(r, "playerName")
+	index, err := api.jukebox.PlayerTrackIndex(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	plist, err := api.jukebox.PlayerPlaylist(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	var length int
+	err = util.WithContext(ctx, func() (err error) {
+		length, err = plist.Len()
+		return
 	})
+	if err != nil {
+		return nil, err
+	}
+	state, err := api.jukebox.PlayerState(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	timAt := time.Now()
+	tim, err := api.jukebox.PlayerTime(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	volume, err := api.jukebox.PlayerVolume(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	locked, lockedBy := api.jukebox.QueueLock(name)
+
+	playlist, _ := mapEvent(player.PlaylistEvent{Index: index})
+	playlist.data.(map[string]interface{})["length"] = length
+	events := []event{playlist}
+	for _, e := range []interface{}{
+		player.PlayStateEvent{State: state},
+		player.TimeEvent{Time: tim, At: timAt},
+		player.VolumeEvent{Volume: volume},
+		jukebox.QueueLockEvent{Locked: locked, By: lockedBy},
+		jukebox.PartyModeEvent{Enabled: api.jukebox.PartyMode(name)},
+		jukebox.PinsEvent{URIs: api.jukebox.PinnedTracks(name)},
+	} {
+		ev, _ := mapEvent(api.rewriteEvent(e))
+		events = append(events, ev)
+	}
+	if state, ok := api.jukebox.RestoreOffer(name); ok {
+		ev, _ := mapEvent(jukebox.RestoreOfferEvent{Available: true, Saved: state.Time})
+		events = append(events, ev)
+	}
+	if settings, err := api.jukebox.PlayerSettings(ctx, name); err == nil {
+		ev, _ := mapEvent(jukebox.SettingsEvent{Settings: settings})
+		events = append(events, ev)
+	} else if err != jukebox.ErrUnsupported {
+		return nil, err
+	}
+	return events, nil
 }
@@ -0,0 +1,570 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+
+	"github.com/polyfloyd/trollibox/src/filter"
+	"github.com/polyfloyd/trollibox/src/filter/keyed"
+	"github.com/polyfloyd/trollibox/src/jukebox"
+	"github.com/polyfloyd/trollibox/src/library"
+	"github.com/polyfloyd/trollibox/src/library/raw"
+	"github.com/polyfloyd/trollibox/src/library/stream"
+	"github.com/polyfloyd/trollibox/src/player"
+)
+
+// newTestServer serves the API for a jukebox with a single dummy player named
+// "dummy".
+func newTestServer(t *testing.T, tracks ...library.Track) (*httptest.Server, *jukebox.Jukebox, func()) {
+	t.Helper()
+	server, _, jb, cleanup := newTestAPI(t, tracks...)
+	return server, jb, cleanup
+}
+
+// newTestAPI is like newTestServer, but also returns the API so it can be
+// configured.
+func newTestAPI(t *testing.T, tracks ...library.Track) (*httptest.Server, *API, *jukebox.Jukebox, func()) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "trollibox-api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	filterdb, err := filter.NewDB(path.Join(dir, "filters"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	streamdb, err := stream.NewDB(path.Join(dir, "streams"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dummy := player.NewDummyPlayer(tracks...)
+	players := player.SimpleList{"dummy": dummy}
+	jb := jukebox.NewJukebox(players, nil, filterdb, streamdb, raw.NewServer("http://localhost/data/raw"))
+	router := chi.NewRouter()
+	api := InitRouter(router, jb, 0)
+	server := httptest.NewServer(router)
+	return server, api, jb, func() {
+		server.Close()
+		api.Close()
+		dummy.Events().Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func getJSON(t *testing.T, url string, v interface{}) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		t.Fatalf("Unexpected status %d: %s", resp.StatusCode, body)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPlaylistQueuedByFilter(t *testing.T) {
+	tracks := []library.Track{{URI: "a"}, {URI: "b"}, {URI: "c"}}
+	server, jb, cleanup := newTestServer(t, tracks...)
+	defer cleanup()
+
+	meta := []player.TrackMeta{{QueuedBy: "user"}, {QueuedBy: "system"}, {QueuedBy: "user"}}
+	if err := jb.InsertTracks(context.Background(), "dummy", -1, tracks, meta); err != nil {
+		t.Fatal(err)
+	}
+
+	var all struct {
+		Tracks []struct {
+			URI      string `json:"uri"`
+			QueuedBy string `json:"queuedby"`
+		} `json:"tracks"`
+	}
+	getJSON(t, server.URL+"/player/dummy/playlist", &all)
+	if len(all.Tracks) != 3 || all.Tracks[1].QueuedBy != "system" {
+		t.Fatalf("Unexpected playlist: %+v", all)
+	}
+
+	var filtered struct {
+		Tracks []struct {
+			URI string `json:"uri"`
+		} `json:"tracks"`
+		Positions []int `json:"positions"`
+	}
+	getJSON(t, server.URL+"/player/dummy/playlist?queuedby=user", &filtered)
+	if len(filtered.Tracks) != 2 || filtered.Tracks[0].URI != "a" || filtered.Tracks[1].URI != "c" {
+		t.Fatalf("Unexpected tracks: %+v", filtered.Tracks)
+	}
+	if len(filtered.Positions) != 2 || filtered.Positions[0] != 0 || filtered.Positions[1] != 2 {
+		t.Fatalf("Unexpected positions: %v", filtered.Positions)
+	}
+}
+
+func TestPlaylistMetaTitle(t *testing.T) {
+	tracks := []library.Track{{URI: "http://radio/stream"}, {URI: "b", Title: "Own Title"}}
+	server, jb, cleanup := newTestServer(t, tracks...)
+	defer cleanup()
+
+	meta := []player.TrackMeta{{QueuedBy: "user", Title: "Radio"}, {QueuedBy: "user", Title: "Other"}}
+	if err := jb.InsertTracks(context.Background(), "dummy", -1, tracks, meta); err != nil {
+		t.Fatal(err)
+	}
+
+	var plist struct {
+		Tracks []struct {
+			Title string `json:"title"`
+		} `json:"tracks"`
+	}
+	getJSON(t, server.URL+"/player/dummy/playlist", &plist)
+	if len(plist.Tracks) != 2 || plist.Tracks[0].Title != "Radio" || plist.Tracks[1].Title != "Own Title" {
+		t.Fatalf("Unexpected playlist: %+v", plist)
+	}
+}
+
+func TestPlaylistInsertIdempotencyKey(t *testing.T) {
+	tracks := []library.Track{{URI: "a"}, {URI: "b"}}
+	server, jb, cleanup := newTestServer(t, tracks...)
+	defer cleanup()
+
+	// Keys are scoped to a client, so the cookie should be retained.
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar}
+	insert := func(body string, expectedPos int) {
+		t.Helper()
+		req, _ := http.NewRequest("PUT", server.URL+"/player/dummy/playlist", strings.NewReader(body))
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Unexpected status: %s", resp.Status)
+		}
+		var result struct {
+			Positions []int `json:"positions"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(result.Positions, []int{expectedPos}) {
+			t.Fatalf("Unexpected positions: %v", result.Positions)
+		}
+	}
+	req := func(uri, key string) string {
+		return `{"position":-1,"tracks":["` + uri + `"],"idempotencykey":"` + key + `"}`
+	}
+	insert(req("a", "1"), 0)
+	// Duplicates report the result of the original request.
+	insert(req("a", "1"), 0)
+	insert(req("b", "2"), 1)
+	insert(req("b", ""), 2)
+	insert(req("b", ""), 3)
+
+	plist, err := jb.PlayerPlaylist(context.Background(), "dummy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := plist.Len(); n != 4 {
+		t.Fatalf("Expected 4 tracks, got %d", n)
+	}
+}
+
+func TestPlaylistInsertIfAbsent(t *testing.T) {
+	tracks := []library.Track{{URI: "a"}, {URI: "b"}, {URI: "c"}, {URI: "d"}}
+	server, _, cleanup := newTestServer(t, tracks...)
+	defer cleanup()
+
+	type insertResult struct {
+		Positions []int `json:"positions"`
+		Existing  []int `json:"existing"`
+	}
+	insert := func(body string) insertResult {
+		t.Helper()
+		req, _ := http.NewRequest("PUT", server.URL+"/player/dummy/playlist", strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Unexpected status: %s", resp.Status)
+		}
+		var result insertResult
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	insert(`{"position":-1,"tracks":["a","b"]}`)
+	result := insert(`{"position":-1,"tracks":["b","c"],"ifabsent":true}`)
+	if !reflect.DeepEqual(result, insertResult{Positions: []int{2}, Existing: []int{1}}) {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+	result = insert(`{"position":-1,"tracks":["a"],"ifabsent":true}`)
+	if !reflect.DeepEqual(result, insertResult{Existing: []int{0}}) {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+	// Without the flag, duplicates are inserted as usual.
+	result = insert(`{"position":-1,"tracks":["a"]}`)
+	if !reflect.DeepEqual(result, insertResult{Positions: []int{3}}) {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+	// Queued tracks after the insertion point have moved and tracks that are
+	// requested twice are inserted once.
+	result = insert(`{"position":0,"tracks":["d","c","d"],"ifabsent":true}`)
+	if !reflect.DeepEqual(result, insertResult{Positions: []int{0}, Existing: []int{3, 0}}) {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+}
+
+func TestTrackArtPaletteDefault(t *testing.T) {
+	server, _, cleanup := newTestServer(t, library.Track{URI: "a"})
+	defer cleanup()
+
+	var palette struct {
+		Colors []string `json:"colors"`
+	}
+	getJSON(t, server.URL+"/player/dummy/tracks/art/palette?track=a", &palette)
+	if !reflect.DeepEqual(palette.Colors, defaultPalette) {
+		t.Fatalf("Unexpected palette: %v", palette.Colors)
+	}
+}
+
+func TestPlayerCapabilities(t *testing.T) {
+	server, _, cleanup := newTestServer(t)
+	defer cleanup()
+
+	var resp struct {
+		Capabilities map[string]bool `json:"capabilities"`
+	}
+	getJSON(t, server.URL+"/player/dummy/capabilities", &resp)
+	if has, ok := resp.Capabilities[player.CapabilityStats]; !ok || has {
+		t.Fatalf("The dummy player does not report stats: %v", resp.Capabilities)
+	}
+	if !resp.Capabilities[player.CapabilityRangeMove] {
+		t.Fatalf("The dummy playlist moves ranges: %v", resp.Capabilities)
+	}
+}
+
+func TestTrackInfo(t *testing.T) {
+	tracks := []library.Track{{URI: "a", Title: "A"}, {URI: "b", Title: "B"}}
+	server, _, cleanup := newTestServer(t, tracks...)
+	defer cleanup()
+
+	type trackInfo struct {
+		URI   string `json:"uri"`
+		Title string `json:"title"`
+	}
+	post := func(body string) (int, []*trackInfo) {
+		t.Helper()
+		resp, err := http.Post(server.URL+"/player/dummy/tracks/info", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var result struct {
+			Tracks []*trackInfo `json:"tracks"`
+		}
+		if resp.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return resp.StatusCode, result.Tracks
+	}
+
+	status, info := post(`["b", "nonexistent", "a"]`)
+	if status != http.StatusOK {
+		t.Fatalf("Unexpected status: %d", status)
+	}
+	if len(info) != 3 || info[0] == nil || info[0].Title != "B" || info[1] != nil || info[2] == nil || info[2].Title != "A" {
+		t.Fatalf("Unexpected tracks: %v", info)
+	}
+	if status, _ := post(`{"uri": "a"}`); status != http.StatusBadRequest {
+		t.Fatalf("Unexpected status for an invalid body: %d", status)
+	}
+}
+
+func TestTrackArtStatusPagination(t *testing.T) {
+	tracks := []library.Track{{URI: "a", HasArt: true}, {URI: "b"}, {URI: "c", HasArt: true}}
+	server, _, cleanup := newTestServer(t, tracks...)
+	defer cleanup()
+
+	body := `{"tracks": ["a", "b", "c"]}`
+	resp, err := http.Post(server.URL+"/player/dummy/tracks/art/status?offset=1&limit=1", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var result struct {
+		HasArt map[string]bool `json:"hasart"`
+		Total  int             `json:"total"`
+		Limit  int             `json:"limit"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.HasArt) != 1 || result.HasArt["b"] {
+		t.Fatalf("Unexpected art status: %v", result.HasArt)
+	}
+	if result.Total != 3 || result.Limit != 1 {
+		t.Fatalf("Unexpected page: %+v", result)
+	}
+}
+
+func TestTracksPagination(t *testing.T) {
+	tracks := []library.Track{{URI: "a"}, {URI: "b"}, {URI: "c"}}
+	server, _, cleanup := newTestServer(t, tracks...)
+	defer cleanup()
+
+	var resp struct {
+		Tracks []struct {
+			URI string `json:"uri"`
+		} `json:"tracks"`
+		Total  int  `json:"total"`
+		Limit  int  `json:"limit"`
+		Capped bool `json:"capped"`
+	}
+	getJSON(t, server.URL+"/player/dummy/tracks?offset=1&limit=1", &resp)
+	if len(resp.Tracks) != 1 || resp.Tracks[0].URI != "b" {
+		t.Fatalf("Unexpected tracks: %v", resp.Tracks)
+	}
+	if resp.Total != 3 || resp.Limit != 1 || resp.Capped {
+		t.Fatalf("Unexpected page: %+v", resp)
+	}
+}
+
+func TestMaxResults(t *testing.T) {
+	api := &API{}
+	api.SetMaxResults(10)
+
+	tests := []struct {
+		limit, expectLimit int
+		total              int
+		capped             bool
+	}{
+		{limit: 0, expectLimit: 10, total: 5, capped: false},
+		{limit: 0, expectLimit: 10, total: 20, capped: true},
+		{limit: 5, expectLimit: 5, total: 20, capped: false},
+		{limit: 1000000, expectLimit: 10, total: 20, capped: true},
+	}
+	for _, test := range tests {
+		resp := map[string]interface{}{}
+		api.newPage(0, test.limit).annotate(resp, test.total)
+		if resp["limit"] != test.expectLimit || resp["capped"] != test.capped {
+			t.Fatalf("Unexpected page for limit %d of %d results: %v", test.limit, test.total, resp)
+		}
+	}
+}
+
+func TestNowPlaying(t *testing.T) {
+	tracks := []library.Track{
+		{URI: "a", Title: "A", HasArt: true, Duration: time.Minute},
+		{URI: "b", Title: "B", Duration: time.Minute * 2},
+		{URI: "http://radio", IsStream: true},
+	}
+	server, jb, cleanup := newTestServer(t, tracks...)
+	defer cleanup()
+
+	type nowPlaying struct {
+		Player *string `json:"player"`
+		State  string  `json:"state"`
+		Track  *struct {
+			URI string `json:"uri"`
+		} `json:"track"`
+		Art *string `json:"art"`
+
+		Remaining        float64 `json:"remaining"`
+		RemainingUnknown int     `json:"remainingunknown"`
+	}
+	var np nowPlaying
+	getJSON(t, server.URL+"/nowplaying", &np)
+	if np.Player != nil || np.State != "stopped" || np.Track != nil {
+		t.Fatalf("Unexpected response without watched players: %+v", np)
+	}
+
+	if err := jb.WatchNowPlaying("dummy"); err != nil {
+		t.Fatal(err)
+	}
+	// Clients queue tracks by their URI, the durations should be looked up
+	// in the library.
+	queued := make([]library.Track, len(tracks))
+	for i, track := range tracks {
+		queued[i].URI = track.URI
+	}
+	if err := jb.InsertTracks(context.Background(), "dummy", -1, queued, make([]player.TrackMeta, len(tracks))); err != nil {
+		t.Fatal(err)
+	}
+	if err := jb.SetPlayerState(context.Background(), "dummy", player.PlayStatePlaying); err != nil {
+		t.Fatal(err)
+	}
+
+	// The snapshot is updated asynchronously.
+	for i := 0; ; i++ {
+		np = nowPlaying{}
+		getJSON(t, server.URL+"/nowplaying", &np)
+		if np.State == "playing" && np.Track != nil {
+			break
+		}
+		if i == 100 {
+			t.Fatalf("The snapshot was not updated: %+v", np)
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	if *np.Player != "dummy" || np.Track.URI != "a" {
+		t.Fatalf("Unexpected now playing: %+v", np)
+	}
+	if np.Art == nil || *np.Art != "player/dummy/tracks/art?track=a" {
+		t.Fatalf("Unexpected art URL: %v", np.Art)
+	}
+	if np.Remaining <= 170 || np.Remaining > 180 || np.RemainingUnknown != 1 {
+		t.Fatalf("Unexpected remaining time: %v, %d unknown", np.Remaining, np.RemainingUnknown)
+	}
+
+	var plist struct {
+		Remaining        float64 `json:"remaining"`
+		RemainingUnknown int     `json:"remainingunknown"`
+	}
+	getJSON(t, server.URL+"/player/dummy/playlist", &plist)
+	if plist.Remaining <= 170 || plist.Remaining > 180 || plist.RemainingUnknown != 1 {
+		t.Fatalf("Unexpected remaining time of the playlist: %v, %d unknown", plist.Remaining, plist.RemainingUnknown)
+	}
+}
+
+func TestTimeNow(t *testing.T) {
+	server, _, cleanup := newTestServer(t)
+	defer cleanup()
+
+	var clock struct {
+		Now int64 `json:"now"`
+	}
+	before := unixMillis(time.Now())
+	getJSON(t, server.URL+"/time/now", &clock)
+	if after := unixMillis(time.Now()); clock.Now < before || clock.Now > after {
+		t.Fatalf("Unexpected server time: %d, expected between %d and %d", clock.Now, before, after)
+	}
+}
+
+func TestPlaylistRemoveByURI(t *testing.T) {
+	tracks := []library.Track{{URI: "a"}, {URI: "b"}, {URI: "c"}}
+	server, jb, cleanup := newTestServer(t, tracks...)
+	defer cleanup()
+	if err := jb.InsertTracks(context.Background(), "dummy", -1, tracks, make([]player.TrackMeta, len(tracks))); err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest("DELETE", server.URL+"/player/dummy/playlist", strings.NewReader(`{"uris":["c","x"]}`))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Unexpected status: %s", resp.Status)
+	}
+	var result struct {
+		Removed []struct {
+			Position int    `json:"position"`
+			URI      string `json:"uri"`
+		} `json:"removed"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Removed) != 1 || result.Removed[0].Position != 2 || result.Removed[0].URI != "c" {
+		t.Fatalf("Unexpected removed tracks: %+v", result.Removed)
+	}
+	plist, err := jb.PlayerPlaylist(context.Background(), "dummy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := plist.Len(); n != 2 {
+		t.Fatalf("Expected 2 tracks, got %d", n)
+	}
+}
+
+func TestFilterTest(t *testing.T) {
+	server, jb, cleanup := newTestServer(t, library.Track{URI: "a", Artist: "Foo", Title: "Bar"})
+	defer cleanup()
+
+	query, err := keyed.CompileQuery("artist:foo", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := jb.FilterDB().Set("test", query); err != nil {
+		t.Fatal(err)
+	}
+
+	type testResult struct {
+		Match  bool `json:"match"`
+		Result struct {
+			Matches map[string][]filter.SearchMatch `json:"matches"`
+			Track   struct {
+				URI string `json:"uri"`
+			} `json:"track"`
+		} `json:"result"`
+	}
+	test := func(path, body string) testResult {
+		t.Helper()
+		resp, err := http.Post(server.URL+path, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			body, _ := ioutil.ReadAll(resp.Body)
+			t.Fatalf("Unexpected status %d: %s", resp.StatusCode, body)
+		}
+		var result testResult
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	if res := test("/filters/test/test", `{"library":"dummy","uri":"a"}`); !res.Match || len(res.Result.Matches["artist"]) != 1 {
+		t.Fatalf("Unexpected result: %+v", res)
+	}
+	inline := `{"filter":{"type":"keyed","value":{"query":"title:baz"}},"library":"dummy","uri":"a"}`
+	if res := test("/filters/test", inline); res.Match || res.Result.Matches == nil || res.Result.Track.URI == "" {
+		t.Fatalf("Unexpected result: %+v", res)
+	}
+
+	// A filter named like the stateless route should still be accessible.
+	var stored map[string]interface{}
+	getJSON(t, server.URL+"/filters/test/", &stored)
+}
+
+func TestJSONTrackDuration(t *testing.T) {
+	api := &API{}
+	track := library.Track{URI: "a", Duration: time.Millisecond * 60512}
+	data, err := json.Marshal(api.newJSONTrack(&track, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Duration float64 `json:"duration"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Duration != 60.512 {
+		t.Fatalf("The duration lost its precision: %s", data)
+	}
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi"
+)
+
+func (api *API) queueLockGet(w http.ResponseWriter, r *http.Request) {
+	locked, by := api.jukebox.QueueLock(chi.URLParam(r, "playerName"))
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"locked": locked,
+		"by":     by,
+	})
+}
+
+// queueLockSet locks or unlocks the queue.
+func (api *API) queueLockSet(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		Locked bool `json:"locked"`
+	}
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	playerName := chi.URLParam(r, "playerName")
+	if err := api.jukebox.SetQueueLock(r.Context(), playerName, data.Locked); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	locked, by := api.jukebox.QueueLock(playerName)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"locked": locked,
+		"by":     by,
+	})
+}
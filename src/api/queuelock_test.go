@@ -0,0 +1,54 @@
+package api
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
+	"testing"
+
+	"github.com/polyfloyd/trollibox/src/library"
+)
+
+func TestQueueLock(t *testing.T) {
+	server, jb, cleanup := newTestServer(t, library.Track{URI: "a"})
+	defer cleanup()
+
+	clients := map[string]*http.Client{}
+	for _, name := range []string{"Host", "Guest"} {
+		jar, _ := cookiejar.New(nil)
+		clients[name] = &http.Client{Jar: jar}
+	}
+	do := func(nickname, method, url, body string, expected int) {
+		t.Helper()
+		req, err := http.NewRequest(method, server.URL+url, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set(NicknameHeader, nickname)
+		resp, err := clients[nickname].Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != expected {
+			t.Fatalf("%s %s by %s: unexpected status: %s", method, url, nickname, resp.Status)
+		}
+	}
+	insert := `{"position":-1,"tracks":["a"]}`
+
+	// Obtain the identities before locking.
+	do("Host", "GET", "/client/", "", http.StatusOK)
+	do("Guest", "GET", "/client/", "", http.StatusOK)
+
+	do("Host", "POST", "/player/dummy/lock", `{"locked":true}`, http.StatusOK)
+	if locked, _ := jb.QueueLock("dummy"); !locked {
+		t.Fatalf("The queue is not locked")
+	}
+	do("Guest", "PUT", "/player/dummy/playlist", insert, http.StatusLocked)
+	do("Guest", "POST", "/player/dummy/lock", `{"locked":false}`, http.StatusLocked)
+	do("Guest", "GET", "/player/dummy/playlist", "", http.StatusOK)
+	do("Host", "PUT", "/player/dummy/playlist", insert, http.StatusOK)
+
+	do("Host", "POST", "/player/dummy/lock", `{"locked":false}`, http.StatusOK)
+	do("Guest", "PUT", "/player/dummy/playlist", insert, http.StatusOK)
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi"
+
+	"github.com/polyfloyd/trollibox/src/jukebox"
+)
+
+func restoreOfferJSON(offer jukebox.RestoreOfferEvent) map[string]interface{} {
+	data := map[string]interface{}{
+		"available": offer.Available,
+	}
+	if offer.Available {
+		data["saved"] = unixMillis(offer.Saved)
+	}
+	return data
+}
+
+// restoreOffer reports whether there is a queue that was saved on the last
+// shutdown which can be restored.
+func (api *API) restoreOffer(w http.ResponseWriter, r *http.Request) {
+	state, ok := api.jukebox.RestoreOffer(chi.URLParam(r, "playerName"))
+	json.NewEncoder(w).Encode(restoreOfferJSON(jukebox.RestoreOfferEvent{Available: ok, Saved: state.Time}))
+}
+
+// restore accepts or declines the offer to restore the queue saved on the
+// last shutdown.
+func (api *API) restore(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		Restore bool `json:"restore"`
+	}
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	if err := api.jukebox.Restore(r.Context(), chi.URLParam(r, "playerName"), data.Restore); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	w.Write([]byte("{}"))
+}
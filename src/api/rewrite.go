@@ -0,0 +1,132 @@
+package api
+
+import (
+	"strings"
+
+	"github.com/polyfloyd/trollibox/src/jukebox"
+	"github.com/polyfloyd/trollibox/src/library"
+	"github.com/polyfloyd/trollibox/src/player"
+)
+
+// A URIRewriter translates the URIs of tracks between the form known by the
+// players and the form reported to clients. This allows tracks to be referred
+// to by URIs that are reachable by clients, like the URL of a proxy that
+// serves the files of MPD.
+type URIRewriter interface {
+	// External maps the URI of a track as known by the players to the URI
+	// reported to clients.
+	External(uri string) string
+	// Internal maps a URI submitted by a client back to the URI known by the
+	// players. It is the inverse of External.
+	Internal(uri string) string
+}
+
+// A PrefixRule maps URIs that start with the Internal prefix to URIs that
+// start with the External prefix.
+type PrefixRule struct {
+	Internal string
+	External string
+}
+
+// PrefixRewriter is a URIRewriter that replaces the prefixes of URIs. The
+// first rule that matches is applied, URIs that match no rule are left as is.
+type PrefixRewriter []PrefixRule
+
+// External implements the URIRewriter interface.
+func (rw PrefixRewriter) External(uri string) string {
+	for _, rule := range rw {
+		if strings.HasPrefix(uri, rule.Internal) {
+			return rule.External + uri[len(rule.Internal):]
+		}
+	}
+	return uri
+}
+
+// Internal implements the URIRewriter interface.
+func (rw PrefixRewriter) Internal(uri string) string {
+	for _, rule := range rw {
+		if strings.HasPrefix(uri, rule.External) {
+			return rule.Internal + uri[len(rule.External):]
+		}
+	}
+	return uri
+}
+
+// SetURIRewriter configures how the URIs of tracks are translated for
+// clients. A nil rewriter leaves URIs as is.
+func (api *API) SetURIRewriter(rw URIRewriter) {
+	api.uriRewriterLock.Lock()
+	defer api.uriRewriterLock.Unlock()
+	api.uriRewriter = rw
+}
+
+func (api *API) rewriter() URIRewriter {
+	api.uriRewriterLock.RLock()
+	defer api.uriRewriterLock.RUnlock()
+	return api.uriRewriter
+}
+
+// externalURI maps the URI of a track to the form reported to clients.
+func (api *API) externalURI(uri string) string {
+	if rw := api.rewriter(); rw != nil && uri != "" {
+		return rw.External(uri)
+	}
+	return uri
+}
+
+// internalURI maps a URI submitted by a client to the form known by the
+// players.
+func (api *API) internalURI(uri string) string {
+	if rw := api.rewriter(); rw != nil && uri != "" {
+		return rw.Internal(uri)
+	}
+	return uri
+}
+
+func (api *API) externalURIs(uris []string) []string {
+	if api.rewriter() == nil {
+		return uris
+	}
+	out := make([]string, len(uris))
+	for i, uri := range uris {
+		out[i] = api.externalURI(uri)
+	}
+	return out
+}
+
+func (api *API) internalURIs(uris []string) []string {
+	if api.rewriter() == nil {
+		return uris
+	}
+	out := make([]string, len(uris))
+	for i, uri := range uris {
+		out[i] = api.internalURI(uri)
+	}
+	return out
+}
+
+// rewriteEvent maps the URIs of tracks in an event to the form reported to
+// clients.
+func (api *API) rewriteEvent(ev interface{}) interface{} {
+	if api.rewriter() == nil {
+		return ev
+	}
+	switch t := ev.(type) {
+	case player.TrackStartedEvent:
+		t.URI = api.externalURI(t.URI)
+		return t
+	case player.TrackMetadataEvent:
+		t.URI = api.externalURI(t.URI)
+		return t
+	case player.StreamReconnectEvent:
+		t.URI = api.externalURI(t.URI)
+		return t
+	case jukebox.PinsEvent:
+		t.URIs = api.externalURIs(t.URIs)
+		return t
+	case library.TrackArtEvent:
+		t.URI = api.externalURI(t.URI)
+		return t
+	}
+	return ev
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/polyfloyd/trollibox/src/library"
+)
+
+func TestPrefixRewriter(t *testing.T) {
+	rw := PrefixRewriter{
+		{Internal: "mpd://music/", External: "https://proxy/music/"},
+		{Internal: "mpd://", External: "https://proxy/other/"},
+	}
+	tests := []struct {
+		internal, external string
+	}{
+		{"mpd://music/a.mp3", "https://proxy/music/a.mp3"},
+		{"mpd://podcasts/b.mp3", "https://proxy/other/podcasts/b.mp3"},
+		{"http://radio/stream", "http://radio/stream"},
+	}
+	for _, test := range tests {
+		if uri := rw.External(test.internal); uri != test.external {
+			t.Errorf("Unexpected external URI for %q: %q", test.internal, uri)
+		}
+		if uri := rw.Internal(test.external); uri != test.internal {
+			t.Errorf("Unexpected internal URI for %q: %q", test.external, uri)
+		}
+	}
+}
+
+func TestURIRewrite(t *testing.T) {
+	server, api, jb, cleanup := newTestAPI(t, library.Track{URI: "mpd://a.mp3", Title: "A"})
+	defer cleanup()
+	api.SetURIRewriter(PrefixRewriter{{Internal: "mpd://", External: "https://proxy/"}})
+
+	var tracks struct {
+		Tracks []struct {
+			URI string `json:"uri"`
+		} `json:"tracks"`
+	}
+	getJSON(t, server.URL+"/player/dummy/tracks", &tracks)
+	if len(tracks.Tracks) != 1 || tracks.Tracks[0].URI != "https://proxy/a.mp3" {
+		t.Fatalf("Unexpected tracks: %+v", tracks.Tracks)
+	}
+
+	req, _ := http.NewRequest("PUT", server.URL+"/player/dummy/playlist", strings.NewReader(`{"position":-1,"tracks":["https://proxy/a.mp3"]}`))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Unexpected status: %d", resp.StatusCode)
+	}
+	plist, err := jb.PlayerPlaylist(context.Background(), "dummy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	queued, err := plist.Tracks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queued) != 1 || queued[0].URI != "mpd://a.mp3" {
+		t.Fatalf("The URI was not mapped back: %+v", queued)
+	}
+
+	getJSON(t, server.URL+"/player/dummy/playlist", &tracks)
+	if len(tracks.Tracks) != 1 || tracks.Tracks[0].URI != "https://proxy/a.mp3" {
+		t.Fatalf("Unexpected playlist: %+v", tracks.Tracks)
+	}
+}
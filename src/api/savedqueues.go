@@ -0,0 +1,54 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi"
+)
+
+func (api *API) savedQueueList(w http.ResponseWriter, r *http.Request) {
+	names, err := api.jukebox.SavedQueues(r.Context(), chi.URLParam(r, "playerName"))
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"savedqueues": names,
+	})
+}
+
+// savedQueueSave saves the playlist. The body may optionally specify whether
+// the playback position is saved as well.
+func (api *API) savedQueueSave(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		Position bool `json:"position"`
+	}
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil && err != io.EOF {
+		WriteError(w, r, err)
+		return
+	}
+	if err := api.jukebox.SaveQueue(r.Context(), chi.URLParam(r, "playerName"), chi.URLParam(r, "name"), data.Position); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	w.Write([]byte("{}"))
+}
+
+func (api *API) savedQueueLoad(w http.ResponseWriter, r *http.Request) {
+	if err := api.jukebox.LoadQueue(r.Context(), chi.URLParam(r, "playerName"), chi.URLParam(r, "name")); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	w.Write([]byte("{}"))
+}
+
+func (api *API) savedQueueRemove(w http.ResponseWriter, r *http.Request) {
+	if err := api.jukebox.RemoveSavedQueue(r.Context(), chi.URLParam(r, "playerName"), chi.URLParam(r, "name")); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	w.Write([]byte("{}"))
+}
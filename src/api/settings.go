@@ -0,0 +1,51 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi"
+
+	"github.com/polyfloyd/trollibox/src/jukebox"
+)
+
+// The maximum size of a request to change settings.
+const maxSettingsSize = 64 << 10
+
+func (api *API) settingsGet(w http.ResponseWriter, r *http.Request) {
+	settings, err := api.jukebox.PlayerSettings(r.Context(), chi.URLParam(r, "playerName"))
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"settings": settings,
+	})
+}
+
+// settingsUpdate merges the settings in the request body into the settings of
+// the player. Settings set to null are removed. Like with partyModeSet, party
+// mode can only be enabled if an admin token is configured.
+func (api *API) settingsUpdate(w http.ResponseWriter, r *http.Request) {
+	var changes jukebox.Settings
+	defer r.Body.Close()
+	body := http.MaxBytesReader(w, r.Body, maxSettingsSize)
+	if err := json.NewDecoder(body).Decode(&changes); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	var partyMode bool
+	if raw, ok := changes[jukebox.SettingPartyMode]; ok && json.Unmarshal(raw, &partyMode) == nil && partyMode && !api.hasAdminToken() {
+		WriteError(w, r, ErrNoAdminToken)
+		return
+	}
+
+	settings, err := api.jukebox.UpdatePlayerSettings(r.Context(), chi.URLParam(r, "playerName"), changes)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"settings": settings,
+	})
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/polyfloyd/trollibox/src/jukebox"
+	"github.com/polyfloyd/trollibox/src/library"
+)
+
+func TestSettings(t *testing.T) {
+	server, api, jb, cleanup := newTestAPI(t, library.Track{URI: "a"})
+	defer cleanup()
+	dir, err := ioutil.TempDir("", "trollibox-settings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := jukebox.NewSettingsStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jb.SetSettingsStore(store)
+
+	patch := func(admin bool, body string, expected int) {
+		t.Helper()
+		req, err := http.NewRequest("PATCH", server.URL+"/player/dummy/settings", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if admin {
+			req.Header.Set(AdminTokenHeader, "secret")
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != expected {
+			t.Fatalf("PATCH %.40s: unexpected status: %s", body, resp.Status)
+		}
+	}
+	type settingsResponse struct {
+		Settings map[string]interface{} `json:"settings"`
+	}
+
+	// Party mode can not be enabled without an admin token.
+	patch(false, `{"party_mode":true}`, http.StatusConflict)
+	api.SetAdminToken("secret")
+	patch(true, `{"save_queue_on_shutdown":true,"autoqueue_no_repeat":{"tracks":10}}`, http.StatusOK)
+	patch(true, `{"autoqueue_no_repeat":null,"party_mode":true}`, http.StatusOK)
+	patch(false, `{"party_mode":false}`, http.StatusForbidden)
+	patch(true, `{"":1}`, http.StatusBadRequest)
+	patch(true, `{"volume":40}`, http.StatusBadRequest)
+	patch(true, `{"save_queue_on_shutdown":"yes"}`, http.StatusBadRequest)
+	patch(true, `{"autoqueue_no_repeat":{"albums":3}}`, http.StatusBadRequest)
+	patch(true, `{"save_queue_on_shutdown":true,"padding":"`+strings.Repeat("x", maxSettingsSize)+`"}`, http.StatusBadRequest)
+
+	var resp settingsResponse
+	getJSON(t, server.URL+"/player/dummy/settings", &resp)
+	expected := map[string]interface{}{"save_queue_on_shutdown": true, "party_mode": true}
+	if !reflect.DeepEqual(resp.Settings, expected) {
+		t.Fatalf("Unexpected settings: %v", resp.Settings)
+	}
+	if !jb.PartyMode("dummy") {
+		t.Fatal("Party mode was not enabled")
+	}
+
+	// The settings survive a restart.
+	store, err = jukebox.NewSettingsStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var save bool
+	if ok, err := store.Get("dummy", jukebox.SettingSaveQueueOnShutdown, &save); err != nil {
+		t.Fatal(err)
+	} else if !ok || !save {
+		t.Fatalf("Unexpected setting: %v %v", ok, save)
+	}
+}
@@ -0,0 +1,36 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi"
+)
+
+func (api *API) storedPlaylistList(w http.ResponseWriter, r *http.Request) {
+	names, err := api.jukebox.StoredPlaylists(r.Context(), chi.URLParam(r, "playerName"))
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"storedplaylists": names,
+	})
+}
+
+func (api *API) storedPlaylistAppend(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		Name string `json:"name"`
+	}
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	if err := api.jukebox.AppendStoredPlaylist(r.Context(), chi.URLParam(r, "playerName"), data.Name); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	w.Write([]byte("{}"))
+}
@@ -42,6 +42,7 @@ $.fn.lazyLoad = function(list, render, thisArg, overrideElemSize) {
 };
 
 function durationToString(seconds) {
+	seconds = Math.floor(seconds);
 	var parts = [];
 	for (var i = 1; i <= 3; i++) {
 		var l = seconds % Math.pow(60, i - 1);
@@ -58,6 +59,11 @@ function durationToString(seconds) {
 	}).join(':');
 }
 
+// Tracks are queued by "system" or by the ID of the user that added them.
+function queuedByClass(queuedby) {
+	return queuedby === 'system' ? 'system' : 'user';
+}
+
 var _formatTrackTitleTemplate = _.template(`<% if (albumtrack) {%><%= albumtrack %>.  <% } %><% if (artist) {%><%= artist %> - <% } %><%= title %><% if (duration) {%> (<%= durationToString(duration) %>)<% } %>`);
 
 function formatTrackTitle(track) {
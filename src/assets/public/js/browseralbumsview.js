@@ -78,6 +78,9 @@ var BrowserAlbumsView = BrowserView.extend({
 		var self = this;
 
 		album.sort((a, b) => {
+			if (a.tracknumber && b.tracknumber && a.tracknumber !== b.tracknumber) {
+				return a.tracknumber - b.tracknumber;
+			}
 			var at = a.albumtrack;
 			var bt = b.albumtrack;
 			// Add a zero padding to make sure '12' > '4'.
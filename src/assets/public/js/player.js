@@ -177,11 +177,11 @@ class Player extends EventTarget {
 		}
 	}
 
-	async moveInPlaylist(from, to) {
+	async moveInPlaylist(from, to, count = 1) {
 		const res = await fetch(`${URLROOT}data/player/${this.name}/playlist`, {
 			method: 'PATCH',
 			headers: { 'Content-Type': 'application/json' },
-			body: JSON.stringify({ from, to }),
+			body: JSON.stringify({ from, to, count }),
 		});
 		if (res.status >= 400) {
 			throw new Error('could not insert into playlist');
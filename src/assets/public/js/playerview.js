@@ -51,11 +51,13 @@ class PlayerView {
 		this.$el.find('.player-current .track-title').text(cur.title || '');
 		this.$el.find('.player-current')
 			.removeClass('queuedby-system queuedby-user')
-			.addClass(`queuedby-${cur.queuedby}`)
-			.toggleClass('track-infinite', cur.duration == 0);
+			.addClass(`queuedby-${queuedByClass(cur.queuedby)}`)
+			.toggleClass('track-infinite', !!cur.isstream || cur.duration == 0);
 		this.$el.find('.track-time-total')
 			.text(cur.duration ? durationToString(cur.duration) : '');
-		this.$el.find('.do-set-time').attr('max', cur.duration || 0);
+		this.$el.find('.do-set-time')
+			.attr('max', cur.duration || 0)
+			.prop('disabled', !!cur.isstream);
 	}
 
 	renderProgress() {
@@ -223,7 +225,7 @@ const playerViewTemplate = _.template(`
 `);
 
 const playerViewPlaylistTemplate = _.template(`
-	<li class="queuedby-<%= queuedby %>">
+	<li class="queuedby-<%= queuedByClass(queuedby) %>"<% if (obj.queuedbyname) { %> title="Queued by <%- obj.queuedbyname %>"<% } %>>
 		<button class="do-remove glyphicon glyphicon-remove"></button>
 		<span class="track-artist"><%- artist %></span><span class="track-title"><%- title %></span>
 	</li>
@@ -3,6 +3,8 @@ package filter
 import (
 	"runtime"
 	"sync"
+	"time"
+	"unicode/utf8"
 
 	"github.com/polyfloyd/trollibox/src/library"
 )
@@ -13,6 +15,17 @@ type Filter interface {
 	Filter(track library.Track) (SearchResult, bool)
 }
 
+// An IndexedFilter is a Filter that is able to use the normalized attributes
+// of a search index instead of normalizing the attributes of each track
+// itself.
+type IndexedFilter interface {
+	Filter
+
+	// Like Filter, but looks up the normalized attributes of the track in
+	// the index. The index may not contain the track.
+	FilterIndexed(track library.Track, index *library.SearchIndex) (SearchResult, bool)
+}
+
 // Func adds an implementation of the Filter interface to a function with a
 // similar signature.
 type Func func(library.Track) (SearchResult, bool)
@@ -22,6 +35,35 @@ func (ff Func) Filter(track library.Track) (SearchResult, bool) {
 	return ff(track)
 }
 
+// All creates a filter that only accepts tracks that pass all of the
+// specified filters. The matches of the filters are combined.
+func All(filters ...Filter) Filter {
+	return Func(func(track library.Track) (SearchResult, bool) {
+		result := SearchResult{Track: track}
+		for _, ft := range filters {
+			res, ok := ft.Filter(track)
+			if !ok {
+				return SearchResult{}, false
+			}
+			for prop, matches := range res.Matches {
+				result.AddMatches(prop, matches...)
+			}
+		}
+		return result, true
+	})
+}
+
+// MaxDuration creates a filter that rejects tracks that are longer than max.
+// Tracks of which the duration is unknown, like streams, are accepted.
+func MaxDuration(max time.Duration) Filter {
+	return Func(func(track library.Track) (SearchResult, bool) {
+		if track.Duration > max {
+			return SearchResult{}, false
+		}
+		return SearchResult{Track: track}, true
+	})
+}
+
 // A SearchMatch records the start and end offset in the matched atttributes
 // value. This information can be used for highlighting.
 type SearchMatch struct {
@@ -60,6 +102,71 @@ func (sr SearchResult) NumMatches() (n int) {
 	return
 }
 
+// BestMatch returns the property that was matched most strongly. This is the
+// property with the most matches. Ties are broken by the total length of the
+// matched portions and then by the name of the property, so the result is
+// deterministic.
+func (sr SearchResult) BestMatch() (property string, ok bool) {
+	bestNum, bestLen := 0, 0
+	for prop, matches := range sr.Matches {
+		if len(matches) == 0 {
+			continue
+		}
+		length := 0
+		for _, m := range matches {
+			length += m.End - m.Start
+		}
+		if ok && (len(matches) < bestNum ||
+			len(matches) == bestNum && (length < bestLen || length == bestLen && prop > property)) {
+			continue
+		}
+		property, bestNum, bestLen, ok = prop, len(matches), length, true
+	}
+	return
+}
+
+// Snippet returns a portion of the value of the property of at most radius
+// bytes on either side of the first match. Ellipses are added where the value
+// was cut. Values without matches are cut after twice the radius. Multibyte
+// characters are never split.
+func (sr SearchResult) Snippet(property string, radius int) string {
+	value, _ := sr.Track.Attr(property).(string)
+	start, end := 0, 2*radius
+	if matches := sr.Matches[property]; len(matches) > 0 {
+		first := matches[0]
+		for _, m := range matches[1:] {
+			if m.Start < first.Start {
+				first = m
+			}
+		}
+		start, end = first.Start-radius, first.End+radius
+	}
+	if start < 0 {
+		start = 0
+	}
+	if end > len(value) {
+		end = len(value)
+	}
+	if start >= end {
+		return value
+	}
+	for start > 0 && !utf8.RuneStart(value[start]) {
+		start--
+	}
+	for end < len(value) && !utf8.RuneStart(value[end]) {
+		end++
+	}
+
+	snippet := value[start:end]
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(value) {
+		snippet += "…"
+	}
+	return snippet
+}
+
 // ByNumMatches implements the sort.Interface to sort a list of search results
 // by the number of times a track attribute was matched in descending order.
 type ByNumMatches []SearchResult
@@ -103,3 +210,14 @@ func Tracks(filter Filter, tracks []library.Track) []SearchResult {
 	}
 	return results
 }
+
+// TracksIndexed is like Tracks, but lets filters that implement IndexedFilter
+// consult the index. The index may be nil.
+func TracksIndexed(filter Filter, tracks []library.Track, index *library.SearchIndex) []SearchResult {
+	if ifl, ok := filter.(IndexedFilter); ok && index != nil {
+		filter = Func(func(track library.Track) (SearchResult, bool) {
+			return ifl.FilterIndexed(track, index)
+		})
+	}
+	return Tracks(filter, tracks)
+}
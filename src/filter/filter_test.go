@@ -4,6 +4,7 @@ import (
 	"sort"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/polyfloyd/trollibox/src/library"
 )
@@ -49,6 +50,60 @@ func TestFilterTracks(t *testing.T) {
 	}
 }
 
+func TestMaxDuration(t *testing.T) {
+	tracks := []library.Track{
+		{URI: "song", Duration: time.Minute * 3},
+		{URI: "mix", Duration: time.Hour * 2},
+		{URI: "stream"},
+	}
+	firstLetter := Func(func(track library.Track) (SearchResult, bool) {
+		res := SearchResult{Track: track}
+		res.AddMatch("uri", 0, 1)
+		return res, track.URI != "stream"
+	})
+
+	results := Tracks(All(firstLetter, MaxDuration(time.Minute*20)), tracks)
+	if len(results) != 1 || results[0].URI != "song" {
+		t.Fatalf("Unexpected results: %v", results)
+	}
+	if results[0].NumMatches() != 1 {
+		t.Fatalf("Matches were not retained: %v", results[0].Matches)
+	}
+
+	results = Tracks(MaxDuration(time.Minute*20), tracks)
+	sort.Slice(results, func(i, j int) bool { return results[i].URI < results[j].URI })
+	if len(results) != 2 || results[0].URI != "song" || results[1].URI != "stream" {
+		t.Fatalf("Unexpected results: %v", results)
+	}
+}
+
+func TestLeastRecent(t *testing.T) {
+	results := []SearchResult{
+		{Track: library.Track{URI: "a"}},
+		{Track: library.Track{URI: "b"}},
+		{Track: library.Track{URI: "c"}},
+	}
+	uris := func(results []SearchResult) []string {
+		out := []string{}
+		for _, res := range results {
+			out = append(out, res.URI)
+		}
+		sort.Strings(out)
+		return out
+	}
+
+	if got := uris(leastRecent(results, nil)); strings.Join(got, ",") != "a,b,c" {
+		t.Fatalf("Unexpected results without recent tracks: %v", got)
+	}
+	if got := uris(leastRecent(results, []string{"a"})); strings.Join(got, ",") != "b,c" {
+		t.Fatalf("Recent tracks were not excluded: %v", got)
+	}
+	// All tracks are recent, so the window is shrunk.
+	if got := uris(leastRecent(results, []string{"b", "a", "c", "x"})); strings.Join(got, ",") != "c" {
+		t.Fatalf("The least recent track was not picked: %v", got)
+	}
+}
+
 func TestNumMatches(t *testing.T) {
 	result := SearchResult{}
 	if n := result.NumMatches(); n != 0 {
@@ -72,6 +127,72 @@ func TestNumMatches(t *testing.T) {
 	}
 }
 
+func TestBestMatch(t *testing.T) {
+	if _, ok := (SearchResult{}).BestMatch(); ok {
+		t.Fatalf("A result without matches should have no best match")
+	}
+
+	tests := []struct {
+		matches map[string][]SearchMatch
+		best    string
+	}{
+		{
+			matches: map[string][]SearchMatch{
+				"artist": {{0, 1}},
+				"title":  {{0, 1}, {2, 3}},
+			},
+			best: "title",
+		},
+		{
+			matches: map[string][]SearchMatch{
+				"artist": {{0, 4}},
+				"title":  {{0, 1}},
+			},
+			best: "artist",
+		},
+		{
+			matches: map[string][]SearchMatch{
+				"title":  {{0, 2}},
+				"artist": {{0, 2}},
+				"album":  {{0, 2}},
+			},
+			best: "album",
+		},
+	}
+	for _, test := range tests {
+		best, ok := SearchResult{Matches: test.matches}.BestMatch()
+		if !ok || best != test.best {
+			t.Fatalf("Unexpected best match for %v: %q", test.matches, best)
+		}
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	result := SearchResult{Track: library.Track{Title: "The quick brown fox jumps over the lazy dog"}}
+	result.AddMatch("title", 16, 19)
+	if s := result.Snippet("title", 6); s != "…brown fox jumps…" {
+		t.Fatalf("Unexpected snippet: %q", s)
+	}
+	if s := result.Snippet("title", 100); s != result.Title {
+		t.Fatalf("Unexpected snippet: %q", s)
+	}
+	if s := result.Snippet("artist", 4); s != "" {
+		t.Fatalf("Unexpected snippet: %q", s)
+	}
+
+	// The cuts fall in the middle of the multibyte characters.
+	result = SearchResult{Track: library.Track{Title: "ééxéé"}}
+	result.AddMatch("title", 4, 5)
+	if s := result.Snippet("title", 1); s != "…éxé…" {
+		t.Fatalf("Unexpected snippet: %q", s)
+	}
+
+	result = SearchResult{Track: library.Track{Title: "abcdefgh"}}
+	if s := result.Snippet("title", 2); s != "abcd…" {
+		t.Fatalf("Unexpected snippet without matches: %q", s)
+	}
+}
+
 func TestMatchSorting(t *testing.T) {
 	results := []SearchResult{
 		{
@@ -11,14 +11,27 @@ import (
 type randFilterIterator struct {
 	filter Filter
 	rand   *rand.Rand
+	recent func() []string
 }
 
 // RandomIterator creates a track iterator which will use the supplied filter
 // to pick random tracks.
 func RandomIterator(filter Filter) player.TrackIterator {
+	return RandomIteratorExcluding(filter, nil)
+}
+
+// RandomIteratorExcluding is like RandomIterator, but avoids picking the
+// tracks of which the URIs are returned by recent, most recent first. It is
+// called for every track that is picked.
+//
+// If all tracks matched by the filter are recent, the window of recent tracks
+// is shrunk until the least recent of them can be picked. So the iterator
+// only runs out of tracks if the filter matches none.
+func RandomIteratorExcluding(filter Filter, recent func() []string) player.TrackIterator {
 	return &randFilterIterator{
 		filter: filter,
 		rand:   rand.New(rand.NewSource(time.Now().Unix())),
+		recent: recent,
 	}
 }
 
@@ -32,5 +45,37 @@ func (it randFilterIterator) NextTrack(lib library.Library) (library.Track, play
 	if len(results) == 0 {
 		return library.Track{}, player.TrackMeta{}, false
 	}
+	if it.recent != nil {
+		results = leastRecent(results, it.recent())
+	}
 	return results[it.rand.Intn(len(results))].Track, player.TrackMeta{QueuedBy: "system"}, true
 }
+
+// leastRecent returns the results that are not in the list of recent URIs. If
+// there are none, the results that were played least recently are returned.
+func leastRecent(results []SearchResult, recent []string) []SearchResult {
+	if len(recent) == 0 {
+		return results
+	}
+	ranks := make(map[string]int, len(recent))
+	for i, uri := range recent {
+		if _, ok := ranks[uri]; !ok {
+			ranks[uri] = i
+		}
+	}
+	var picked []SearchResult
+	pickedRank := -1
+	for _, res := range results {
+		rank, ok := ranks[res.Track.URI]
+		if !ok {
+			rank = len(recent)
+		}
+		if rank > pickedRank {
+			picked, pickedRank = picked[:0], rank
+		}
+		if rank == pickedRank {
+			picked = append(picked, res)
+		}
+	}
+	return picked
+}
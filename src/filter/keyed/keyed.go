@@ -3,9 +3,10 @@ package keyed
 import (
 	"encoding/json"
 	"fmt"
-	"regexp"
 	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/polyfloyd/trollibox/src/filter"
 	"github.com/polyfloyd/trollibox/src/library"
@@ -21,7 +22,7 @@ func parser(untaggedFields []string) ParseFunc {
 	strOperation := pAny(pLiterals("=", ":")...)
 	strMatchValue := pApply(pAtLeastOne(pAny(pWordLit(), pLast(pLiterals("\\", " ")...))), gJoinStrings)
 
-	ordKey := pLit("duration")
+	ordKey := pAny(pLiterals("duration", "rating", "playcount")...)
 	ordOperation := pAny(pLiterals("=", "<", ">")...)
 	ordMatchValue := pApply(pAtLeastOne(digit), gJoinStrings)
 
@@ -40,52 +41,124 @@ func parser(untaggedFields []string) ParseFunc {
 	return query
 }
 
+// pWordLit parses a single letter, digit or underscore. Letters with
+// diacritics are accepted so they can be searched for.
 func pWordLit() ParseFunc {
-	re := regexp.MustCompile(`\w`)
 	return func(source string) (interface{}, int) {
-		if len(source) > 0 && re.MatchString(source[:1]) {
-			return source[:1], 1
+		r, n := utf8.DecodeRuneInString(source)
+		if n > 0 && (r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)) {
+			return source[:n], n
 		}
 		return nil, -1
 	}
 }
 
 type rule interface {
-	Match(obj interface{ Attr(string) interface{} }) map[string][]filter.SearchMatch
+	Match(sub subject) map[string][]filter.SearchMatch
+}
+
+// A subject is a track that is matched by rules, along with its entry in a
+// search index if there is one.
+type subject struct {
+	track *library.Track
+	entry *library.IndexEntry
+	// Whether values are matched after normalizing them, see
+	// CompileNormalizedQuery.
+	normalize bool
+}
+
+// find looks for the needle in the values of an attribute. Depending on the
+// query, either the lowercased needle or the normalized needle is used. If
+// equal is set, a value must be equal to the needle instead of containing it.
+func (sub subject) find(property, needle, normalized string, equal bool) (filter.SearchMatch, bool) {
+	if sub.normalize {
+		return sub.match(property, normalized, equal)
+	}
+	if !equal {
+		s, ok := sub.track.Attr(property).(string)
+		if !ok {
+			return filter.SearchMatch{}, false
+		}
+		i := strings.Index(strings.ToLower(s), needle)
+		if i == -1 {
+			return filter.SearchMatch{}, false
+		}
+		return filter.SearchMatch{Start: i, End: i + len(needle)}, true
+	}
+	offset := 0
+	for _, value := range sub.track.AttrValues(property) {
+		if strings.ToLower(value) == needle {
+			return filter.SearchMatch{Start: offset, End: offset + len(needle)}, true
+		}
+		offset += len(value) + len(library.MultiValueSeparator)
+	}
+	return filter.SearchMatch{}, false
+}
+
+// match looks for the needle in the normalized values of an attribute, which
+// are taken from the index if possible. If equal is set, a value must be equal
+// to the needle instead of containing it. The match refers to the values as
+// joined by Track.Attr.
+func (sub subject) match(property, needle string, equal bool) (filter.SearchMatch, bool) {
+	var values []string
+	normalized, ok := sub.entry.Values(property)
+	if !ok {
+		values = sub.track.AttrValues(property)
+		normalized = make([]string, len(values))
+		for i, value := range values {
+			normalized[i] = library.NormalizeText(value)
+		}
+	}
+	for i, norm := range normalized {
+		start := strings.Index(norm, needle)
+		if start < 0 || equal && norm != needle {
+			continue
+		}
+		if values == nil {
+			values = sub.track.AttrValues(property)
+		}
+		if len(values) != len(normalized) {
+			break
+		}
+		offset := 0
+		for _, value := range values[:i] {
+			offset += len(value) + len(library.MultiValueSeparator)
+		}
+		s, e := library.NormalizedSpan(values[i], start, start+len(needle))
+		return filter.SearchMatch{Start: offset + s, End: offset + e}, true
+	}
+	return filter.SearchMatch{}, false
 }
 
 type stringContainsRule struct {
 	property string
 	needle   string
+	// The needle as normalized by library.NormalizeText.
+	normalized string
 }
 
-func (rule stringContainsRule) Match(obj interface{ Attr(string) interface{} }) map[string][]filter.SearchMatch {
-	s, ok := obj.Attr(rule.property).(string)
+func (rule stringContainsRule) Match(sub subject) map[string][]filter.SearchMatch {
+	m, ok := sub.find(rule.property, rule.needle, rule.normalized, false)
 	if !ok {
 		return nil
 	}
-	i := strings.Index(strings.ToLower(s), rule.needle)
-	if i == -1 {
-		return nil
-	}
-	return map[string][]filter.SearchMatch{
-		rule.property: {filter.SearchMatch{Start: i, End: i + len(rule.needle)}},
-	}
+	return map[string][]filter.SearchMatch{rule.property: {m}}
 }
 
 type stringEqualsRule struct {
-	property string
-	needle   string
+	property   string
+	needle     string
+	normalized string
 }
 
-func (rule stringEqualsRule) Match(obj interface{ Attr(string) interface{} }) map[string][]filter.SearchMatch {
-	s, ok := obj.Attr(rule.property).(string)
-	if !ok || strings.ToLower(s) != rule.needle {
+// Match implements the rule interface. Attributes with multiple values match
+// if any of the values is equal.
+func (rule stringEqualsRule) Match(sub subject) map[string][]filter.SearchMatch {
+	m, ok := sub.find(rule.property, rule.needle, rule.normalized, true)
+	if !ok {
 		return nil
 	}
-	return map[string][]filter.SearchMatch{
-		rule.property: {filter.SearchMatch{Start: 0, End: len(rule.needle)}},
-	}
+	return map[string][]filter.SearchMatch{rule.property: {m}}
 }
 
 type ordEqualsRule struct {
@@ -93,8 +166,8 @@ type ordEqualsRule struct {
 	ref      int64
 }
 
-func (rule ordEqualsRule) Match(obj interface{ Attr(string) interface{} }) map[string][]filter.SearchMatch {
-	i, ok := obj.Attr(rule.property).(int64)
+func (rule ordEqualsRule) Match(sub subject) map[string][]filter.SearchMatch {
+	i, ok := sub.track.Attr(rule.property).(int64)
 	if !ok || i != rule.ref {
 		return nil
 	}
@@ -108,8 +181,8 @@ type ordLessThanRule struct {
 	ref      int64
 }
 
-func (rule ordLessThanRule) Match(obj interface{ Attr(string) interface{} }) map[string][]filter.SearchMatch {
-	i, ok := obj.Attr(rule.property).(int64)
+func (rule ordLessThanRule) Match(sub subject) map[string][]filter.SearchMatch {
+	i, ok := sub.track.Attr(rule.property).(int64)
 	if !ok || i >= rule.ref {
 		return nil
 	}
@@ -123,8 +196,8 @@ type ordGreaterThanRule struct {
 	ref      int64
 }
 
-func (rule ordGreaterThanRule) Match(obj interface{ Attr(string) interface{} }) map[string][]filter.SearchMatch {
-	i, ok := obj.Attr(rule.property).(int64)
+func (rule ordGreaterThanRule) Match(sub subject) map[string][]filter.SearchMatch {
+	i, ok := sub.track.Attr(rule.property).(int64)
 	if !ok || i <= rule.ref {
 		return nil
 	}
@@ -136,18 +209,14 @@ func (rule ordGreaterThanRule) Match(obj interface{ Attr(string) interface{} })
 type unkeyedRule struct {
 	properties []string
 	needle     string
+	normalized string
 }
 
-func (rule unkeyedRule) Match(obj interface{ Attr(string) interface{} }) map[string][]filter.SearchMatch {
+func (rule unkeyedRule) Match(sub subject) map[string][]filter.SearchMatch {
 	m := map[string][]filter.SearchMatch{}
 	for _, prop := range rule.properties {
-		s, ok := obj.Attr(prop).(string)
-		if !ok {
-			continue
-		}
-		i := strings.Index(strings.ToLower(s), rule.needle)
-		if i >= 0 {
-			m[prop] = append(m[prop], filter.SearchMatch{Start: i, End: i + len(rule.needle)})
+		if match, ok := sub.find(prop, rule.needle, rule.normalized, false); ok {
+			m[prop] = append(m[prop], match)
 		}
 	}
 	return m
@@ -166,6 +235,7 @@ func gMapUnkeyedRule(untaggedFields []string) ApplyFunc {
 		return unkeyedRule{
 			properties: untaggedFields,
 			needle:     strings.ToLower(v.(string)),
+			normalized: library.NormalizeText(v.(string)),
 		}
 	}
 }
@@ -176,9 +246,17 @@ func gMapStringRule(v interface{}) interface{} {
 	argument := v.([]interface{})[2].(string)
 	switch operation {
 	case ":":
-		return stringContainsRule{property: property, needle: strings.ToLower(argument)}
+		return stringContainsRule{
+			property:   property,
+			needle:     strings.ToLower(argument),
+			normalized: library.NormalizeText(argument),
+		}
 	case "=":
-		return stringEqualsRule{property: property, needle: strings.ToLower(argument)}
+		return stringEqualsRule{
+			property:   property,
+			needle:     strings.ToLower(argument),
+			normalized: library.NormalizeText(argument),
+		}
 	}
 	panic("unreachable")
 }
@@ -218,6 +296,9 @@ func init() {
 type nojsonQuery struct {
 	Query    string   `json:"query"`
 	Untagged []string `json:"untagged"`
+	// Whether diacritics and punctuation are ignored, see
+	// CompileNormalizedQuery.
+	Normalized bool `json:"normalized,omitempty"`
 
 	rules []rule
 }
@@ -239,7 +320,23 @@ type Query nojsonQuery
 //
 // The query could look something like this:
 //   foo bar baz title:something album:one\ two artist:foo*ar
+//
+// Values are matched case insensitively.
 func CompileQuery(query string, untaggedFields []string) (*Query, error) {
+	return compileQuery(query, untaggedFields, false)
+}
+
+// CompileNormalizedQuery is like CompileQuery, but values are matched after
+// normalizing them with library.NormalizeText, so diacritics and punctuation
+// are ignored. For example, "bjork" finds "Björk" and "ac dc" finds "AC/DC".
+//
+// Normalizing is more expensive than lowercasing, unless the tracks are
+// filtered with a search index, see filter.TracksIndexed.
+func CompileNormalizedQuery(query string, untaggedFields []string) (*Query, error) {
+	return compileQuery(query, untaggedFields, true)
+}
+
+func compileQuery(query string, untaggedFields []string, normalized bool) (*Query, error) {
 	v, r := parser(untaggedFields)(query)
 	if r < 0 {
 		return nil, fmt.Errorf("parse error")
@@ -255,9 +352,10 @@ func CompileQuery(query string, untaggedFields []string) (*Query, error) {
 	}
 
 	return &Query{
-		Query:    query,
-		Untagged: untaggedFields,
-		rules:    rules,
+		Query:      query,
+		Untagged:   untaggedFields,
+		Normalized: normalized,
+		rules:      rules,
 	}, nil
 }
 
@@ -266,7 +364,7 @@ func (sq *Query) UnmarshalJSON(data []byte) error {
 	if err := json.Unmarshal(data, (*nojsonQuery)(sq)); err != nil {
 		return err
 	}
-	q, err := CompileQuery(sq.Query, sq.Untagged)
+	q, err := compileQuery(sq.Query, sq.Untagged, sq.Normalized)
 	if err != nil {
 		return err
 	}
@@ -274,18 +372,70 @@ func (sq *Query) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// serverTags are the properties that servers are known to be able to search
+// by.
+var serverTags = map[string]bool{
+	"artist":      true,
+	"title":       true,
+	"album":       true,
+	"albumartist": true,
+	"genre":       true,
+}
+
+// Tags returns the value searched for by property if the query only matches
+// common tags like the artist or title by case insensitive equality or
+// substring and does not match a property more than once. Such queries can be
+// narrowed down by servers that support searching by tag. Tracks found that
+// way are a superset of the tracks that pass the query, so they should still
+// be filtered by it.
+//
+// Normalized queries with values that are changed by normalization, like those
+// with diacritics or punctuation, are not reported, because the server would
+// not find the tracks that only match after normalizing them.
+func (sq *Query) Tags() (map[string]string, bool) {
+	if sq == nil || len(sq.rules) == 0 {
+		return nil, false
+	}
+	tags := map[string]string{}
+	for _, r := range sq.rules {
+		var property, needle, normalized string
+		switch r := r.(type) {
+		case stringContainsRule:
+			property, needle, normalized = r.property, r.needle, r.normalized
+		case stringEqualsRule:
+			property, needle, normalized = r.property, r.needle, r.normalized
+		default:
+			return nil, false
+		}
+		if !serverTags[property] || sq.Normalized && needle != normalized {
+			return nil, false
+		}
+		if _, ok := tags[property]; ok {
+			return nil, false
+		}
+		tags[property] = needle
+	}
+	return tags, true
+}
+
 // Filter implements the filter.Filter interface.
 func (sq *Query) Filter(track library.Track) (filter.SearchResult, bool) {
+	return sq.FilterIndexed(track, nil)
+}
+
+// FilterIndexed implements the filter.IndexedFilter interface.
+func (sq *Query) FilterIndexed(track library.Track, index *library.SearchIndex) (filter.SearchResult, bool) {
 	if sq == nil || len(sq.rules) == 0 {
 		return filter.SearchResult{}, false
 	}
 
-	result := filter.SearchResult{
-		Track:   track,
-		Matches: map[string][]filter.SearchMatch{},
+	result := filter.SearchResult{Track: track}
+	sub := subject{track: &track, normalize: sq.Normalized}
+	if sq.Normalized {
+		sub.entry, _ = index.Entry(track.URI)
 	}
 	for _, rule := range sq.rules {
-		matches := rule.Match(&track)
+		matches := rule.Match(sub)
 		if len(matches) == 0 {
 			return filter.SearchResult{}, false
 		}
@@ -2,9 +2,11 @@ package keyed
 
 import (
 	"encoding/json"
+	"fmt"
 	"reflect"
 	"testing"
 
+	"github.com/polyfloyd/trollibox/src/filter"
 	"github.com/polyfloyd/trollibox/src/library"
 )
 
@@ -15,11 +17,11 @@ func TestParser(t *testing.T) {
 	}{
 		{
 			"artist:foo",
-			[]rule{stringContainsRule{property: "artist", needle: "foo"}},
+			[]rule{stringContainsRule{property: "artist", needle: "foo", normalized: "foo"}},
 		},
 		{
 			"title=foo",
-			[]rule{stringEqualsRule{property: "title", needle: "foo"}},
+			[]rule{stringEqualsRule{property: "title", needle: "foo", normalized: "foo"}},
 		},
 		{
 			"duration=42",
@@ -33,30 +35,38 @@ func TestParser(t *testing.T) {
 			"duration>1337",
 			[]rule{ordGreaterThanRule{property: "duration", ref: 1337}},
 		},
+		{
+			"rating>6",
+			[]rule{ordGreaterThanRule{property: "rating", ref: 6}},
+		},
+		{
+			"playcount=0",
+			[]rule{ordEqualsRule{property: "playcount", ref: 0}},
+		},
 		{
 			"foo",
-			[]rule{unkeyedRule{properties: []string{"property"}, needle: "foo"}},
+			[]rule{unkeyedRule{properties: []string{"property"}, needle: "foo", normalized: "foo"}},
 		},
 		{
 			"foo\\ bar",
-			[]rule{unkeyedRule{properties: []string{"property"}, needle: "foo bar"}},
+			[]rule{unkeyedRule{properties: []string{"property"}, needle: "foo bar", normalized: "foo bar"}},
 		},
 		{
 			"artist:foo\\ bar",
-			[]rule{stringContainsRule{property: "artist", needle: "foo bar"}},
+			[]rule{stringContainsRule{property: "artist", needle: "foo bar", normalized: "foo bar"}},
 		},
 		{
 			"foo bar",
 			[]rule{
-				unkeyedRule{properties: []string{"property"}, needle: "foo"},
-				unkeyedRule{properties: []string{"property"}, needle: "bar"},
+				unkeyedRule{properties: []string{"property"}, needle: "foo", normalized: "foo"},
+				unkeyedRule{properties: []string{"property"}, needle: "bar", normalized: "bar"},
 			},
 		},
 		{
 			"foo artist:bar",
 			[]rule{
-				unkeyedRule{properties: []string{"property"}, needle: "foo"},
-				stringContainsRule{property: "artist", needle: "bar"},
+				unkeyedRule{properties: []string{"property"}, needle: "foo", normalized: "foo"},
+				stringContainsRule{property: "artist", needle: "bar", normalized: "bar"},
 			},
 		},
 	}
@@ -151,6 +161,101 @@ func TestFilter(t *testing.T) {
 	}
 }
 
+func TestFilterMultiValue(t *testing.T) {
+	var track library.Track
+	track.SetArtists("Foo", "Bar")
+
+	query, err := CompileQuery("artist=bar", []string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, ok := query.Filter(track)
+	if !ok {
+		t.Fatalf("Any value of a multi-valued attribute should match")
+	} else if m := result.Matches["artist"][0]; m.Start != 5 || m.End != 8 {
+		t.Fatalf("Unexpected match indices: %#v", m)
+	}
+
+	query, err = CompileQuery("artist:bar", []string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := query.Filter(track); !ok {
+		t.Fatalf("Any value of a multi-valued attribute should match")
+	}
+}
+
+func TestFilterDiacritics(t *testing.T) {
+	track := library.Track{URI: "a", Artist: "Björk", Title: "Jóga"}
+	index := library.NewSearchIndex([]library.Track{track})
+
+	query, err := CompileNormalizedQuery("bjork artist=björk title:joga", []string{"artist"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, idx := range []*library.SearchIndex{nil, index} {
+		result, ok := query.FilterIndexed(track, idx)
+		if !ok {
+			t.Fatalf("Matching should ignore diacritics")
+		}
+		if m := result.Matches["artist"][0]; m.Start != 0 || m.End != len("Björk") {
+			t.Fatalf("Unexpected match indices: %#v", m)
+		}
+		if m := result.Matches["title"][0]; m.Start != 0 || m.End != len("Jóga") {
+			t.Fatalf("Unexpected match indices: %#v", m)
+		}
+	}
+
+	// By default, values are only lowercased.
+	query, err = CompileQuery("bjork", []string{"artist"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := query.FilterIndexed(track, index); ok {
+		t.Fatalf("Diacritics should only be ignored by normalized queries")
+	}
+	query, err = CompileQuery("artist=BJÖRK", []string{"artist"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := query.Filter(track); !ok {
+		t.Fatalf("Matching should be case insensitive")
+	}
+}
+
+func TestQueryTags(t *testing.T) {
+	tests := []struct {
+		query      string
+		normalized bool
+		tags       map[string]string
+	}{
+		{query: "artist:Foo album=bar", tags: map[string]string{"artist": "foo", "album": "bar"}},
+		{query: "artist:Foo album=bar", normalized: true, tags: map[string]string{"artist": "foo", "album": "bar"}},
+		{query: "artist:foo artist:bar"},
+		{query: "artist:foo duration>100"},
+		{query: "artist:foo baz"},
+		{query: "uri:foo"},
+		{query: "mood:happy"},
+		{query: "title:jóga", tags: map[string]string{"title": "jóga"}},
+		{query: "title:jóga", normalized: true},
+		{query: "artist:foo\\ \\ bar", normalized: true},
+	}
+	for _, test := range tests {
+		compile := CompileQuery
+		if test.normalized {
+			compile = CompileNormalizedQuery
+		}
+		query, err := compile(test.query, []string{"title"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tags, ok := query.Tags()
+		if ok != (test.tags != nil) || !reflect.DeepEqual(tags, test.tags) {
+			t.Fatalf("Unexpected tags for %q: %v, %v", test.query, tags, ok)
+		}
+	}
+}
+
 func TestJSON(t *testing.T) {
 	query, err := CompileQuery("foo artist:baz", []string{"artist", "title"})
 	if err != nil {
@@ -173,6 +278,9 @@ func TestJSON(t *testing.T) {
 	if !reflect.DeepEqual(decodedQuery.Untagged, query.Untagged) {
 		t.Fatalf("Incorrect Untagged after decoding: %v", decodedQuery.Untagged)
 	}
+	if decodedQuery.Normalized {
+		t.Fatalf("The query should not be normalized after decoding")
+	}
 
 	result, ok := decodedQuery.Filter(library.Track{
 		Artist: "baz",
@@ -184,3 +292,50 @@ func TestJSON(t *testing.T) {
 		t.Fatalf("Unexpected number of matches: %v", n)
 	}
 }
+
+func benchmarkLibrary(n int) []library.Track {
+	tracks := make([]library.Track, n)
+	for i := range tracks {
+		tracks[i] = library.Track{
+			URI:    fmt.Sprintf("file:///music/Artíst %d/Álbum %d/%d - Tráck %d.flac", i%1000, i%5000, i%20, i),
+			Artist: fmt.Sprintf("Artíst %d", i%1000),
+			Title:  fmt.Sprintf("Tráck %d", i),
+			Album:  fmt.Sprintf("Álbum %d", i%5000),
+		}
+	}
+	return tracks
+}
+
+func benchmarkQuery(b *testing.B, normalized, indexed bool) {
+	tracks := benchmarkLibrary(100000)
+	var index *library.SearchIndex
+	if indexed {
+		index = library.NewSearchIndex(tracks)
+	}
+	compile := CompileQuery
+	if normalized {
+		compile = CompileNormalizedQuery
+	}
+	query, err := compile("artist=artíst\\ 42 tráck", []string{"artist", "title", "album"})
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if results := filter.TracksIndexed(query, tracks, index); len(results) != 100 {
+			b.Fatalf("Unexpected number of results: %d", len(results))
+		}
+	}
+}
+
+func BenchmarkQuery(b *testing.B) {
+	benchmarkQuery(b, false, false)
+}
+
+func BenchmarkQueryNormalized(b *testing.B) {
+	benchmarkQuery(b, true, false)
+}
+
+func BenchmarkQueryIndexed(b *testing.B) {
+	benchmarkQuery(b, true, true)
+}
@@ -68,8 +68,12 @@ func (rule Rule) MatchFunc() (func(library.Track) ([]filter.SearchMatch, bool),
 	}
 
 	// Prevent type errors further down.
+	attrZero := (&library.Track{}).Attr(rule.Attribute)
+	if attrZero == nil {
+		return nil, fmt.Errorf("unknown attribute %q, expected one of %v or a tag", rule.Attribute, library.TrackAttributes())
+	}
 	typeVal := reflect.ValueOf(rule.Value).Kind()
-	typeTrack := reflect.ValueOf((&library.Track{}).Attr(rule.Attribute)).Kind()
+	typeTrack := reflect.ValueOf(attrZero).Kind()
 	if typeVal != typeTrack && !(typeVal == reflect.Float64 && typeTrack == reflect.Int64) {
 		return nil, fmt.Errorf("value and attribute types do not match (%v, %v)", typeVal, typeTrack)
 	}
@@ -114,13 +118,22 @@ func (rule Rule) MatchFunc() (func(library.Track) ([]filter.SearchMatch, bool),
 				}}, inv(true)
 			}, nil
 		case opEquals:
+			// Attributes with multiple values are equal if any of the
+			// values is.
 			return func(track library.Track) ([]filter.SearchMatch, bool) {
-				if inv(track.Attr(rule.Attribute).(string) == strVal) {
-					return []filter.SearchMatch{{
-						Start: 0, End: len(strVal),
-					}}, true
+				offset := 0
+				for _, value := range track.AttrValues(rule.Attribute) {
+					if value == strVal {
+						if inv(true) {
+							return []filter.SearchMatch{{
+								Start: offset, End: offset + len(strVal),
+							}}, true
+						}
+						return nil, false
+					}
+					offset += len(value) + len(library.MultiValueSeparator)
 				}
-				return nil, false
+				return nil, inv(false)
 			}, nil
 		case opGreater:
 			return func(track library.Track) ([]filter.SearchMatch, bool) {
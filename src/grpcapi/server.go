@@ -0,0 +1,345 @@
+// Package grpcapi implements a gRPC interface to a Jukebox for remote control
+// applications.
+//
+// The Go code for the service is generated from trollibox.proto.
+package grpcapi
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative trollibox.proto
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/polyfloyd/trollibox/src/jukebox"
+	"github.com/polyfloyd/trollibox/src/library"
+	"github.com/polyfloyd/trollibox/src/player"
+	"github.com/polyfloyd/trollibox/src/util"
+)
+
+// Server serves the Trollibox gRPC service.
+type Server struct {
+	UnimplementedTrolliboxServer
+
+	jukebox   *jukebox.Jukebox
+	server    *grpc.Server
+	closing   chan struct{}
+	closeOnce sync.Once
+}
+
+// The client under which calls are performed. Remote controls do not
+// authenticate, so they are guests.
+var grpcClient = jukebox.Client{ID: "grpc", PublicID: "grpc"}
+
+// NewServer creates a server for the jukebox. Calls that do not complete
+// within the timeout are aborted, event streams are exempt.
+func NewServer(jb *jukebox.Jukebox, timeout time.Duration) *Server {
+	srv := &Server{
+		jukebox: jb,
+		closing: make(chan struct{}),
+	}
+	srv.server = grpc.NewServer(grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		ctx = jukebox.WithClient(ctx, grpcClient)
+		res, err := handler(ctx, req)
+		return res, statusError(err)
+	}))
+	RegisterTrolliboxServer(srv.server, srv)
+	return srv
+}
+
+// Serve accepts connections on the listener until the server is closed.
+func (srv *Server) Serve(lis net.Listener) error {
+	return srv.server.Serve(lis)
+}
+
+// Close ends all event streams and stops the server once all pending calls
+// have completed.
+func (srv *Server) Close() {
+	srv.closeOnce.Do(func() {
+		close(srv.closing)
+	})
+	srv.server.GracefulStop()
+}
+
+// statusError converts errors returned by the jukebox to gRPC status errors.
+func statusError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, jukebox.ErrPlayerUnavailable):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, jukebox.ErrUnsupported):
+		return status.Error(codes.Unimplemented, err.Error())
+	case errors.Is(err, jukebox.ErrPartyMode), errors.Is(err, jukebox.ErrBlocked):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, jukebox.ErrQueueCap):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	return status.Error(codes.Unknown, err.Error())
+}
+
+// GetPlayers implements TrolliboxServer.
+func (srv *Server) GetPlayers(ctx context.Context, _ *Empty) (*Players, error) {
+	names, err := srv.jukebox.Players(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Players{Names: names}, nil
+}
+
+// GetState implements TrolliboxServer.
+func (srv *Server) GetState(ctx context.Context, req *PlayerRequest) (*PlayerState, error) {
+	state, err := srv.jukebox.PlayerState(ctx, req.Player)
+	if err != nil {
+		return nil, err
+	}
+	tim, err := srv.jukebox.PlayerTime(ctx, req.Player)
+	if err != nil {
+		return nil, err
+	}
+	volume, err := srv.jukebox.PlayerVolume(ctx, req.Player)
+	if err != nil {
+		return nil, err
+	}
+	index, err := srv.jukebox.PlayerTrackIndex(ctx, req.Player)
+	if err != nil {
+		return nil, err
+	}
+	return &PlayerState{
+		State:  playStateToProto(state),
+		Time:   tim.Seconds(),
+		Volume: float32(volume) / 100.0,
+		Index:  int32(index),
+	}, nil
+}
+
+// SetPlayState implements TrolliboxServer.
+func (srv *Server) SetPlayState(ctx context.Context, req *SetPlayStateRequest) (*Empty, error) {
+	state, ok := playStateFromProto(req.State)
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid play state: %v", req.State)
+	}
+	return &Empty{}, srv.jukebox.SetPlayerState(ctx, req.Player, state)
+}
+
+// SetVolume implements TrolliboxServer.
+func (srv *Server) SetVolume(ctx context.Context, req *SetVolumeRequest) (*Empty, error) {
+	return &Empty{}, srv.jukebox.SetPlayerVolume(ctx, req.Player, int(req.Volume*100))
+}
+
+// SetTime implements TrolliboxServer.
+func (srv *Server) SetTime(ctx context.Context, req *SetTimeRequest) (*Empty, error) {
+	return &Empty{}, srv.jukebox.SetPlayerTime(ctx, req.Player, time.Duration(req.Time*float64(time.Second)))
+}
+
+// SetCurrent implements TrolliboxServer.
+func (srv *Server) SetCurrent(ctx context.Context, req *SetCurrentRequest) (*Empty, error) {
+	return &Empty{}, srv.jukebox.SetPlayerTrackIndex(ctx, req.Player, int(req.Index), req.Relative)
+}
+
+// GetPlaylist implements TrolliboxServer.
+func (srv *Server) GetPlaylist(ctx context.Context, req *PlayerRequest) (*Playlist, error) {
+	plist, err := srv.jukebox.PlayerPlaylist(ctx, req.Player)
+	if err != nil {
+		return nil, err
+	}
+	var tracks []library.Track
+	var meta []player.TrackMeta
+	err = util.WithContext(ctx, func() (err error) {
+		if tracks, err = plist.Tracks(); err != nil {
+			return err
+		}
+		meta, err = plist.Meta()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	libs, err := srv.jukebox.PlayerLibraries(ctx, req.Player)
+	if err != nil {
+		return nil, err
+	}
+	uris := make([]string, len(tracks))
+	for i, track := range tracks {
+		uris[i] = track.URI
+	}
+	if tracks, err = library.AllTrackInfo(libs, uris...); err != nil {
+		return nil, err
+	}
+	index, err := srv.jukebox.PlayerTrackIndex(ctx, req.Player)
+	if err != nil {
+		return nil, err
+	}
+	if index < 0 || index >= len(tracks) {
+		index = -1
+	}
+	tim, err := srv.jukebox.PlayerTime(ctx, req.Player)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Playlist{
+		Tracks:  make([]*Track, len(tracks)),
+		Current: int32(index),
+		Time:    tim.Seconds(),
+	}
+	for i := range tracks {
+		res.Tracks[i] = trackToProto(&tracks[i])
+		if i < len(meta) {
+			res.Tracks[i].QueuedBy = meta[i].QueuedBy
+			if res.Tracks[i].Title == "" {
+				res.Tracks[i].Title = meta[i].Title
+			}
+		}
+	}
+	return res, nil
+}
+
+// InsertTracks implements TrolliboxServer.
+func (srv *Server) InsertTracks(ctx context.Context, req *InsertTracksRequest) (*Empty, error) {
+	tracks := make([]library.Track, len(req.Uris))
+	meta := make([]player.TrackMeta, len(req.Uris))
+	for i, uri := range req.Uris {
+		tracks[i].URI = uri
+		meta[i] = jukebox.UserTrackMeta(ctx)
+	}
+	return &Empty{}, srv.jukebox.InsertTracks(ctx, req.Player, int(req.Position), tracks, meta)
+}
+
+// MoveTrack implements TrolliboxServer.
+func (srv *Server) MoveTrack(ctx context.Context, req *MoveTrackRequest) (*Empty, error) {
+	return &Empty{}, srv.jukebox.MoveTracks(ctx, req.Player, int(req.From), int(req.To), 1)
+}
+
+// RemoveTracks implements TrolliboxServer.
+func (srv *Server) RemoveTracks(ctx context.Context, req *RemoveTracksRequest) (*Empty, error) {
+	positions := make([]int, len(req.Positions))
+	for i, pos := range req.Positions {
+		positions[i] = int(pos)
+	}
+	return &Empty{}, srv.jukebox.RemoveTracks(ctx, req.Player, positions)
+}
+
+// Search implements TrolliboxServer.
+func (srv *Server) Search(ctx context.Context, req *SearchRequest) (*SearchResponse, error) {
+	results, err := srv.jukebox.SearchTracks(ctx, req.Library, req.Query, req.Untagged)
+	if err != nil {
+		return nil, err
+	}
+	res := &SearchResponse{Results: make([]*SearchResult, len(results))}
+	for i, result := range results {
+		res.Results[i] = &SearchResult{Track: trackToProto(&result.Track)}
+		for property, matches := range result.Matches {
+			for _, match := range matches {
+				res.Results[i].Matches = append(res.Results[i].Matches, &SearchMatch{
+					Property: property,
+					Start:    int32(match.Start),
+					End:      int32(match.End),
+				})
+			}
+		}
+	}
+	return res, nil
+}
+
+// Events implements TrolliboxServer.
+func (srv *Server) Events(req *PlayerRequest, stream Trollibox_EventsServer) error {
+	emitter, err := srv.jukebox.PlayerEvents(stream.Context(), req.Player)
+	if err != nil {
+		return statusError(err)
+	}
+	listener := emitter.Subscribe(stream.Context().Done())
+	defer listener.Close()
+	for {
+		select {
+		case e, ok := <-listener.C:
+			if !ok {
+				return nil
+			}
+			ev, ok := eventToProto(e)
+			if !ok {
+				continue
+			}
+			if err := stream.Send(ev); err != nil {
+				return err
+			}
+		case <-srv.closing:
+			return status.Error(codes.Unavailable, "server is shutting down")
+		}
+	}
+}
+
+func eventToProto(ev interface{}) (*Event, bool) {
+	switch t := ev.(type) {
+	case player.PlaylistEvent:
+		return &Event{Event: &Event_Playlist{Playlist: &PlaylistEvent{Index: int32(t.Index)}}}, true
+	case player.PlayStateEvent:
+		return &Event{Event: &Event_PlayState{PlayState: &PlayStateEvent{State: playStateToProto(t.State)}}}, true
+	case player.TimeEvent:
+		return &Event{Event: &Event_Time{Time: &TimeEvent{Time: t.Time.Seconds()}}}, true
+	case player.VolumeEvent:
+		return &Event{Event: &Event_Volume{Volume: &VolumeEvent{Volume: float32(t.Volume) / 100.0}}}, true
+	case player.ListEvent:
+		return &Event{Event: &Event_List{List: &ListEvent{}}}, true
+	case player.AvailabilityEvent:
+		return &Event{Event: &Event_Availability{Availability: &AvailabilityEvent{Available: t.Available}}}, true
+	}
+	return nil, false
+}
+
+func trackToProto(track *library.Track) *Track {
+	return &Track{
+		Uri:         track.URI,
+		Artist:      track.Artist,
+		Title:       track.Title,
+		Genre:       track.Genre,
+		Album:       track.Album,
+		AlbumArtist: track.AlbumArtist,
+		AlbumTrack:  track.AlbumTrack,
+		AlbumDisc:   track.AlbumDisc,
+		Duration:    track.Duration.Seconds(),
+		HasArt:      track.HasArt,
+		Source:      track.Source,
+		Tags:        track.Tags,
+	}
+}
+
+func playStateToProto(state player.PlayState) PlayState {
+	switch state {
+	case player.PlayStatePlaying:
+		return PlayState_PLAYING
+	case player.PlayStatePaused:
+		return PlayState_PAUSED
+	case player.PlayStateStopped:
+		return PlayState_STOPPED
+	}
+	return PlayState_PLAY_STATE_INVALID
+}
+
+func playStateFromProto(state PlayState) (player.PlayState, bool) {
+	switch state {
+	case PlayState_PLAYING:
+		return player.PlayStatePlaying, true
+	case PlayState_PAUSED:
+		return player.PlayStatePaused, true
+	case PlayState_STOPPED:
+		return player.PlayStateStopped, true
+	}
+	return player.PlayStateInvalid, false
+}
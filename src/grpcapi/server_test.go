@@ -0,0 +1,82 @@
+package grpcapi
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/polyfloyd/trollibox/src/filter"
+	"github.com/polyfloyd/trollibox/src/jukebox"
+	"github.com/polyfloyd/trollibox/src/library/stream"
+	"github.com/polyfloyd/trollibox/src/player"
+)
+
+func TestServer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trollibox-grpc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filterdb, err := filter.NewDB(path.Join(dir, "filters"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	streamdb, err := stream.NewDB(path.Join(dir, "streams"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	jb := jukebox.NewJukebox(player.SimpleList{}, nil, filterdb, streamdb, nil)
+
+	lis := bufconn.Listen(1 << 16)
+	srv := NewServer(jb, time.Second)
+	go srv.Serve(lis)
+	defer srv.Close()
+
+	ctx := context.Background()
+	conn, err := grpc.DialContext(ctx, "bufnet", grpc.WithInsecure(), grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+		return lis.Dial()
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := NewTrolliboxClient(conn)
+
+	players, err := client.GetPlayers(ctx, &Empty{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(players.Names) != 0 {
+		t.Fatalf("Unexpected players: %v", players.Names)
+	}
+
+	if _, err := client.SetPlayState(ctx, &SetPlayStateRequest{Player: "nope"}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Expected an invalid argument error, got %v", err)
+	}
+	if _, err := client.GetState(ctx, &PlayerRequest{Player: "nope"}); err == nil {
+		t.Fatal("Expected an error for an unknown player")
+	}
+}
+
+func TestEventToProto(t *testing.T) {
+	ev, ok := eventToProto(player.PlayStateEvent{State: player.PlayStatePaused})
+	if !ok || ev.GetPlayState().GetState() != PlayState_PAUSED {
+		t.Fatalf("Unexpected event: %v", ev)
+	}
+	ev, ok = eventToProto(player.VolumeEvent{Volume: 40})
+	if !ok || ev.GetVolume().GetVolume() != 0.4 {
+		t.Fatalf("Unexpected event: %v", ev)
+	}
+	if _, ok := eventToProto(struct{}{}); ok {
+		t.Fatal("Unknown events should not be mapped")
+	}
+}
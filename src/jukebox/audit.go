@@ -0,0 +1,176 @@
+package jukebox
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/polyfloyd/trollibox/src/library"
+)
+
+// The actions recorded in the audit log.
+const (
+	AuditInsert = "insert"
+	AuditRemove = "remove"
+	AuditMove   = "move"
+	AuditLoad   = "load"
+	// The playlist was replaced, the URIs are those of the new playlist.
+	AuditReplace = "replace"
+
+	// Changes to playback, the detail holds the new value.
+	AuditJump   = "jump"
+	AuditSeek   = "seek"
+	AuditState  = "state"
+	AuditVolume = "volume"
+)
+
+// An AuditEntry describes a change made to the playlist or playback of a
+// player.
+type AuditEntry struct {
+	// The position of the entry in the log, starting at 0.
+	ID   int       `json:"-"`
+	Time time.Time `json:"time"`
+	// The public ID of the client, see Client.
+	Client   string   `json:"client"`
+	Nickname string   `json:"nickname,omitempty"`
+	Action   string   `json:"action"`
+	URIs     []string `json:"uris,omitempty"`
+	// Additional information, like the name of a loaded queue.
+	Detail string `json:"detail,omitempty"`
+}
+
+// An AuditLog is an append-only record of changes made to playlists. The
+// entries of each player are appended to a file in a directory with one JSON
+// object per line.
+type AuditLog struct {
+	directory string
+	lock      sync.Mutex
+}
+
+// NewAuditLog creates an audit log that keeps its records in the specified
+// directory.
+func NewAuditLog(directory string) (*AuditLog, error) {
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return nil, err
+	}
+	return &AuditLog{directory: directory}, nil
+}
+
+// Append adds an entry to the log of the named player.
+func (al *AuditLog) Append(playerName string, entry AuditEntry) error {
+	al.lock.Lock()
+	defer al.lock.Unlock()
+	fd, err := os.OpenFile(al.file(playerName), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(fd).Encode(entry); err != nil {
+		fd.Close()
+		return err
+	}
+	return fd.Close()
+}
+
+// Entries returns up to limit entries of the named player that precede the
+// entry with the specified ID, newest first. A negative before starts at the
+// most recent entry.
+func (al *AuditLog) Entries(playerName string, before, limit int) ([]AuditEntry, error) {
+	al.lock.Lock()
+	defer al.lock.Unlock()
+	fd, err := os.Open(al.file(playerName))
+	if os.IsNotExist(err) {
+		return []AuditEntry{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(fd)
+	scanner.Buffer(nil, 1024*1024)
+	for id := 0; scanner.Scan(); id++ {
+		if before >= 0 && id >= before {
+			break
+		}
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// A line may have been left incomplete by a crash.
+			continue
+		}
+		entry.ID = id
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	if entries == nil {
+		entries = []AuditEntry{}
+	}
+	return entries, nil
+}
+
+func (al *AuditLog) file(playerName string) string {
+	return path.Join(al.directory, playerName+".jsonl")
+}
+
+// SetAuditLog configures where changes to playlists are recorded. A nil log
+// disables auditing.
+func (jb *Jukebox) SetAuditLog(al *AuditLog) {
+	jb.auditLog = al
+}
+
+// AuditEntries returns recent changes to the playlist of the named player.
+// See AuditLog.Entries.
+func (jb *Jukebox) AuditEntries(ctx context.Context, playerName string, before, limit int) ([]AuditEntry, error) {
+	if _, err := jb.players.PlayerByName(playerName); err != nil {
+		return nil, err
+	}
+	if jb.auditLog == nil {
+		return nil, ErrUnsupported
+	}
+	return jb.auditLog.Entries(playerName, before, limit)
+}
+
+// RecordAudit records a change to the playlist of the named player on behalf
+// of the client of the context. Most operations of the jukebox record
+// themselves, this is for changes made to the playlist directly.
+//
+// Failures are logged, as they should not fail the change itself.
+func (jb *Jukebox) RecordAudit(ctx context.Context, playerName, action string, uris []string, detail string) {
+	if jb.auditLog == nil {
+		return
+	}
+	client, _ := ClientFromContext(ctx)
+	entry := AuditEntry{
+		Time:     time.Now(),
+		Client:   client.PublicID,
+		Nickname: client.Nickname,
+		Action:   action,
+		URIs:     uris,
+		Detail:   detail,
+	}
+	if err := jb.auditLog.Append(playerName, entry); err != nil {
+		log.WithField("player", playerName).Errorf("Error writing the audit log: %v", err)
+	}
+}
+
+func trackURIs(tracks []library.Track) []string {
+	uris := make([]string, len(tracks))
+	for i, track := range tracks {
+		uris[i] = track.URI
+	}
+	return uris
+}
@@ -0,0 +1,56 @@
+package jukebox
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/polyfloyd/trollibox/src/library"
+	"github.com/polyfloyd/trollibox/src/player"
+)
+
+func TestAuditPlayback(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trollibox-audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	auditLog, err := NewAuditLog(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tracks := []library.Track{{URI: "a"}, {URI: "b"}}
+	pl := player.NewDummyPlayer(tracks...)
+	defer pl.Events().Close()
+	jb := NewJukebox(player.SimpleList{"dummy": pl}, nil, nil, nil, nil)
+	jb.SetAuditLog(auditLog)
+
+	ctx := WithClient(context.Background(), Client{ID: "secret", PublicID: "remote"})
+	if err := jb.InsertTracks(ctx, "dummy", -1, tracks, make([]player.TrackMeta, len(tracks))); err != nil {
+		t.Fatal(err)
+	}
+	if err := jb.SetPlayerTrackIndex(ctx, "dummy", 1, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := jb.SetPlayerVolume(ctx, "dummy", 40); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := jb.AuditEntries(ctx, "dummy", -1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Unexpected entries: %+v", entries)
+	}
+	if entries[0].Action != AuditVolume || entries[0].Detail != "40" || entries[1].Action != AuditJump || entries[1].Detail != "1" {
+		t.Fatalf("Unexpected entries: %+v", entries)
+	}
+	for _, entry := range entries {
+		if entry.Client != "remote" {
+			t.Fatalf("The entry does not hold the public ID: %+v", entry)
+		}
+	}
+}
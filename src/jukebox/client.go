@@ -0,0 +1,46 @@
+package jukebox
+
+import (
+	"context"
+
+	"github.com/polyfloyd/trollibox/src/player"
+)
+
+// A Client identifies the person on whose behalf an operation is performed.
+type Client struct {
+	// An anonymous identifier which is stable across requests. It is a
+	// secret of the client and must never be shown to others.
+	ID string
+	// An identifier derived from ID that can be shown to others, for
+	// example to attribute queued tracks.
+	PublicID string
+	// An optional display name chosen by the person.
+	Nickname string
+	// Whether the client has authenticated as an admin. Admins are not
+	// bound by restrictions like locked queues.
+	Admin bool
+}
+
+type clientContextKey struct{}
+
+// WithClient returns a context that carries the identity of a client.
+func WithClient(ctx context.Context, client Client) context.Context {
+	return context.WithValue(ctx, clientContextKey{}, client)
+}
+
+// ClientFromContext returns the client carried by the context, if any.
+func ClientFromContext(ctx context.Context) (Client, bool) {
+	client, ok := ctx.Value(clientContextKey{}).(Client)
+	return client, ok
+}
+
+// UserTrackMeta returns the metadata for tracks queued by the client of the
+// context. Tracks are attributed to a generic "user" if the client is not
+// known.
+func UserTrackMeta(ctx context.Context) player.TrackMeta {
+	client, ok := ClientFromContext(ctx)
+	if !ok || client.PublicID == "" {
+		return player.TrackMeta{QueuedBy: "user"}
+	}
+	return player.TrackMeta{QueuedBy: client.PublicID, QueuedByName: client.Nickname}
+}
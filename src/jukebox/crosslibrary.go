@@ -0,0 +1,102 @@
+package jukebox
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/polyfloyd/trollibox/src/library"
+	"github.com/polyfloyd/trollibox/src/player"
+)
+
+// ErrUnplayable is returned when tracks are queued on a player that can not
+// play them.
+var ErrUnplayable = fmt.Errorf("the player can not play this track")
+
+// resolveTracks makes tracks from other libraries playable by the player.
+//
+// Tracks that the player does not support are looked up in the libraries
+// they came from and replaced by the track in the library of the player with
+// the same artist, title and, if known, album. ErrUnplayable is returned if
+// no such track exists.
+func (jb *Jukebox) resolveTracks(pl player.Player, tracks []library.Track) ([]library.Track, error) {
+	supporter, ok := pl.(player.URISupporter)
+	if !ok {
+		return tracks, nil
+	}
+	var foreign []int
+	for i, track := range tracks {
+		if !supporter.Supports(track.URI) {
+			foreign = append(foreign, i)
+		}
+	}
+	if len(foreign) == 0 {
+		return tracks, nil
+	}
+
+	uris := make([]string, len(foreign))
+	for i, index := range foreign {
+		uris[i] = tracks[index].URI
+	}
+	sources, err := library.AllTrackInfo(jb.foreignLibraries(pl), uris...)
+	if err != nil {
+		return nil, err
+	}
+	own, err := pl.Library().Tracks()
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := append([]library.Track(nil), tracks...)
+	for i, index := range foreign {
+		equivalent, ok := findEquivalentTrack(own, sources[i])
+		if !ok {
+			return nil, fmt.Errorf("%w: %q is not in the library of %v", ErrUnplayable, uris[i], pl)
+		}
+		resolved[index] = equivalent
+	}
+	return resolved, nil
+}
+
+// foreignLibraries returns all libraries other than the one of the player.
+// Standalone libraries are listed before those of other players.
+func (jb *Jukebox) foreignLibraries(pl player.Player) []library.Library {
+	own := pl.Library()
+	var libs []library.Library
+	jb.librariesLock.RLock()
+	for _, lib := range jb.libraries {
+		if lib != own {
+			libs = append(libs, lib)
+		}
+	}
+	jb.librariesLock.RUnlock()
+
+	names, _ := jb.players.PlayerNames()
+	for _, name := range names {
+		other, err := jb.players.PlayerByName(name)
+		if err != nil || other == pl {
+			continue
+		}
+		if lib := other.Library(); lib != own {
+			libs = append(libs, lib)
+		}
+	}
+	return libs
+}
+
+// findEquivalentTrack looks for a track with the same artist and title as the
+// specified one. If both tracks have an album, it must match too.
+func findEquivalentTrack(tracks []library.Track, track library.Track) (library.Track, bool) {
+	if track.Artist == "" || track.Title == "" {
+		return library.Track{}, false
+	}
+	for _, candidate := range tracks {
+		if !strings.EqualFold(candidate.Artist, track.Artist) || !strings.EqualFold(candidate.Title, track.Title) {
+			continue
+		}
+		if candidate.Album != "" && track.Album != "" && !strings.EqualFold(candidate.Album, track.Album) {
+			continue
+		}
+		return candidate, true
+	}
+	return library.Track{}, false
+}
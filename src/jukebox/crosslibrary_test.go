@@ -0,0 +1,52 @@
+package jukebox
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/polyfloyd/trollibox/src/library"
+	"github.com/polyfloyd/trollibox/src/player"
+)
+
+type mpdLikePlayer struct {
+	*player.DummyPlayer
+}
+
+func (pl *mpdLikePlayer) Supports(uri string) bool {
+	return strings.HasPrefix(uri, "mpd://")
+}
+
+func TestInsertTracksCrossLibrary(t *testing.T) {
+	pl := &mpdLikePlayer{DummyPlayer: player.NewDummyPlayer(
+		library.Track{URI: "mpd://one.mp3", Artist: "Foo", Title: "One", Album: "Bar"},
+		library.Track{URI: "mpd://two.mp3", Artist: "Foo", Title: "Two"},
+	)}
+	defer pl.Events().Close()
+	files := player.NewDummyPlayer(
+		library.Track{URI: "file:///music/one.mp3", Artist: "foo", Title: "one", Album: "Bar"},
+		library.Track{URI: "file:///music/three.mp3", Artist: "Foo", Title: "Three"},
+	)
+	defer files.Events().Close()
+	jb := NewJukebox(player.SimpleList{"dummy": pl}, nil, nil, nil, nil)
+	if err := jb.AddLibrary("files", files); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	tracks := []library.Track{{URI: "mpd://two.mp3"}, {URI: "file:///music/one.mp3"}}
+	if err := jb.InsertTracks(ctx, "dummy", -1, tracks, make([]player.TrackMeta, 2)); err != nil {
+		t.Fatal(err)
+	}
+	plTracks, _ := pl.Playlist().Tracks()
+	if len(plTracks) != 2 || plTracks[0].URI != "mpd://two.mp3" || plTracks[1].URI != "mpd://one.mp3" {
+		t.Fatalf("Unexpected playlist: %v", plTracks)
+	}
+
+	tracks = []library.Track{{URI: "file:///music/three.mp3"}}
+	err := jb.InsertTracks(ctx, "dummy", -1, tracks, make([]player.TrackMeta, 1))
+	if !errors.Is(err, ErrUnplayable) {
+		t.Fatalf("Unexpected error for a track without equivalent: %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+package jukebox
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/polyfloyd/trollibox/src/library"
+	"github.com/polyfloyd/trollibox/src/player"
+	"github.com/polyfloyd/trollibox/src/util"
+)
+
+// IdempotencyWindow is how long the result of an operation performed with an
+// idempotency key is remembered.
+const IdempotencyWindow = 2 * time.Minute
+
+type idempotencyKey struct {
+	client string
+	key    string
+}
+
+type idempotentResult struct {
+	done    chan struct{}
+	value   interface{}
+	err     error
+	expires time.Time
+}
+
+// idempotencyCache remembers the results of recently performed operations so
+// retries of the same operation are not performed twice.
+type idempotencyCache struct {
+	entries map[idempotencyKey]*idempotentResult
+	lock    sync.Mutex
+}
+
+func newIdempotencyCache() *idempotencyCache {
+	return &idempotencyCache{entries: map[idempotencyKey]*idempotentResult{}}
+}
+
+// do runs fn unless an operation with the same key was started within the
+// idempotency window, in which case the result of that operation is returned
+// instead. Failed operations are forgotten so they may be retried.
+func (cache *idempotencyCache) do(key idempotencyKey, fn func() (interface{}, error)) (interface{}, error) {
+	cache.lock.Lock()
+	now := time.Now()
+	for k, res := range cache.entries {
+		if !res.expires.IsZero() && now.After(res.expires) {
+			delete(cache.entries, k)
+		}
+	}
+	if res, ok := cache.entries[key]; ok {
+		cache.lock.Unlock()
+		<-res.done
+		return res.value, res.err
+	}
+	res := &idempotentResult{done: make(chan struct{})}
+	cache.entries[key] = res
+	cache.lock.Unlock()
+
+	res.value, res.err = fn()
+
+	cache.lock.Lock()
+	if res.err != nil {
+		delete(cache.entries, key)
+	} else {
+		res.expires = time.Now().Add(IdempotencyWindow)
+	}
+	cache.lock.Unlock()
+	close(res.done)
+	return res.value, res.err
+}
+
+// InsertTracksOnce is like InsertTracks, but ignores requests that repeat an
+// idempotency key the client has used recently. Duplicates return the result
+// of the original request. An empty key disables deduplication.
+//
+// If ifAbsent is set, tracks that are already queued to be played are not
+// inserted again. Their positions are returned as Existing, see
+// insertTracksIfAbsent.
+//
+// The positions and identifiers of the inserted tracks are returned.
+func (jb *Jukebox) InsertTracksOnce(ctx context.Context, playerName, key string, pos int, tracks []library.Track, meta []player.TrackMeta, ifAbsent bool) (player.InsertResult, error) {
+	pl, err := jb.player(ctx, playerName)
+	if err != nil {
+		return player.InsertResult{}, err
+	}
+	if err := jb.CheckQueueLock(ctx, playerName); err != nil {
+		return player.InsertResult{}, err
+	}
+	var result player.InsertResult
+	err = util.RunUnlessDone(ctx, func() error {
+		tracks, err := jb.resolveTracks(pl, tracks)
+		if err != nil {
+			return err
+		}
+		insert := func() (player.InsertResult, error) {
+			if ifAbsent {
+				return jb.insertTracksIfAbsent(ctx, pl, playerName, pos, tracks, meta)
+			}
+			if err := jb.checkGuestInsert(ctx, pl, playerName, tracks); err != nil {
+				return player.InsertResult{}, err
+			}
+			res, err := jb.insertTracksResult(pl, playerName, pos, tracks, meta)
+			if err == nil {
+				jb.RecordAudit(ctx, playerName, AuditInsert, trackURIs(tracks), "")
+			}
+			return res, err
+		}
+		if key == "" {
+			res, err := insert()
+			result = res
+			return err
+		}
+		client, _ := ClientFromContext(ctx)
+		ikey := idempotencyKey{client: client.ID, key: playerName + "\x00" + key}
+		value, err := jb.idempotency.do(ikey, func() (interface{}, error) {
+			return insert()
+		})
+		if err == nil {
+			result = value.(player.InsertResult)
+		}
+		return err
+	})
+	return result, err
+}
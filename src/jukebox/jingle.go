@@ -0,0 +1,139 @@
+package jukebox
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/polyfloyd/trollibox/src/library"
+	"github.com/polyfloyd/trollibox/src/player"
+)
+
+// AttachJingle inserts the track with the specified URI right after the
+// playing track of the named player each time interval tracks have started
+// playing. Jingles themselves do not count towards the interval and no jingle
+// is inserted if the next track already is one.
+//
+// A player has at most one jingle, attaching another replaces it. The jingle
+// is detached by DetachJingle or when the player is closed.
+func (jb *Jukebox) AttachJingle(playerName, uri string, interval int) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid jingle interval: %d", interval)
+	}
+	pl, err := jb.players.PlayerByName(playerName)
+	if err != nil {
+		return err
+	}
+	jb.jinglesLock.Lock()
+	defer jb.jinglesLock.Unlock()
+	if stop, ok := jb.jingles[playerName]; ok {
+		close(stop)
+	}
+	stop := make(chan struct{})
+	jb.jingles[playerName] = stop
+
+	listener := pl.Events().Subscribe(stop)
+	go func() {
+		defer listener.Close()
+		counter := jingleCounter{uri: uri, interval: interval}
+		for event := range listener.C {
+			switch event.(type) {
+			case player.PlaylistEvent, player.PlayStateEvent:
+			default:
+				continue
+			}
+			index, tracks, err := playingTrack(pl)
+			if err != nil {
+				log.WithField("player", playerName).Errorf("Error inserting jingle: %v", err)
+				continue
+			}
+			pos := counter.observe(index, tracks)
+			if pos < 0 {
+				continue
+			}
+			if err := jb.insertJingle(pl, playerName, pos, uri); err != nil {
+				log.WithField("player", playerName).Errorf("Error inserting jingle: %v", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// DetachJingle stops inserting the jingle of the named player, if it has one.
+func (jb *Jukebox) DetachJingle(playerName string) {
+	jb.jinglesLock.Lock()
+	defer jb.jinglesLock.Unlock()
+	if stop, ok := jb.jingles[playerName]; ok {
+		close(stop)
+		delete(jb.jingles, playerName)
+	}
+}
+
+// insertJingle inserts a jingle at the specified position through the same
+// path as other tracks, so it is resolved like any other track.
+func (jb *Jukebox) insertJingle(pl player.Player, playerName string, pos int, uri string) error {
+	tracks, err := jb.resolveTracks(pl, []library.Track{{URI: uri}})
+	if err != nil {
+		return err
+	}
+	return jb.insertTracks(pl, playerName, pos, tracks, []player.TrackMeta{{QueuedBy: "system"}})
+}
+
+// A jingleCounter counts the tracks that start playing to determine when a
+// jingle is due.
+type jingleCounter struct {
+	// The URI of the jingle.
+	uri      string
+	interval int
+	count    int
+
+	// The entry of the playlist that was playing when last observed.
+	lastURI   string
+	lastIndex int
+	lastLen   int
+}
+
+// observe is called with the index of the playing track and the playlist it
+// is in each time either changes. The position at which a jingle should be
+// inserted is returned, -1 if no jingle is due.
+func (jc *jingleCounter) observe(index int, tracks []library.Track) int {
+	if index < 0 {
+		return -1
+	}
+	uri := tracks[index].URI
+	// Tracks inserted or removed before the playing track shift its index
+	// along with the length of the playlist, it is still the same entry. The
+	// URI alone can not tell repeated tracks apart.
+	shift := index - jc.lastIndex
+	same := uri == jc.lastURI && (shift == 0 || shift == len(tracks)-jc.lastLen)
+	jc.lastURI, jc.lastIndex, jc.lastLen = uri, index, len(tracks)
+	if same || uri == jc.uri {
+		return -1
+	}
+	if jc.count++; jc.count < jc.interval {
+		return -1
+	}
+	jc.count = 0
+	if index+1 < len(tracks) && tracks[index+1].URI == jc.uri {
+		return -1
+	}
+	return index + 1
+}
+
+// playingTrack returns the index of the track that is currently playing and
+// the playlist it is in. The index is -1 if the player is not playing.
+func playingTrack(pl player.Player) (int, []library.Track, error) {
+	state, err := pl.State()
+	if err != nil || state != player.PlayStatePlaying {
+		return -1, nil, err
+	}
+	index, err := pl.TrackIndex()
+	if err != nil || index < 0 {
+		return -1, nil, err
+	}
+	tracks, err := pl.Playlist().Tracks()
+	if err != nil || index >= len(tracks) {
+		return -1, nil, err
+	}
+	return index, tracks, nil
+}
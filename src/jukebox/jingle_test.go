@@ -0,0 +1,80 @@
+package jukebox
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/polyfloyd/trollibox/src/library"
+	"github.com/polyfloyd/trollibox/src/player"
+)
+
+func TestJingle(t *testing.T) {
+	tracks := []library.Track{{URI: "a"}, {URI: "b"}}
+	pl := player.NewDummyPlayer(tracks...)
+	defer pl.Events().Close()
+	jb := NewJukebox(player.SimpleList{"dummy": pl}, nil, nil, nil, nil)
+	if err := jb.AttachJingle("dummy", "jingle", 1); err != nil {
+		t.Fatal(err)
+	}
+	defer jb.DetachJingle("dummy")
+	if err := pl.Playlist().InsertWithMeta(-1, tracks, make([]player.TrackMeta, len(tracks))); err != nil {
+		t.Fatal(err)
+	}
+	if err := jb.SetPlayerState(context.Background(), "dummy", player.PlayStatePlaying); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"a", "jingle", "b"}
+	var uris []string
+	for i := 0; i < 100; i++ {
+		plist, err := pl.Playlist().Tracks()
+		if err != nil {
+			t.Fatal(err)
+		}
+		uris = make([]string, len(plist))
+		for i, track := range plist {
+			uris[i] = track.URI
+		}
+		if reflect.DeepEqual(uris, expected) {
+			return
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	t.Fatalf("Unexpected playlist: %v", uris)
+}
+
+func TestJingleCounter(t *testing.T) {
+	playlist := func(uris ...string) []library.Track {
+		tracks := make([]library.Track, len(uris))
+		for i, uri := range uris {
+			tracks[i].URI = uri
+		}
+		return tracks
+	}
+	jc := jingleCounter{uri: "jingle", interval: 2}
+	steps := []struct {
+		index  int
+		tracks []library.Track
+		pos    int
+	}{
+		{0, playlist("a", "a", "b", "c"), -1},
+		// Repeated tracks count separately.
+		{1, playlist("a", "a", "b", "c"), 2},
+		{1, playlist("a", "a", "jingle", "b", "c"), -1},
+		{2, playlist("a", "a", "jingle", "b", "c"), -1},
+		{3, playlist("a", "a", "jingle", "b", "c"), -1},
+		// Removing a track before the playing one does not start a track.
+		{2, playlist("a", "jingle", "b", "c"), -1},
+		{3, playlist("a", "jingle", "b", "c"), 4},
+		// Nor does stopping and playing again.
+		{-1, nil, -1},
+		{3, playlist("a", "jingle", "b", "c", "jingle"), -1},
+	}
+	for i, step := range steps {
+		if pos := jc.observe(step.index, step.tracks); pos != step.pos {
+			t.Fatalf("Unexpected jingle position at step %d: %d != %d", i, pos, step.pos)
+		}
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"math/rand"
 	"sort"
+	"strconv"
+	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -14,6 +17,7 @@ import (
 	"github.com/polyfloyd/trollibox/src/library"
 	"github.com/polyfloyd/trollibox/src/library/netmedia"
 	"github.com/polyfloyd/trollibox/src/library/raw"
+	"github.com/polyfloyd/trollibox/src/library/silence"
 	"github.com/polyfloyd/trollibox/src/library/stream"
 	"github.com/polyfloyd/trollibox/src/player"
 	"github.com/polyfloyd/trollibox/src/util"
@@ -23,6 +27,24 @@ import (
 // when a player is registered but unreachable for any reason.
 var ErrPlayerUnavailable = fmt.Errorf("the player is not available")
 
+// ErrUnsupported is returned from functions that require functionality which
+// the player does not implement. It is the same error as
+// player.ErrUnsupported.
+var ErrUnsupported = player.ErrUnsupported
+
+// InsertMode determines where tracks that are appended by users end up in the
+// playlist of a player.
+type InsertMode int
+
+const (
+	// InsertAppend appends tracks to the end of the playlist.
+	InsertAppend InsertMode = iota
+	// InsertRandom inserts tracks at a random position after the currently
+	// playing track, which may also be the end of the playlist. This prevents
+	// early arrivals from monopolizing the front of the playlist.
+	InsertRandom
+)
+
 // Jukebox augments one or more players with with filters, streams and other
 // functionality.
 type Jukebox struct {
@@ -31,27 +53,408 @@ type Jukebox struct {
 	filterdb  *filter.DB
 	streamdb  *stream.DB
 	rawServer *raw.Server
+
+	silenceServer *silence.Server
+
+	queueStore  *player.QueueStore
+	idempotency *idempotencyCache
+
+	playHistory      *player.PlayHistory
+	trendingHalfLife time.Duration
+
+	// The most recent plays by player name, see recentPlays.
+	recentPlays     map[string][]player.Play
+	recentPlaysLock sync.Mutex
+
+	auditLog *AuditLog
+	settings *SettingsStore
+
+	libraries     map[string]library.Library
+	librariesLock sync.RWMutex
+
+	// Serializes insertions that check which tracks are already queued.
+	ifAbsentLock sync.Mutex
+
+	insertModes     map[string]InsertMode
+	insertModesLock sync.RWMutex
+
+	serverSearch     map[string]bool
+	serverSearchLock sync.RWMutex
+	// Whether searches ignore diacritics and punctuation.
+	normalizedSearch bool
+
+	// Maps player names to the ID of the client that locked the queue.
+	queueLocks     map[string]Client
+	queueLocksLock sync.RWMutex
+
+	// Whether party mode is enabled, by player name. Filled from the
+	// settings as players are looked up.
+	partyMode     map[string]bool
+	partyModeLock sync.Mutex
+
+	// The votes to skip the current track, by player name.
+	skipVotes     map[string]*skipVote
+	skipVotesLock sync.Mutex
+
+	// Maps player names to the URIs of the tracks pinned to the front of
+	// their queue.
+	pins        map[string][]string
+	pinWatchers map[string]bool
+	pinsLock    sync.Mutex
+
+	// Maps player names to the channels that stop their jingles.
+	jingles     map[string]chan struct{}
+	jinglesLock sync.Mutex
+
+	// The queues saved on the last shutdown that may be restored.
+	restoreOffers     map[string]ShutdownState
+	restoreOffersLock sync.Mutex
+
+	nowPlaying        map[string]NowPlaying
+	defaultNowPlaying string
+	nowPlayingLock    sync.Mutex
+
+	rand     *rand.Rand
+	randLock sync.Mutex
 }
 
 func NewJukebox(players player.List, netServer *netmedia.Server, filterdb *filter.DB, streamdb *stream.DB, rawServer *raw.Server) *Jukebox {
 	return &Jukebox{
-		players:   players,
-		netServer: netServer,
-		filterdb:  filterdb,
-		streamdb:  streamdb,
-		rawServer: rawServer,
+		players:       players,
+		netServer:     netServer,
+		filterdb:      filterdb,
+		streamdb:      streamdb,
+		rawServer:     rawServer,
+		libraries:     map[string]library.Library{},
+		insertModes:   map[string]InsertMode{},
+		serverSearch:  map[string]bool{},
+		queueLocks:    map[string]Client{},
+		partyMode:     map[string]bool{},
+		skipVotes:     map[string]*skipVote{},
+		recentPlays:   map[string][]player.Play{},
+		idempotency:   newIdempotencyCache(),
+		nowPlaying:    map[string]NowPlaying{},
+		restoreOffers: map[string]ShutdownState{},
+		pins:          map[string][]string{},
+		pinWatchers:   map[string]bool{},
+		jingles:       map[string]chan struct{}{},
+		rand:          rand.New(rand.NewSource(time.Now().Unix())),
+	}
+}
+
+// SetInsertMode configures how tracks appended to the playlist of the named
+// player are positioned. The default is InsertAppend.
+func (jb *Jukebox) SetInsertMode(playerName string, mode InsertMode) {
+	jb.insertModesLock.Lock()
+	defer jb.insertModesLock.Unlock()
+	jb.insertModes[playerName] = mode
+}
+
+// SetServerSearch configures whether searches in the library of the named
+// player are offloaded to the server when possible. This requires the player
+// to implement player.ServerSearcher.
+//
+// Queries that only match tags are sent to the server, while others are
+// always handled by filtering all tracks. Because the server only knows the
+// tags of the files, tracks of which Trollibox infers metadata may be missed.
+func (jb *Jukebox) SetServerSearch(playerName string, enabled bool) {
+	jb.serverSearchLock.Lock()
+	defer jb.serverSearchLock.Unlock()
+	jb.serverSearch[playerName] = enabled
+}
+
+// SetNormalizedSearch configures whether searches ignore diacritics and
+// punctuation, see keyed.CompileNormalizedQuery. By default, searches are
+// only case insensitive. It should be called before searching.
+//
+// Libraries that keep a search index build it on the first search.
+func (jb *Jukebox) SetNormalizedSearch(enabled bool) {
+	jb.normalizedSearch = enabled
+}
+
+// InsertTracks inserts tracks into the playlist of the named player at the
+// specified position. Position -1 appends the tracks, which is subject to the
+// player's InsertMode and party mode.
+//
+// Tracks from the libraries of other players or standalone libraries which
+// the player can not play are replaced by the equivalent track in its own
+// library. ErrUnplayable is returned if there is none.
+//
+// Guests can not queue tracks on the blocklist or more tracks than the queue
+// cap allows, see SettingBlocklist and SettingQueueCap.
+func (jb *Jukebox) InsertTracks(ctx context.Context, playerName string, pos int, tracks []library.Track, meta []player.TrackMeta) error {
+	pl, err := jb.player(ctx, playerName)
+	if err != nil {
+		return err
+	}
+	if err := jb.CheckQueueLock(ctx, playerName); err != nil {
+		return err
+	}
+	return util.RunUnlessDone(ctx, func() error {
+		tracks, err := jb.resolveTracks(pl, tracks)
+		if err != nil {
+			return err
+		}
+		if err := jb.checkGuestInsert(ctx, pl, playerName, tracks); err != nil {
+			return err
+		}
+		if err := jb.insertTracks(pl, playerName, pos, tracks, meta); err != nil {
+			return err
+		}
+		jb.RecordAudit(ctx, playerName, AuditInsert, trackURIs(tracks), "")
+		return nil
+	})
+}
+
+// SetPlaylist changes the playlist of the named player so it holds exactly the
+// specified tracks. Tracks that are already queued are kept in place. See
+// player.SetPlaylist.
+//
+// If dryRun is set, only the operations that would be performed are returned.
+// Guests can not replace the playlist in party mode, nor queue tracks on the
+// blocklist. The queue cap does not apply, as the whole playlist is replaced.
+func (jb *Jukebox) SetPlaylist(ctx context.Context, playerName string, tracks []library.Track, meta []player.TrackMeta, dryRun bool) ([]player.PlaylistOp, error) {
+	pl, err := jb.player(ctx, playerName)
+	if err != nil {
+		return nil, err
+	}
+	if err := jb.CheckQueueLock(ctx, playerName); err != nil {
+		return nil, err
+	}
+	if err := jb.CheckPartyMode(ctx, playerName); err != nil {
+		return nil, err
+	}
+	var ops []player.PlaylistOp
+	err = util.RunUnlessDone(ctx, func() error {
+		tracks, err := jb.resolveTracks(pl, tracks)
+		if err != nil {
+			return err
+		}
+		if err := jb.checkBlocklist(ctx, playerName, tracks); err != nil {
+			return err
+		}
+		if ops, err = player.SetPlaylist(pl.Playlist(), tracks, meta, dryRun); err != nil {
+			return err
+		}
+		if !dryRun {
+			jb.RecordAudit(ctx, playerName, AuditReplace, trackURIs(tracks), "")
+		}
+		return nil
+	})
+	return ops, err
+}
+
+func (jb *Jukebox) insertTracks(pl player.Player, playerName string, pos int, tracks []library.Track, meta []player.TrackMeta) error {
+	_, err := jb.insertTracksResult(pl, playerName, pos, tracks, meta)
+	return err
+}
+
+// insertTracksResult is like insertTracks, but also reports where the tracks
+// ended up.
+func (jb *Jukebox) insertTracksResult(pl player.Player, playerName string, pos int, tracks []library.Track, meta []player.TrackMeta) (player.InsertResult, error) {
+	if pos == -1 {
+		jb.insertModesLock.RLock()
+		mode := jb.insertModes[playerName]
+		jb.insertModesLock.RUnlock()
+
+		if mode == InsertRandom || jb.PartyMode(playerName) {
+			var err error
+			if pos, err = jb.randomInsertPosition(pl); err != nil {
+				return player.InsertResult{}, err
+			}
+		}
+	}
+	plist := pl.Playlist()
+	if reporter, ok := plist.(player.InsertReporter); ok {
+		return reporter.InsertWithMetaResult(pos, tracks, meta)
+	}
+
+	start := pos
+	if pos == -1 {
+		var err error
+		if start, err = plist.Len(); err != nil {
+			return player.InsertResult{}, err
+		}
+	}
+	if err := plist.InsertWithMeta(pos, tracks, meta); err != nil {
+		return player.InsertResult{}, err
+	}
+	result := player.InsertResult{Positions: make([]int, len(tracks))}
+	for i := range tracks {
+		result.Positions[i] = start + i
+	}
+	return result, nil
+}
+
+// insertTracksIfAbsent inserts only the tracks that are not already queued.
+// The current track and those after it count as queued, tracks that have
+// already been played may be requested again. Tracks that occur multiple
+// times in the request are inserted once.
+//
+// Every requested track is reported in the result: the inserted tracks in
+// Positions and the skipped ones in Existing, both in the order of the
+// request. Existing holds the positions after the insertion.
+func (jb *Jukebox) insertTracksIfAbsent(ctx context.Context, pl player.Player, playerName string, pos int, tracks []library.Track, meta []player.TrackMeta) (player.InsertResult, error) {
+	jb.ifAbsentLock.Lock()
+	defer jb.ifAbsentLock.Unlock()
+	queued, err := pl.Playlist().Tracks()
+	if err != nil {
+		return player.InsertResult{}, err
+	}
+	current, err := pl.TrackIndex()
+	if err != nil {
+		return player.InsertResult{}, err
+	}
+	if current < 0 {
+		current = 0
+	}
+	queuedAt := map[string]int{}
+	for i := len(queued) - 1; i >= current; i-- {
+		queuedAt[queued[i].URI] = i
+	}
+
+	// A skipped track is either queued at a position in the playlist or is
+	// inserted by this request, in which case it refers to the index of the
+	// inserted track.
+	type skippedTrack struct {
+		at, absent int
 	}
+	var skipped []skippedTrack
+	var absent []library.Track
+	var absentMeta []player.TrackMeta
+	requested := map[string]int{}
+	for i, track := range tracks {
+		if at, ok := queuedAt[track.URI]; ok {
+			skipped = append(skipped, skippedTrack{at: at, absent: -1})
+			continue
+		}
+		if j, ok := requested[track.URI]; ok {
+			skipped = append(skipped, skippedTrack{absent: j})
+			continue
+		}
+		requested[track.URI] = len(absent)
+		absent = append(absent, track)
+		absentMeta = append(absentMeta, meta[i])
+	}
+
+	if err := jb.checkGuestInsert(ctx, pl, playerName, absent); err != nil {
+		return player.InsertResult{}, err
+	}
+	var result player.InsertResult
+	if len(absent) > 0 {
+		if result, err = jb.insertTracksResult(pl, playerName, pos, absent, absentMeta); err != nil {
+			return player.InsertResult{}, err
+		}
+		jb.RecordAudit(ctx, playerName, AuditInsert, trackURIs(absent), "")
+	}
+	inserted := append([]int(nil), result.Positions...)
+	sort.Ints(inserted)
+	for _, skip := range skipped {
+		if skip.absent >= 0 {
+			result.Existing = append(result.Existing, result.Positions[skip.absent])
+			continue
+		}
+		// Queued tracks move down by each track inserted before them.
+		at := skip.at
+		for _, p := range inserted {
+			if p <= at {
+				at++
+			}
+		}
+		result.Existing = append(result.Existing, at)
+	}
+	return result, nil
+}
+
+// randomInsertPosition picks a random position in the part of the playlist
+// that has not been played yet. The currently playing track is never
+// displaced.
+//
+// The end of the playlist is one of the candidates, so every gap between the
+// upcoming tracks is equally likely, including the one after the last track.
+// Excluding it would always place a track before the last queued one.
+func (jb *Jukebox) randomInsertPosition(pl player.Player) (int, error) {
+	plistLen, err := pl.Playlist().Len()
+	if err != nil {
+		return -1, err
+	}
+	current, err := pl.TrackIndex()
+	if err != nil {
+		return -1, err
+	}
+	lower := current + 1
+	if lower >= plistLen {
+		return -1, nil
+	}
+	jb.randLock.Lock()
+	defer jb.randLock.Unlock()
+	return lower + jb.rand.Intn(plistLen-lower+1), nil
 }
 
 func (jb *Jukebox) Players(ctx context.Context) ([]string, error) {
 	return jb.players.PlayerNames()
 }
 
+// AddLibrary registers a library that exists independently of any player.
+//
+// Libraries are looked up by the same name space as players, the library of a
+// player takes precedence over a standalone library with the same name.
+func (jb *Jukebox) AddLibrary(name string, lib library.Library) error {
+	if !player.ValidListName.MatchString(name) {
+		return fmt.Errorf("invalid library name: %q", name)
+	}
+	if _, err := jb.players.PlayerByName(name); err == nil {
+		log.Warnf("The library %q is shadowed by the player with the same name", name)
+	}
+	jb.librariesLock.Lock()
+	defer jb.librariesLock.Unlock()
+	jb.libraries[name] = lib
+	return nil
+}
+
+// Libraries returns the names of all libraries, including those of players.
+func (jb *Jukebox) Libraries(ctx context.Context) ([]string, error) {
+	names, err := jb.players.PlayerNames()
+	if err != nil {
+		return nil, err
+	}
+	jb.librariesLock.RLock()
+	defer jb.librariesLock.RUnlock()
+	for name := range jb.libraries {
+		if _, err := jb.players.PlayerByName(name); err != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Library looks up the library of the player with the specified name or a
+// standalone library if there is no such player.
+func (jb *Jukebox) Library(ctx context.Context, name string) (library.Library, error) {
+	if _, err := jb.players.PlayerByName(name); err == nil {
+		return jb.PlayerLibrary(ctx, name)
+	}
+	jb.librariesLock.RLock()
+	lib, ok := jb.libraries[name]
+	jb.librariesLock.RUnlock()
+	if ok {
+		return lib, nil
+	}
+	return jb.PlayerLibrary(ctx, name)
+}
+
 func (jb *Jukebox) AppendRawFile(ctx context.Context, playerName string, file io.Reader, filename string) error {
-	pl, err := jb.player(playerName)
+	pl, err := jb.player(ctx, playerName)
 	if err != nil {
 		return err
 	}
+	if err := jb.CheckQueueLock(ctx, playerName); err != nil {
+		return err
+	}
+	if err := jb.checkQueueCap(ctx, pl, playerName, 1); err != nil {
+		return err
+	}
 
 	track, errs := jb.rawServer.Add(ctx, filename, nil, "", func(ctx context.Context, w io.Writer) error {
 		_, err := io.Copy(w, file)
@@ -66,18 +469,61 @@ func (jb *Jukebox) AppendRawFile(ctx context.Context, playerName string, file io
 	// the server.
 	go jb.removeRawTrack(playerName, track, jb.rawServer)
 
-	return pl.Playlist().InsertWithMeta(-1, []library.Track{track}, []player.TrackMeta{
-		{QueuedBy: "user"},
+	err = jb.insertTracks(pl, playerName, -1, []library.Track{track}, []player.TrackMeta{
+		UserTrackMeta(ctx),
 	})
+	if err != nil {
+		return err
+	}
+	jb.RecordAudit(ctx, playerName, AuditInsert, []string{track.URI}, filename)
+	return nil
 }
 
-func (jb *Jukebox) AppendNetFile(ctx context.Context, playerName, url string) error {
-	pl, err := jb.player(playerName)
+// AppendSilence appends a gap of silence lasting the specified duration to the
+// playlist of the named player. See silence.Server.
+func (jb *Jukebox) AppendSilence(ctx context.Context, playerName string, duration time.Duration) error {
+	pl, err := jb.player(ctx, playerName)
+	if err != nil {
+		return err
+	}
+	if err := jb.CheckQueueLock(ctx, playerName); err != nil {
+		return err
+	}
+	if jb.silenceServer == nil {
+		return ErrUnsupported
+	}
+	if err := jb.checkQueueCap(ctx, pl, playerName, 1); err != nil {
+		return err
+	}
+	track, err := jb.silenceServer.Track(duration)
 	if err != nil {
 		return err
 	}
+	if err := jb.insertTracks(pl, playerName, -1, []library.Track{track}, []player.TrackMeta{UserTrackMeta(ctx)}); err != nil {
+		return err
+	}
+	jb.RecordAudit(ctx, playerName, AuditInsert, []string{track.URI}, "")
+	return nil
+}
+
+// AppendNetFile downloads the media at the specified URL and appends it to
+// the playlist of the named player.
+//
+// If title is not empty, it is displayed as the title of the track instead of
+// the title reported by the source.
+func (jb *Jukebox) AppendNetFile(ctx context.Context, playerName, url, title string) error {
+	pl, err := jb.player(ctx, playerName)
+	if err != nil {
+		return err
+	}
+	if err := jb.CheckQueueLock(ctx, playerName); err != nil {
+		return err
+	}
+	if err := jb.checkQueueCap(ctx, pl, playerName, 1); err != nil {
+		return err
+	}
 
-	track, errc := jb.netServer.Download(url)
+	track, errc := jb.netServer.Download(url, title)
 	go func() {
 		if err := <-errc; err != nil {
 			log.Error(err)
@@ -89,92 +535,480 @@ func (jb *Jukebox) AppendNetFile(ctx context.Context, playerName, url string) er
 	// the server.
 	go jb.removeRawTrack(playerName, track, jb.netServer.RawServer())
 
-	return pl.Playlist().InsertWithMeta(-1, []library.Track{track}, []player.TrackMeta{
-		{QueuedBy: "user"},
-	})
+	// The title is kept in the metadata too, as the raw track is gone once
+	// the server restarts.
+	meta := UserTrackMeta(ctx)
+	meta.Title = title
+	if err := jb.insertTracks(pl, playerName, -1, []library.Track{track}, []player.TrackMeta{meta}); err != nil {
+		return err
+	}
+	jb.RecordAudit(ctx, playerName, AuditInsert, []string{track.URI}, url)
+	return nil
 }
 
 func (jb *Jukebox) PlayerTrackIndex(ctx context.Context, playerName string) (int, error) {
-	pl, err := jb.player(playerName)
+	pl, err := jb.queryPlayer(ctx, playerName)
 	if err != nil {
 		return -1, err
 	}
-	return pl.TrackIndex()
+	var index int
+	err = util.WithContext(ctx, func() (err error) {
+		index, err = pl.TrackIndex()
+		return
+	})
+	return index, err
 }
 
+// SetPlayerTrackIndex jumps playback to the track at the specified index,
+// which is relative to the current track if relative is set. Guests can not
+// skip tracks in party mode, see VoteSkip.
 func (jb *Jukebox) SetPlayerTrackIndex(ctx context.Context, playerName string, index int, relative bool) error {
-	pl, err := jb.player(playerName)
+	pl, err := jb.player(ctx, playerName)
 	if err != nil {
 		return err
 	}
-	if relative {
-		cur, err := pl.TrackIndex()
+	if err := jb.CheckPartyMode(ctx, playerName); err != nil {
+		return err
+	}
+	return util.RunUnlessDone(ctx, func() error {
+		if relative {
+			cur, err := pl.TrackIndex()
+			if err != nil {
+				return err
+			}
+			index += cur
+		}
+		if err := pl.SetTrackIndex(index); err != nil {
+			return err
+		}
+		jb.RecordAudit(ctx, playerName, AuditJump, nil, strconv.Itoa(index))
+		return nil
+	})
+}
+
+// PlayQueueIndex jumps playback to the track at the specified index in the
+// playlist of the player, optionally removing all tracks before it. Guests can
+// not remove tracks in party mode.
+func (jb *Jukebox) PlayQueueIndex(ctx context.Context, playerName string, index int, dropPreceding bool) error {
+	pl, err := jb.player(ctx, playerName)
+	if err != nil {
+		return err
+	}
+	if err := jb.CheckQueueLock(ctx, playerName); err != nil {
+		return err
+	}
+	if dropPreceding {
+		if err := jb.CheckPartyMode(ctx, playerName); err != nil {
+			return err
+		}
+	}
+	return util.RunUnlessDone(ctx, func() error {
+		var dropped []library.Track
+		if dropPreceding && index > 0 {
+			tracks, err := pl.Playlist().Tracks()
+			if err != nil {
+				return err
+			}
+			if index <= len(tracks) {
+				dropped = tracks[:index]
+			}
+		}
+		if err := player.PlayQueueIndex(pl, index, dropPreceding); err != nil {
+			return err
+		}
+		if len(dropped) > 0 {
+			jb.RecordAudit(ctx, playerName, AuditRemove, trackURIs(dropped), "")
+		}
+		return nil
+	})
+}
+
+// ErrNoNextAlbum is returned when skipping to the next album while the rest of
+// the playlist is part of the current album.
+var ErrNoNextAlbum = fmt.Errorf("there is no other album in the playlist")
+
+// PlayNextAlbum skips the rest of the album of the current track and plays the
+// first track after it that is part of another album. Albums are told apart
+// by their album artist and title. If dropPreceding is set, all tracks before
+// it are removed, including those of the skipped album.
+//
+// The index of the track that is now playing is returned. Guests can not skip
+// albums in party mode, as the queued tracks of the album would be lost.
+func (jb *Jukebox) PlayNextAlbum(ctx context.Context, playerName string, dropPreceding bool) (int, error) {
+	pl, err := jb.player(ctx, playerName)
+	if err != nil {
+		return -1, err
+	}
+	if err := jb.CheckQueueLock(ctx, playerName); err != nil {
+		return -1, err
+	}
+	if err := jb.CheckPartyMode(ctx, playerName); err != nil {
+		return -1, err
+	}
+	var next int
+	err = util.RunUnlessDone(ctx, func() error {
+		current, err := pl.TrackIndex()
+		if err != nil {
+			return err
+		}
+		if current < 0 {
+			return fmt.Errorf("there is no current track")
+		}
+		tracks, err := pl.Playlist().Tracks()
+		if err != nil {
+			return err
+		}
+		if current >= len(tracks) {
+			return ErrNoNextAlbum
+		}
+		// The playlist may only hold the URIs of the tracks, so the albums
+		// are looked up in the libraries.
+		libs, err := jb.PlayerLibraries(ctx, playerName)
 		if err != nil {
 			return err
 		}
-		index += cur
+		info, err := library.AllTrackInfo(libs, trackURIs(tracks[current:])...)
+		if err != nil {
+			return err
+		}
+		if next = nextAlbumIndex(info, 0); next < 0 {
+			return ErrNoNextAlbum
+		}
+		next += current
+		var dropped []library.Track
+		if dropPreceding {
+			dropped = tracks[:next]
+		}
+		if err := player.PlayQueueIndex(pl, next, dropPreceding); err != nil {
+			return err
+		}
+		if len(dropped) > 0 {
+			jb.RecordAudit(ctx, playerName, AuditRemove, trackURIs(dropped), "")
+			next = 0
+		}
+		return nil
+	})
+	return next, err
+}
+
+// nextAlbumIndex returns the index of the first track after current that is
+// not part of the same album, or -1 if there is none.
+func nextAlbumIndex(tracks []library.Track, current int) int {
+	if current < 0 || current >= len(tracks) {
+		return -1
+	}
+	cur := tracks[current]
+	for i := current + 1; i < len(tracks); i++ {
+		if tracks[i].AlbumArtist != cur.AlbumArtist || tracks[i].Album != cur.Album {
+			return i
+		}
+	}
+	return -1
+}
+
+// PlayerCurrentTrack returns the track the player is currently at, or nil if
+// there is none.
+func (jb *Jukebox) PlayerCurrentTrack(ctx context.Context, playerName string) (*library.Track, error) {
+	pl, err := jb.queryPlayer(ctx, playerName)
+	if err != nil {
+		return nil, err
 	}
-	return pl.SetTrackIndex(index)
+	var track *library.Track
+	err = util.WithContext(ctx, func() error {
+		index, err := pl.TrackIndex()
+		if err != nil || index < 0 {
+			return err
+		}
+		tracks, err := pl.Playlist().Tracks()
+		if err != nil || index >= len(tracks) {
+			return err
+		}
+		libs, err := jb.PlayerLibraries(ctx, playerName)
+		if err != nil {
+			return err
+		}
+		info, err := library.AllTrackInfo(libs, tracks[index].URI)
+		if err != nil {
+			return err
+		}
+		track = &info[0]
+		return nil
+	})
+	return track, err
 }
 
 func (jb *Jukebox) PlayerTime(ctx context.Context, playerName string) (time.Duration, error) {
-	pl, err := jb.player(playerName)
+	pl, err := jb.queryPlayer(ctx, playerName)
 	if err != nil {
 		return 0, err
 	}
-	return pl.Time()
+	var tim time.Duration
+	err = util.WithContext(ctx, func() (err error) {
+		tim, err = pl.Time()
+		return
+	})
+	return tim, err
 }
 
 func (jb *Jukebox) SetPlayerTime(ctx context.Context, playerName string, t time.Duration) error {
-	pl, err := jb.player(playerName)
+	pl, err := jb.player(ctx, playerName)
 	if err != nil {
 		return err
 	}
-	return pl.SetTime(t)
+	return util.RunUnlessDone(ctx, func() error {
+		if err := pl.SetTime(t); err != nil {
+			return err
+		}
+		jb.RecordAudit(ctx, playerName, AuditSeek, nil, t.String())
+		return nil
+	})
 }
 
 func (jb *Jukebox) PlayerState(ctx context.Context, playerName string) (player.PlayState, error) {
-	pl, err := jb.player(playerName)
+	pl, err := jb.queryPlayer(ctx, playerName)
 	if err != nil {
 		return player.PlayStateInvalid, err
 	}
-	return pl.State()
+	var state player.PlayState
+	err = util.WithContext(ctx, func() (err error) {
+		state, err = pl.State()
+		return
+	})
+	return state, err
 }
 
+// SetPlayerState changes the playstate of the named player. Guests can not
+// stop playback in party mode.
 func (jb *Jukebox) SetPlayerState(ctx context.Context, playerName string, state player.PlayState) error {
-	pl, err := jb.player(playerName)
+	pl, err := jb.player(ctx, playerName)
 	if err != nil {
 		return err
 	}
-	return pl.SetState(state)
+	if state == player.PlayStateStopped {
+		if err := jb.CheckPartyMode(ctx, playerName); err != nil {
+			return err
+		}
+	}
+	return util.RunUnlessDone(ctx, func() error {
+		if err := pl.SetState(state); err != nil {
+			return err
+		}
+		jb.RecordAudit(ctx, playerName, AuditState, nil, string(state))
+		return nil
+	})
 }
 
 func (jb *Jukebox) PlayerVolume(ctx context.Context, playerName string) (int, error) {
-	pl, err := jb.player(playerName)
+	pl, err := jb.queryPlayer(ctx, playerName)
 	if err != nil {
 		return 0, err
 	}
-	return pl.Volume()
+	var vol int
+	err = util.WithContext(ctx, func() (err error) {
+		vol, err = pl.Volume()
+		return
+	})
+	return vol, err
 }
 
 func (jb *Jukebox) SetPlayerVolume(ctx context.Context, playerName string, vol int) error {
-	pl, err := jb.player(playerName)
+	pl, err := jb.player(ctx, playerName)
 	if err != nil {
 		return err
 	}
-	return pl.SetVolume(vol)
+	return util.RunUnlessDone(ctx, func() error {
+		if err := pl.SetVolume(vol); err != nil {
+			return err
+		}
+		jb.RecordAudit(ctx, playerName, AuditVolume, nil, strconv.Itoa(vol))
+		return nil
+	})
+}
+
+func (jb *Jukebox) PlayerServerStats(ctx context.Context, playerName string) (player.ServerStats, error) {
+	pl, err := jb.queryPlayer(ctx, playerName)
+	if err != nil {
+		return player.ServerStats{}, err
+	}
+	reporter, ok := pl.(player.StatsReporter)
+	if !ok {
+		return player.ServerStats{}, ErrUnsupported
+	}
+	var stats player.ServerStats
+	err = util.WithContext(ctx, func() (err error) {
+		stats, err = reporter.ServerStats()
+		return
+	})
+	return stats, err
+}
+
+// A PlayCount is a track along with the number of times it was played.
+type PlayCount struct {
+	Track library.Track
+	Count int
+}
+
+// MostPlayed returns the tracks of the named player that were played most
+// often, most played first. A positive limit caps the number of tracks
+// returned.
+func (jb *Jukebox) MostPlayed(ctx context.Context, playerName string, limit int) ([]PlayCount, error) {
+	pl, err := jb.queryPlayer(ctx, playerName)
+	if err != nil {
+		return nil, err
+	}
+	counter, ok := pl.(player.PlayCounter)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+	var mostPlayed []PlayCount
+	err = util.WithContext(ctx, func() error {
+		counts, err := counter.PlayCounts()
+		if err != nil {
+			return err
+		}
+		uris := rankCounts(counts, limit)
+		tracks, err := pl.Library().TrackInfo(uris...)
+		if err != nil {
+			return err
+		}
+		mostPlayed = make([]PlayCount, len(uris))
+		for i, uri := range uris {
+			mostPlayed[i] = PlayCount{Track: tracks[i], Count: counts[uri]}
+			mostPlayed[i].Track.URI = uri
+		}
+		return nil
+	})
+	return mostPlayed, err
+}
+
+// A SkipCount is a track along with the number of times it was skipped and
+// played.
+type SkipCount struct {
+	Track library.Track
+	Skips int
+	// The number of plays, zero if the player does not count plays.
+	Plays int
+}
+
+// MostSkipped returns the tracks of the named player that were skipped most
+// often, most skipped first. A positive limit caps the number of tracks
+// returned.
+func (jb *Jukebox) MostSkipped(ctx context.Context, playerName string, limit int) ([]SkipCount, error) {
+	pl, err := jb.queryPlayer(ctx, playerName)
+	if err != nil {
+		return nil, err
+	}
+	counter, ok := pl.(player.SkipCounter)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+	var mostSkipped []SkipCount
+	err = util.WithContext(ctx, func() error {
+		skips, err := counter.SkipCounts()
+		if err != nil {
+			return err
+		}
+		plays := map[string]int{}
+		if playCounter, ok := pl.(player.PlayCounter); ok {
+			if plays, err = playCounter.PlayCounts(); err != nil {
+				return err
+			}
+		}
+		uris := rankCounts(skips, limit)
+		tracks, err := pl.Library().TrackInfo(uris...)
+		if err != nil {
+			return err
+		}
+		mostSkipped = make([]SkipCount, len(uris))
+		for i, uri := range uris {
+			mostSkipped[i] = SkipCount{Track: tracks[i], Skips: skips[uri], Plays: plays[uri]}
+			mostSkipped[i].Track.URI = uri
+		}
+		return nil
+	})
+	return mostSkipped, err
+}
+
+// rankCounts returns the URIs of the counts from highest to lowest. A
+// positive limit caps the number of URIs returned.
+func rankCounts(counts map[string]int, limit int) []string {
+	uris := make([]string, 0, len(counts))
+	for uri := range counts {
+		uris = append(uris, uri)
+	}
+	sort.Slice(uris, func(i, j int) bool {
+		if counts[uris[i]] != counts[uris[j]] {
+			return counts[uris[i]] > counts[uris[j]]
+		}
+		return uris[i] < uris[j]
+	})
+	if limit > 0 && len(uris) > limit {
+		uris = uris[:limit]
+	}
+	return uris
+}
+
+// PlayerPoolStats reports on the connections of the named player. Unlike most
+// other functions, it also works if the player is unavailable.
+func (jb *Jukebox) PlayerPoolStats(ctx context.Context, playerName string) (player.PoolStats, error) {
+	pl, err := jb.players.PlayerByName(playerName)
+	if err != nil {
+		return player.PoolStats{}, err
+	}
+	reporter, ok := pl.(player.PoolReporter)
+	if !ok {
+		return player.PoolStats{}, ErrUnsupported
+	}
+	return reporter.PoolStats(), nil
+}
+
+// PlayerCapabilities reports the optional features supported by the named
+// player, see player.Capabilities. Like PlayerPoolStats, it also works if the
+// player is unavailable.
+func (jb *Jukebox) PlayerCapabilities(ctx context.Context, playerName string) (map[string]bool, error) {
+	pl, err := jb.players.PlayerByName(playerName)
+	if err != nil {
+		return nil, err
+	}
+	return player.Capabilities(pl), nil
 }
 
 func (jb *Jukebox) Tracks(ctx context.Context, playerName string) ([]library.Track, error) {
-	pl, err := jb.player(playerName)
+	pl, err := jb.player(ctx, playerName)
 	if err != nil {
 		return nil, err
 	}
 	return pl.Library().Tracks()
 }
 
+// ReadReplayGain sets the ReplayGain of the tracks from their files if the
+// named player does not report it along with the other info of tracks, see
+// player.ReplayGainReader. The tracks are left alone otherwise.
+func (jb *Jukebox) ReadReplayGain(ctx context.Context, playerName string, tracks []library.Track) error {
+	pl, err := jb.queryPlayer(ctx, playerName)
+	if err != nil {
+		return err
+	}
+	reader, ok := pl.(player.ReplayGainReader)
+	if !ok {
+		return nil
+	}
+	// The reader may still be running if the context is done first, so it
+	// is handed a copy.
+	read := make([]library.Track, len(tracks))
+	copy(read, tracks)
+	err = util.WithContext(ctx, func() error {
+		return reader.ReadReplayGain(read)
+	})
+	if err != nil {
+		return err
+	}
+	copy(tracks, read)
+	return nil
+}
+
 func (jb *Jukebox) TrackArt(ctx context.Context, playerName, uri string) (io.Reader, string, error) {
-	pl, err := jb.player(playerName)
+	pl, err := jb.player(ctx, playerName)
 	if err != nil {
 		return nil, "", err
 	}
@@ -182,26 +1016,70 @@ func (jb *Jukebox) TrackArt(ctx context.Context, playerName, uri string) (io.Rea
 	return image, mime, nil
 }
 
-func (jb *Jukebox) SearchTracks(ctx context.Context, playerName, query string, untagged []string) ([]filter.SearchResult, error) {
-	compiledQuery, err := keyed.CompileQuery(query, untagged)
+// SearchTracks runs a keyed query on the tracks of the named library. See
+// Library for how libraries are looked up.
+func (jb *Jukebox) SearchTracks(ctx context.Context, libraryName, query string, untagged []string) ([]filter.SearchResult, error) {
+	compile := keyed.CompileQuery
+	if jb.normalizedSearch {
+		compile = keyed.CompileNormalizedQuery
+	}
+	compiledQuery, err := compile(query, untagged)
 	if err != nil {
 		return nil, err
 	}
-	pl, err := jb.player(playerName)
+	lib, err := jb.Library(ctx, libraryName)
 	if err != nil {
 		return nil, err
 	}
-	tracks, err := pl.Library().Tracks()
+	var tracks []library.Track
+	var index *library.SearchIndex
+	tags, offload := compiledQuery.Tags()
+	searcher, ok := jb.serverSearcher(ctx, libraryName)
+	if ok && offload {
+		tracks, err = searcher.SearchServerSide(tags)
+	} else if jb.normalizedSearch {
+		tracks, index, err = indexedTracks(lib)
+	} else {
+		tracks, err = lib.Tracks()
+	}
 	if err != nil {
 		return nil, err
 	}
-	results := filter.Tracks(compiledQuery, tracks)
+	// Results of the server are filtered as well to obtain the matches.
+	results := filter.TracksIndexed(compiledQuery, tracks, index)
 	sort.Sort(filter.ByNumMatches(results))
 	return results, nil
 }
 
+// indexedTracks returns the tracks of the library along with its search index
+// if it keeps one.
+func indexedTracks(lib library.Library) ([]library.Track, *library.SearchIndex, error) {
+	if il, ok := lib.(library.IndexedLibrary); ok {
+		return il.IndexedTracks()
+	}
+	tracks, err := lib.Tracks()
+	return tracks, nil, err
+}
+
+// serverSearcher returns the player of which the library is searched by name
+// if searches should be offloaded to it.
+func (jb *Jukebox) serverSearcher(ctx context.Context, name string) (player.ServerSearcher, bool) {
+	jb.serverSearchLock.RLock()
+	enabled := jb.serverSearch[name]
+	jb.serverSearchLock.RUnlock()
+	if !enabled {
+		return nil, false
+	}
+	pl, err := jb.player(ctx, name)
+	if err != nil {
+		return nil, false
+	}
+	searcher, ok := pl.(player.ServerSearcher)
+	return searcher, ok
+}
+
 func (jb *Jukebox) PlayerPlaylist(ctx context.Context, playerName string) (player.MetaPlaylist, error) {
-	pl, err := jb.player(playerName)
+	pl, err := jb.queryPlayer(ctx, playerName)
 	if err != nil {
 		return nil, err
 	}
@@ -209,19 +1087,25 @@ func (jb *Jukebox) PlayerPlaylist(ctx context.Context, playerName string) (playe
 }
 
 func (jb *Jukebox) PlayerLibraries(ctx context.Context, playerName string) ([]library.Library, error) {
-	pl, err := jb.player(playerName)
+	pl, err := jb.player(ctx, playerName)
 	if err != nil {
 		return nil, err
 	}
-	return []library.Library{
-		jb.streamdb,
-		jb.rawServer,
-		pl.Library(),
-	}, nil
+	var libs []library.Library
+	if jb.streamdb != nil {
+		libs = append(libs, jb.streamdb)
+	}
+	if jb.rawServer != nil {
+		libs = append(libs, jb.rawServer)
+	}
+	if jb.silenceServer != nil {
+		libs = append(libs, jb.silenceServer)
+	}
+	return append(libs, pl.Library()), nil
 }
 
 func (jb *Jukebox) PlayerLibrary(ctx context.Context, playerName string) (library.Library, error) {
-	pl, err := jb.player(playerName)
+	pl, err := jb.player(ctx, playerName)
 	if err != nil {
 		return nil, err
 	}
@@ -229,24 +1113,42 @@ func (jb *Jukebox) PlayerLibrary(ctx context.Context, playerName string) (librar
 }
 
 func (jb *Jukebox) PlayerEvents(ctx context.Context, playerName string) (*util.Emitter, error) {
-	pl, err := jb.player(playerName)
+	pl, err := jb.player(ctx, playerName)
 	if err != nil {
 		return nil, err
 	}
 	return pl.Events(), nil
 }
 
-func (jb *Jukebox) player(name string) (player.Player, error) {
+func (jb *Jukebox) player(ctx context.Context, name string) (player.Player, error) {
 	pl, err := jb.players.PlayerByName(name)
 	if err != nil {
 		return nil, err
 	}
-	if !pl.Available() {
-		return nil, ErrPlayerUnavailable
+	bound := player.WithContext(ctx, pl)
+	err = util.WithContext(ctx, func() error {
+		if !bound.Available() {
+			return ErrPlayerUnavailable
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return pl, nil
 }
 
+// queryPlayer is like player, but the operations of the returned player are
+// bound to the context, see player.WithContext. It is meant for queries, as
+// changes are run to completion once started, see util.RunUnlessDone.
+func (jb *Jukebox) queryPlayer(ctx context.Context, name string) (player.Player, error) {
+	pl, err := jb.player(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return player.WithContext(ctx, pl), nil
+}
+
 func (jb *Jukebox) removeRawTrack(playerName string, track library.Track, rawServer *raw.Server) {
 	emitter, err := jb.PlayerEvents(context.Background(), playerName)
 	if err != nil {
@@ -291,3 +1193,14 @@ func (jb *Jukebox) StreamDB() *stream.DB {
 func (jb *Jukebox) RawServer() *raw.Server {
 	return jb.rawServer
 }
+
+// SetSilenceServer configures the server of the gaps of silence that can be
+// queued with AppendSilence. A nil server disables them.
+func (jb *Jukebox) SetSilenceServer(sv *silence.Server) {
+	jb.silenceServer = sv
+}
+
+// SilenceServer returns the server configured with SetSilenceServer.
+func (jb *Jukebox) SilenceServer() *silence.Server {
+	return jb.silenceServer
+}
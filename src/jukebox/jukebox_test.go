@@ -0,0 +1,142 @@
+package jukebox
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/polyfloyd/trollibox/src/library"
+	"github.com/polyfloyd/trollibox/src/player"
+)
+
+type searchingPlayer struct {
+	*player.DummyPlayer
+	searches []map[string]string
+}
+
+func (pl *searchingPlayer) SearchServerSide(tags map[string]string) ([]library.Track, error) {
+	pl.searches = append(pl.searches, tags)
+	tracks, _ := pl.Library().Tracks()
+	var found []library.Track
+	for _, track := range tracks {
+		if strings.Contains(strings.ToLower(track.Artist), tags["artist"]) {
+			found = append(found, track)
+		}
+	}
+	return found, nil
+}
+
+func TestSearchTracksServerSide(t *testing.T) {
+	tracks := []library.Track{
+		{URI: "a", Artist: "Foo", Title: "One"},
+		{URI: "b", Artist: "Foobar", Title: "Two"},
+		{URI: "c", Artist: "Bar", Title: "Three"},
+	}
+	pl := &searchingPlayer{DummyPlayer: player.NewDummyPlayer(tracks...)}
+	defer pl.Events().Close()
+	jb := NewJukebox(player.SimpleList{"dummy": pl}, nil, nil, nil, nil)
+
+	// The server is only used once enabled.
+	if _, err := jb.SearchTracks(context.Background(), "dummy", "artist:foo", nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(pl.searches) != 0 {
+		t.Fatalf("Unexpected server searches: %v", pl.searches)
+	}
+
+	jb.SetServerSearch("dummy", true)
+	results, err := jb.SearchTracks(context.Background(), "dummy", "artist=foo", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pl.searches) != 1 || pl.searches[0]["artist"] != "foo" {
+		t.Fatalf("Unexpected server searches: %v", pl.searches)
+	}
+	// The candidates of the server are filtered by the exact query.
+	if len(results) != 1 || results[0].URI != "a" || len(results[0].Matches["artist"]) != 1 {
+		t.Fatalf("Unexpected results: %v", results)
+	}
+
+	// Queries that can not be expressed as tags are filtered locally.
+	results, err = jb.SearchTracks(context.Background(), "dummy", "one", []string{"title"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pl.searches) != 1 || len(results) != 1 || results[0].URI != "a" {
+		t.Fatalf("Unexpected results: %v, searches: %v", results, pl.searches)
+	}
+}
+
+func TestPlayNextAlbum(t *testing.T) {
+	tracks := []library.Track{
+		{URI: "a1", AlbumArtist: "A", Album: "First"},
+		{URI: "a2", AlbumArtist: "A", Album: "First"},
+		{URI: "a3", AlbumArtist: "A", Album: "First"},
+		{URI: "b1", AlbumArtist: "B", Album: "First"},
+		{URI: "b2", AlbumArtist: "B", Album: "First"},
+	}
+	pl := player.NewDummyPlayer(tracks...)
+	defer pl.Events().Close()
+	// Only the URIs are queued, the albums are in the library.
+	queued := make([]library.Track, len(tracks))
+	for i, track := range tracks {
+		queued[i].URI = track.URI
+	}
+	if err := pl.Playlist().InsertWithMeta(-1, queued, make([]player.TrackMeta, len(tracks))); err != nil {
+		t.Fatal(err)
+	}
+	jb := NewJukebox(player.SimpleList{"dummy": pl}, nil, nil, nil, nil)
+	ctx := context.Background()
+
+	if err := pl.SetTrackIndex(1); err != nil {
+		t.Fatal(err)
+	}
+	index, err := jb.PlayNextAlbum(ctx, "dummy", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Albums with the same title by different artists are distinct.
+	if cur, _ := pl.TrackIndex(); index != 3 || cur != 3 {
+		t.Fatalf("Unexpected index: %d, current: %d", index, cur)
+	}
+
+	// The rest of the playlist is the same album.
+	if _, err := jb.PlayNextAlbum(ctx, "dummy", false); err != ErrNoNextAlbum {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := pl.SetTrackIndex(0); err != nil {
+		t.Fatal(err)
+	}
+	if index, err := jb.PlayNextAlbum(ctx, "dummy", true); err != nil || index != 0 {
+		t.Fatalf("Unexpected index: %d, error: %v", index, err)
+	}
+	if plTracks, _ := pl.Playlist().Tracks(); len(plTracks) != 2 || plTracks[0].URI != "b1" {
+		t.Fatalf("Unexpected playlist: %v", plTracks)
+	}
+}
+
+func TestLibraryPrecedence(t *testing.T) {
+	pl := player.NewDummyPlayer(library.Track{URI: "a"})
+	defer pl.Events().Close()
+	files := player.NewDummyPlayer(library.Track{URI: "b"})
+	defer files.Events().Close()
+	jb := NewJukebox(player.SimpleList{"dummy": pl}, nil, nil, nil, nil)
+	if err := jb.AddLibrary("dummy", files); err != nil {
+		t.Fatal(err)
+	}
+	if err := jb.AddLibrary("files", files); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	for name, uri := range map[string]string{"dummy": "a", "files": "b"} {
+		lib, err := jb.Library(ctx, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tracks, _ := lib.Tracks(); len(tracks) != 1 || tracks[0].URI != uri {
+			t.Fatalf("Unexpected tracks of %q: %v", name, tracks)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package jukebox
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/polyfloyd/trollibox/src/library"
+	"github.com/polyfloyd/trollibox/src/player"
+)
+
+// SettingQueueCap is the name of the integer player setting which limits the
+// number of tracks a guest may have queued at once, see checkGuestInsert. Zero
+// or less means there is no limit.
+const SettingQueueCap = "queue_cap"
+
+// DefaultPartyQueueCap is the queue cap that applies in party mode if
+// SettingQueueCap is not set.
+const DefaultPartyQueueCap = 5
+
+// SettingBlocklist is the name of the player setting which holds the URIs of
+// the tracks that guests can not queue.
+const SettingBlocklist = "blocklist"
+
+// ErrQueueCap is returned when a guest attempts to queue more tracks than the
+// queue cap of the player allows.
+var ErrQueueCap = fmt.Errorf("you have queued the maximum number of tracks")
+
+// ErrBlocked is returned when a guest attempts to queue a track that is on the
+// blocklist of the player.
+var ErrBlocked = fmt.Errorf("this track is blocked")
+
+// checkGuestInsert returns ErrBlocked if one of the tracks is on the blocklist
+// of the named player and ErrQueueCap if queueing them would exceed the queue
+// cap of the client of the context. See checkBlocklist and checkQueueCap.
+func (jb *Jukebox) checkGuestInsert(ctx context.Context, pl player.Player, playerName string, tracks []library.Track) error {
+	if err := jb.checkBlocklist(ctx, playerName, tracks); err != nil {
+		return err
+	}
+	return jb.checkQueueCap(ctx, pl, playerName, len(tracks))
+}
+
+// checkBlocklist returns ErrBlocked if one of the tracks is on the blocklist
+// of the named player. Admins may queue blocked tracks and nothing is checked
+// if no settings store is configured.
+func (jb *Jukebox) checkBlocklist(ctx context.Context, playerName string, tracks []library.Track) error {
+	if client, _ := ClientFromContext(ctx); client.Admin || jb.settings == nil || len(tracks) == 0 {
+		return nil
+	}
+	var blocklist []string
+	if _, err := jb.settings.Get(playerName, SettingBlocklist, &blocklist); err != nil {
+		return err
+	}
+	blocked := make(map[string]bool, len(blocklist))
+	for _, uri := range blocklist {
+		blocked[uri] = true
+	}
+	for _, track := range tracks {
+		if blocked[track.URI] {
+			return ErrBlocked
+		}
+	}
+	return nil
+}
+
+// checkQueueCap returns ErrQueueCap if queueing the specified number of tracks
+// would exceed the queue cap of the client of the context.
+//
+// The tracks of a client count towards its cap from the current track
+// onwards, as tracks that have been played no longer hold up others. Clients
+// that are not known share the cap, like they share their attribution, see
+// UserTrackMeta. Admins are not capped.
+func (jb *Jukebox) checkQueueCap(ctx context.Context, pl player.Player, playerName string, count int) error {
+	if client, _ := ClientFromContext(ctx); client.Admin || count == 0 {
+		return nil
+	}
+	limit, err := jb.queueCap(playerName)
+	if err != nil || limit <= 0 {
+		return err
+	}
+	if count > limit {
+		return ErrQueueCap
+	}
+	meta, err := pl.Playlist().Meta()
+	if err != nil {
+		return err
+	}
+	current, err := pl.TrackIndex()
+	if err != nil {
+		return err
+	}
+	if current < 0 {
+		current = 0
+	}
+	queuedBy := UserTrackMeta(ctx).QueuedBy
+	queued := 0
+	for i := current; i < len(meta); i++ {
+		if meta[i].QueuedBy == queuedBy {
+			queued++
+		}
+	}
+	if queued+count > limit {
+		return ErrQueueCap
+	}
+	return nil
+}
+
+// queueCap returns the queue cap of the named player, zero or less if there is
+// none. If the cap is not set, DefaultPartyQueueCap applies in party mode.
+func (jb *Jukebox) queueCap(playerName string) (int, error) {
+	var limit int
+	found := false
+	if jb.settings != nil {
+		var err error
+		if found, err = jb.settings.Get(playerName, SettingQueueCap, &limit); err != nil {
+			return 0, err
+		}
+	}
+	if !found && jb.PartyMode(playerName) {
+		return DefaultPartyQueueCap, nil
+	}
+	return limit, nil
+}
@@ -0,0 +1,138 @@
+package jukebox
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/polyfloyd/trollibox/src/library"
+	"github.com/polyfloyd/trollibox/src/library/silence"
+	"github.com/polyfloyd/trollibox/src/player"
+)
+
+func newModeratedJukebox(t *testing.T, tracks ...library.Track) (*Jukebox, *SettingsStore, func()) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "trollibox-moderation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewSettingsStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pl := player.NewDummyPlayer(tracks...)
+	jb := NewJukebox(player.SimpleList{"dummy": pl}, nil, nil, nil, nil)
+	jb.SetSettingsStore(store)
+	return jb, store, func() {
+		pl.Events().Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestQueueCap(t *testing.T) {
+	tracks := []library.Track{{URI: "a"}, {URI: "b"}, {URI: "c"}}
+	jb, store, cleanup := newModeratedJukebox(t, tracks...)
+	defer cleanup()
+	if err := store.Set("dummy", SettingQueueCap, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	alice := WithClient(context.Background(), Client{ID: "alice", PublicID: "a1"})
+	bob := WithClient(context.Background(), Client{ID: "bob", PublicID: "b1"})
+	admin := WithClient(context.Background(), Client{ID: "host", PublicID: "h1", Admin: true})
+	insert := func(ctx context.Context, tracks ...library.Track) error {
+		meta := make([]player.TrackMeta, len(tracks))
+		for i := range meta {
+			meta[i] = UserTrackMeta(ctx)
+		}
+		return jb.InsertTracks(ctx, "dummy", -1, tracks, meta)
+	}
+
+	if err := insert(alice, tracks...); err != ErrQueueCap {
+		t.Fatalf("More tracks than the cap were queued at once: %v", err)
+	}
+	if err := insert(alice, tracks[:2]...); err != nil {
+		t.Fatal(err)
+	}
+	if err := insert(alice, tracks[2]); err != ErrQueueCap {
+		t.Fatalf("A track beyond the cap was queued: %v", err)
+	}
+	jb.SetSilenceServer(silence.NewServer("http://localhost/data/silence"))
+	if err := jb.AppendSilence(alice, "dummy", time.Second); err != ErrQueueCap {
+		t.Fatalf("Silence beyond the cap was queued: %v", err)
+	}
+	if err := insert(bob, tracks[2]); err != nil {
+		t.Fatalf("The cap of one client affected another: %v", err)
+	}
+	if err := insert(admin, tracks...); err != nil {
+		t.Fatalf("An admin was capped: %v", err)
+	}
+
+	// Tracks that have been played no longer count.
+	if err := jb.SetPlayerTrackIndex(admin, "dummy", 1, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := insert(alice, tracks[2]); err != nil {
+		t.Fatalf("A played track counted towards the cap: %v", err)
+	}
+}
+
+func TestPartyQueueCap(t *testing.T) {
+	tracks := make([]library.Track, DefaultPartyQueueCap+1)
+	for i := range tracks {
+		tracks[i].URI = string(rune('a' + i))
+	}
+	jb, store, cleanup := newModeratedJukebox(t, tracks...)
+	defer cleanup()
+
+	guest := WithClient(context.Background(), Client{ID: "guest", PublicID: "g1"})
+	insert := func() error {
+		return jb.InsertTracks(guest, "dummy", -1, tracks, make([]player.TrackMeta, len(tracks)))
+	}
+	if err := jb.SetPartyMode(context.Background(), "dummy", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := insert(); err != ErrQueueCap {
+		t.Fatalf("The default cap did not apply in party mode: %v", err)
+	}
+	// A cap that is set, even if it is zero, overrides the default.
+	if err := store.Set("dummy", SettingQueueCap, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := insert(); err != nil {
+		t.Fatalf("The configured cap did not apply in party mode: %v", err)
+	}
+}
+
+func TestBlocklist(t *testing.T) {
+	tracks := []library.Track{{URI: "a"}, {URI: "b"}}
+	jb, store, cleanup := newModeratedJukebox(t, tracks...)
+	defer cleanup()
+	if err := store.Set("dummy", SettingBlocklist, []string{"b"}); err != nil {
+		t.Fatal(err)
+	}
+
+	guest := WithClient(context.Background(), Client{ID: "guest", PublicID: "g1"})
+	admin := WithClient(context.Background(), Client{ID: "host", PublicID: "h1", Admin: true})
+	meta := []player.TrackMeta{{}, {}}
+	if err := jb.InsertTracks(guest, "dummy", -1, tracks, meta); err != ErrBlocked {
+		t.Fatalf("A blocked track was queued: %v", err)
+	}
+	if _, err := jb.InsertTracksOnce(guest, "dummy", "", -1, tracks, meta, true); err != ErrBlocked {
+		t.Fatalf("A blocked track was queued if absent: %v", err)
+	}
+	if _, err := jb.SetPlaylist(guest, "dummy", tracks, meta, false); err != ErrBlocked {
+		t.Fatalf("A blocked track was queued by replacing the playlist: %v", err)
+	}
+	if err := jb.PinTrack(guest, "dummy", "b"); err != ErrBlocked {
+		t.Fatalf("A blocked track was pinned: %v", err)
+	}
+	if err := jb.InsertTracks(guest, "dummy", -1, tracks[:1], meta[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if err := jb.InsertTracks(admin, "dummy", -1, tracks, meta); err != nil {
+		t.Fatalf("An admin could not queue a blocked track: %v", err)
+	}
+}
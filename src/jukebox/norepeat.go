@@ -0,0 +1,129 @@
+package jukebox
+
+import (
+	"time"
+
+	"github.com/polyfloyd/trollibox/src/player"
+)
+
+// SettingNoRepeatWindow is the name of the player setting which holds the
+// NoRepeatWindow of the autoqueuer.
+const SettingNoRepeatWindow = "autoqueue_no_repeat"
+
+// MaxNoRepeatTracks is the highest number of tracks of a NoRepeatWindow that is
+// taken into account.
+const MaxNoRepeatTracks = 1000
+
+// A NoRepeatWindow describes which recently played tracks the autoqueuer
+// should not pick again. A track is excluded if it is among the most recently
+// played tracks or was played within the time span.
+type NoRepeatWindow struct {
+	// The number of most recently played tracks, at most MaxNoRepeatTracks.
+	Tracks int `json:"tracks,omitempty"`
+	// The time span in seconds.
+	Span float64 `json:"span,omitempty"`
+}
+
+// NoRepeatURIs returns the URIs of the tracks that are within the no-repeat
+// window of the named player, most recently played first. Plays are taken
+// from the play history, so nil is returned if no history is configured.
+//
+// The most recent plays are kept in memory, so the history is only read if the
+// time span reaches further back than those.
+//
+// The window is a preference rather than a hard limit, see
+// filter.RandomIteratorExcluding.
+func (jb *Jukebox) NoRepeatURIs(playerName string) ([]string, error) {
+	if _, err := jb.players.PlayerByName(playerName); err != nil {
+		return nil, err
+	}
+	if jb.playHistory == nil || jb.settings == nil {
+		return nil, nil
+	}
+	var window NoRepeatWindow
+	if ok, err := jb.settings.Get(playerName, SettingNoRepeatWindow, &window); err != nil || !ok {
+		return nil, err
+	}
+	if window.Tracks <= 0 && window.Span <= 0 {
+		return nil, nil
+	}
+	if window.Tracks > MaxNoRepeatTracks {
+		window.Tracks = MaxNoRepeatTracks
+	}
+	now := time.Now()
+	plays, err := jb.recentPlaysOf(playerName)
+	if err != nil {
+		return nil, err
+	}
+	// Fewer recent plays than the maximum means that they are all plays in
+	// the history.
+	since := now.Add(-time.Duration(window.Span * float64(time.Second)))
+	if window.Span > 0 && len(plays) == MaxNoRepeatTracks && plays[0].Time.After(since) {
+		if plays, err = jb.playHistory.Plays(playerName, since); err != nil {
+			return nil, err
+		}
+	}
+	return noRepeatURIs(plays, window, now), nil
+}
+
+// recordPlay adds a play to the play history and to the recent plays of the
+// named player.
+func (jb *Jukebox) recordPlay(playerName string, play player.Play) error {
+	jb.recentPlaysLock.Lock()
+	defer jb.recentPlaysLock.Unlock()
+	if err := jb.playHistory.Record(playerName, play); err != nil {
+		return err
+	}
+	if plays, ok := jb.recentPlays[playerName]; ok {
+		plays = append(plays, play)
+		// Trim the plays once in a while rather than on every play.
+		if len(plays) >= MaxNoRepeatTracks*2 {
+			plays = append([]player.Play(nil), plays[len(plays)-MaxNoRepeatTracks:]...)
+		}
+		jb.recentPlays[playerName] = plays
+	}
+	return nil
+}
+
+// recentPlaysOf returns up to MaxNoRepeatTracks of the most recent plays of the
+// named player in the order they were recorded. The plays are read from the
+// history the first time and kept up to date by recordPlay from then on.
+func (jb *Jukebox) recentPlaysOf(playerName string) ([]player.Play, error) {
+	jb.recentPlaysLock.Lock()
+	defer jb.recentPlaysLock.Unlock()
+	plays, ok := jb.recentPlays[playerName]
+	if !ok {
+		var err error
+		if plays, err = jb.playHistory.Plays(playerName, time.Time{}); err != nil {
+			return nil, err
+		}
+		if len(plays) > MaxNoRepeatTracks {
+			plays = append([]player.Play(nil), plays[len(plays)-MaxNoRepeatTracks:]...)
+		}
+		jb.recentPlays[playerName] = plays
+	}
+	if len(plays) > MaxNoRepeatTracks {
+		plays = plays[len(plays)-MaxNoRepeatTracks:]
+	}
+	return append([]player.Play(nil), plays...), nil
+}
+
+// noRepeatURIs returns the unique URIs of the plays in the window, most
+// recently played first. The plays are expected in the order they were
+// recorded.
+func noRepeatURIs(plays []player.Play, window NoRepeatWindow, now time.Time) []string {
+	since := now.Add(-time.Duration(window.Span * float64(time.Second)))
+	seen := map[string]bool{}
+	uris := []string{}
+	for i := len(plays) - 1; i >= 0; i-- {
+		n := len(plays) - i
+		if n > window.Tracks && !plays[i].Time.After(since) {
+			break
+		}
+		if !seen[plays[i].URI] {
+			seen[plays[i].URI] = true
+			uris = append(uris, plays[i].URI)
+		}
+	}
+	return uris
+}
@@ -0,0 +1,98 @@
+package jukebox
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/polyfloyd/trollibox/src/player"
+)
+
+func TestNoRepeatURIs(t *testing.T) {
+	now := time.Now()
+	plays := []player.Play{
+		{URI: "a", Time: now.Add(-time.Hour * 3)},
+		{URI: "b", Time: now.Add(-time.Hour * 2)},
+		{URI: "c", Time: now.Add(-time.Minute * 30)},
+		{URI: "b", Time: now.Add(-time.Minute * 10)},
+	}
+
+	uris := noRepeatURIs(plays, NoRepeatWindow{Tracks: 3}, now)
+	if !reflect.DeepEqual(uris, []string{"b", "c"}) {
+		t.Fatalf("Unexpected URIs in a window of tracks: %v", uris)
+	}
+	uris = noRepeatURIs(plays, NoRepeatWindow{Span: time.Hour.Seconds()}, now)
+	if !reflect.DeepEqual(uris, []string{"b", "c"}) {
+		t.Fatalf("Unexpected URIs in a window of time: %v", uris)
+	}
+	uris = noRepeatURIs(plays, NoRepeatWindow{Tracks: 1, Span: (time.Hour * 4).Seconds()}, now)
+	if !reflect.DeepEqual(uris, []string{"b", "c", "a"}) {
+		t.Fatalf("Unexpected URIs in a combined window: %v", uris)
+	}
+	uris = noRepeatURIs(plays, NoRepeatWindow{Tracks: 10}, now)
+	if !reflect.DeepEqual(uris, []string{"b", "c", "a"}) {
+		t.Fatalf("Unexpected URIs in a window larger than the history: %v", uris)
+	}
+}
+
+func TestNoRepeatRecentPlays(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trollibox-norepeat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	history, err := player.NewPlayHistory(path.Join(dir, "history"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings, err := NewSettingsStore(path.Join(dir, "settings"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pl := player.NewDummyPlayer()
+	defer pl.Events().Close()
+	jb := NewJukebox(player.SimpleList{"dummy": pl}, nil, nil, nil, nil)
+	jb.SetPlayHistory(history, 0)
+	jb.SetSettingsStore(settings)
+	if err := settings.Set("dummy", SettingNoRepeatWindow, NoRepeatWindow{Tracks: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	if err := history.Record("dummy", player.Play{URI: "old", Time: now.Add(-time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	uris, err := jb.NoRepeatURIs("dummy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(uris, []string{"old"}) {
+		t.Fatalf("The recent plays were not read from the history: %v", uris)
+	}
+	if err := jb.recordPlay("dummy", player.Play{URI: "new", Time: now}); err != nil {
+		t.Fatal(err)
+	}
+	if uris, _ = jb.NoRepeatURIs("dummy"); !reflect.DeepEqual(uris, []string{"new", "old"}) {
+		t.Fatalf("A recorded play was not added to the recent plays: %v", uris)
+	}
+
+	// Time spans beyond the recent plays are read from the history.
+	for i := 0; i < MaxNoRepeatTracks; i++ {
+		if err := jb.recordPlay("dummy", player.Play{URI: fmt.Sprint(i), Time: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := settings.Set("dummy", SettingNoRepeatWindow, NoRepeatWindow{Span: time.Hour.Seconds() * 2}); err != nil {
+		t.Fatal(err)
+	}
+	if uris, _ = jb.NoRepeatURIs("dummy"); len(uris) != MaxNoRepeatTracks+2 || uris[len(uris)-1] != "old" {
+		t.Fatalf("Unexpected URIs in a window beyond the recent plays: %d", len(uris))
+	}
+	if plays, _ := jb.recentPlaysOf("dummy"); len(plays) != MaxNoRepeatTracks {
+		t.Fatalf("Unexpected number of recent plays: %d", len(plays))
+	}
+}
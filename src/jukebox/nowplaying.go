@@ -0,0 +1,175 @@
+package jukebox
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/polyfloyd/trollibox/src/library"
+	"github.com/polyfloyd/trollibox/src/player"
+)
+
+// NowPlaying is a snapshot of what a player is doing.
+type NowPlaying struct {
+	Player string
+	State  player.PlayState
+	// The current track, nil if there is none.
+	Track *library.Track
+	Meta  *player.TrackMeta
+	// The playback offset at the time the snapshot was taken.
+	Time    time.Duration
+	Updated time.Time
+	// The duration of the tracks queued after the current track and the
+	// number of those of which the duration is unknown.
+	Queue        time.Duration
+	QueueUnknown int
+}
+
+// Progress returns the playback offset at the specified time, assuming
+// playback continued uninterrupted since the snapshot was taken.
+func (np NowPlaying) Progress(now time.Time) time.Duration {
+	if np.State != player.PlayStatePlaying {
+		return np.Time
+	}
+	progress := np.Time + now.Sub(np.Updated)
+	if np.Track != nil && np.Track.Duration > 0 && progress > np.Track.Duration {
+		progress = np.Track.Duration
+	}
+	return progress
+}
+
+// Remaining returns the time it takes to play the rest of the current track
+// and all queued tracks at the specified time. The number of tracks of which
+// the duration is unknown is also returned, if this is not zero the duration
+// is a lower bound.
+func (np NowPlaying) Remaining(now time.Time) (time.Duration, int) {
+	if np.Track == nil {
+		return np.Queue, np.QueueUnknown
+	}
+	current, unknown := player.QueueDuration([]library.Track{*np.Track}, 0, np.Progress(now))
+	return current + np.Queue, unknown + np.QueueUnknown
+}
+
+// SetDefaultNowPlaying sets the player of which the snapshot is returned by
+// NowPlaying if no player is playing.
+func (jb *Jukebox) SetDefaultNowPlaying(playerName string) {
+	jb.nowPlayingLock.Lock()
+	defer jb.nowPlayingLock.Unlock()
+	jb.defaultNowPlaying = playerName
+}
+
+// WatchNowPlaying keeps a snapshot of what the named player is doing, which
+// is updated as the player emits events. This allows NowPlaying to answer
+// without communicating with the player.
+func (jb *Jukebox) WatchNowPlaying(playerName string) error {
+	pl, err := jb.players.PlayerByName(playerName)
+	if err != nil {
+		return err
+	}
+	events := pl.Events().Listen()
+	go func() {
+		defer pl.Events().Unlisten(events)
+		jb.updateNowPlaying(playerName, pl)
+		for event := range events {
+			switch event.(type) {
+			case player.PlaylistEvent, player.PlayStateEvent, player.TimeEvent, player.AvailabilityEvent, player.TrackMetadataEvent:
+				jb.updateNowPlaying(playerName, pl)
+			}
+		}
+	}()
+	return nil
+}
+
+func (jb *Jukebox) updateNowPlaying(playerName string, pl player.Player) {
+	np := NowPlaying{Player: playerName, State: player.PlayStateStopped, Updated: time.Now()}
+	if pl.Available() {
+		var err error
+		if np, err = jb.nowPlayingSnapshot(playerName, pl); err != nil {
+			log.WithField("player", playerName).Errorf("Error updating now playing: %v", err)
+			return
+		}
+	}
+	jb.nowPlayingLock.Lock()
+	defer jb.nowPlayingLock.Unlock()
+	jb.nowPlaying[playerName] = np
+}
+
+func (jb *Jukebox) nowPlayingSnapshot(playerName string, pl player.Player) (NowPlaying, error) {
+	np := NowPlaying{Player: playerName}
+	var err error
+	if np.State, err = pl.State(); err != nil {
+		return np, err
+	}
+	if np.Time, err = pl.Time(); err != nil {
+		return np, err
+	}
+	np.Updated = time.Now()
+	if np.State == player.PlayStateStopped {
+		return np, nil
+	}
+
+	index, err := pl.TrackIndex()
+	if err != nil || index < 0 {
+		return np, err
+	}
+	tracks, err := pl.Playlist().Tracks()
+	if err != nil || index >= len(tracks) {
+		return np, err
+	}
+	meta, err := pl.Playlist().Meta()
+	if err != nil {
+		return np, err
+	}
+	if index < len(meta) {
+		np.Meta = &meta[index]
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	libs, err := jb.PlayerLibraries(ctx, playerName)
+	if err != nil {
+		return np, err
+	}
+	// The playlist itself may not know the duration of tracks from other
+	// libraries, so the queue duration is computed from the info of the
+	// tracks.
+	uris := make([]string, len(tracks)-index)
+	for i, track := range tracks[index:] {
+		uris[i] = track.URI
+	}
+	info, err := library.AllTrackInfo(libs, uris...)
+	if err != nil {
+		return np, err
+	}
+	np.Track = &info[0]
+	np.Queue, np.QueueUnknown = player.QueueDuration(info, 1, 0)
+	return np, nil
+}
+
+// NowPlaying returns the most recent snapshot of a player that is watched
+// using WatchNowPlaying. The player that is playing is preferred, followed by
+// the default player. False is returned if no player is watched.
+func (jb *Jukebox) NowPlaying() (NowPlaying, bool) {
+	jb.nowPlayingLock.Lock()
+	defer jb.nowPlayingLock.Unlock()
+	var best NowPlaying
+	bestRank := -1
+	for _, np := range jb.nowPlaying {
+		rank := rankNowPlaying(np, jb.defaultNowPlaying)
+		if rank > bestRank || rank == bestRank && np.Updated.After(best.Updated) {
+			best, bestRank = np, rank
+		}
+	}
+	return best, bestRank >= 0
+}
+
+func rankNowPlaying(np NowPlaying, defaultPlayer string) int {
+	switch {
+	case np.State == player.PlayStatePlaying:
+		return 2
+	case np.Player == defaultPlayer:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,108 @@
+package jukebox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/polyfloyd/trollibox/src/player"
+)
+
+// SettingPartyMode is the name of the boolean player setting which enables
+// party mode, see SetPartyMode.
+const SettingPartyMode = "party_mode"
+
+// ErrPartyMode is returned when a guest attempts an operation that is disabled
+// while party mode is enabled.
+var ErrPartyMode = fmt.Errorf("this operation is disabled in party mode")
+
+// PartyModeEvent is emitted by the player when party mode is enabled or
+// disabled.
+type PartyModeEvent struct {
+	Enabled bool
+}
+
+// SetPartyMode enables or disables party mode for the named player. Party
+// mode is a single switch for hosts that lets guests queue tracks while
+// keeping the party going:
+//
+//   - Tracks appended to the playlist are inserted at a random position after
+//     the current track, as with InsertRandom.
+//   - Guests can not stop playback, skip tracks or albums, remove tracks or
+//     replace the playlist, see CheckPartyMode.
+//   - Guests may have DefaultPartyQueueCap tracks queued at once, unless
+//     SettingQueueCap is set.
+//   - Guests skip the current track by voting, DefaultPartySkipVotes votes are
+//     needed unless SettingSkipVotes is set, see VoteSkip.
+//
+// The blocklist applies regardless of party mode. Admins keep full control.
+// The insert mode and queue lock of the player are left as configured and
+// apply again once party mode is disabled.
+//
+// If a settings store is configured, party mode is persisted as the
+// SettingPartyMode setting so it survives a restart.
+func (jb *Jukebox) SetPartyMode(ctx context.Context, playerName string, enabled bool) error {
+	pl, err := jb.players.PlayerByName(playerName)
+	if err != nil {
+		return err
+	}
+	if jb.settings != nil {
+		settings, err := jb.settings.Update(playerName, Settings{SettingPartyMode: json.RawMessage(strconv.FormatBool(enabled))})
+		if err != nil {
+			return err
+		}
+		pl.Events().Emit(SettingsEvent{Settings: settings})
+	}
+	jb.applyPartyMode(pl, playerName, enabled)
+	return nil
+}
+
+// applyPartyMode enables or disables party mode in memory and emits a
+// PartyModeEvent if it changed.
+func (jb *Jukebox) applyPartyMode(pl player.Player, playerName string, enabled bool) {
+	jb.partyModeLock.Lock()
+	changed := jb.partyModeLocked(playerName) != enabled
+	jb.partyMode[playerName] = enabled
+	jb.partyModeLock.Unlock()
+
+	if changed {
+		pl.Events().Emit(PartyModeEvent{Enabled: enabled})
+	}
+}
+
+// PartyMode reports whether party mode is enabled for the named player.
+func (jb *Jukebox) PartyMode(playerName string) bool {
+	jb.partyModeLock.Lock()
+	defer jb.partyModeLock.Unlock()
+	return jb.partyModeLocked(playerName)
+}
+
+// partyModeLocked reports whether party mode is enabled for the named player.
+// The first time a player is looked up, party mode is loaded from the
+// settings. The partyModeLock must be held.
+func (jb *Jukebox) partyModeLocked(playerName string) bool {
+	enabled, ok := jb.partyMode[playerName]
+	if !ok && jb.settings != nil {
+		if _, err := jb.settings.Get(playerName, SettingPartyMode, &enabled); err != nil {
+			log.Errorf("Unable to load the party mode of %q: %v", playerName, err)
+		}
+		jb.partyMode[playerName] = enabled
+	}
+	return enabled
+}
+
+// CheckPartyMode returns ErrPartyMode if party mode is enabled for the named
+// player and the client of the context is not an admin. Operations without a
+// client are treated as coming from a guest.
+func (jb *Jukebox) CheckPartyMode(ctx context.Context, playerName string) error {
+	if client, _ := ClientFromContext(ctx); client.Admin {
+		return nil
+	}
+	if jb.PartyMode(playerName) {
+		return ErrPartyMode
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package jukebox
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/polyfloyd/trollibox/src/library"
+	"github.com/polyfloyd/trollibox/src/player"
+)
+
+func TestPartyModeWithoutClient(t *testing.T) {
+	tracks := []library.Track{{URI: "a"}, {URI: "b"}, {URI: "c"}}
+	pl := player.NewDummyPlayer(tracks...)
+	defer pl.Events().Close()
+	jb := NewJukebox(player.SimpleList{"dummy": pl}, nil, nil, nil, nil)
+	if err := pl.Playlist().InsertWithMeta(-1, tracks, make([]player.TrackMeta, len(tracks))); err != nil {
+		t.Fatal(err)
+	}
+	if err := jb.SetPartyMode(context.Background(), "dummy", true); err != nil {
+		t.Fatal(err)
+	}
+
+	guest := context.Background()
+	admin := WithClient(context.Background(), Client{ID: "host", Admin: true})
+	if err := jb.RemoveTracks(guest, "dummy", []int{0}); err != ErrPartyMode {
+		t.Fatalf("A guest without a client removed tracks: %v", err)
+	}
+	if err := jb.PlayQueueIndex(guest, "dummy", 1, true); err != ErrPartyMode {
+		t.Fatalf("A guest without a client dropped tracks: %v", err)
+	}
+	if err := jb.SetPlayerTrackIndex(guest, "dummy", 1, true); err != ErrPartyMode {
+		t.Fatalf("A guest without a client skipped a track: %v", err)
+	}
+	if _, err := jb.PlayNextAlbum(guest, "dummy", false); err != ErrPartyMode {
+		t.Fatalf("A guest without a client skipped an album: %v", err)
+	}
+	if err := jb.PinTrack(guest, "dummy", "c"); err != ErrPartyMode {
+		t.Fatalf("A guest without a client pinned a track: %v", err)
+	}
+	if err := jb.RemoveTracks(admin, "dummy", []int{0}); err != nil {
+		t.Fatalf("An admin could not remove tracks: %v", err)
+	}
+	if err := jb.PinTrack(admin, "dummy", "c"); err != nil {
+		t.Fatalf("An admin could not pin a track: %v", err)
+	}
+}
+
+func TestPartyModePersisted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trollibox-partymode")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := NewSettingsStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pl := player.NewDummyPlayer()
+	defer pl.Events().Close()
+	jb := NewJukebox(player.SimpleList{"dummy": pl}, nil, nil, nil, nil)
+	jb.SetSettingsStore(store)
+	if err := jb.SetPartyMode(context.Background(), "dummy", true); err != nil {
+		t.Fatal(err)
+	}
+	var enabled bool
+	if ok, err := store.Get("dummy", SettingPartyMode, &enabled); err != nil {
+		t.Fatal(err)
+	} else if !ok || !enabled {
+		t.Fatalf("Party mode was not stored: %v %v", ok, enabled)
+	}
+
+	// Party mode is restored after a restart.
+	store, err = NewSettingsStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jb = NewJukebox(player.SimpleList{"dummy": pl}, nil, nil, nil, nil)
+	jb.SetSettingsStore(store)
+	if !jb.PartyMode("dummy") {
+		t.Fatal("Party mode was not restored")
+	}
+	if err := jb.SetPartyMode(context.Background(), "dummy", false); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := store.Get("dummy", SettingPartyMode, &enabled); err != nil {
+		t.Fatal(err)
+	} else if !ok || enabled {
+		t.Fatalf("Party mode was not disabled in the settings: %v %v", ok, enabled)
+	}
+}
@@ -0,0 +1,237 @@
+package jukebox
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/polyfloyd/trollibox/src/library"
+	"github.com/polyfloyd/trollibox/src/player"
+	"github.com/polyfloyd/trollibox/src/util"
+)
+
+// maxPinnedTracks limits the number of tracks that can be pinned to the front
+// of the queue of a player at once.
+const maxPinnedTracks = 8
+
+// PinsEvent is emitted by the player when tracks are pinned or unpinned.
+type PinsEvent struct {
+	// The URIs of the pinned tracks in the order they are queued.
+	URIs []string
+}
+
+// PinTrack pins the track with the specified URI to the front of the queue of
+// the named player, so it is played next regardless of how the playlist is
+// changed in the meantime. If the track is not queued after the current
+// track, it is inserted.
+//
+// Multiple tracks may be pinned, they are kept in the order in which they
+// were pinned. A track is unpinned once it starts playing or when it is
+// removed from the playlist.
+//
+// As pinning lets a track skip the queue, guests can not pin tracks in party
+// mode.
+func (jb *Jukebox) PinTrack(ctx context.Context, playerName, uri string) error {
+	pl, err := jb.player(ctx, playerName)
+	if err != nil {
+		return err
+	}
+	if err := jb.CheckQueueLock(ctx, playerName); err != nil {
+		return err
+	}
+	if err := jb.CheckPartyMode(ctx, playerName); err != nil {
+		return err
+	}
+	return util.RunUnlessDone(ctx, func() error {
+		jb.pinsLock.Lock()
+		defer jb.pinsLock.Unlock()
+		pins := jb.pins[playerName]
+		for _, pinned := range pins {
+			if pinned == uri {
+				return nil
+			}
+		}
+		if len(pins) >= maxPinnedTracks {
+			return fmt.Errorf("no more than %d tracks can be pinned", maxPinnedTracks)
+		}
+
+		tracks, lower, err := unplayedTracks(pl)
+		if err != nil {
+			return err
+		}
+		queued := false
+		for _, track := range tracks[lower:] {
+			if track.URI == uri {
+				queued = true
+				break
+			}
+		}
+		if !queued {
+			resolved, err := jb.resolveTracks(pl, []library.Track{{URI: uri}})
+			if err != nil {
+				return err
+			}
+			// The track may have been replaced by its equivalent in the
+			// library of the player.
+			uri = resolved[0].URI
+			if err := jb.checkGuestInsert(ctx, pl, playerName, resolved); err != nil {
+				return err
+			}
+			meta := []player.TrackMeta{UserTrackMeta(ctx)}
+			if err := jb.insertTracks(pl, playerName, lower+len(pins), resolved, meta); err != nil {
+				return err
+			}
+			jb.RecordAudit(ctx, playerName, AuditInsert, []string{uri}, "")
+		}
+
+		jb.setPins(pl, playerName, append(append([]string{}, pins...), uri))
+		if err := jb.reassertPins(pl, playerName); err != nil {
+			return err
+		}
+		jb.watchPins(pl, playerName)
+		return nil
+	})
+}
+
+// UnpinTrack unpins the track with the specified URI from the front of the
+// queue of the named player. The track remains queued where it is.
+func (jb *Jukebox) UnpinTrack(ctx context.Context, playerName, uri string) error {
+	pl, err := jb.players.PlayerByName(playerName)
+	if err != nil {
+		return err
+	}
+	if err := jb.CheckQueueLock(ctx, playerName); err != nil {
+		return err
+	}
+	jb.pinsLock.Lock()
+	defer jb.pinsLock.Unlock()
+	pins := jb.pins[playerName]
+	for i, pinned := range pins {
+		if pinned != uri {
+			continue
+		}
+		pins = append(pins[:i:i], pins[i+1:]...)
+		jb.setPins(pl, playerName, pins)
+		return nil
+	}
+	return fmt.Errorf("the track is not pinned: %q", uri)
+}
+
+// PinnedTracks returns the URIs of the tracks that are pinned to the front of
+// the queue of the named player in the order in which they are queued.
+func (jb *Jukebox) PinnedTracks(playerName string) []string {
+	jb.pinsLock.Lock()
+	defer jb.pinsLock.Unlock()
+	return append([]string{}, jb.pins[playerName]...)
+}
+
+// watchPins keeps the pinned tracks of the named player at the front of the
+// queue as the playlist changes. The watch ends once no tracks are pinned.
+// The pinsLock must be held.
+func (jb *Jukebox) watchPins(pl player.Player, playerName string) {
+	if jb.pinWatchers[playerName] {
+		return
+	}
+	jb.pinWatchers[playerName] = true
+	events := pl.Events().Listen()
+	go func() {
+		defer pl.Events().Unlisten(events)
+		for event := range events {
+			if _, ok := event.(player.PlaylistEvent); !ok {
+				continue
+			}
+			jb.pinsLock.Lock()
+			if err := jb.reassertPins(pl, playerName); err != nil {
+				log.WithField("player", playerName).Errorf("Error keeping pinned tracks in place: %v", err)
+			}
+			done := len(jb.pins[playerName]) == 0
+			if done {
+				delete(jb.pinWatchers, playerName)
+			}
+			jb.pinsLock.Unlock()
+			if done {
+				return
+			}
+		}
+		jb.pinsLock.Lock()
+		delete(jb.pinWatchers, playerName)
+		jb.pinsLock.Unlock()
+	}()
+}
+
+// reassertPins moves the pinned tracks of the named player to the positions
+// right after the current track. Pins of tracks that have started playing or
+// are no longer queued are dropped. The pinsLock must be held.
+//
+// Because played tracks remain in the playlist before the current track,
+// pinned tracks are looked for after it.
+func (jb *Jukebox) reassertPins(pl player.Player, playerName string) error {
+	pins := jb.pins[playerName]
+	if len(pins) == 0 {
+		return nil
+	}
+	tracks, lower, err := unplayedTracks(pl)
+	if err != nil {
+		return err
+	}
+
+	plist := pl.Playlist()
+	kept := make([]string, 0, len(pins))
+	pos := lower
+	for _, uri := range pins {
+		from := -1
+		for i := pos; i < len(tracks); i++ {
+			if tracks[i].URI == uri {
+				from = i
+				break
+			}
+		}
+		if from < 0 {
+			continue
+		}
+		if from != pos {
+			if err := plist.Move(from, pos); err != nil {
+				return err
+			}
+			moved := tracks[from]
+			copy(tracks[pos+1:from+1], tracks[pos:from])
+			tracks[pos] = moved
+		}
+		kept = append(kept, uri)
+		pos++
+	}
+	if len(kept) != len(pins) {
+		jb.setPins(pl, playerName, kept)
+	}
+	return nil
+}
+
+// setPins replaces the pinned tracks of the named player and emits a
+// PinsEvent. The pinsLock must be held.
+func (jb *Jukebox) setPins(pl player.Player, playerName string, pins []string) {
+	if len(pins) == 0 {
+		delete(jb.pins, playerName)
+	} else {
+		jb.pins[playerName] = pins
+	}
+	pl.Events().Emit(PinsEvent{URIs: append([]string{}, pins...)})
+}
+
+// unplayedTracks returns the playlist of the player along with the position
+// of the first track after the current one.
+func unplayedTracks(pl player.Player) ([]library.Track, int, error) {
+	tracks, err := pl.Playlist().Tracks()
+	if err != nil {
+		return nil, 0, err
+	}
+	current, err := pl.TrackIndex()
+	if err != nil {
+		return nil, 0, err
+	}
+	lower := current + 1
+	if lower > len(tracks) {
+		lower = len(tracks)
+	}
+	return tracks, lower, nil
+}
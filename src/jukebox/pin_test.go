@@ -0,0 +1,86 @@
+package jukebox
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/polyfloyd/trollibox/src/library"
+	"github.com/polyfloyd/trollibox/src/player"
+)
+
+func TestPinTrack(t *testing.T) {
+	tracks := []library.Track{{URI: "a"}, {URI: "b"}, {URI: "c"}, {URI: "d"}, {URI: "e"}}
+	pl := player.NewDummyPlayer(tracks...)
+	defer pl.Events().Close()
+	jb := NewJukebox(player.SimpleList{"dummy": pl}, nil, nil, nil, nil)
+	ctx := context.Background()
+	if err := pl.Playlist().InsertWithMeta(-1, tracks[:4], make([]player.TrackMeta, 4)); err != nil {
+		t.Fatal(err)
+	}
+	if err := pl.SetTrackIndex(0); err != nil {
+		t.Fatal(err)
+	}
+
+	expectPlaylist := func(expected ...string) {
+		t.Helper()
+		var uris []string
+		for i := 0; i < 100; i++ {
+			plist, err := pl.Playlist().Tracks()
+			if err != nil {
+				t.Fatal(err)
+			}
+			uris = trackURIs(plist)
+			if reflect.DeepEqual(uris, expected) {
+				return
+			}
+			time.Sleep(time.Millisecond * 10)
+		}
+		t.Fatalf("Unexpected playlist: %v", uris)
+	}
+
+	// Queued tracks are moved to the front, others are inserted after the
+	// pinned tracks.
+	if err := jb.PinTrack(ctx, "dummy", "d"); err != nil {
+		t.Fatal(err)
+	}
+	expectPlaylist("a", "d", "b", "c")
+	if err := jb.PinTrack(ctx, "dummy", "e"); err != nil {
+		t.Fatal(err)
+	}
+	expectPlaylist("a", "d", "e", "b", "c")
+	if pins := jb.PinnedTracks("dummy"); !reflect.DeepEqual(pins, []string{"d", "e"}) {
+		t.Fatalf("Unexpected pins: %v", pins)
+	}
+
+	// Pinned tracks stay in front as the playlist changes.
+	if err := pl.Playlist().Move(4, 1); err != nil {
+		t.Fatal(err)
+	}
+	expectPlaylist("a", "d", "e", "c", "b")
+	if err := pl.Playlist().InsertWithMeta(1, []library.Track{{URI: "f"}}, make([]player.TrackMeta, 1)); err != nil {
+		t.Fatal(err)
+	}
+	expectPlaylist("a", "d", "e", "f", "c", "b")
+
+	// A track is unpinned once it starts playing.
+	if err := pl.SetTrackIndex(1); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100 && len(jb.PinnedTracks("dummy")) != 1; i++ {
+		time.Sleep(time.Millisecond * 10)
+	}
+	if pins := jb.PinnedTracks("dummy"); !reflect.DeepEqual(pins, []string{"e"}) {
+		t.Fatalf("Unexpected pins: %v", pins)
+	}
+
+	if err := jb.UnpinTrack(ctx, "dummy", "e"); err != nil {
+		t.Fatal(err)
+	}
+	if err := pl.Playlist().Move(2, 5); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond * 20)
+	expectPlaylist("a", "d", "f", "c", "b", "e")
+}
@@ -0,0 +1,114 @@
+package jukebox
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/polyfloyd/trollibox/src/player"
+	"github.com/polyfloyd/trollibox/src/util"
+)
+
+// MoveTracks moves count consecutive tracks of the playlist of the named
+// player from one position to another. See player.MoveRange.
+func (jb *Jukebox) MoveTracks(ctx context.Context, playerName string, from, to, count int) error {
+	pl, err := jb.player(ctx, playerName)
+	if err != nil {
+		return err
+	}
+	if err := jb.CheckQueueLock(ctx, playerName); err != nil {
+		return err
+	}
+	return util.RunUnlessDone(ctx, func() error {
+		plist := pl.Playlist()
+		tracks, err := plist.Tracks()
+		if err != nil {
+			return err
+		}
+		if err := player.MoveRange(plist, from, to, count); err != nil {
+			return err
+		}
+		var moved []string
+		for i := from; i < from+count && i >= 0 && i < len(tracks); i++ {
+			moved = append(moved, tracks[i].URI)
+		}
+		jb.RecordAudit(ctx, playerName, AuditMove, moved, fmt.Sprintf("%d to %d", from, to))
+		return nil
+	})
+}
+
+// RemoveTracks removes the tracks at the specified positions from the playlist
+// of the named player. Guests can not remove tracks in party mode.
+func (jb *Jukebox) RemoveTracks(ctx context.Context, playerName string, positions []int) error {
+	pl, err := jb.player(ctx, playerName)
+	if err != nil {
+		return err
+	}
+	if err := jb.CheckQueueLock(ctx, playerName); err != nil {
+		return err
+	}
+	if err := jb.CheckPartyMode(ctx, playerName); err != nil {
+		return err
+	}
+	return util.RunUnlessDone(ctx, func() error {
+		plist := pl.Playlist()
+		tracks, err := plist.Tracks()
+		if err != nil {
+			return err
+		}
+		if err := plist.Remove(positions...); err != nil {
+			return err
+		}
+		var removed []string
+		for _, pos := range positions {
+			if pos >= 0 && pos < len(tracks) {
+				removed = append(removed, tracks[pos].URI)
+			}
+		}
+		jb.RecordAudit(ctx, playerName, AuditRemove, removed, "")
+		return nil
+	})
+}
+
+// RemoveTracksSelectively removes the tracks with the specified URIs and
+// identifiers from the playlist of the named player, regardless of their
+// positions. ErrUnsupported is returned if the playlist is not a
+// player.SelectiveRemover. Guests can not remove tracks in party mode.
+//
+// The removed tracks are returned along with their former positions.
+func (jb *Jukebox) RemoveTracksSelectively(ctx context.Context, playerName string, uris, ids []string) ([]player.RemovedTrack, error) {
+	pl, err := jb.player(ctx, playerName)
+	if err != nil {
+		return nil, err
+	}
+	if err := jb.CheckQueueLock(ctx, playerName); err != nil {
+		return nil, err
+	}
+	if err := jb.CheckPartyMode(ctx, playerName); err != nil {
+		return nil, err
+	}
+	remover, ok := pl.Playlist().(player.SelectiveRemover)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+	var removed []player.RemovedTrack
+	err = util.RunUnlessDone(ctx, func() error {
+		byURI, err := remover.RemoveURIs(uris...)
+		if err != nil {
+			return err
+		}
+		var byID []player.RemovedTrack
+		if len(ids) > 0 {
+			if byID, err = remover.RemoveIDs(ids...); err != nil {
+				return err
+			}
+		}
+		removed = append(byURI, byID...)
+		removedURIs := make([]string, len(removed))
+		for i, rm := range removed {
+			removedURIs[i] = rm.Track.URI
+		}
+		jb.RecordAudit(ctx, playerName, AuditRemove, removedURIs, "")
+		return nil
+	})
+	return removed, err
+}
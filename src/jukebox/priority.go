@@ -0,0 +1,55 @@
+package jukebox
+
+import (
+	"context"
+
+	"github.com/polyfloyd/trollibox/src/player"
+	"github.com/polyfloyd/trollibox/src/util"
+)
+
+// TrackPriorities returns the priority of each entry of the playlist of the
+// named player. ErrUnsupported is returned if the player is not a
+// player.Prioritizer.
+func (jb *Jukebox) TrackPriorities(ctx context.Context, playerName string) ([]int, error) {
+	pl, err := jb.queryPlayer(ctx, playerName)
+	if err != nil {
+		return nil, err
+	}
+	prioritizer, ok := pl.(player.Prioritizer)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+	var priorities []int
+	err = util.WithContext(ctx, func() (err error) {
+		priorities, err = prioritizer.TrackPriorities()
+		return
+	})
+	return priorities, err
+}
+
+// SetTrackPriority sets the priority of the entry at the specified position of
+// the playlist of the named player.
+//
+// The priority only affects playback in random order, which is a setting of
+// the player itself that Trollibox does not control. Entries with a higher
+// priority are then played first. Guests can not change priorities in party
+// mode, as that would let them jump the queue.
+func (jb *Jukebox) SetTrackPriority(ctx context.Context, playerName string, pos, prio int) error {
+	pl, err := jb.player(ctx, playerName)
+	if err != nil {
+		return err
+	}
+	if err := jb.CheckQueueLock(ctx, playerName); err != nil {
+		return err
+	}
+	if err := jb.CheckPartyMode(ctx, playerName); err != nil {
+		return err
+	}
+	prioritizer, ok := pl.(player.Prioritizer)
+	if !ok {
+		return ErrUnsupported
+	}
+	return util.RunUnlessDone(ctx, func() error {
+		return prioritizer.SetTrackPriority(pos, prio)
+	})
+}
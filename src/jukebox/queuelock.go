@@ -0,0 +1,83 @@
+package jukebox
+
+import (
+	"context"
+	"fmt"
+)
+
+// ErrQueueLocked is returned when a client attempts to change a playlist that
+// has been locked by someone else.
+var ErrQueueLocked = fmt.Errorf("the queue is locked")
+
+// QueueLockEvent is emitted by the player when its queue is locked or
+// unlocked.
+type QueueLockEvent struct {
+	Locked bool
+	// The public ID of the client that locked the queue.
+	By string
+}
+
+// SetQueueLock locks or unlocks the playlist of the named player. While the
+// queue is locked, only the client that locked it and admins may change it.
+// A locked queue may only be unlocked by those same clients.
+//
+// The lock applies to all operations of the jukebox that change the playlist,
+// see CheckQueueLock. Playback controls and the autoqueuer are not affected.
+func (jb *Jukebox) SetQueueLock(ctx context.Context, playerName string, locked bool) error {
+	pl, err := jb.players.PlayerByName(playerName)
+	if err != nil {
+		return err
+	}
+	client, _ := ClientFromContext(ctx)
+	if locked && client.ID == "" {
+		return fmt.Errorf("the queue can only be locked by an identified client")
+	}
+
+	jb.queueLocksLock.Lock()
+	holder, isLocked := jb.queueLocks[playerName]
+	if isLocked && !client.Admin && client.ID != holder.ID {
+		jb.queueLocksLock.Unlock()
+		return ErrQueueLocked
+	}
+	if locked {
+		jb.queueLocks[playerName] = client
+	} else {
+		delete(jb.queueLocks, playerName)
+	}
+	jb.queueLocksLock.Unlock()
+
+	if locked != isLocked || locked && holder.ID != client.ID {
+		ev := QueueLockEvent{Locked: locked}
+		if locked {
+			ev.By = client.PublicID
+		}
+		pl.Events().Emit(ev)
+	}
+	return nil
+}
+
+// QueueLock reports whether the playlist of the named player is locked and
+// the public ID of the client that locked it.
+func (jb *Jukebox) QueueLock(playerName string) (bool, string) {
+	jb.queueLocksLock.RLock()
+	defer jb.queueLocksLock.RUnlock()
+	holder, ok := jb.queueLocks[playerName]
+	return ok, holder.PublicID
+}
+
+// CheckQueueLock returns ErrQueueLocked if the client of the context may not
+// change the playlist of the named player. Operations without a client are
+// treated as coming from a guest, like with CheckPartyMode.
+func (jb *Jukebox) CheckQueueLock(ctx context.Context, playerName string) error {
+	client, _ := ClientFromContext(ctx)
+	if client.Admin {
+		return nil
+	}
+	jb.queueLocksLock.RLock()
+	holder, locked := jb.queueLocks[playerName]
+	jb.queueLocksLock.RUnlock()
+	if locked && (client.ID == "" || client.ID != holder.ID) {
+		return ErrQueueLocked
+	}
+	return nil
+}
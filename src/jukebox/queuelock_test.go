@@ -0,0 +1,51 @@
+package jukebox
+
+import (
+	"context"
+	"testing"
+
+	"github.com/polyfloyd/trollibox/src/library"
+	"github.com/polyfloyd/trollibox/src/player"
+)
+
+func TestQueueLockEnforced(t *testing.T) {
+	tracks := []library.Track{{URI: "a"}, {URI: "b"}}
+	pl := player.NewDummyPlayer(tracks...)
+	defer pl.Events().Close()
+	jb := NewJukebox(player.SimpleList{"dummy": pl}, nil, nil, nil, nil)
+	events := pl.Events().Listen()
+	defer pl.Events().Unlisten(events)
+
+	host := WithClient(context.Background(), Client{ID: "secret", PublicID: "public"})
+	if err := jb.SetQueueLock(host, "dummy", true); err != nil {
+		t.Fatal(err)
+	}
+	if locked, by := jb.QueueLock("dummy"); !locked || by != "public" {
+		t.Fatalf("Unexpected lock: %v, %q", locked, by)
+	}
+	for ev := range events {
+		if ev, ok := ev.(QueueLockEvent); ok {
+			if ev.By != "public" {
+				t.Fatalf("The lock event exposes the holder: %q", ev.By)
+			}
+			break
+		}
+	}
+
+	// Remote controls and operations without a client are guests.
+	for _, ctx := range []context.Context{
+		context.Background(),
+		WithClient(context.Background(), Client{ID: "grpc", PublicID: "grpc"}),
+		WithClient(context.Background(), Client{PublicID: "secret"}),
+	} {
+		if err := jb.InsertTracks(ctx, "dummy", -1, tracks[:1], []player.TrackMeta{{}}); err != ErrQueueLocked {
+			t.Fatalf("Inserted into a locked queue: %v", err)
+		}
+		if err := jb.RemoveTracks(ctx, "dummy", []int{0}); err != ErrQueueLocked {
+			t.Fatalf("Removed from a locked queue: %v", err)
+		}
+	}
+	if err := jb.InsertTracks(host, "dummy", -1, tracks[:1], []player.TrackMeta{{}}); err != nil {
+		t.Fatalf("The holder could not insert: %v", err)
+	}
+}
@@ -0,0 +1,175 @@
+package jukebox
+
+import (
+	"context"
+
+	"github.com/polyfloyd/trollibox/src/player"
+	"github.com/polyfloyd/trollibox/src/util"
+)
+
+// SetQueueStore configures where the queues of players that can not save
+// their queues themselves are stored. Without a store, such players do not
+// support saved queues.
+func (jb *Jukebox) SetQueueStore(store *player.QueueStore) {
+	jb.queueStore = store
+}
+
+// SavedQueues lists the names of the queues saved by the named player. The
+// queue saved on shutdown is not listed, it is offered for restoring instead.
+func (jb *Jukebox) SavedQueues(ctx context.Context, playerName string) ([]string, error) {
+	pl, err := jb.queryPlayer(ctx, playerName)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	err = util.WithContext(ctx, func() (err error) {
+		if saver, ok := pl.(player.QueueSaver); ok {
+			names, err = saver.SavedQueues()
+		} else if jb.queueStore != nil {
+			names, err = jb.queueStore.Names(playerName)
+		} else {
+			err = ErrUnsupported
+		}
+		return
+	})
+	for i, name := range names {
+		if name == player.ShutdownQueueName {
+			names = append(names[:i], names[i+1:]...)
+			break
+		}
+	}
+	return names, err
+}
+
+// SaveQueue stores the playlist of the named player under the specified
+// name.
+//
+// If withPosition is set, the current track and the time in it are saved as
+// well, so playback continues there when the queue is loaded. This requires a
+// queue store, also for players that save queues themselves.
+func (jb *Jukebox) SaveQueue(ctx context.Context, playerName, name string, withPosition bool) error {
+	if err := player.ValidateQueueName(name); err != nil {
+		return err
+	}
+	return jb.saveQueue(ctx, playerName, name, withPosition)
+}
+
+// saveQueue is like SaveQueue, but also accepts reserved names.
+func (jb *Jukebox) saveQueue(ctx context.Context, playerName, name string, withPosition bool) error {
+	pl, err := jb.player(ctx, playerName)
+	if err != nil {
+		return err
+	}
+	if withPosition && jb.queueStore == nil {
+		return ErrUnsupported
+	}
+	return util.RunUnlessDone(ctx, func() error {
+		var pos *player.QueuePosition
+		if withPosition {
+			index, err := pl.TrackIndex()
+			if err != nil {
+				return err
+			}
+			if index >= 0 {
+				offset, err := pl.Time()
+				if err != nil {
+					return err
+				}
+				pos = &player.QueuePosition{Index: index, Offset: offset}
+			}
+		}
+
+		var err error
+		if saver, ok := pl.(player.QueueSaver); ok {
+			err = saver.SaveQueue(name)
+		} else if jb.queueStore != nil {
+			err = jb.queueStore.Save(playerName, name, pl.Playlist())
+		} else {
+			return ErrUnsupported
+		}
+		if err != nil || jb.queueStore == nil {
+			return err
+		}
+		// A position of an earlier save is removed.
+		return jb.queueStore.SavePosition(playerName, name, pos)
+	})
+}
+
+// LoadQueue appends the tracks of the named saved queue to the playlist of
+// the named player.
+//
+// If the queue was saved with its position, playback continues at that
+// position in the appended tracks.
+func (jb *Jukebox) LoadQueue(ctx context.Context, playerName, name string) error {
+	if err := player.ValidateQueueName(name); err != nil {
+		return err
+	}
+	return jb.loadQueue(ctx, playerName, name, false)
+}
+
+// loadQueue is like LoadQueue, but also accepts reserved names. If onlyIfEmpty
+// is set, the saved position is only applied if the playlist was empty, so
+// tracks that were queued in the meantime keep playing.
+func (jb *Jukebox) loadQueue(ctx context.Context, playerName, name string, onlyIfEmpty bool) error {
+	pl, err := jb.player(ctx, playerName)
+	if err != nil {
+		return err
+	}
+	if err := jb.CheckQueueLock(ctx, playerName); err != nil {
+		return err
+	}
+	return util.RunUnlessDone(ctx, func() error {
+		start, err := pl.Playlist().Len()
+		if err != nil {
+			return err
+		}
+		if saver, ok := pl.(player.QueueSaver); ok {
+			err = saver.LoadQueue(name)
+		} else if jb.queueStore != nil {
+			err = jb.queueStore.Load(playerName, name, pl.Playlist())
+		} else {
+			return ErrUnsupported
+		}
+		if err != nil {
+			return err
+		}
+		jb.RecordAudit(ctx, playerName, AuditLoad, nil, name)
+
+		if jb.queueStore == nil || (onlyIfEmpty && start > 0) {
+			return nil
+		}
+		pos, err := jb.queueStore.Position(playerName, name)
+		if err != nil || pos == nil {
+			return err
+		}
+		if err := pl.SetTrackIndex(start + pos.Index); err != nil {
+			return err
+		}
+		return pl.SetTime(pos.Offset)
+	})
+}
+
+// RemoveSavedQueue deletes the named saved queue of the named player.
+func (jb *Jukebox) RemoveSavedQueue(ctx context.Context, playerName, name string) error {
+	if err := player.ValidateQueueName(name); err != nil {
+		return err
+	}
+	pl, err := jb.player(ctx, playerName)
+	if err != nil {
+		return err
+	}
+	return util.RunUnlessDone(ctx, func() error {
+		if saver, ok := pl.(player.QueueSaver); ok {
+			if err := saver.RemoveSavedQueue(name); err != nil {
+				return err
+			}
+			if jb.queueStore != nil {
+				return jb.queueStore.SavePosition(playerName, name, nil)
+			}
+			return nil
+		} else if jb.queueStore != nil {
+			return jb.queueStore.Remove(playerName, name)
+		}
+		return ErrUnsupported
+	})
+}
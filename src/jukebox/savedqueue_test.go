@@ -0,0 +1,104 @@
+package jukebox
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/polyfloyd/trollibox/src/library"
+	"github.com/polyfloyd/trollibox/src/player"
+)
+
+func TestLoadQueuePosition(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trollibox-savedqueue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tracks := []library.Track{{URI: "a"}, {URI: "b"}, {URI: "c"}}
+	pl := player.NewDummyPlayer(tracks...)
+	defer pl.Events().Close()
+	jb := NewJukebox(player.SimpleList{"dummy": pl}, nil, nil, nil, nil)
+	store, err := player.NewQueueStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jb.SetQueueStore(store)
+	ctx := context.Background()
+
+	if err := jb.InsertTracks(ctx, "dummy", -1, tracks, make([]player.TrackMeta, len(tracks))); err != nil {
+		t.Fatal(err)
+	}
+	if err := pl.SetTrackIndex(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := jb.SaveQueue(ctx, "dummy", "book", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := jb.SaveQueue(ctx, "dummy", "plain", false); err != nil {
+		t.Fatal(err)
+	}
+
+	// Playback continues at the saved track in the appended tracks.
+	if err := jb.LoadQueue(ctx, "dummy", "book"); err != nil {
+		t.Fatal(err)
+	}
+	if index, _ := pl.TrackIndex(); index != 5 {
+		t.Fatalf("Unexpected track index: %d", index)
+	}
+	// Queues saved without their position do not affect playback.
+	if err := jb.LoadQueue(ctx, "dummy", "plain"); err != nil {
+		t.Fatal(err)
+	}
+	if index, _ := pl.TrackIndex(); index != 5 {
+		t.Fatalf("Unexpected track index: %d", index)
+	}
+
+	// Removing the queue removes its position.
+	if err := jb.RemoveSavedQueue(ctx, "dummy", "book"); err != nil {
+		t.Fatal(err)
+	}
+	if pos, err := store.Position("dummy", "book"); err != nil || pos != nil {
+		t.Fatalf("Unexpected position: %v, %v", pos, err)
+	}
+}
+
+func TestReservedQueueName(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trollibox-savedqueue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tracks := []library.Track{{URI: "a"}}
+	pl := player.NewDummyPlayer(tracks...)
+	defer pl.Events().Close()
+	jb := NewJukebox(player.SimpleList{"dummy": pl}, nil, nil, nil, nil)
+	store, err := player.NewQueueStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jb.SetQueueStore(store)
+	ctx := context.Background()
+
+	if err := jb.InsertTracks(ctx, "dummy", -1, tracks, make([]player.TrackMeta, len(tracks))); err != nil {
+		t.Fatal(err)
+	}
+	if err := jb.SaveQueue(ctx, "dummy", player.ShutdownQueueName, false); err == nil {
+		t.Fatal("Clients should not be able to save the shutdown queue")
+	}
+	if err := jb.saveQueue(ctx, "dummy", player.ShutdownQueueName, true); err != nil {
+		t.Fatal(err)
+	}
+	if names, err := jb.SavedQueues(ctx, "dummy"); err != nil {
+		t.Fatal(err)
+	} else if len(names) != 0 {
+		t.Fatalf("The shutdown queue should not be listed: %q", names)
+	}
+	if err := jb.LoadQueue(ctx, "dummy", player.ShutdownQueueName); err == nil {
+		t.Fatal("Clients should not be able to load the shutdown queue")
+	}
+	if err := jb.RemoveSavedQueue(ctx, "dummy", player.ShutdownQueueName); err == nil {
+		t.Fatal("Clients should not be able to remove the shutdown queue")
+	}
+}
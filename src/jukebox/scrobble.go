@@ -0,0 +1,97 @@
+package jukebox
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/polyfloyd/trollibox/src/library"
+	"github.com/polyfloyd/trollibox/src/player"
+	"github.com/polyfloyd/trollibox/src/scrobble"
+)
+
+// AttachScrobbler submits the tracks played by the named player to the
+// scrobbler. Tracks are announced as soon as they start playing and scrobbled
+// once they count as played.
+//
+// The scrobbler is detached when the player is closed.
+//
+// Only players that emit player.PlayEvent are scrobbled, which currently
+// rules out SlimServer players.
+func (jb *Jukebox) AttachScrobbler(playerName string, scrobbler scrobble.Scrobbler) error {
+	pl, err := jb.players.PlayerByName(playerName)
+	if err != nil {
+		return err
+	}
+	events := pl.Events().Listen()
+	go func() {
+		defer pl.Events().Unlisten(events)
+		lastAnnounced := ""
+		for event := range events {
+			switch t := event.(type) {
+			case player.PlaylistEvent, player.PlayStateEvent:
+				uri, err := jb.playingTrackURI(pl)
+				if err != nil {
+					log.WithField("player", playerName).Errorf("Error scrobbling: %v", err)
+					continue
+				}
+				if uri == "" || uri == lastAnnounced {
+					continue
+				}
+				lastAnnounced = uri
+				track, err := jb.scrobbleTrackInfo(playerName, uri)
+				if err != nil {
+					log.WithField("player", playerName).Errorf("Error scrobbling: %v", err)
+					continue
+				}
+				if err := scrobbler.NowPlaying(track); err != nil {
+					log.WithField("player", playerName).Errorf("Error announcing now playing to %v: %v", scrobbler, err)
+				}
+
+			case player.PlayEvent:
+				track, err := jb.scrobbleTrackInfo(playerName, t.URI)
+				if err != nil {
+					log.WithField("player", playerName).Errorf("Error scrobbling: %v", err)
+					continue
+				}
+				if err := scrobbler.Scrobble(track, t.Started); err != nil {
+					log.WithField("player", playerName).Errorf("Error scrobbling to %v: %v", scrobbler, err)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// playingTrackURI returns the URI of the track that is currently playing or an
+// empty string if the player is not playing.
+func (jb *Jukebox) playingTrackURI(pl player.Player) (string, error) {
+	state, err := pl.State()
+	if err != nil || state != player.PlayStatePlaying {
+		return "", err
+	}
+	index, err := pl.TrackIndex()
+	if err != nil || index < 0 {
+		return "", err
+	}
+	tracks, err := pl.Playlist().Tracks()
+	if err != nil || index >= len(tracks) {
+		return "", err
+	}
+	return tracks[index].URI, nil
+}
+
+func (jb *Jukebox) scrobbleTrackInfo(playerName, uri string) (library.Track, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	libs, err := jb.PlayerLibraries(ctx, playerName)
+	if err != nil {
+		return library.Track{}, err
+	}
+	tracks, err := library.AllTrackInfo(libs, uri)
+	if err != nil {
+		return library.Track{}, err
+	}
+	return tracks[0], nil
+}
@@ -0,0 +1,236 @@
+package jukebox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sync"
+)
+
+// Settings are the persisted settings of a player, keyed by name. Values are
+// kept as JSON so each feature can decode its own type.
+type Settings map[string]json.RawMessage
+
+// clientSettings are the settings that clients may change, along with a
+// function that returns a value of the type of the setting.
+var clientSettings = map[string]func() interface{}{
+	SettingSaveQueueOnShutdown: func() interface{} { return new(bool) },
+	SettingNoRepeatWindow:      func() interface{} { return new(NoRepeatWindow) },
+	SettingPartyMode:           func() interface{} { return new(bool) },
+	SettingQueueCap:            func() interface{} { return new(int) },
+	SettingBlocklist:           func() interface{} { return new([]string) },
+	SettingSkipVotes:           func() interface{} { return new(int) },
+}
+
+// SettingsEvent is emitted by the player when its settings have changed.
+type SettingsEvent struct {
+	Settings Settings
+}
+
+// A SettingsStore persists the settings of players. The settings of each
+// player are stored as a JSON object in a file in a directory.
+type SettingsStore struct {
+	directory string
+	cache     map[string]Settings
+	lock      sync.Mutex
+}
+
+// NewSettingsStore creates a store that keeps settings in the specified
+// directory.
+func NewSettingsStore(directory string) (*SettingsStore, error) {
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return nil, err
+	}
+	return &SettingsStore{directory: directory, cache: map[string]Settings{}}, nil
+}
+
+// Settings returns all settings of the named player.
+func (store *SettingsStore) Settings(playerName string) (Settings, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	settings, err := store.load(playerName)
+	if err != nil {
+		return nil, err
+	}
+	return settings.copy(), nil
+}
+
+// Get decodes the setting with the specified key of the named player into
+// value. False is returned if the setting has not been set, in which case
+// value is left untouched.
+func (store *SettingsStore) Get(playerName, key string, value interface{}) (bool, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	settings, err := store.load(playerName)
+	if err != nil {
+		return false, err
+	}
+	raw, ok := settings[key]
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal(raw, value); err != nil {
+		return false, fmt.Errorf("error decoding setting %q: %v", key, err)
+	}
+	return true, nil
+}
+
+// Set stores a single setting of the named player. Setting a nil value
+// removes the setting.
+func (store *SettingsStore) Set(playerName, key string, value interface{}) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	_, err = store.Update(playerName, Settings{key: raw})
+	return err
+}
+
+// Update merges the changes into the settings of the named player and returns
+// the result. Settings with a JSON null value are removed.
+func (store *SettingsStore) Update(playerName string, changes Settings) (Settings, error) {
+	for key, value := range changes {
+		if key == "" {
+			return nil, fmt.Errorf("setting names can not be empty")
+		}
+		if !json.Valid(value) {
+			return nil, fmt.Errorf("invalid value for setting %q", key)
+		}
+	}
+
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	current, err := store.load(playerName)
+	if err != nil {
+		return nil, err
+	}
+	settings := current.copy()
+	for key, value := range changes {
+		if string(value) == "null" {
+			delete(settings, key)
+		} else {
+			settings[key] = append(json.RawMessage(nil), value...)
+		}
+	}
+
+	// Write to a temporary file first so the settings are not lost if
+	// writing fails.
+	file := store.file(playerName)
+	fd, err := os.Create(file + ".tmp")
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewEncoder(fd).Encode(settings); err != nil {
+		fd.Close()
+		os.Remove(file + ".tmp")
+		return nil, err
+	}
+	if err := fd.Close(); err != nil {
+		os.Remove(file + ".tmp")
+		return nil, err
+	}
+	if err := os.Rename(file+".tmp", file); err != nil {
+		return nil, err
+	}
+	store.cache[playerName] = settings
+	return settings.copy(), nil
+}
+
+// load returns the settings of the named player, reading them from disk if
+// they have not been cached yet. The lock must be held.
+func (store *SettingsStore) load(playerName string) (Settings, error) {
+	if settings, ok := store.cache[playerName]; ok {
+		return settings, nil
+	}
+	settings := Settings{}
+	fd, err := os.Open(store.file(playerName))
+	if err == nil {
+		defer fd.Close()
+		if err := json.NewDecoder(fd).Decode(&settings); err != nil {
+			return nil, fmt.Errorf("error reading the settings of %q: %v", playerName, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	store.cache[playerName] = settings
+	return settings, nil
+}
+
+func (store *SettingsStore) file(playerName string) string {
+	return path.Join(store.directory, playerName+".json")
+}
+
+func (settings Settings) copy() Settings {
+	c := make(Settings, len(settings))
+	for key, value := range settings {
+		c[key] = value
+	}
+	return c
+}
+
+// SetSettingsStore configures where the settings of players are persisted. A
+// nil store disables settings.
+func (jb *Jukebox) SetSettingsStore(store *SettingsStore) {
+	jb.settings = store
+}
+
+// PlayerSettings returns the settings of the named player.
+func (jb *Jukebox) PlayerSettings(ctx context.Context, playerName string) (Settings, error) {
+	if _, err := jb.players.PlayerByName(playerName); err != nil {
+		return nil, err
+	}
+	if jb.settings == nil {
+		return nil, ErrUnsupported
+	}
+	return jb.settings.Settings(playerName)
+}
+
+// UpdatePlayerSettings merges the changes into the settings of the named
+// player, see SettingsStore.Update. Only the settings that are meant to be
+// changed by clients are accepted and their values must be of the right type.
+// A SettingsEvent is emitted so other clients can pick up the new settings.
+func (jb *Jukebox) UpdatePlayerSettings(ctx context.Context, playerName string, changes Settings) (Settings, error) {
+	pl, err := jb.players.PlayerByName(playerName)
+	if err != nil {
+		return nil, err
+	}
+	if jb.settings == nil {
+		return nil, ErrUnsupported
+	}
+	for key, value := range changes {
+		if err := checkClientSetting(key, value); err != nil {
+			return nil, err
+		}
+	}
+	settings, err := jb.settings.Update(playerName, changes)
+	if err != nil {
+		return nil, err
+	}
+	if raw, ok := changes[SettingPartyMode]; ok {
+		// The setting has been stored already, only apply it.
+		var enabled bool
+		json.Unmarshal(raw, &enabled)
+		jb.applyPartyMode(pl, playerName, enabled)
+	}
+	pl.Events().Emit(SettingsEvent{Settings: settings.copy()})
+	return settings, nil
+}
+
+// checkClientSetting checks whether the setting may be changed by clients and
+// whether the value is valid. A null value, which removes the setting, is
+// always valid.
+func checkClientSetting(key string, value json.RawMessage) error {
+	newValue, ok := clientSettings[key]
+	if !ok {
+		return fmt.Errorf("unknown setting %q", key)
+	}
+	dec := json.NewDecoder(bytes.NewReader(value))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(newValue()); err != nil {
+		return fmt.Errorf("invalid value for setting %q: %v", key, err)
+	}
+	return nil
+}
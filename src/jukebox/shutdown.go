@@ -0,0 +1,147 @@
+package jukebox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/polyfloyd/trollibox/src/player"
+)
+
+// SettingSaveQueueOnShutdown is the name of the boolean player setting which
+// enables saving the queue when Trollibox is shut down, so it can be restored
+// after starting again.
+const SettingSaveQueueOnShutdown = "save_queue_on_shutdown"
+
+// The player setting in which a clean shutdown is recorded. It is removed on
+// startup, so it is only present if Trollibox was shut down cleanly after it
+// last started.
+const settingShutdownState = "shutdown_state"
+
+// A ShutdownState describes the queue of a player that was saved when
+// Trollibox was last shut down.
+type ShutdownState struct {
+	Time time.Time `json:"time"`
+}
+
+// RestoreOfferEvent is emitted by the player when a queue saved on shutdown
+// becomes available for restoring or is no longer available.
+type RestoreOfferEvent struct {
+	Available bool
+	// The moment the queue was saved.
+	Saved time.Time
+}
+
+// Shutdown saves the queues of players that have SettingSaveQueueOnShutdown
+// enabled. It should be called when Trollibox is shut down intentionally.
+//
+// Jingles are detached first, so none are inserted while the queues are
+// saved.
+func (jb *Jukebox) Shutdown(ctx context.Context) error {
+	jb.jinglesLock.Lock()
+	for name, stop := range jb.jingles {
+		close(stop)
+		delete(jb.jingles, name)
+	}
+	jb.jinglesLock.Unlock()
+
+	if jb.settings == nil {
+		return nil
+	}
+	names, err := jb.players.PlayerNames()
+	if err != nil {
+		return err
+	}
+	var firstErr error
+	for _, name := range names {
+		var enabled bool
+		if _, err := jb.settings.Get(name, SettingSaveQueueOnShutdown, &enabled); err != nil || !enabled {
+			continue
+		}
+		if err := jb.saveShutdownQueue(ctx, name); err != nil {
+			log.WithField("player", name).Errorf("Error saving the queue on shutdown: %v", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+func (jb *Jukebox) saveShutdownQueue(ctx context.Context, playerName string) error {
+	if err := jb.saveQueue(ctx, playerName, player.ShutdownQueueName, true); err != nil {
+		return err
+	}
+	return jb.settings.Set(playerName, settingShutdownState, ShutdownState{Time: time.Now()})
+}
+
+// PrepareRestore offers the queues that were saved on the last shutdown for
+// restoring. It should be called once on startup.
+//
+// Queues are only offered if Trollibox was shut down cleanly, as the record of
+// a clean shutdown is consumed here.
+func (jb *Jukebox) PrepareRestore() error {
+	if jb.settings == nil {
+		return nil
+	}
+	names, err := jb.players.PlayerNames()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		var state ShutdownState
+		ok, err := jb.settings.Get(name, settingShutdownState, &state)
+		if err != nil {
+			return err
+		} else if !ok {
+			continue
+		}
+		if err := jb.settings.Set(name, settingShutdownState, nil); err != nil {
+			return err
+		}
+		jb.restoreOffersLock.Lock()
+		jb.restoreOffers[name] = state
+		jb.restoreOffersLock.Unlock()
+	}
+	return nil
+}
+
+// RestoreOffer returns the queue of the named player that was saved on the
+// last shutdown if it has not been restored or declined yet.
+func (jb *Jukebox) RestoreOffer(playerName string) (ShutdownState, bool) {
+	jb.restoreOffersLock.Lock()
+	defer jb.restoreOffersLock.Unlock()
+	state, ok := jb.restoreOffers[playerName]
+	return state, ok
+}
+
+// Restore accepts or declines the offer to restore the queue of the named
+// player that was saved on the last shutdown.
+//
+// The saved tracks are appended to the playlist. If the playlist was empty,
+// playback also continues where it was at the shutdown.
+func (jb *Jukebox) Restore(ctx context.Context, playerName string, accept bool) error {
+	pl, err := jb.players.PlayerByName(playerName)
+	if err != nil {
+		return err
+	}
+	if accept {
+		if err := jb.CheckQueueLock(ctx, playerName); err != nil {
+			return err
+		}
+	}
+	jb.restoreOffersLock.Lock()
+	_, ok := jb.restoreOffers[playerName]
+	delete(jb.restoreOffers, playerName)
+	jb.restoreOffersLock.Unlock()
+	if !ok {
+		return fmt.Errorf("there is no queue to restore")
+	}
+	pl.Events().Emit(RestoreOfferEvent{Available: false})
+	if !accept {
+		return nil
+	}
+	return jb.loadQueue(ctx, playerName, player.ShutdownQueueName, true)
+}
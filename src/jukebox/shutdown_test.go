@@ -0,0 +1,105 @@
+package jukebox
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/polyfloyd/trollibox/src/library"
+	"github.com/polyfloyd/trollibox/src/player"
+)
+
+func TestShutdownRestore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trollibox-shutdown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tracks := []library.Track{{URI: "a"}, {URI: "b"}, {URI: "c"}}
+	ctx := context.Background()
+
+	// start simulates starting Trollibox with an empty playlist.
+	start := func() (*Jukebox, *player.DummyPlayer) {
+		t.Helper()
+		pl := player.NewDummyPlayer(tracks...)
+		jb := NewJukebox(player.SimpleList{"dummy": pl}, nil, nil, nil, nil)
+		settings, err := NewSettingsStore(path.Join(dir, "settings"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		queues, err := player.NewQueueStore(path.Join(dir, "queues"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		jb.SetSettingsStore(settings)
+		jb.SetQueueStore(queues)
+		if err := jb.PrepareRestore(); err != nil {
+			t.Fatal(err)
+		}
+		return jb, pl
+	}
+
+	jb, pl := start()
+	defer pl.Events().Close()
+	if err := jb.settings.Set("dummy", SettingSaveQueueOnShutdown, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := jb.InsertTracks(ctx, "dummy", -1, tracks, make([]player.TrackMeta, len(tracks))); err != nil {
+		t.Fatal(err)
+	}
+	if err := pl.SetTrackIndex(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := jb.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	jb, pl = start()
+	defer pl.Events().Close()
+	if _, ok := jb.RestoreOffer("dummy"); !ok {
+		t.Fatal("The queue saved on shutdown is not offered")
+	}
+	if err := jb.Restore(ctx, "dummy", true); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := pl.Playlist().Len(); n != len(tracks) {
+		t.Fatalf("Unexpected playlist length: %d", n)
+	}
+	if index, _ := pl.TrackIndex(); index != 1 {
+		t.Fatalf("Unexpected track index: %d", index)
+	}
+	if _, ok := jb.RestoreOffer("dummy"); ok {
+		t.Fatal("The queue is still offered after restoring")
+	}
+
+	// Playback of tracks queued since starting is not interrupted.
+	if err := jb.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	jb, pl = start()
+	defer pl.Events().Close()
+	if err := jb.InsertTracks(ctx, "dummy", -1, tracks[:1], make([]player.TrackMeta, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := pl.SetTrackIndex(0); err != nil {
+		t.Fatal(err)
+	}
+	if err := jb.Restore(ctx, "dummy", true); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := pl.Playlist().Len(); n != len(tracks)+1 {
+		t.Fatalf("Unexpected playlist length: %d", n)
+	}
+	if index, _ := pl.TrackIndex(); index != 0 {
+		t.Fatalf("Unexpected track index: %d", index)
+	}
+
+	// Without a clean shutdown, nothing is offered.
+	jb, pl = start()
+	defer pl.Events().Close()
+	if _, ok := jb.RestoreOffer("dummy"); ok {
+		t.Fatal("A queue is offered after an unclean shutdown")
+	}
+}
@@ -0,0 +1,115 @@
+package jukebox
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/polyfloyd/trollibox/src/util"
+)
+
+// SettingSkipVotes is the name of the integer player setting which holds the
+// number of votes needed to skip the current track, see VoteSkip. Zero or less
+// disables skip voting.
+const SettingSkipVotes = "skip_votes"
+
+// DefaultPartySkipVotes is the number of votes needed to skip a track in party
+// mode if SettingSkipVotes is not set.
+const DefaultPartySkipVotes = 3
+
+// ErrSkipVoting is returned when voting to skip a track of a player that has
+// skip voting disabled.
+var ErrSkipVoting = fmt.Errorf("skip voting is disabled")
+
+// SkipVoteEvent is emitted by the player when a vote to skip the current
+// track has been cast.
+type SkipVoteEvent struct {
+	// The number of votes cast to skip the current track.
+	Votes int
+	// The number of votes needed to skip the current track.
+	Needed int
+}
+
+// A skipVote holds the clients that voted to skip the track at an index of a
+// playlist.
+type skipVote struct {
+	index  int
+	uri    string
+	voters map[string]bool
+}
+
+// VoteSkip casts a vote of the client of the context to skip the current track
+// of the named player. The track is skipped once enough clients have voted,
+// see SettingSkipVotes. Each client votes once per track and clients that are
+// not known share their vote, like they share their attribution, see
+// UserTrackMeta.
+//
+// The number of votes and the number of votes needed are returned. The votes
+// are discarded once the current track changes.
+func (jb *Jukebox) VoteSkip(ctx context.Context, playerName string) (votes, needed int, err error) {
+	pl, err := jb.player(ctx, playerName)
+	if err != nil {
+		return 0, 0, err
+	}
+	if needed, err = jb.skipVotesNeeded(playerName); err != nil {
+		return 0, 0, err
+	} else if needed <= 0 {
+		return 0, 0, ErrSkipVoting
+	}
+	err = util.RunUnlessDone(ctx, func() error {
+		index, err := pl.TrackIndex()
+		if err != nil {
+			return err
+		}
+		tracks, err := pl.Playlist().Tracks()
+		if err != nil {
+			return err
+		}
+		if index < 0 || index >= len(tracks) {
+			return fmt.Errorf("there is no track to skip")
+		}
+
+		jb.skipVotesLock.Lock()
+		vote, ok := jb.skipVotes[playerName]
+		if !ok || vote.index != index || vote.uri != tracks[index].URI {
+			vote = &skipVote{index: index, uri: tracks[index].URI, voters: map[string]bool{}}
+			jb.skipVotes[playerName] = vote
+		}
+		vote.voters[UserTrackMeta(ctx).QueuedBy] = true
+		votes = len(vote.voters)
+		skip := votes >= needed
+		if skip {
+			delete(jb.skipVotes, playerName)
+		}
+		jb.skipVotesLock.Unlock()
+
+		pl.Events().Emit(SkipVoteEvent{Votes: votes, Needed: needed})
+		if !skip {
+			return nil
+		}
+		if err := pl.SetTrackIndex(index + 1); err != nil {
+			return err
+		}
+		jb.RecordAudit(ctx, playerName, AuditJump, nil, strconv.Itoa(index+1))
+		return nil
+	})
+	return votes, needed, err
+}
+
+// skipVotesNeeded returns the number of votes needed to skip a track of the
+// named player, zero or less if skip voting is disabled. If the number is not
+// set, DefaultPartySkipVotes applies in party mode.
+func (jb *Jukebox) skipVotesNeeded(playerName string) (int, error) {
+	var needed int
+	found := false
+	if jb.settings != nil {
+		var err error
+		if found, err = jb.settings.Get(playerName, SettingSkipVotes, &needed); err != nil {
+			return 0, err
+		}
+	}
+	if !found && jb.PartyMode(playerName) {
+		return DefaultPartySkipVotes, nil
+	}
+	return needed, nil
+}
@@ -0,0 +1,65 @@
+package jukebox
+
+import (
+	"context"
+	"testing"
+
+	"github.com/polyfloyd/trollibox/src/library"
+	"github.com/polyfloyd/trollibox/src/player"
+)
+
+func TestVoteSkip(t *testing.T) {
+	tracks := []library.Track{{URI: "a"}, {URI: "b"}, {URI: "c"}}
+	jb, store, cleanup := newModeratedJukebox(t, tracks...)
+	defer cleanup()
+	pl, _ := jb.players.PlayerByName("dummy")
+	if err := pl.Playlist().InsertWithMeta(-1, tracks, make([]player.TrackMeta, len(tracks))); err != nil {
+		t.Fatal(err)
+	}
+	if err := pl.SetTrackIndex(0); err != nil {
+		t.Fatal(err)
+	}
+	alice := WithClient(context.Background(), Client{ID: "alice", PublicID: "a1"})
+	bob := WithClient(context.Background(), Client{ID: "bob", PublicID: "b1"})
+	carol := WithClient(context.Background(), Client{ID: "carol", PublicID: "c1"})
+	expectIndex := func(expected int) {
+		t.Helper()
+		if index, err := pl.TrackIndex(); err != nil || index != expected {
+			t.Fatalf("Unexpected track index: %d, %v", index, err)
+		}
+	}
+
+	if _, _, err := jb.VoteSkip(alice, "dummy"); err != ErrSkipVoting {
+		t.Fatalf("Voted while skip voting is disabled: %v", err)
+	}
+
+	// Party mode enables skip voting.
+	if err := jb.SetPartyMode(context.Background(), "dummy", true); err != nil {
+		t.Fatal(err)
+	}
+	for i, ctx := range []context.Context{alice, alice, bob} {
+		if votes, needed, err := jb.VoteSkip(ctx, "dummy"); err != nil {
+			t.Fatal(err)
+		} else if needed != DefaultPartySkipVotes || votes != i/2+1 {
+			t.Fatalf("Unexpected votes: %d/%d", votes, needed)
+		}
+	}
+	expectIndex(0)
+	if _, _, err := jb.VoteSkip(carol, "dummy"); err != nil {
+		t.Fatal(err)
+	}
+	expectIndex(1)
+
+	// Votes do not carry over to the next track and the number of votes
+	// needed can be configured.
+	if err := store.Set("dummy", SettingSkipVotes, 2); err != nil {
+		t.Fatal(err)
+	}
+	if votes, needed, err := jb.VoteSkip(alice, "dummy"); err != nil || votes != 1 || needed != 2 {
+		t.Fatalf("Unexpected votes: %d/%d, %v", votes, needed, err)
+	}
+	if _, _, err := jb.VoteSkip(bob, "dummy"); err != nil {
+		t.Fatal(err)
+	}
+	expectIndex(2)
+}
@@ -0,0 +1,75 @@
+package jukebox
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/polyfloyd/trollibox/src/player"
+	"github.com/polyfloyd/trollibox/src/util"
+)
+
+// StoredPlaylists lists the names of the playlists that are stored by the
+// named player, e.g. the stored playlists of MPD.
+func (jb *Jukebox) StoredPlaylists(ctx context.Context, playerName string) ([]string, error) {
+	pl, err := jb.queryPlayer(ctx, playerName)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	err = util.WithContext(ctx, func() error {
+		lists, err := pl.Lists()
+		if err != nil {
+			return err
+		}
+		names = make([]string, 0, len(lists))
+		for name := range lists {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil
+	})
+	return names, err
+}
+
+// AppendStoredPlaylist appends the tracks of the named stored playlist to the
+// playlist of the named player. The tracks are subject to the player's
+// InsertMode.
+func (jb *Jukebox) AppendStoredPlaylist(ctx context.Context, playerName, name string) error {
+	pl, err := jb.player(ctx, playerName)
+	if err != nil {
+		return err
+	}
+	if err := jb.CheckQueueLock(ctx, playerName); err != nil {
+		return err
+	}
+	return util.RunUnlessDone(ctx, func() error {
+		lists, err := pl.Lists()
+		if err != nil {
+			return err
+		}
+		list, ok := lists[name]
+		if !ok {
+			return fmt.Errorf("no stored playlist with name %q", name)
+		}
+		tracks, err := list.Tracks()
+		if err != nil {
+			return err
+		}
+		if len(tracks) == 0 {
+			return nil
+		}
+		if err := jb.checkGuestInsert(ctx, pl, playerName, tracks); err != nil {
+			return err
+		}
+		meta := make([]player.TrackMeta, len(tracks))
+		for i := range meta {
+			meta[i] = UserTrackMeta(ctx)
+		}
+		if err := jb.insertTracks(pl, playerName, -1, tracks, meta); err != nil {
+			return err
+		}
+		jb.RecordAudit(ctx, playerName, AuditInsert, trackURIs(tracks), name)
+		return nil
+	})
+}
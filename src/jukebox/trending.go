@@ -0,0 +1,132 @@
+package jukebox
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/polyfloyd/trollibox/src/library"
+	"github.com/polyfloyd/trollibox/src/player"
+	"github.com/polyfloyd/trollibox/src/util"
+)
+
+// DefaultTrendingHalfLife is the default time after which a play counts for
+// half as much towards a track's trending score.
+const DefaultTrendingHalfLife = time.Hour * 24 * 7
+
+// Plays older than this many half-lives contribute less than 0.01% to the
+// score and are discarded.
+const trendingHorizon = 14
+
+// A TrendingTrack is a track along with its trending score, which is the
+// number of times it was played with each play weighted by how recent it is.
+type TrendingTrack struct {
+	Track library.Track
+	Score float64
+}
+
+// SetPlayHistory configures where the plays of players are recorded. A zero
+// half-life selects the DefaultTrendingHalfLife. Plays are only recorded for
+// players attached with AttachPlayHistory.
+func (jb *Jukebox) SetPlayHistory(history *player.PlayHistory, halfLife time.Duration) {
+	if halfLife <= 0 {
+		halfLife = DefaultTrendingHalfLife
+	}
+	jb.playHistory = history
+	jb.trendingHalfLife = halfLife
+}
+
+// AttachPlayHistory records each track that counts as played by the named
+// player in the play history.
+//
+// Plays that are too old to affect the trending tracks are pruned from the
+// history on attaching.
+func (jb *Jukebox) AttachPlayHistory(playerName string) error {
+	pl, err := jb.players.PlayerByName(playerName)
+	if err != nil {
+		return err
+	}
+	if jb.playHistory == nil {
+		return ErrUnsupported
+	}
+	horizon := time.Now().Add(-jb.trendingHalfLife * trendingHorizon)
+	if err := jb.playHistory.Prune(playerName, horizon); err != nil {
+		log.WithField("player", playerName).Errorf("Error pruning the play history: %v", err)
+	}
+	events := pl.Events().Listen()
+	go func() {
+		defer pl.Events().Unlisten(events)
+		for event := range events {
+			ev, ok := event.(player.PlayEvent)
+			if !ok {
+				continue
+			}
+			if err := jb.recordPlay(playerName, player.Play{URI: ev.URI, Time: time.Now()}); err != nil {
+				log.WithField("player", playerName).Errorf("Error recording play: %v", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// Trending returns the tracks of the named player with the highest trending
+// score, highest first. A positive limit caps the number of tracks returned.
+func (jb *Jukebox) Trending(ctx context.Context, playerName string, limit int) ([]TrendingTrack, error) {
+	pl, err := jb.queryPlayer(ctx, playerName)
+	if err != nil {
+		return nil, err
+	}
+	if jb.playHistory == nil {
+		return nil, ErrUnsupported
+	}
+	var trending []TrendingTrack
+	err = util.WithContext(ctx, func() error {
+		now := time.Now()
+		plays, err := jb.playHistory.Plays(playerName, now.Add(-jb.trendingHalfLife*trendingHorizon))
+		if err != nil {
+			return err
+		}
+		scores := trendingScores(plays, now, jb.trendingHalfLife)
+		uris := make([]string, 0, len(scores))
+		for uri := range scores {
+			uris = append(uris, uri)
+		}
+		sort.Slice(uris, func(i, j int) bool {
+			if scores[uris[i]] != scores[uris[j]] {
+				return scores[uris[i]] > scores[uris[j]]
+			}
+			return uris[i] < uris[j]
+		})
+		if limit > 0 && len(uris) > limit {
+			uris = uris[:limit]
+		}
+		tracks, err := pl.Library().TrackInfo(uris...)
+		if err != nil {
+			return err
+		}
+		trending = make([]TrendingTrack, len(uris))
+		for i, uri := range uris {
+			trending[i] = TrendingTrack{Track: tracks[i], Score: scores[uri]}
+			trending[i].Track.URI = uri
+		}
+		return nil
+	})
+	return trending, err
+}
+
+// trendingScores sums the plays of each track, with each play decaying
+// exponentially with the specified half-life.
+func trendingScores(plays []player.Play, now time.Time, halfLife time.Duration) map[string]float64 {
+	scores := map[string]float64{}
+	for _, play := range plays {
+		age := now.Sub(play.Time)
+		if age < 0 {
+			age = 0
+		}
+		scores[play.URI] += math.Exp2(-float64(age) / float64(halfLife))
+	}
+	return scores
+}
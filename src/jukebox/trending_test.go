@@ -0,0 +1,31 @@
+package jukebox
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/polyfloyd/trollibox/src/player"
+)
+
+func TestTrendingScores(t *testing.T) {
+	now := time.Now()
+	halfLife := time.Hour * 24
+	plays := []player.Play{
+		// Played a lot, but long ago.
+		{URI: "old", Time: now.Add(-halfLife * 3)},
+		{URI: "old", Time: now.Add(-halfLife * 3)},
+		{URI: "old", Time: now.Add(-halfLife * 3)},
+		{URI: "old", Time: now.Add(-halfLife * 3)},
+		// Played twice recently.
+		{URI: "new", Time: now},
+		{URI: "new", Time: now.Add(-halfLife)},
+	}
+	scores := trendingScores(plays, now, halfLife)
+	if math.Abs(scores["old"]-0.5) > 1e-9 {
+		t.Fatalf("Unexpected score of old: %v", scores["old"])
+	}
+	if math.Abs(scores["new"]-1.5) > 1e-9 {
+		t.Fatalf("Unexpected score of new: %v", scores["new"])
+	}
+}
@@ -0,0 +1,166 @@
+package library
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/polyfloyd/trollibox/src/util"
+)
+
+type namedLibrary struct {
+	name string
+	lib  Library
+}
+
+// An Aggregate is a Library that merges the tracks of multiple libraries into
+// one view.
+//
+// Tracks that are found in more than one library are only listed once,
+// precedence is given to the library that was added first. The Source of each
+// track is set to the name of the library it was found in.
+type Aggregate struct {
+	util.Emitter
+
+	libs     []namedLibrary
+	libsLock sync.RWMutex
+}
+
+// NewAggregate creates an empty aggregate library.
+func NewAggregate() *Aggregate {
+	return &Aggregate{}
+}
+
+// Add adds a library to the aggregate under the specified name. Update events
+// emitted by the library are propagated.
+func (agg *Aggregate) Add(name string, lib Library) {
+	agg.libsLock.Lock()
+	agg.libs = append(agg.libs, namedLibrary{name: name, lib: lib})
+	agg.libsLock.Unlock()
+
+	go func() {
+		listener := lib.Events().Listen()
+		defer lib.Events().Unlisten(listener)
+		for event := range listener {
+			if _, ok := event.(UpdateEvent); ok {
+				agg.Emit(event)
+			}
+		}
+	}()
+	agg.Emit(UpdateEvent{})
+}
+
+func (agg *Aggregate) libraries() []namedLibrary {
+	agg.libsLock.RLock()
+	defer agg.libsLock.RUnlock()
+	return append([]namedLibrary(nil), agg.libs...)
+}
+
+// fanOut runs fn for all libraries in parallel and returns the results in the
+// order the libraries were added.
+//
+// A library that fails is logged and skipped, its result is nil. An error is
+// only returned if all libraries fail.
+func (agg *Aggregate) fanOut(fn func(Library) ([]Track, error)) ([]namedLibrary, [][]Track, error) {
+	libs := agg.libraries()
+	results := make([][]Track, len(libs))
+	errs := make([]error, len(libs))
+	var wg sync.WaitGroup
+	wg.Add(len(libs))
+	for i, nl := range libs {
+		go func(i int, lib Library) {
+			defer wg.Done()
+			results[i], errs[i] = fn(lib)
+		}(i, nl.lib)
+	}
+	wg.Wait()
+	var lastErr error
+	failed := 0
+	for i, err := range errs {
+		if err != nil {
+			lastErr = fmt.Errorf("error querying library %q: %v", libs[i].name, err)
+			log.Warnf("%v: Skipping library: %v", agg, lastErr)
+			failed++
+		}
+	}
+	if failed > 0 && failed == len(libs) {
+		return nil, nil, lastErr
+	}
+	return libs, results, nil
+}
+
+// Tracks implements the library.Library interface.
+func (agg *Aggregate) Tracks() ([]Track, error) {
+	libs, results, err := agg.fanOut(func(lib Library) ([]Track, error) {
+		return lib.Tracks()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	total := 0
+	for _, tracks := range results {
+		total += len(tracks)
+	}
+	merged := make([]Track, 0, total)
+	seen := make(map[string]struct{}, total)
+	for i, tracks := range results {
+		for _, track := range tracks {
+			if _, ok := seen[track.URI]; ok {
+				continue
+			}
+			seen[track.URI] = struct{}{}
+			track.Source = libs[i].name
+			merged = append(merged, track)
+		}
+	}
+	return merged, nil
+}
+
+// TrackInfo implements the library.Library interface.
+func (agg *Aggregate) TrackInfo(uris ...string) ([]Track, error) {
+	libs, results, err := agg.fanOut(func(lib Library) ([]Track, error) {
+		return lib.TrackInfo(uris...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tracks := make([]Track, len(uris))
+	for i, libraryResult := range results {
+		for index, tr := range libraryResult {
+			if tr.URI != "" && tracks[index].URI == "" {
+				tr.Source = libs[i].name
+				tracks[index] = tr
+			}
+		}
+	}
+	return tracks, nil
+}
+
+// TrackArt implements the library.Library interface.
+func (agg *Aggregate) TrackArt(uri string) (image io.ReadCloser, mime string) {
+	for _, nl := range agg.libraries() {
+		if image, mime = nl.lib.TrackArt(uri); image != nil {
+			return
+		}
+	}
+	return nil, ""
+}
+
+// Events implements the util.Eventer interface.
+func (agg *Aggregate) Events() *util.Emitter {
+	return &agg.Emitter
+}
+
+func (agg *Aggregate) String() string {
+	libs := agg.libraries()
+	names := make([]string, len(libs))
+	for i, nl := range libs {
+		names[i] = nl.name
+	}
+	return fmt.Sprintf("Aggregate{%s}", strings.Join(names, ", "))
+}
@@ -0,0 +1,88 @@
+package library
+
+import (
+	"fmt"
+	"testing"
+)
+
+type failingLibrary struct {
+	DummyLibrary
+}
+
+func (lib *failingLibrary) Tracks() ([]Track, error) {
+	return nil, fmt.Errorf("unavailable")
+}
+
+func (lib *failingLibrary) TrackInfo(uris ...string) ([]Track, error) {
+	return nil, fmt.Errorf("unavailable")
+}
+
+func TestAggregateTracks(t *testing.T) {
+	lib1 := DummyLibrary([]Track{
+		{URI: "foo", Title: "lib1"},
+	})
+	lib2 := DummyLibrary([]Track{
+		{URI: "foo", Title: "lib2"},
+		{URI: "bar", Title: "lib2"},
+	})
+	agg := NewAggregate()
+	agg.Add("one", &lib1)
+	agg.Add("two", &lib2)
+
+	tracks, err := agg.Tracks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tracks) != 2 {
+		t.Fatalf("Duplicate tracks were not merged: %v", tracks)
+	}
+	for _, tr := range tracks {
+		switch tr.URI {
+		case "foo":
+			if tr.Title != "lib1" || tr.Source != "one" {
+				t.Fatalf("Precedence was not given to the first library: %#v", tr)
+			}
+		case "bar":
+			if tr.Source != "two" {
+				t.Fatalf("Unexpected source: %#v", tr)
+			}
+		}
+	}
+
+	info, err := agg.TrackInfo("bar", "baz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info[0].Source != "two" || info[1].URI != "" {
+		t.Fatalf("Unexpected track info: %#v", info)
+	}
+}
+
+func TestAggregateSkipsFailingLibrary(t *testing.T) {
+	lib := DummyLibrary([]Track{{URI: "foo"}})
+	agg := NewAggregate()
+	agg.Add("broken", &failingLibrary{})
+	agg.Add("one", &lib)
+
+	tracks, err := agg.Tracks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tracks) != 1 || tracks[0].Source != "one" {
+		t.Fatalf("Unexpected tracks: %v", tracks)
+	}
+	info, err := agg.TrackInfo("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info[0].Source != "one" {
+		t.Fatalf("Unexpected track info: %#v", info)
+	}
+
+	// Only if all libraries fail, the aggregate fails.
+	agg = NewAggregate()
+	agg.Add("broken", &failingLibrary{})
+	if _, err := agg.Tracks(); err == nil {
+		t.Fatal("Expected an error")
+	}
+}
@@ -0,0 +1,36 @@
+package library
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultArtMIME is the MIME type assumed for track art of which the type can
+// not be determined.
+const DefaultArtMIME = "image/jpeg"
+
+type multiReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// SniffArt detects the MIME type of an image by inspecting its first bytes.
+// DefaultArtMIME is returned if the data is not recognized as an image.
+//
+// The returned reader yields the full image, including the inspected bytes,
+// and closes the original reader.
+func SniffArt(image io.ReadCloser) (io.ReadCloser, string) {
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(image, head)
+	head = head[:n]
+	mime := http.DetectContentType(head)
+	if !strings.HasPrefix(mime, "image/") {
+		mime = DefaultArtMIME
+	}
+	return multiReadCloser{
+		Reader: io.MultiReader(bytes.NewReader(head), image),
+		Closer: image,
+	}, mime
+}
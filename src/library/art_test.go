@@ -0,0 +1,34 @@
+package library
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func TestSniffArt(t *testing.T) {
+	cases := []struct {
+		mime string
+		data []byte
+	}{
+		{"image/png", append([]byte("\x89PNG\x0D\x0A\x1A\x0A"), make([]byte, 16)...)},
+		{"image/webp", append([]byte("RIFF\x00\x00\x00\x00WEBPVP"), make([]byte, 16)...)},
+		{"image/jpeg", append([]byte("\xFF\xD8\xFF"), make([]byte, 16)...)},
+		// Unrecognized data falls back to the default.
+		{DefaultArtMIME, []byte("garbage")},
+	}
+	for _, c := range cases {
+		expected, data := c.mime, c.data
+		image, mime := SniffArt(ioutil.NopCloser(bytes.NewReader(data)))
+		if mime != expected {
+			t.Errorf("Unexpected MIME type: %q != %q", expected, mime)
+		}
+		read, err := ioutil.ReadAll(image)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(read, data) {
+			t.Errorf("Image data was altered: %q != %q", data, read)
+		}
+	}
+}
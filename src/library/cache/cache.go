@@ -3,6 +3,7 @@ package cache
 import (
 	"fmt"
 	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
@@ -21,9 +22,18 @@ type Cache struct {
 	lock   sync.RWMutex
 	tracks []library.Track
 	index  map[string]*library.Track
-	err    error
+	// The normalized attributes of the tracks. The index is built once it is
+	// first requested and is then rebuilt along with the tracks.
+	searchIndex *library.SearchIndex
+	err         error
+
+	debounce time.Duration
 }
 
+// maxDebounceDelays limits how many quiet periods a reload may be postponed by
+// a continuous stream of update events.
+const maxDebounceDelays = 10
+
 // NewCache wraps the specified library and caches it's contents.
 func NewCache(lib library.Library) *Cache {
 	cache := &Cache{Library: lib}
@@ -48,6 +58,26 @@ func (cache *Cache) Tracks() ([]library.Track, error) {
 	return cache.tracks, cache.err
 }
 
+// IndexedTracks implements the library.IndexedLibrary interface.
+func (cache *Cache) IndexedTracks() ([]library.Track, *library.SearchIndex, error) {
+	cache.lock.RLock()
+	defer cache.lock.RUnlock()
+
+	if cache.tracks == nil || cache.searchIndex == nil {
+		cache.lock.RUnlock()
+		cache.lock.Lock()
+		if cache.tracks == nil {
+			cache.reloadTracks()
+		}
+		if cache.tracks != nil && cache.searchIndex == nil {
+			cache.searchIndex = library.NewSearchIndex(cache.tracks)
+		}
+		cache.lock.Unlock()
+		cache.lock.RLock()
+	}
+	return cache.tracks, cache.searchIndex, cache.err
+}
+
 // TrackInfo implements the library.Library interface.
 func (cache *Cache) TrackInfo(uris ...string) ([]library.Track, error) {
 	cache.lock.RLock()
@@ -81,6 +111,19 @@ func (cache *Cache) TrackInfo(uris ...string) ([]library.Track, error) {
 	return results, nil
 }
 
+// SetDebounce sets the quiet period that must pass after an update event of
+// the library before the tracks are reloaded. Each update event during the
+// quiet period restarts it, so a burst of updates results in a single reload.
+// To ensure the cache does not lag behind indefinitely, a reload is postponed
+// by at most 10 quiet periods.
+//
+// A zero duration, the default, reloads immediately on every update.
+func (cache *Cache) SetDebounce(quiet time.Duration) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	cache.debounce = quiet
+}
+
 // Events implements the util.Eventer interface.
 func (cache *Cache) Events() *util.Emitter {
 	return &cache.Emitter
@@ -96,32 +139,93 @@ func (cache *Cache) run() {
 	cache.lock.Unlock()
 	cache.Emit(library.UpdateEvent{})
 
-	for event := range listener {
-		if _, ok := event.(library.UpdateEvent); ok {
-			cache.lock.Lock()
-			cache.reloadTracks()
-			cache.lock.Unlock()
+	var reload <-chan time.Time
+	var timer *time.Timer
+	var deadline time.Time
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+	for {
+		select {
+		case event, ok := <-listener:
+			if !ok {
+				return
+			}
+			if _, ok := event.(library.UpdateEvent); !ok {
+				cache.Emit(event)
+				continue
+			}
+			cache.lock.RLock()
+			quiet := cache.debounce
+			cache.lock.RUnlock()
+			if quiet <= 0 {
+				cache.update(event)
+				continue
+			}
+
+			now := time.Now()
+			if reload == nil {
+				deadline = now.Add(quiet * maxDebounceDelays)
+			}
+			wait := quiet
+			if d := deadline.Sub(now); d < wait {
+				wait = d
+			}
+			if timer != nil {
+				timer.Stop()
+			}
+			timer = time.NewTimer(wait)
+			reload = timer.C
+
+		case <-reload:
+			reload = nil
+			cache.update(library.UpdateEvent{})
 		}
-		cache.Emit(event)
 	}
 }
 
-func (cache *Cache) reloadTracks() {
+// update reloads the tracks and emits the event that caused the update
+// followed by events for tracks of which the art has changed.
+func (cache *Cache) update(event interface{}) {
+	cache.lock.Lock()
+	artChanged := cache.reloadTracks()
+	cache.lock.Unlock()
+	cache.Emit(event)
+	for _, uri := range artChanged {
+		cache.Emit(library.TrackArtEvent{URI: uri})
+	}
+}
+
+// reloadTracks reloads all tracks from the library. The URIs of tracks for
+// which the availability of art has changed are returned.
+func (cache *Cache) reloadTracks() (artChanged []string) {
 	log.Infof("%v: Reloading tracks", cache)
 
 	tracks, err := cache.Library.Tracks()
 	if err != nil {
 		cache.err = err
-		cache.tracks, cache.index = nil, nil
-		return
+		cache.tracks, cache.index, cache.searchIndex = nil, nil, nil
+		return nil
 	}
 
-	cache.tracks, cache.index = tracks, map[string]*library.Track{}
+	prevIndex := cache.index
+	indexed := cache.searchIndex != nil
+	cache.tracks, cache.index, cache.err = tracks, map[string]*library.Track{}, nil
 	for i, track := range cache.tracks {
 		cache.index[track.URI] = &cache.tracks[i]
+		if prev, ok := prevIndex[track.URI]; ok && prev.HasArt != track.HasArt {
+			artChanged = append(artChanged, track.URI)
+		}
+	}
+	cache.searchIndex = nil
+	if indexed {
+		cache.searchIndex = library.NewSearchIndex(cache.tracks)
 	}
 
 	log.Infof("%v: Done reloading tracks", cache)
+	return artChanged
 }
 
 func (cache *Cache) String() string {
@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/polyfloyd/trollibox/src/library"
+	"github.com/polyfloyd/trollibox/src/util"
+)
+
+type countingLibrary struct {
+	util.Emitter
+	reloads int32
+}
+
+func (lib *countingLibrary) Tracks() ([]library.Track, error) {
+	atomic.AddInt32(&lib.reloads, 1)
+	return []library.Track{{URI: "a"}}, nil
+}
+
+func (lib *countingLibrary) TrackInfo(uris ...string) ([]library.Track, error) {
+	return make([]library.Track, len(uris)), nil
+}
+
+func (lib *countingLibrary) TrackArt(uri string) (io.ReadCloser, string) {
+	return nil, ""
+}
+
+func (lib *countingLibrary) Events() *util.Emitter {
+	return &lib.Emitter
+}
+
+func TestCacheDebounce(t *testing.T) {
+	lib := &countingLibrary{}
+	cache := NewCache(lib)
+	defer lib.Close()
+	cache.SetDebounce(time.Millisecond * 50)
+
+	listener := cache.Listen()
+	defer cache.Unlisten(listener)
+	// Wait for the initial load, which may or may not have been emitted
+	// before listening.
+	for atomic.LoadInt32(&lib.reloads) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case <-listener:
+	case <-time.After(time.Millisecond * 10):
+	}
+
+	for i := 0; i < 10; i++ {
+		lib.Emit(library.UpdateEvent{})
+		time.Sleep(time.Millisecond * 5)
+	}
+	if n := atomic.LoadInt32(&lib.reloads); n != 1 {
+		t.Fatalf("Reloaded during a burst of updates: %d", n)
+	}
+	select {
+	case event := <-listener:
+		if _, ok := event.(library.UpdateEvent); !ok {
+			t.Fatalf("Unexpected event: %#v", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("No reload after the last update")
+	}
+	if n := atomic.LoadInt32(&lib.reloads); n != 2 {
+		t.Fatalf("Unexpected number of reloads: %d", n)
+	}
+}
+
+func TestCacheSearchIndex(t *testing.T) {
+	lib := &countingLibrary{}
+	cache := NewCache(lib)
+	defer lib.Close()
+
+	tracks, index, err := cache.IndexedTracks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tracks) != 1 {
+		t.Fatalf("Unexpected tracks: %v", tracks)
+	}
+	if _, ok := index.Entry(tracks[0].URI); !ok {
+		t.Fatalf("The tracks were not indexed")
+	}
+}
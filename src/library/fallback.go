@@ -0,0 +1,140 @@
+package library
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// A FallbackRule fills an attribute of a track that is empty with the value
+// of the first of its sources that is not.
+//
+// The target may be one of artist, title, genre, album, albumartist or a tag.
+// Sources may be any textual attribute as well as the following values, which
+// are derived from the track as it was before any rules were applied:
+//
+//	filename             The name of the file without its extension.
+//	filename.artist      The artist in a "<artist> - <title>" file name.
+//	filename.title       The title in a "<artist> - <title>" file name.
+//	title.artist         The artist in a "<artist> - <title>" title.
+//	title.title          The title in a "<artist> - <title>" title.
+//	interpolated.artist  The artist as set by InterpolateMissingFields.
+//	interpolated.title   The title as set by InterpolateMissingFields.
+//
+// Sources derived from file names are empty for streams.
+//
+// A rule that replaces is also applied if the target is not empty.
+type FallbackRule struct {
+	Target  string
+	Sources []string
+	Replace bool
+}
+
+// DefaultFallbackRules interpolate the artist and title of tracks which lack
+// either in the same way as InterpolateMissingFields.
+var DefaultFallbackRules = []FallbackRule{
+	{Target: "artist", Sources: []string{"interpolated.artist"}, Replace: true},
+	{Target: "title", Sources: []string{"interpolated.title"}, Replace: true},
+}
+
+var fallbackTargets = map[string]bool{
+	"artist":      true,
+	"title":       true,
+	"genre":       true,
+	"album":       true,
+	"albumartist": true,
+}
+
+// fallbackSources are the sources that may be used besides the targets.
+var fallbackSources = map[string]bool{
+	"uri":             true,
+	"albumtrack":      true,
+	"albumdisc":       true,
+	"filename":        true,
+	"filename.artist": true,
+	"filename.title":  true,
+	"title.artist":    true,
+	"title.title":     true,
+
+	"interpolated.artist": true,
+	"interpolated.title":  true,
+}
+
+// Validate checks whether the target and sources of the rule exist.
+func (rule FallbackRule) Validate() error {
+	if !fallbackTargets[rule.Target] && !strings.HasPrefix(rule.Target, "tag:") {
+		return fmt.Errorf("invalid fallback target: %q", rule.Target)
+	}
+	if len(rule.Sources) == 0 {
+		return fmt.Errorf("fallback rule for %q has no sources", rule.Target)
+	}
+	for _, source := range rule.Sources {
+		if !fallbackSources[source] && !fallbackTargets[source] && !strings.HasPrefix(source, "tag:") {
+			return fmt.Errorf("invalid fallback source for %q: %q", rule.Target, source)
+		}
+	}
+	return nil
+}
+
+// ApplyFallbackRules applies the rules to the track in order. A rule may use
+// an attribute that was filled by an earlier rule as its source.
+func ApplyFallbackRules(track *Track, rules []FallbackRule) {
+	derived := derivedFallbackValues(*track)
+	for _, rule := range rules {
+		if s, _ := track.Attr(rule.Target).(string); s != "" && !rule.Replace {
+			continue
+		}
+		for _, source := range rule.Sources {
+			value, ok := derived[source]
+			if !ok {
+				value, _ = track.Attr(source).(string)
+			}
+			if value != "" {
+				setFallbackAttr(track, rule.Target, value)
+				break
+			}
+		}
+	}
+}
+
+func derivedFallbackValues(track Track) map[string]string {
+	values := map[string]string{}
+	interpolated := track
+	InterpolateMissingFields(&interpolated)
+	values["interpolated.artist"], values["interpolated.title"] = interpolated.Artist, interpolated.Title
+	if match := interpArtistTitleInTitle.FindStringSubmatch(track.Title); match != nil {
+		values["title.artist"], values["title.title"] = match[1], match[2]
+	}
+	if IsStreamURI(track.URI) {
+		return values
+	}
+	if match := interpArtistTitleInFilename.FindStringSubmatch(track.URI); match != nil {
+		values["filename.artist"], values["filename.title"] = match[1], match[2]
+	}
+	if base := path.Base(track.URI); base != "." && base != "/" {
+		values["filename"] = strings.TrimSuffix(base, path.Ext(base))
+	}
+	return values
+}
+
+func setFallbackAttr(track *Track, attr, value string) {
+	switch attr {
+	case "artist":
+		track.Artist = value
+	case "title":
+		track.Title = value
+	case "genre":
+		track.Genre = value
+	case "album":
+		track.Album = value
+	case "albumartist":
+		track.AlbumArtist = value
+	default:
+		if strings.HasPrefix(attr, "tag:") {
+			if track.Tags == nil {
+				track.Tags = map[string]string{}
+			}
+			track.Tags[strings.TrimPrefix(attr, "tag:")] = value
+		}
+	}
+}
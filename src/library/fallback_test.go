@@ -0,0 +1,72 @@
+package library
+
+import (
+	"testing"
+)
+
+func TestApplyFallbackRules(t *testing.T) {
+	rules := []FallbackRule{
+		{Target: "artist", Sources: []string{"title.artist", "filename.artist"}},
+		{Target: "albumartist", Sources: []string{"artist"}},
+		{Target: "album", Sources: []string{"tag:work", "filename"}},
+	}
+	for _, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	track := Track{URI: "music/Some Artist - Some Title.flac", Title: "Other Title"}
+	ApplyFallbackRules(&track, rules)
+	if track.Artist != "Some Artist" || track.Title != "Other Title" {
+		t.Fatalf("Unexpected artist and title: %q - %q", track.Artist, track.Title)
+	}
+	// Rules may use values filled in by earlier rules.
+	if track.AlbumArtist != "Some Artist" {
+		t.Fatalf("Unexpected album artist: %q", track.AlbumArtist)
+	}
+	if track.Album != "Some Artist - Some Title" {
+		t.Fatalf("Unexpected album: %q", track.Album)
+	}
+
+	track = Track{URI: "http://radio/stream.mp3", Album: "Radio", Tags: map[string]string{"work": "Work"}}
+	ApplyFallbackRules(&track, rules)
+	if track.Artist != "" || track.Album != "Radio" {
+		t.Fatalf("Unexpected artist and album: %q, %q", track.Artist, track.Album)
+	}
+
+	for _, invalid := range []FallbackRule{
+		{Target: "uri", Sources: []string{"title"}},
+		{Target: "title", Sources: []string{"duration"}},
+		{Target: "title"},
+	} {
+		if err := invalid.Validate(); err == nil {
+			t.Fatalf("Expected an error for %v", invalid)
+		}
+	}
+}
+
+func TestDefaultFallbackRules(t *testing.T) {
+	for _, track := range []Track{
+		{URI: "music/Artist - Title.mp3"},
+		{URI: "music/01. Artist - Title.mp3", Title: "Tagged"},
+		{URI: "music/Artist - Title.mp3", Artist: "Tagged"},
+		{URI: "music/track.mp3", Title: "Artist - Title"},
+		{URI: "music/track.mp3", Artist: "Artist", Title: "Other - Title"},
+		{URI: "music/track.mp3"},
+		{URI: "music/track.mp3", Artist: "Artist"},
+		{URI: "http://radio/Artist - Title.mp3"},
+	} {
+		expected := track
+		InterpolateMissingFields(&expected)
+		ApplyFallbackRules(&track, DefaultFallbackRules)
+		if track.Artist != expected.Artist || track.Title != expected.Title {
+			t.Fatalf("Unexpected artist and title: %q - %q, expected %q - %q", track.Artist, track.Title, expected.Artist, expected.Title)
+		}
+	}
+	for _, rule := range DefaultFallbackRules {
+		if err := rule.Validate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package fs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dhowden/tag"
+	"github.com/fsnotify/fsnotify"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/polyfloyd/trollibox/src/library"
+	"github.com/polyfloyd/trollibox/src/util"
+)
+
+const uriSchema = "file://"
+
+var audioExtensions = map[string]struct{}{
+	".flac": {},
+	".m4a":  {},
+	".mp3":  {},
+	".mp4":  {},
+	".ogg":  {},
+	".opus": {},
+}
+
+// A Library is a library.Library that is backed by a directory tree of audio
+// files. It does not depend on any player.
+//
+// The directory is watched for changes, files that are created, modified or
+// removed are rescanned individually.
+type Library struct {
+	util.Emitter
+
+	root    string
+	watcher *fsnotify.Watcher
+
+	lock   sync.RWMutex
+	tracks map[string]library.Track
+}
+
+// NewLibrary scans the specified directory and starts watching it for
+// changes.
+func NewLibrary(root string) (*Library, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("unable to watch %q: %v", root, err)
+	}
+	lib := &Library{
+		Emitter: util.Emitter{Release: time.Millisecond * 100},
+		root:    root,
+		watcher: watcher,
+		tracks:  map[string]library.Track{},
+	}
+	if err := lib.scan(root); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	go lib.watchLoop()
+	return lib, nil
+}
+
+// Close stops watching the directory for changes.
+func (lib *Library) Close() error {
+	return lib.watcher.Close()
+}
+
+// scan walks the directory tree at the specified path and (re)loads all audio
+// files found. Directories are added to the watcher.
+func (lib *Library) scan(dir string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if err := lib.watcher.Add(path); err != nil {
+				return fmt.Errorf("unable to watch %q: %v", path, err)
+			}
+			return nil
+		}
+		lib.scanFile(path)
+		return nil
+	})
+}
+
+func (lib *Library) scanFile(path string) {
+	if _, ok := audioExtensions[strings.ToLower(filepath.Ext(path))]; !ok {
+		return
+	}
+	track, err := readTrack(path)
+	if err != nil {
+		log.Debugf("%v: Could not read %q: %v", lib, path, err)
+		return
+	}
+	lib.lock.Lock()
+	prev, existed := lib.tracks[track.URI]
+	lib.tracks[track.URI] = track
+	lib.lock.Unlock()
+	if existed && prev.HasArt != track.HasArt {
+		lib.Emit(library.TrackArtEvent{URI: track.URI})
+	}
+}
+
+// forget removes all tracks at or below the specified path.
+func (lib *Library) forget(path string) {
+	uri := pathToURI(path)
+	lib.lock.Lock()
+	defer lib.lock.Unlock()
+	for key := range lib.tracks {
+		if key == uri || strings.HasPrefix(key, uri+"/") {
+			delete(lib.tracks, key)
+		}
+	}
+}
+
+func (lib *Library) watchLoop() {
+	for {
+		select {
+		case event, ok := <-lib.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+				lib.forget(event.Name)
+			}
+			if event.Op&(fsnotify.Create|fsnotify.Write) != 0 {
+				if info, err := os.Stat(event.Name); err != nil {
+					continue
+				} else if info.IsDir() {
+					if err := lib.scan(event.Name); err != nil {
+						log.Errorf("%v: %v", lib, err)
+					}
+				} else {
+					lib.scanFile(event.Name)
+				}
+			}
+			lib.Emit(library.UpdateEvent{})
+
+		case err, ok := <-lib.watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Errorf("%v: %v", lib, err)
+		}
+	}
+}
+
+// Tracks implements the library.Library interface.
+func (lib *Library) Tracks() ([]library.Track, error) {
+	lib.lock.RLock()
+	defer lib.lock.RUnlock()
+	tracks := make([]library.Track, 0, len(lib.tracks))
+	for _, track := range lib.tracks {
+		tracks = append(tracks, track)
+	}
+	sort.Slice(tracks, func(i, j int) bool {
+		return tracks[i].URI < tracks[j].URI
+	})
+	return tracks, nil
+}
+
+// TrackInfo implements the library.Library interface.
+func (lib *Library) TrackInfo(uris ...string) ([]library.Track, error) {
+	lib.lock.RLock()
+	defer lib.lock.RUnlock()
+	tracks := make([]library.Track, len(uris))
+	for i, uri := range uris {
+		tracks[i] = lib.tracks[uri]
+	}
+	return tracks, nil
+}
+
+// TrackArt implements the library.Library interface.
+func (lib *Library) TrackArt(uri string) (image io.ReadCloser, mime string) {
+	lib.lock.RLock()
+	_, ok := lib.tracks[uri]
+	lib.lock.RUnlock()
+	if !ok {
+		return nil, ""
+	}
+	fd, err := os.Open(uriToPath(uri))
+	if err != nil {
+		return nil, ""
+	}
+	defer fd.Close()
+	meta, err := tag.ReadFrom(fd)
+	if err != nil || meta.Picture() == nil {
+		return nil, ""
+	}
+	pic := meta.Picture()
+	image = ioutil.NopCloser(bytes.NewReader(pic.Data))
+	if pic.MIMEType == "" {
+		return library.SniffArt(image)
+	}
+	return image, pic.MIMEType
+}
+
+// Events implements the util.Eventer interface.
+func (lib *Library) Events() *util.Emitter {
+	return &lib.Emitter
+}
+
+func (lib *Library) String() string {
+	return fmt.Sprintf("FS{%s}", lib.root)
+}
+
+func readTrack(path string) (library.Track, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return library.Track{}, err
+	}
+	defer fd.Close()
+
+	track := library.Track{URI: pathToURI(path)}
+	if meta, err := tag.ReadFrom(fd); err == nil {
+		track.Artist = meta.Artist()
+		track.Title = meta.Title()
+		track.Genre = meta.Genre()
+		track.Album = meta.Album()
+		track.AlbumArtist = meta.AlbumArtist()
+		if n, total := meta.Track(); n > 0 {
+			track.AlbumTrack = strconv.Itoa(n)
+			track.TrackNumber, track.TrackTotal = n, total
+		}
+		if n, total := meta.Disc(); n > 0 {
+			track.AlbumDisc = strconv.Itoa(n)
+			track.DiscNumber, track.DiscTotal = n, total
+		}
+		track.HasArt = meta.Picture() != nil
+	} else if err != tag.ErrNoTagsFound {
+		return library.Track{}, err
+	}
+	library.InterpolateMissingFields(&track)
+	return track, nil
+}
+
+func pathToURI(path string) string {
+	return uriSchema + filepath.ToSlash(path)
+}
+
+func uriToPath(uri string) string {
+	return filepath.FromSlash(strings.TrimPrefix(uri, uriSchema))
+}
@@ -0,0 +1,89 @@
+package fs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/polyfloyd/trollibox/src/library"
+)
+
+const testdata = "../../../testdata"
+
+func TestScan(t *testing.T) {
+	lib, err := NewLibrary(testdata)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lib.Close()
+
+	tracks, err := lib.Tracks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tracks) != 3 {
+		t.Fatalf("Unexpected number of tracks: %d", len(tracks))
+	}
+	info, err := lib.TrackInfo(tracks[0].URI, "file:///nonexistent.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info[0].URI != tracks[0].URI {
+		t.Fatalf("Track info mismatch: %v != %v", info[0], tracks[0])
+	}
+	if info[1].URI != "" {
+		t.Fatalf("Expected a zero track for a nonexistent URI, got %v", info[1])
+	}
+}
+
+func TestIncrementalRescan(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trollibox-fs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	lib, err := NewLibrary(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lib.Close()
+
+	l := lib.Events().Listen()
+	defer lib.Events().Unlisten(l)
+
+	data, err := ioutil.ReadFile(filepath.Join(testdata, "01.mp3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "01.mp3"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitForUpdate(t, l)
+	if tracks, _ := lib.Tracks(); len(tracks) != 1 {
+		t.Fatalf("Added track was not picked up: %v", tracks)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "01.mp3")); err != nil {
+		t.Fatal(err)
+	}
+	waitForUpdate(t, l)
+	if tracks, _ := lib.Tracks(); len(tracks) != 0 {
+		t.Fatalf("Removed track was not forgotten: %v", tracks)
+	}
+}
+
+func waitForUpdate(t *testing.T, l <-chan interface{}) {
+	for {
+		select {
+		case event := <-l:
+			if _, ok := event.(library.UpdateEvent); ok {
+				return
+			}
+		case <-time.After(time.Second * 2):
+			t.Fatal("Update event was not emitted")
+		}
+	}
+}
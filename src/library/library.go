@@ -10,6 +10,12 @@ import (
 // changed.
 type UpdateEvent struct{}
 
+// A TrackArtEvent is emitted when art has become available for a track or when
+// the art of a track was removed.
+type TrackArtEvent struct {
+	URI string
+}
+
 // A Library is a database that is able to recall tracks that can be played.
 type Library interface {
 	// An UpdateEvent may be emitted after the track library was changed.
@@ -39,11 +39,14 @@ func NewServer(rawServer *raw.Server) (*Server, error) {
 //
 // The returned track's audio stream may be incomplete as downloading happens
 // in the background.
-func (sv *Server) Download(url string) (library.Track, <-chan error) {
+func (sv *Server) Download(url, title string) (library.Track, <-chan error) {
 	info, err := readMediaInfo(context.Background(), url)
 	if err != nil {
 		return library.Track{}, util.ErrorAsChannel(err)
 	}
+	if title != "" {
+		info.Title = title
+	}
 	var image []byte
 	var imageMime string
 	if info.Thumbnail != "" {
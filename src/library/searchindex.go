@@ -0,0 +1,199 @@
+package library
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// indexedAttrs are the string attributes of tracks that are kept in a
+// SearchIndex.
+var indexedAttrs = []string{"uri", "artist", "title", "genre", "album", "albumartist"}
+
+// Letters that are not decomposed into a base letter and diacritics by Unicode
+// but are commonly written without them.
+var foldedLetters = map[rune]string{
+	'ø': "o",
+	'ł': "l",
+	'đ': "d",
+	'ħ': "h",
+	'ı': "i",
+	'æ': "ae",
+	'œ': "oe",
+	'ß': "ss",
+	'þ': "th",
+}
+
+// NormalizeText prepares text for searching. It is lowercased, diacritics are
+// removed and it is split into words of letters and digits which are joined
+// by single spaces.
+//
+// For example, "Björk - Jóga" becomes "bjork joga".
+func NormalizeText(s string) string {
+	normalized, _, _ := normalizeText(s, false)
+	return normalized
+}
+
+// NormalizedSpan maps the span of bytes [start, end) of NormalizeText(s) to
+// the span of s it was derived from.
+func NormalizedSpan(s string, start, end int) (int, int) {
+	normalized, starts, ends := normalizeText(s, true)
+	if start >= len(normalized) {
+		return len(s), len(s)
+	}
+	if end <= start {
+		return starts[start], starts[start]
+	}
+	if end > len(normalized) {
+		end = len(normalized)
+	}
+	return starts[start], ends[end-1]
+}
+
+// normalizeText implements NormalizeText. If spans is set, the offsets of the
+// start and end of the runes of s from which each byte of the result was
+// derived are returned as well.
+func normalizeText(s string, spans bool) (string, []int, []int) {
+	var out strings.Builder
+	out.Grow(len(s))
+	var starts, ends []int
+	lastLen := 0
+	emit := func(str string, start, end int) {
+		out.WriteString(str)
+		lastLen = len(str)
+		if spans {
+			for j := 0; j < len(str); j++ {
+				starts = append(starts, start)
+				ends = append(ends, end)
+			}
+		}
+	}
+
+	separated := false
+	separatorStart := 0
+	var buf [utf8.UTFMax * 4]byte
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		start, end := i, i+size
+		i = end
+		var folded string
+		if r < utf8.RuneSelf {
+			if 'A' <= r && r <= 'Z' {
+				r += 'a' - 'A'
+			}
+			if ('a' <= r && r <= 'z') || ('0' <= r && r <= '9') {
+				folded = string(r)
+			}
+		} else if unicode.Is(unicode.Mn, r) {
+			// Diacritics that are not combined with the preceding
+			// letter are part of it.
+			if spans && !separated {
+				for j := len(ends) - lastLen; j < len(ends); j++ {
+					ends[j] = end
+				}
+			}
+			continue
+		} else {
+			folded = foldRune(r, buf[:0])
+		}
+
+		if folded == "" {
+			if !separated {
+				separated, separatorStart = true, start
+			}
+			continue
+		}
+		if separated && out.Len() > 0 {
+			emit(" ", separatorStart, start)
+		}
+		separated = false
+		emit(folded, start, end)
+	}
+	return out.String(), starts, ends
+}
+
+// foldRune lowercases a non-ASCII rune and removes its diacritics. An empty
+// string is returned for runes that are not letters or digits.
+func foldRune(r rune, buf []byte) string {
+	r = unicode.ToLower(r)
+	if s, ok := foldedLetters[r]; ok {
+		return s
+	}
+	var folded []rune
+	for _, d := range string(norm.NFD.AppendString(buf, string(r))) {
+		if unicode.Is(unicode.Mn, d) {
+			continue
+		}
+		if !unicode.IsLetter(d) && !unicode.IsDigit(d) {
+			continue
+		}
+		folded = append(folded, d)
+	}
+	return string(folded)
+}
+
+// A SearchIndex holds the normalized values of the string attributes of
+// tracks, so they do not have to be normalized again for every search.
+//
+// See NormalizeText.
+type SearchIndex struct {
+	entries map[string]*IndexEntry
+}
+
+// An IndexEntry holds the normalized attributes of a single track.
+type IndexEntry struct {
+	values [][]string
+}
+
+// NewSearchIndex normalizes the attributes of the specified tracks.
+func NewSearchIndex(tracks []Track) *SearchIndex {
+	index := &SearchIndex{entries: make(map[string]*IndexEntry, len(tracks))}
+	for i := range tracks {
+		track := &tracks[i]
+		entry := &IndexEntry{values: make([][]string, len(indexedAttrs))}
+		for j, attr := range indexedAttrs {
+			values := track.AttrValues(attr)
+			normalized := make([]string, len(values))
+			for k, value := range values {
+				normalized[k] = NormalizeText(value)
+			}
+			entry.values[j] = normalized
+		}
+		index.entries[track.URI] = entry
+	}
+	return index
+}
+
+// Entry returns the normalized attributes of the track with the specified
+// URI. False is returned if the track is not indexed.
+func (index *SearchIndex) Entry(uri string) (*IndexEntry, bool) {
+	if index == nil {
+		return nil, false
+	}
+	entry, ok := index.entries[uri]
+	return entry, ok
+}
+
+// Values returns the normalized values of an attribute in the same order as
+// Track.AttrValues. False is returned if the attribute is not indexed.
+func (entry *IndexEntry) Values(attr string) ([]string, bool) {
+	if entry == nil {
+		return nil, false
+	}
+	for i, a := range indexedAttrs {
+		if a == attr {
+			return entry.values[i], true
+		}
+	}
+	return nil, false
+}
+
+// An IndexedLibrary is a Library which keeps a SearchIndex of its tracks.
+type IndexedLibrary interface {
+	Library
+
+	// Returns all available tracks along with an index of them.
+	IndexedTracks() ([]Track, *SearchIndex, error)
+}
@@ -0,0 +1,75 @@
+package library
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeText(t *testing.T) {
+	tests := []struct {
+		text, normalized string
+	}{
+		{text: "Foo Bar", normalized: "foo bar"},
+		{text: "Björk - Jóga", normalized: "bjork joga"},
+		{text: "  AC/DC  ", normalized: "ac dc"},
+		{text: "Mötley Crüe", normalized: "motley crue"},
+		{text: "Sigur Rós", normalized: "sigur ros"},
+		// Decomposed diacritics.
+		{text: "Sigur Ro\u0301s", normalized: "sigur ros"},
+		{text: "Straße", normalized: "strasse"},
+		{text: "Œuvre Ørsted", normalized: "oeuvre orsted"},
+		{text: "東京", normalized: "東京"},
+		{text: "", normalized: ""},
+	}
+	for _, test := range tests {
+		if n := NormalizeText(test.text); n != test.normalized {
+			t.Errorf("Unexpected normalization of %q: %q", test.text, n)
+		}
+	}
+}
+
+func TestNormalizedSpan(t *testing.T) {
+	text := "Björk - Jo\u0301ga"
+	// "bjork joga"
+	tests := []struct {
+		start, end   int
+		expectString string
+	}{
+		{start: 0, end: 5, expectString: "Björk"},
+		{start: 2, end: 3, expectString: "ö"},
+		{start: 6, end: 10, expectString: "Jo\u0301ga"},
+		{start: 7, end: 8, expectString: "o\u0301"},
+		{start: 4, end: 7, expectString: "k - J"},
+	}
+	for _, test := range tests {
+		start, end := NormalizedSpan(text, test.start, test.end)
+		if s := text[start:end]; s != test.expectString {
+			t.Errorf("Unexpected span for [%d, %d): %q", test.start, test.end, s)
+		}
+	}
+}
+
+func TestSearchIndex(t *testing.T) {
+	var track Track
+	track.URI = "foo"
+	track.Title = "Jóga"
+	track.SetArtists("Björk", "Sigur Rós")
+	index := NewSearchIndex([]Track{track})
+
+	if _, ok := index.Entry("bar"); ok {
+		t.Fatalf("Unknown tracks should not be indexed")
+	}
+	entry, ok := index.Entry("foo")
+	if !ok {
+		t.Fatalf("Track not indexed")
+	}
+	if values, ok := entry.Values("artist"); !ok || !reflect.DeepEqual(values, []string{"bjork", "sigur ros"}) {
+		t.Fatalf("Unexpected artists: %q, %v", values, ok)
+	}
+	if values, ok := entry.Values("title"); !ok || !reflect.DeepEqual(values, []string{"joga"}) {
+		t.Fatalf("Unexpected title: %q, %v", values, ok)
+	}
+	if _, ok := entry.Values("duration"); ok {
+		t.Fatalf("Non-string attributes should not be indexed")
+	}
+}
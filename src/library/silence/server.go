@@ -0,0 +1,186 @@
+package silence
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/polyfloyd/trollibox/src/library"
+	"github.com/polyfloyd/trollibox/src/util"
+)
+
+// MaxDuration is the longest gap of silence that can be served.
+const MaxDuration = time.Hour * 4
+
+// The format of the generated audio. A low sample rate keeps the stream small,
+// as it does not affect the sound of silence.
+const (
+	sampleRate    = 8000
+	bytesPerFrame = 2
+	headerSize    = 44
+)
+
+// A Server serves audio consisting of silence of any duration. Queueing it
+// makes playback pause for that duration, after which the player advances to
+// the next track as usual.
+//
+// The audio is generated on the fly from the URI, so tracks do not have to be
+// created or removed and any player that can play HTTP streams can play them.
+type Server struct {
+	util.Emitter
+	urlRoot string
+}
+
+// NewServer creates a new server that configures tracks using the specified
+// URL-root. The URL-root is registered with library.RegisterFiniteURLRoot, so
+// players do not treat the silence as a stream that can be refreshed or
+// reconnected.
+func NewServer(urlRoot string) *Server {
+	library.RegisterFiniteURLRoot(urlRoot)
+	return &Server{urlRoot: urlRoot}
+}
+
+// Track returns the track of silence lasting the specified duration, which is
+// rounded to whole seconds.
+func (sv *Server) Track(duration time.Duration) (library.Track, error) {
+	seconds := int(duration.Round(time.Second) / time.Second)
+	if seconds < 1 || time.Duration(seconds)*time.Second > MaxDuration {
+		return library.Track{}, fmt.Errorf("the duration of silence must be between 1s and %v", MaxDuration)
+	}
+	return sv.track(seconds), nil
+}
+
+func (sv *Server) track(seconds int) library.Track {
+	return library.Track{
+		URI:      fmt.Sprintf("%s/%d.wav", sv.urlRoot, seconds),
+		Title:    fmt.Sprintf("Silence (%v)", time.Duration(seconds)*time.Second),
+		Duration: time.Duration(seconds) * time.Second,
+	}
+}
+
+// seconds returns the duration of the silence of a URI served by this server.
+func (sv *Server) seconds(uri string) (int, bool) {
+	if !strings.HasPrefix(uri, sv.urlRoot+"/") {
+		return 0, false
+	}
+	return parseSeconds(strings.TrimPrefix(uri, sv.urlRoot+"/"))
+}
+
+func parseSeconds(name string) (int, bool) {
+	if !strings.HasSuffix(name, ".wav") {
+		return 0, false
+	}
+	seconds, err := strconv.Atoi(strings.TrimSuffix(name, ".wav"))
+	if err != nil || seconds < 1 || time.Duration(seconds)*time.Second > MaxDuration {
+		return 0, false
+	}
+	return seconds, true
+}
+
+func (sv *Server) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	seconds, ok := parseSeconds(path.Base(req.URL.Path))
+	if !ok {
+		http.NotFound(res, req)
+		return
+	}
+	// Players fetch the audio at the pace it is played, which takes far
+	// longer than the write timeout of the server. Not all response writers
+	// support deadlines, in which case there is no timeout to lift.
+	util.ResponseController(res, req).SetWriteDeadline(time.Time{})
+	res.Header().Set("Content-Type", "audio/wav")
+	http.ServeContent(res, req, "", time.Time{}, newWAV(seconds))
+}
+
+// Tracks implements the library.Library interface. Silence is not listed, as
+// there is a track for every duration.
+func (sv *Server) Tracks() ([]library.Track, error) {
+	return []library.Track{}, nil
+}
+
+// TrackInfo implements the library.Library interface.
+func (sv *Server) TrackInfo(uris ...string) ([]library.Track, error) {
+	tracks := make([]library.Track, len(uris))
+	for i, uri := range uris {
+		if seconds, ok := sv.seconds(uri); ok {
+			tracks[i] = sv.track(seconds)
+		}
+	}
+	return tracks, nil
+}
+
+// TrackArt implements the library.Library interface.
+func (sv *Server) TrackArt(uri string) (io.ReadCloser, string) {
+	return nil, ""
+}
+
+// Events implements the player.Player interface.
+func (sv *Server) Events() *util.Emitter {
+	return &sv.Emitter
+}
+
+// wavReader reads a WAV file of silence without keeping the samples in
+// memory.
+type wavReader struct {
+	header [headerSize]byte
+	size   int64
+	offset int64
+}
+
+func newWAV(seconds int) *wavReader {
+	dataSize := uint32(seconds * sampleRate * bytesPerFrame)
+	wav := &wavReader{size: headerSize + int64(dataSize)}
+	h := wav.header[:]
+	copy(h[0:], "RIFF")
+	binary.LittleEndian.PutUint32(h[4:], 36+dataSize)
+	copy(h[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(h[16:], 16)                       // Size of the format chunk.
+	binary.LittleEndian.PutUint16(h[20:], 1)                        // PCM.
+	binary.LittleEndian.PutUint16(h[22:], 1)                        // Channels.
+	binary.LittleEndian.PutUint32(h[24:], sampleRate)               // Sample rate.
+	binary.LittleEndian.PutUint32(h[28:], sampleRate*bytesPerFrame) // Byte rate.
+	binary.LittleEndian.PutUint16(h[32:], bytesPerFrame)            // Block align.
+	binary.LittleEndian.PutUint16(h[34:], 8*bytesPerFrame)          // Bits per sample.
+	copy(h[36:], "data")
+	binary.LittleEndian.PutUint32(h[40:], dataSize)
+	return wav
+}
+
+func (wav *wavReader) Read(p []byte) (int, error) {
+	if wav.offset >= wav.size {
+		return 0, io.EOF
+	}
+	if remaining := wav.size - wav.offset; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n := 0
+	if wav.offset < headerSize {
+		n = copy(p, wav.header[wav.offset:])
+	}
+	for i := n; i < len(p); i++ {
+		p[i] = 0
+	}
+	wav.offset += int64(len(p))
+	return len(p), nil
+}
+
+func (wav *wavReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += wav.offset
+	case io.SeekEnd:
+		offset += wav.size
+	default:
+		return 0, fmt.Errorf("invalid whence: %d", whence)
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("negative offset: %d", offset)
+	}
+	wav.offset = offset
+	return offset, nil
+}
@@ -0,0 +1,85 @@
+package silence
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/polyfloyd/trollibox/src/library"
+)
+
+func TestServer(t *testing.T) {
+	sv := NewServer("http://localhost/data/silence")
+	track, err := sv.Track(time.Second * 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if track.URI != "http://localhost/data/silence/3.wav" || track.Duration != time.Second*3 {
+		t.Fatalf("Unexpected track: %+v", track)
+	}
+	if library.IsStreamURI(track.URI) {
+		t.Fatal("Silence should not be treated as a stream")
+	}
+	if !library.IsStreamURI("http://localhost/data/raw?track=1") {
+		t.Fatal("Other URLs should still be streams")
+	}
+	if _, err := sv.Track(MaxDuration + time.Second); err == nil {
+		t.Fatal("A duration beyond the maximum should be rejected")
+	}
+
+	tracks, err := sv.TrackInfo(track.URI, "http://localhost/data/raw?track=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tracks[0].URI != track.URI || tracks[0].Title != track.Title || tracks[1].URI != "" {
+		t.Fatalf("Unexpected track info: %+v", tracks)
+	}
+
+	res := httptest.NewRecorder()
+	sv.ServeHTTP(res, httptest.NewRequest("GET", "/3.wav", nil))
+	body, _ := ioutil.ReadAll(res.Body)
+	if res.Code != http.StatusOK || len(body) != headerSize+3*sampleRate*bytesPerFrame {
+		t.Fatalf("Unexpected response: %d, %d bytes", res.Code, len(body))
+	}
+	if string(body[:4]) != "RIFF" || string(body[8:12]) != "WAVE" {
+		t.Fatalf("Not a WAV file: %q", body[:12])
+	}
+	for _, b := range body[headerSize:] {
+		if b != 0 {
+			t.Fatal("The audio is not silent")
+		}
+	}
+
+	res = httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/3.wav", nil)
+	req.Header.Set("Range", "bytes=44-")
+	sv.ServeHTTP(res, req)
+	if res.Code != http.StatusPartialContent || res.Body.Len() != 3*sampleRate*bytesPerFrame {
+		t.Fatalf("Unexpected ranged response: %d, %d bytes", res.Code, res.Body.Len())
+	}
+}
+
+func TestServerWriteTimeout(t *testing.T) {
+	server := httptest.NewUnstartedServer(NewServer("http://localhost/data/silence"))
+	server.Config.WriteTimeout = time.Millisecond * 100
+	server.Start()
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/600.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	// Read slower than the write timeout allows.
+	time.Sleep(time.Millisecond * 300)
+	n, err := io.Copy(ioutil.Discard, resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != headerSize+600*sampleRate*bytesPerFrame {
+		t.Fatalf("The response was cut off after %d bytes", n)
+	}
+}
@@ -110,9 +110,10 @@ func (stream *Stream) encodeM3U(out io.Writer) error {
 // PlayerTrack builds a library track for use in players.
 func (stream *Stream) PlayerTrack() library.Track {
 	return library.Track{
-		URI:    stream.URL,
-		Title:  stream.Title,
-		HasArt: stream.ArtURI != "",
+		URI:      stream.URL,
+		Title:    stream.Title,
+		HasArt:   stream.ArtURI != "",
+		IsStream: true,
 	}
 }
 
@@ -2,8 +2,11 @@ package library
 
 import (
 	"fmt"
+	"math"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -13,6 +16,10 @@ var (
 	interpFilename              = regexp.MustCompile(`^.*\/(.+)\.\w+$`)
 )
 
+// MultiValueSeparator separates the values of attributes that have more than
+// one value when they are returned by Track.Attr.
+const MultiValueSeparator = "; "
+
 // Track holds all information associated with a single piece of music.
 type Track struct {
 	URI         string        `json:"uri"`
@@ -25,32 +32,87 @@ type Track struct {
 	AlbumDisc   string        `json:"albumdisc,omitempty"`
 	Duration    time.Duration `json:"duration"`
 	HasArt      bool          `json:"hasart"`
+
+	// IsStream is set for tracks that are played from a network location
+	// rather than from a local file. Streams can generally not be seeked.
+	IsStream bool `json:"isstream"`
+
+	// Artists, Genres and AlbumArtists hold all values of their respective
+	// attributes if the backend reported more than one. The first value is
+	// equal to the scalar field.
+	Artists      []string `json:"artists,omitempty"`
+	Genres       []string `json:"genres,omitempty"`
+	AlbumArtists []string `json:"albumartists,omitempty"`
+
+	// The numeric position of the track on its album along with the total
+	// number of tracks and the same for the disc. These are zero if unknown.
+	TrackNumber int `json:"tracknumber,omitempty"`
+	TrackTotal  int `json:"tracktotal,omitempty"`
+	DiscNumber  int `json:"discnumber,omitempty"`
+	DiscTotal   int `json:"disctotal,omitempty"`
+
+	// The ReplayGain of the track in dB and its peak amplitude, where 1 is
+	// full scale. Nil if unknown. Some players only read these on request,
+	// see player.ReplayGainReader.
+	ReplayGain     *float64 `json:"replaygain,omitempty"`
+	ReplayGainPeak *float64 `json:"replaygainpeak,omitempty"`
+
+	// The rating of the track and the number of times it has been played.
+	// These are zero if the backend does not keep them.
+	Rating    int `json:"rating,omitempty"`
+	PlayCount int `json:"playcount,omitempty"`
+
+	// Source is the name of the library the track was found in. It is only
+	// set by libraries that combine other libraries.
+	Source string `json:"source,omitempty"`
+
+	// Tags holds arbitrary attributes of the track which have no dedicated
+	// field. Their availability depends on the backend.
+	Tags map[string]string `json:"tags,omitempty"`
 }
 
-// Attr gets an attribute of a track by its name. Accepted names are:
-//   "uri"
-//   "artist"
-//   "title"
-//   "genre"
-//   "album"
-//   "albumartist"
-//   "albumtrack"
-//   "albumdisc"
-//   "duration"
+// TrackAttributes returns the names of all attributes that are accepted by
+// Track.Attr, not including tags.
+func TrackAttributes() []string {
+	return []string{
+		"uri",
+		"artist",
+		"title",
+		"genre",
+		"album",
+		"albumartist",
+		"albumtrack",
+		"albumdisc",
+		"duration",
+		"hasart",
+		"rating",
+		"playcount",
+	}
+}
+
+// Attr gets an attribute of a track by its name. Accepted names are those
+// returned by TrackAttributes() and tags in the form of "tag:<name>".
+//
+// Durations are returned as an int64 in seconds, as are the rating and play
+// count, which are zero if the backend does not keep them. Tags are always
+// strings, a
+// tag that is not set yields an empty string. Nil is returned for unknown
+// attributes. All values of attributes with multiple values are joined by
+// MultiValueSeparator, starting with the primary value.
 func (track *Track) Attr(attr string) interface{} {
 	switch attr {
 	case "uri":
 		return track.URI
 	case "artist":
-		return track.Artist
+		return joinValues(track.Artist, track.Artists)
 	case "title":
 		return track.Title
 	case "genre":
-		return track.Genre
+		return joinValues(track.Genre, track.Genres)
 	case "album":
 		return track.Album
 	case "albumartist":
-		return track.AlbumArtist
+		return joinValues(track.AlbumArtist, track.AlbumArtists)
 	case "albumtrack":
 		return track.AlbumTrack
 	case "albumdisc":
@@ -59,14 +121,177 @@ func (track *Track) Attr(attr string) interface{} {
 		return int64(track.Duration / time.Second)
 	case "hasart":
 		return track.HasArt
+	case "rating":
+		return int64(track.Rating)
+	case "playcount":
+		return int64(track.PlayCount)
+	}
+	if strings.HasPrefix(attr, "tag:") {
+		return track.Tags[strings.TrimPrefix(attr, "tag:")]
 	}
 	return nil
 }
 
+// AttrValues returns the individual values of a string attribute. Only the
+// artist, genre and albumartist attributes may have more than one value. Nil
+// is returned for attributes that are not strings.
+func (track *Track) AttrValues(attr string) []string {
+	var values []string
+	switch attr {
+	case "artist":
+		values = track.Artists
+	case "genre":
+		values = track.Genres
+	case "albumartist":
+		values = track.AlbumArtists
+	}
+	if len(values) > 1 {
+		return values
+	}
+	if s, ok := track.Attr(attr).(string); ok {
+		return []string{s}
+	}
+	return nil
+}
+
+// SetArtists sets all artists of the track. The first is the primary artist.
+func (track *Track) SetArtists(values ...string) {
+	track.Artist, track.Artists = splitValues(values)
+}
+
+// SetGenres sets all genres of the track. The first is the primary genre.
+func (track *Track) SetGenres(values ...string) {
+	track.Genre, track.Genres = splitValues(values)
+}
+
+// SetAlbumArtists sets all album artists of the track. The first is the
+// primary album artist.
+func (track *Track) SetAlbumArtists(values ...string) {
+	track.AlbumArtist, track.AlbumArtists = splitValues(values)
+}
+
+func splitValues(values []string) (string, []string) {
+	switch len(values) {
+	case 0:
+		return "", nil
+	case 1:
+		return values[0], nil
+	}
+	return values[0], values
+}
+
+func joinValues(primary string, all []string) string {
+	if len(all) > 1 {
+		return strings.Join(all, MultiValueSeparator)
+	}
+	return primary
+}
+
+// SetAlbumTrack sets the position of the track on its album from a tag value
+// such as "3" or "3/12". AlbumTrack is normalized to the number alone.
+func (track *Track) SetAlbumTrack(value string) {
+	track.AlbumTrack, track.TrackNumber, track.TrackTotal = parseAlbumPosition(value)
+}
+
+// SetAlbumDisc sets the disc of the album the track is on from a tag value
+// such as "1" or "1/2". AlbumDisc is normalized to the number alone.
+func (track *Track) SetAlbumDisc(value string) {
+	track.AlbumDisc, track.DiscNumber, track.DiscTotal = parseAlbumPosition(value)
+}
+
+func parseAlbumPosition(value string) (string, int, int) {
+	number, total := ParseTrackNumber(value)
+	if number == 0 {
+		// Retain values that are not numbers, like vinyl sides, so they can
+		// still be displayed.
+		return strings.TrimSpace(value), 0, 0
+	}
+	return strconv.Itoa(number), number, total
+}
+
+// ParseTrackNumber parses a track or disc number as found in tags. Besides
+// plain numbers, the "<number>/<total>" notation is accepted. Zero is
+// returned for values that are missing or can not be parsed.
+func ParseTrackNumber(value string) (number, total int) {
+	numStr, totalStr := value, ""
+	if i := strings.IndexByte(value, '/'); i >= 0 {
+		numStr, totalStr = value[:i], value[i+1:]
+	}
+	number, err := strconv.Atoi(strings.TrimSpace(numStr))
+	if err != nil || number < 0 {
+		return 0, 0
+	}
+	if total, err = strconv.Atoi(strings.TrimSpace(totalStr)); err != nil || total < number {
+		total = 0
+	}
+	return number, total
+}
+
+// ParseReplayGain parses a ReplayGain gain or peak value as found in tags, such
+// as "-6.5 dB" or "0.988". False is returned if the value is missing or can
+// not be parsed.
+func ParseReplayGain(value string) (float64, bool) {
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && strings.EqualFold(value[len(value)-2:], "db") {
+		value = strings.TrimSpace(value[:len(value)-2])
+	}
+	// Some taggers write the decimal separator of their locale.
+	value = strings.Replace(value, ",", ".", 1)
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, false
+	}
+	return f, true
+}
+
+// SetReplayGain sets the ReplayGain of the track from tag values. Values that
+// can not be parsed leave the respective field unset.
+func (track *Track) SetReplayGain(gain, peak string) {
+	track.ReplayGain, track.ReplayGainPeak = nil, nil
+	if f, ok := ParseReplayGain(gain); ok {
+		track.ReplayGain = &f
+	}
+	if f, ok := ParseReplayGain(peak); ok && f >= 0 {
+		track.ReplayGainPeak = &f
+	}
+}
+
 func (track Track) String() string {
 	return fmt.Sprintf("%s - %s (%v)", track.Artist, track.Title, track.Duration)
 }
 
+// URL roots under which audio of a fixed length is served, see
+// RegisterFiniteURLRoot.
+var (
+	finiteURLRoots     []string
+	finiteURLRootsLock sync.RWMutex
+)
+
+// RegisterFiniteURLRoot marks the URIs below the URL root as audio of a fixed
+// length rather than streams, so IsStreamURI reports false for them. This is
+// meant for audio that is generated by Trollibox itself, like gaps of
+// silence.
+func RegisterFiniteURLRoot(urlRoot string) {
+	finiteURLRootsLock.Lock()
+	defer finiteURLRootsLock.Unlock()
+	finiteURLRoots = append(finiteURLRoots, strings.TrimSuffix(urlRoot, "/")+"/")
+}
+
+// IsStreamURI reports whether the URI refers to a network location.
+func IsStreamURI(uri string) bool {
+	if !strings.HasPrefix(uri, "http://") && !strings.HasPrefix(uri, "https://") {
+		return false
+	}
+	finiteURLRootsLock.RLock()
+	defer finiteURLRootsLock.RUnlock()
+	for _, root := range finiteURLRoots {
+		if strings.HasPrefix(uri, root) {
+			return false
+		}
+	}
+	return true
+}
+
 // InterpolateMissingFields extracts the artist and title from other track
 // information if they are unavailable and applies them to the specified track.
 //
@@ -60,3 +60,127 @@ func TestInterpolateMissingFields(t *testing.T) {
 		t.Fatalf("Unexpected artist and title: %q - %q", track.Artist, track.Title)
 	}
 }
+
+func TestTrackAttrTags(t *testing.T) {
+	track := Track{Tags: map[string]string{"mood": "happy"}}
+	if v := track.Attr("tag:mood"); v != "happy" {
+		t.Fatalf("Unexpected tag value: %#v", v)
+	}
+	if v := track.Attr("tag:energy"); v != "" {
+		t.Fatalf("Unset tags should yield an empty string, got %#v", v)
+	}
+}
+
+func TestTrackAttributes(t *testing.T) {
+	var track Track
+	for _, attr := range TrackAttributes() {
+		if track.Attr(attr) == nil {
+			t.Fatalf("Attribute %q is listed but not accepted by Attr", attr)
+		}
+	}
+	if track.Attr("nonexistent") != nil {
+		t.Fatalf("Unknown attributes should yield nil")
+	}
+}
+
+func TestParseTrackNumber(t *testing.T) {
+	tests := []struct {
+		value         string
+		number, total int
+	}{
+		{"", 0, 0},
+		{"3", 3, 0},
+		{"03", 3, 0},
+		{" 3 / 12 ", 3, 12},
+		{"3/12", 3, 12},
+		{"3/", 3, 0},
+		{"3/x", 3, 0},
+		{"12/3", 12, 0},
+		{"/12", 0, 0},
+		{"A1", 0, 0},
+		{"-1", 0, 0},
+	}
+	for _, test := range tests {
+		number, total := ParseTrackNumber(test.value)
+		if number != test.number || total != test.total {
+			t.Fatalf("Unexpected result for %q: %d/%d", test.value, number, total)
+		}
+	}
+}
+
+func TestParseReplayGain(t *testing.T) {
+	tests := []struct {
+		value string
+		gain  float64
+		ok    bool
+	}{
+		{"", 0, false},
+		{"-6.50 dB", -6.5, true},
+		{"+1.2dB", 1.2, true},
+		{" 0.988 ", 0.988, true},
+		{"-3,25 DB", -3.25, true},
+		{"dB", 0, false},
+		{"loud", 0, false},
+		{"NaN", 0, false},
+	}
+	for _, test := range tests {
+		gain, ok := ParseReplayGain(test.value)
+		if gain != test.gain || ok != test.ok {
+			t.Fatalf("Unexpected result for %q: %v, %v", test.value, gain, ok)
+		}
+	}
+
+	var track Track
+	track.SetReplayGain("-6.5 dB", "")
+	if track.ReplayGain == nil || *track.ReplayGain != -6.5 || track.ReplayGainPeak != nil {
+		t.Fatalf("Unexpected ReplayGain: %v, %v", track.ReplayGain, track.ReplayGainPeak)
+	}
+}
+
+func TestSetAlbumTrack(t *testing.T) {
+	var track Track
+	track.SetAlbumTrack("3/12")
+	track.SetAlbumDisc("1/2")
+	if track.AlbumTrack != "3" || track.TrackNumber != 3 || track.TrackTotal != 12 {
+		t.Fatalf("Unexpected track number: %q %d/%d", track.AlbumTrack, track.TrackNumber, track.TrackTotal)
+	}
+	if track.AlbumDisc != "1" || track.DiscNumber != 1 || track.DiscTotal != 2 {
+		t.Fatalf("Unexpected disc number: %q %d/%d", track.AlbumDisc, track.DiscNumber, track.DiscTotal)
+	}
+
+	track.SetAlbumTrack("B2")
+	if track.AlbumTrack != "B2" || track.TrackNumber != 0 || track.TrackTotal != 0 {
+		t.Fatalf("Unexpected track number: %q %d/%d", track.AlbumTrack, track.TrackNumber, track.TrackTotal)
+	}
+}
+
+func TestTrackMultiValue(t *testing.T) {
+	var track Track
+	track.SetArtists("A", "B")
+	track.SetGenres("Rock")
+	if track.Artist != "A" || track.Attr("artist") != "A; B" {
+		t.Fatalf("Unexpected artist: %q, %q", track.Artist, track.Attr("artist"))
+	}
+	if values := track.AttrValues("artist"); len(values) != 2 || values[1] != "B" {
+		t.Fatalf("Unexpected artist values: %q", values)
+	}
+	if track.Genre != "Rock" || track.Genres != nil || track.Attr("genre") != "Rock" {
+		t.Fatalf("Unexpected genre: %q, %q", track.Genre, track.Genres)
+	}
+	if values := track.AttrValues("duration"); values != nil {
+		t.Fatalf("Non-string attributes should have no values: %q", values)
+	}
+}
+
+func TestIsStreamURI(t *testing.T) {
+	for uri, expected := range map[string]bool{
+		"http://radio.example.com/stream":  true,
+		"https://radio.example.com/stream": true,
+		"mpd://music/song.mp3":             false,
+		"file:///music/http.mp3":           false,
+	} {
+		if IsStreamURI(uri) != expected {
+			t.Fatalf("Unexpected result for %q", uri)
+		}
+	}
+}
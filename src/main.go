@@ -2,14 +2,21 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"crypto/rand"
 	"flag"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"mime"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"path"
 	"sort"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/go-chi/chi"
@@ -21,19 +28,30 @@ import (
 	"github.com/polyfloyd/trollibox/src/assets"
 	"github.com/polyfloyd/trollibox/src/filter"
 	"github.com/polyfloyd/trollibox/src/filter/ruled"
+	"github.com/polyfloyd/trollibox/src/grpcapi"
 	"github.com/polyfloyd/trollibox/src/jukebox"
+	"github.com/polyfloyd/trollibox/src/library"
+	"github.com/polyfloyd/trollibox/src/library/fs"
 	"github.com/polyfloyd/trollibox/src/library/netmedia"
 	"github.com/polyfloyd/trollibox/src/library/raw"
+	"github.com/polyfloyd/trollibox/src/library/silence"
 	"github.com/polyfloyd/trollibox/src/library/stream"
+	"github.com/polyfloyd/trollibox/src/mqtt"
 	"github.com/polyfloyd/trollibox/src/player"
 	"github.com/polyfloyd/trollibox/src/player/mpd"
 	"github.com/polyfloyd/trollibox/src/player/slimserver"
+	"github.com/polyfloyd/trollibox/src/scrobble"
 	"github.com/polyfloyd/trollibox/src/util"
+	"github.com/polyfloyd/trollibox/src/webhook"
 )
 
 const (
 	publicDir = "public"
 	confFile  = "config.yaml"
+
+	// The maximum amount of time in-flight requests are given to complete
+	// when shutting down.
+	shutdownTimeout = time.Second * 10
 )
 
 var (
@@ -49,10 +67,31 @@ type config struct {
 	Address string `yaml:"bind"`
 	URLRoot string `yaml:"url_root"`
 
+	APITimeout time.Duration `yaml:"api_timeout"`
+
+	GRPCAddress string `yaml:"grpc_bind"`
+
 	StorageDir string `yaml:"storage_dir"`
 
 	AutoQueue     bool   `yaml:"autoqueue"`
 	DefaultPlayer string `yaml:"default_player"`
+	Kiosk         bool   `yaml:"kiosk"`
+
+	ArtPlaceholder string `yaml:"art_placeholder"`
+
+	AdminToken      string `yaml:"admin_token"`
+	MaxResults      int    `yaml:"max_results"`
+	NormalizeSearch bool   `yaml:"normalize_search"`
+	AuditLog        bool   `yaml:"audit_log"`
+
+	URIRewrite []struct {
+		Internal string `yaml:"internal"`
+		External string `yaml:"external"`
+	} `yaml:"uri_rewrite"`
+
+	TrendingHalfLife time.Duration `yaml:"trending_half_life"`
+
+	AggregateLibrary string `yaml:"aggregate_library"`
 
 	Colors struct {
 		Background     string `yaml:"background"`
@@ -63,12 +102,65 @@ type config struct {
 	} `yaml:"colors"`
 
 	MPD []struct {
-		Name     string  `yaml:"name"`
-		Network  string  `yaml:"network"`
-		Address  string  `yaml:"address"`
-		Password *string `yaml:"password"`
+		Name          string   `yaml:"name"`
+		Network       string   `yaml:"network"`
+		Address       string   `yaml:"address"`
+		Password      *string  `yaml:"password"`
+		Partition     string   `yaml:"partition"`
+		LibraryRoot   string   `yaml:"library_root"`
+		DefaultVolume int      `yaml:"default_volume"`
+		RandomInsert  bool     `yaml:"random_insert"`
+		ServerSearch  bool     `yaml:"server_search"`
+		StickerTags   []string `yaml:"sticker_tags"`
+		// The subsystems of MPD to watch, nil for the defaults.
+		Subsystems []string `yaml:"subsystems"`
+
+		// The maximum duration of tracks selected by the autoqueuer.
+		AutoQueueMaxDuration time.Duration `yaml:"autoqueue_max_duration"`
+
+		LibraryDebounce time.Duration `yaml:"library_debounce"`
+
+		// The interval at which the title of a playing stream is
+		// refreshed, nil for the default.
+		StreamRefresh *time.Duration `yaml:"stream_refresh"`
+		// How often a stream that stops because of an error is played
+		// again. Zero attempts disables reconnecting.
+		StreamReconnect struct {
+			Attempts int           `yaml:"attempts"`
+			Delay    time.Duration `yaml:"delay"`
+		} `yaml:"stream_reconnect"`
+
+		PlayCountThreshold *struct {
+			Ratio float64       `yaml:"ratio"`
+			Max   time.Duration `yaml:"max"`
+		} `yaml:"play_count_threshold"`
+
+		// Rules to fill in missing metadata of tracks.
+		Fallback []struct {
+			Target  string   `yaml:"target"`
+			Sources []string `yaml:"sources"`
+			Replace bool     `yaml:"replace"`
+		} `yaml:"fallback"`
+
+		Jingle *struct {
+			URI      string `yaml:"uri"`
+			Interval int    `yaml:"interval"`
+		} `yaml:"jingle"`
+
+		// A second MPD server that takes over while the first is
+		// unavailable.
+		Failover *struct {
+			Network  string  `yaml:"network"`
+			Address  string  `yaml:"address"`
+			Password *string `yaml:"password"`
+		} `yaml:"failover"`
 	} `yaml:"mpd"`
 
+	Filesystem []struct {
+		Name string `yaml:"name"`
+		Path string `yaml:"path"`
+	} `yaml:"filesystem"`
+
 	SlimServer *struct {
 		Network  string  `yaml:"network"`
 		Address  string  `yaml:"address"`
@@ -76,6 +168,29 @@ type config struct {
 		Password *string `yaml:"password"`
 		WebURL   string  `yaml:"weburl"`
 	} `yaml:"slimserver"`
+
+	ListenBrainz []struct {
+		Player string `yaml:"player"`
+		Token  string `yaml:"token"`
+		URL    string `yaml:"url"`
+	} `yaml:"listenbrainz"`
+
+	MQTT []struct {
+		Player   string `yaml:"player"`
+		Broker   string `yaml:"broker"`
+		ClientID string `yaml:"client_id"`
+		Username string `yaml:"username"`
+		Password string `yaml:"password"`
+		Topic    string `yaml:"topic"`
+		Commands bool   `yaml:"commands"`
+	} `yaml:"mqtt"`
+
+	Webhooks []struct {
+		URL    string   `yaml:"url"`
+		Events []string `yaml:"events"`
+		Player string   `yaml:"player"`
+		Secret string   `yaml:"secret"`
+	} `yaml:"webhooks"`
 }
 
 func (conf *config) Validate() (errs []error) {
@@ -85,6 +200,11 @@ func (conf *config) Validate() (errs []error) {
 	if len(conf.MPD) == 0 && conf.SlimServer == nil {
 		errs = append(errs, fmt.Errorf("config: no media servers configured"))
 	}
+	for _, mpdConf := range conf.MPD {
+		if mpdConf.DefaultVolume < 0 || mpdConf.DefaultVolume > 100 {
+			errs = append(errs, fmt.Errorf("config: `default_volume` of %q must be between 1 and 100", mpdConf.Name))
+		}
+	}
 	return
 }
 
@@ -188,12 +308,6 @@ func main() {
 		}
 	}
 
-	if config.AutoQueue {
-		// TODO: Currently, only players which are active at startup attached
-		// to a queuer.
-		attachAutoQueuer(players, filterdb)
-	}
-
 	fullURLRoot, err := util.DetermineFullURLRoot(config.URLRoot, config.Address)
 	if err != nil {
 		log.Fatal(err)
@@ -204,10 +318,74 @@ func main() {
 		log.Fatal(err)
 	}
 
+	var auditLog *jukebox.AuditLog
+	if config.AuditLog {
+		if auditLog, err = jukebox.NewAuditLog(path.Join(storeDir, "audit")); err != nil {
+			log.Fatalf("Unable to create audit log: %v", err)
+		}
+	}
+
+	settingsStore, err := jukebox.NewSettingsStore(path.Join(storeDir, "settings"))
+	if err != nil {
+		log.Fatalf("Unable to create settings store: %v", err)
+	}
+
 	jukebox := jukebox.NewJukebox(players, netServer, filterdb, streamdb, rawServer)
+	if err := configureJukebox(jukebox, config); err != nil {
+		log.Fatal(err)
+	}
+	queueStore, err := player.NewQueueStore(path.Join(storeDir, "savedqueues"))
+	if err != nil {
+		log.Fatalf("Unable to create saved queue store: %v", err)
+	}
+	jukebox.SetQueueStore(queueStore)
+	playHistory, err := player.NewPlayHistory(path.Join(storeDir, "playhistory"))
+	if err != nil {
+		log.Fatalf("Unable to create play history: %v", err)
+	}
+	jukebox.SetPlayHistory(playHistory, config.TrendingHalfLife)
+	jukebox.SetNormalizedSearch(config.NormalizeSearch)
+	jukebox.SetAuditLog(auditLog)
+	jukebox.SetSettingsStore(settingsStore)
+	jukebox.SetSilenceServer(silence.NewServer(fmt.Sprintf("%sdata/silence", fullURLRoot)))
+	if err := jukebox.PrepareRestore(); err != nil {
+		log.Fatalf("Unable to read the queues saved on shutdown: %v", err)
+	}
+	if names, err := players.PlayerNames(); err != nil {
+		log.Fatal(err)
+	} else {
+		for _, name := range names {
+			if err := jukebox.AttachPlayHistory(name); err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+	if config.AutoQueue {
+		// TODO: Currently, only players which are active at startup attached
+		// to a queuer.
+		attachAutoQueuer(players, filterdb, jukebox, config)
+	}
+	if err := addLibraries(jukebox, config, players); err != nil {
+		log.Fatal(err)
+	}
+	if err := attachScrobblers(jukebox, config, storeDir); err != nil {
+		log.Fatal(err)
+	}
+	if err := watchNowPlaying(jukebox, config, players); err != nil {
+		log.Fatal(err)
+	}
+	publishers, err := connectMQTT(jukebox, config, players)
+	if err != nil {
+		log.Fatal(err)
+	}
+	webhooks, err := attachWebhooks(config, players)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	service := chi.NewRouter()
 	service.Use(util.LogHandler)
+	service.Use(util.ControlHandler)
 	service.Use(middleware.DefaultCompress)
 	for _, file := range assets.AssetNames() {
 		if !strings.HasPrefix(file, publicDir) {
@@ -220,27 +398,307 @@ func main() {
 
 	service.Get("/", htRedirectToDefaultPlayer(config, players))
 	service.Get("/player/{player}", htBrowserPage(config, players))
+	var apiHandle *api.API
 	service.Route("/data", func(r chi.Router) {
-		api.InitRouter(r, jukebox)
+		apiHandle = api.InitRouter(r, jukebox, config.APITimeout)
 	})
+	clientKey, err := loadClientKey(path.Join(storeDir, "client-key"))
+	if err != nil {
+		log.Fatalf("Unable to load the client key: %v", err)
+	}
+	apiHandle.SetAdminToken(config.AdminToken)
+	apiHandle.SetClientKey(clientKey)
+	apiHandle.SetMaxResults(config.MaxResults)
+	var kioskAPIHandle *api.API
+	if config.Kiosk {
+		service.Route("/kiosk/data", func(r chi.Router) {
+			kioskAPIHandle = api.InitReadOnlyRouter(r, jukebox, config.APITimeout)
+		})
+		kioskAPIHandle.SetClientKey(clientKey)
+		kioskAPIHandle.SetMaxResults(config.MaxResults)
+	}
+	if len(config.URIRewrite) > 0 {
+		rewriter := make(api.PrefixRewriter, len(config.URIRewrite))
+		for i, rule := range config.URIRewrite {
+			if rule.Internal == "" || rule.External == "" {
+				log.Fatalf("URI rewrite rule %d must have both an internal and an external prefix", i+1)
+			}
+			rewriter[i] = api.PrefixRule{Internal: rule.Internal, External: rule.External}
+		}
+		for _, handle := range []*api.API{apiHandle, kioskAPIHandle} {
+			if handle != nil {
+				handle.SetURIRewriter(rewriter)
+			}
+		}
+	}
+	if config.ArtPlaceholder != "" {
+		data, err := ioutil.ReadFile(config.ArtPlaceholder)
+		if err != nil {
+			log.Fatalf("Unable to read the art placeholder: %v", err)
+		}
+		for _, handle := range []*api.API{apiHandle, kioskAPIHandle} {
+			if handle == nil {
+				continue
+			}
+			if err := handle.SetArtPlaceholder(data); err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+
+	// Serve everything below the path of the URL root so Trollibox can be
+	// hosted behind a reverse proxy at a subpath.
+	var handler http.Handler = service
+	if basePath := util.URLRootPath(config.URLRoot); basePath != "/" {
+		root := chi.NewRouter()
+		root.Mount(strings.TrimSuffix(basePath, "/"), service)
+		handler = root
+	}
 
 	log.Infof("Now accepting HTTP connections on %v", config.Address)
 	server := &http.Server{
 		Addr:           config.Address,
-		Handler:        service,
+		Handler:        handler,
 		ReadTimeout:    10 * time.Second,
 		WriteTimeout:   10 * time.Second,
 		MaxHeaderBytes: 1 << 20,
 	}
-	log.Fatalf("Error running webserver: %v", server.ListenAndServe())
+	// Event streams never become idle, they are ended when the server shuts
+	// down so it does not have to wait for them.
+	server.RegisterOnShutdown(func() {
+		apiHandle.Close()
+		if kioskAPIHandle != nil {
+			kioskAPIHandle.Close()
+		}
+	})
+	go func() {
+		if err := server.ListenAndServe(); err != http.ErrServerClosed {
+			log.Fatalf("Error running webserver: %v", err)
+		}
+	}()
+
+	var grpcServer *grpcapi.Server
+	if config.GRPCAddress != "" {
+		lis, err := net.Listen("tcp", config.GRPCAddress)
+		if err != nil {
+			log.Fatal(err)
+		}
+		timeout := config.APITimeout
+		if timeout == 0 {
+			timeout = api.DefaultTimeout
+		}
+		grpcServer = grpcapi.NewServer(jukebox, timeout)
+		log.Infof("Now accepting gRPC connections on %v", config.GRPCAddress)
+		go func() {
+			if err := grpcServer.Serve(lis); err != nil {
+				log.Fatalf("Error running gRPC server: %v", err)
+			}
+		}()
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-signals
+	log.Infof("Received %v, shutting down", sig)
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Errorf("Error shutting down webserver: %v", err)
+	}
+	if grpcServer != nil {
+		grpcServer.Close()
+	}
+	for _, pub := range publishers {
+		pub.Close()
+	}
+	webhooks.Close()
+	if err := jukebox.Shutdown(ctx); err != nil {
+		log.Errorf("Error saving queues: %v", err)
+	}
+	closePlayers(players)
+}
+
+// closePlayers closes all players that hold resources that need releasing.
+// loadClientKey reads the key with which the API signs the IDs of clients from
+// the file, creating it if it does not exist yet.
+func loadClientKey(file string) ([]byte, error) {
+	key, err := ioutil.ReadFile(file)
+	if err == nil && len(key) > 0 {
+		return key, nil
+	} else if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	key = make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(file, key, 0600); err != nil {
+		return nil, err
+	}
+	return key, nil
 }
 
-func attachAutoQueuer(players player.List, filterdb *filter.DB) {
+func closePlayers(players player.List) {
+	names, err := players.PlayerNames()
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	for _, name := range names {
+		pl, err := players.PlayerByName(name)
+		if err != nil {
+			continue
+		}
+		if closer, ok := pl.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				log.WithField("player", name).Errorf("Error closing player: %v", err)
+			}
+		}
+	}
+}
+
+func configureJukebox(jb *jukebox.Jukebox, config *config) error {
+	for _, mpdConf := range config.MPD {
+		if mpdConf.RandomInsert {
+			jb.SetInsertMode(mpdConf.Name, jukebox.InsertRandom)
+		}
+		jb.SetServerSearch(mpdConf.Name, mpdConf.ServerSearch)
+		if mpdConf.Jingle != nil {
+			if err := jb.AttachJingle(mpdConf.Name, mpdConf.Jingle.URI, mpdConf.Jingle.Interval); err != nil {
+				return fmt.Errorf("unable to attach jingle: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+func addLibraries(jb *jukebox.Jukebox, config *config, players player.List) error {
+	var aggregate *library.Aggregate
+	if config.AggregateLibrary != "" {
+		aggregate = library.NewAggregate()
+		names, err := players.PlayerNames()
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			pl, err := players.PlayerByName(name)
+			if err != nil {
+				return err
+			}
+			aggregate.Add(name, pl.Library())
+		}
+	}
+
+	for _, fsConf := range config.Filesystem {
+		lib, err := fs.NewLibrary(strings.Replace(fsConf.Path, "~", os.Getenv("HOME"), 1))
+		if err != nil {
+			return fmt.Errorf("unable to load filesystem library: %v", err)
+		}
+		if err := jb.AddLibrary(fsConf.Name, lib); err != nil {
+			return err
+		}
+		if aggregate != nil {
+			aggregate.Add(fsConf.Name, lib)
+		}
+	}
+
+	if aggregate != nil {
+		return jb.AddLibrary(config.AggregateLibrary, aggregate)
+	}
+	return nil
+}
+
+func attachScrobblers(jb *jukebox.Jukebox, config *config, storeDir string) error {
+	for i, lbConf := range config.ListenBrainz {
+		lb := &scrobble.ListenBrainz{URL: lbConf.URL, Token: lbConf.Token}
+		file := path.Join(storeDir, fmt.Sprintf("scrobble-listenbrainz-%s-%d.json", lbConf.Player, i))
+		queue, err := scrobble.NewQueue(lb, file)
+		if err != nil {
+			return err
+		}
+		if err := jb.AttachScrobbler(lbConf.Player, queue); err != nil {
+			return fmt.Errorf("unable to attach ListenBrainz scrobbler: %v", err)
+		}
+	}
+	return nil
+}
+
+func watchNowPlaying(jb *jukebox.Jukebox, config *config, players player.List) error {
+	names, err := players.PlayerNames()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err := jb.WatchNowPlaying(name); err != nil {
+			return err
+		}
+	}
+	jb.SetDefaultNowPlaying(config.DefaultPlayer)
+	return nil
+}
+
+func connectMQTT(jb *jukebox.Jukebox, config *config, players player.List) ([]*mqtt.Publisher, error) {
+	var publishers []*mqtt.Publisher
+	for _, mqttConf := range config.MQTT {
+		pl, err := players.PlayerByName(mqttConf.Player)
+		if err != nil {
+			return nil, fmt.Errorf("unable to publish to MQTT: %v", err)
+		}
+		pub, err := mqtt.Connect(jb, mqttConf.Player, pl.Events(), mqtt.Config{
+			Broker:   mqttConf.Broker,
+			ClientID: mqttConf.ClientID,
+			Username: mqttConf.Username,
+			Password: mqttConf.Password,
+			Topic:    mqttConf.Topic,
+			Commands: mqttConf.Commands,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to publish to MQTT: %v", err)
+		}
+		publishers = append(publishers, pub)
+	}
+	return publishers, nil
+}
+
+func attachWebhooks(config *config, players player.List) (*webhook.Dispatcher, error) {
+	hooks := make([]webhook.Webhook, len(config.Webhooks))
+	for i, hookConf := range config.Webhooks {
+		if hookConf.URL == "" {
+			return nil, fmt.Errorf("config: webhook %d has no `url`", i)
+		}
+		hooks[i] = webhook.Webhook{
+			URL:    hookConf.URL,
+			Events: hookConf.Events,
+			Player: hookConf.Player,
+			Secret: hookConf.Secret,
+		}
+	}
+	disp := webhook.NewDispatcher(hooks)
+	names, err := players.PlayerNames()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		pl, err := players.PlayerByName(name)
+		if err != nil {
+			return nil, err
+		}
+		disp.Attach(name, pl.Events())
+	}
+	return disp, nil
+}
+
+func attachAutoQueuer(players player.List, filterdb *filter.DB, jb *jukebox.Jukebox, config *config) {
 	names, err := players.PlayerNames()
 	if err != nil {
 		log.Errorf("error attaching autoqueuer: %v", err)
 		return
 	}
+	maxDurations := map[string]time.Duration{}
+	for _, mpdConf := range config.MPD {
+		maxDurations[mpdConf.Name] = mpdConf.AutoQueueMaxDuration
+	}
 	for _, name := range names {
 		pl, err := players.PlayerByName(name)
 		if err != nil {
@@ -259,8 +717,20 @@ func attachAutoQueuer(players player.List, filterdb *filter.DB) {
 						log.WithField("player", name).Errorf("Error while autoqueueing: %v", err)
 					}
 				}
+				// Manually queued tracks are not subject to the maximum
+				// duration, so it is only applied here.
+				if max := maxDurations[name]; max > 0 {
+					ft = filter.All(ft, filter.MaxDuration(max))
+				}
 				cancel := make(chan struct{})
-				com := player.AutoAppend(pl, filter.RandomIterator(ft), cancel)
+				iter := filter.RandomIteratorExcluding(ft, func() []string {
+					uris, err := jb.NoRepeatURIs(name)
+					if err != nil {
+						log.WithField("player", name).Errorf("Error determining recently played tracks: %v", err)
+					}
+					return uris
+				})
+				com := player.AutoAppend(pl, iter, cancel)
 				select {
 				case err := <-com:
 					if err != nil {
@@ -277,13 +747,75 @@ func attachAutoQueuer(players player.List, filterdb *filter.DB) {
 func connectToPlayers(config *config) (player.List, error) {
 	mpdPlayers := player.SimpleList{}
 	for _, mpdConf := range config.MPD {
-		mpdPlayer, err := mpd.Connect(mpdConf.Network, mpdConf.Address, mpdConf.Password)
-		if err != nil {
-			return nil, fmt.Errorf("unable to connect to MPD: %v", err)
-		}
 		if _, ok := mpdPlayers[mpdConf.Name]; ok {
 			return nil, fmt.Errorf("duplicate player name: %q", mpdConf.Name)
 		}
+		connect := func(network, address string, password *string) (*mpd.Player, error) {
+			mpdPlayer, err := mpd.Connect(network, address, password, mpd.Options{
+				Partition:   mpdConf.Partition,
+				LibraryRoot: mpdConf.LibraryRoot,
+				StickerTags: mpdConf.StickerTags,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("unable to connect to MPD: %v", err)
+			}
+			if t := mpdConf.PlayCountThreshold; t != nil {
+				mpdPlayer.SetPlayCountThreshold(t.Ratio, t.Max)
+			}
+			mpdPlayer.SetLibraryDebounce(mpdConf.LibraryDebounce)
+			if mpdConf.StreamRefresh != nil {
+				mpdPlayer.SetStreamRefresh(*mpdConf.StreamRefresh)
+			}
+			if r := mpdConf.StreamReconnect; r.Attempts > 0 {
+				delay := r.Delay
+				if delay == 0 {
+					delay = mpd.DefaultStreamReconnectDelay
+				}
+				mpdPlayer.SetStreamReconnect(r.Attempts, delay)
+			}
+			if mpdConf.Subsystems != nil {
+				subsystems := make([]mpd.Event, len(mpdConf.Subsystems))
+				for i, name := range mpdConf.Subsystems {
+					subsystems[i] = mpd.Event(name)
+				}
+				if err := mpdPlayer.SetSubsystems(subsystems); err != nil {
+					mpdPlayer.Close()
+					return nil, fmt.Errorf("invalid subsystems for %q: %v", mpdConf.Name, err)
+				}
+			}
+			if mpdConf.Fallback != nil {
+				rules := make([]library.FallbackRule, len(mpdConf.Fallback))
+				for i, rule := range mpdConf.Fallback {
+					rules[i] = library.FallbackRule{Target: rule.Target, Sources: rule.Sources, Replace: rule.Replace}
+				}
+				if err := mpdPlayer.SetFallbackRules(rules); err != nil {
+					mpdPlayer.Close()
+					return nil, fmt.Errorf("invalid fallback rules for %q: %v", mpdConf.Name, err)
+				}
+			}
+			if mpdConf.DefaultVolume > 0 {
+				if err := mpdPlayer.SetDefaultVolume(mpdConf.DefaultVolume); err != nil {
+					mpdPlayer.Close()
+					return nil, fmt.Errorf("unable to set the default volume of %q: %v", mpdConf.Name, err)
+				}
+			}
+			return mpdPlayer, nil
+		}
+
+		mpdPlayer, err := connect(mpdConf.Network, mpdConf.Address, mpdConf.Password)
+		if err != nil {
+			return nil, err
+		}
+		if fc := mpdConf.Failover; fc != nil {
+			// The backup is optional, so being unable to reach it does not
+			// prevent the primary from being used.
+			backup, err := connect(fc.Network, fc.Address, fc.Password)
+			if err == nil {
+				mpdPlayers.Set(mpdConf.Name, player.NewFailover(mpdPlayer, backup))
+				continue
+			}
+			log.WithField("player", mpdConf.Name).Warnf("Unable to connect to the failover, continuing without it: %v", err)
+		}
 		mpdPlayers.Set(mpdConf.Name, mpdPlayer)
 	}
 
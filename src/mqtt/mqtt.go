@@ -0,0 +1,253 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/polyfloyd/trollibox/src/jukebox"
+	"github.com/polyfloyd/trollibox/src/player"
+	"github.com/polyfloyd/trollibox/src/util"
+)
+
+const (
+	// The interval at which connecting to the broker is retried.
+	reconnectInterval = time.Second * 10
+	// The maximum amount of time querying the player or broker may take.
+	timeout = time.Second * 8
+)
+
+// The client under which commands are executed. Anyone who can publish to the
+// broker can send commands, so they are guests.
+var mqttClient = jukebox.Client{ID: "mqtt", PublicID: "mqtt"}
+
+// Config holds the settings for publishing the state of a single player.
+type Config struct {
+	// The URL of the broker, e.g. tcp://127.0.0.1:1883.
+	Broker   string
+	ClientID string
+	Username string
+	Password string
+	// The topic below which all state is published.
+	Topic string
+	// Whether to accept commands published to the "set" subtopics.
+	Commands bool
+}
+
+// A Publisher publishes the state of a player to an MQTT broker so it can be
+// picked up by home automation systems.
+//
+// All state is published as retained messages below the configured topic:
+//
+//	<topic>/available  "online" or "offline"
+//	<topic>/state      "playing", "paused" or "stopped"
+//	<topic>/volume     the volume in the range 0-100
+//	<topic>/track      the current track as JSON, empty if there is none
+//
+// If commands are enabled, the player can be controlled by publishing to
+// <topic>/set/state, <topic>/set/volume and <topic>/set/next.
+type Publisher struct {
+	jukebox    *jukebox.Jukebox
+	playerName string
+	conf       Config
+	client     paho.Client
+	closed     chan struct{}
+	closeOnce  sync.Once
+}
+
+// Connect starts publishing the state of the named player. The events of the
+// player are read from the specified emitter.
+//
+// Connecting to the broker happens in the background and is retried until it
+// succeeds, so an unreachable broker does not affect the player.
+func Connect(jb *jukebox.Jukebox, playerName string, events *util.Emitter, conf Config) (*Publisher, error) {
+	if conf.Broker == "" {
+		return nil, fmt.Errorf("no MQTT broker configured")
+	}
+	if conf.Topic == "" {
+		conf.Topic = "trollibox/" + playerName
+	}
+	conf.Topic = strings.TrimSuffix(conf.Topic, "/")
+
+	pub := &Publisher{
+		jukebox:    jb,
+		playerName: playerName,
+		conf:       conf,
+		closed:     make(chan struct{}),
+	}
+	opts := paho.NewClientOptions().
+		AddBroker(conf.Broker).
+		SetClientID(conf.ClientID).
+		SetUsername(conf.Username).
+		SetPassword(conf.Password).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(reconnectInterval).
+		SetMaxReconnectInterval(reconnectInterval).
+		SetWill(conf.Topic+"/available", "offline", 1, true).
+		SetOnConnectHandler(pub.onConnect).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			log.WithField("player", playerName).Warnf("Lost connection to MQTT broker: %v", err)
+		})
+	pub.client = paho.NewClient(opts)
+	pub.client.Connect()
+
+	go pub.run(events.Subscribe(pub.closed))
+	return pub, nil
+}
+
+// Close disconnects from the broker. It is safe to call Close more than once.
+func (pub *Publisher) Close() error {
+	pub.closeOnce.Do(func() {
+		close(pub.closed)
+		pub.publish("available", "offline")
+		pub.client.Disconnect(250)
+	})
+	return nil
+}
+
+func (pub *Publisher) run(listener *util.Listener) {
+	for event := range listener.C {
+		switch t := event.(type) {
+		case player.PlayStateEvent:
+			pub.publish("state", string(t.State))
+		case player.VolumeEvent:
+			pub.publish("volume", strconv.Itoa(t.Volume))
+		case player.PlaylistEvent:
+			pub.publishTrack()
+		case player.AvailabilityEvent:
+			pub.publishAll()
+		}
+	}
+}
+
+// onConnect is called by the client each time a connection to the broker is
+// made.
+func (pub *Publisher) onConnect(client paho.Client) {
+	log.WithField("player", pub.playerName).Infof("Connected to MQTT broker %s", pub.conf.Broker)
+	// Callbacks of the client may not block on operations of the client.
+	go func() {
+		if pub.conf.Commands {
+			token := client.Subscribe(pub.conf.Topic+"/set/+", 1, pub.onCommand)
+			if token.WaitTimeout(timeout) && token.Error() != nil {
+				log.WithField("player", pub.playerName).Errorf("Could not subscribe to MQTT commands: %v", token.Error())
+			}
+		}
+		pub.publishAll()
+	}()
+}
+
+func (pub *Publisher) onCommand(_ paho.Client, msg paho.Message) {
+	name := strings.TrimPrefix(msg.Topic(), pub.conf.Topic+"/set/")
+	if err := pub.command(name, string(msg.Payload())); err != nil {
+		log.WithField("player", pub.playerName).Errorf("Error executing MQTT command %q: %v", name, err)
+	}
+}
+
+func (pub *Publisher) command(name, payload string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	ctx = jukebox.WithClient(ctx, mqttClient)
+	switch name {
+	case "state":
+		state, err := parseState(payload)
+		if err != nil {
+			return err
+		}
+		return pub.jukebox.SetPlayerState(ctx, pub.playerName, state)
+	case "volume":
+		vol, err := parseVolume(payload)
+		if err != nil {
+			return err
+		}
+		return pub.jukebox.SetPlayerVolume(ctx, pub.playerName, vol)
+	case "next":
+		return pub.jukebox.SetPlayerTrackIndex(ctx, pub.playerName, 1, true)
+	}
+	return fmt.Errorf("unknown command")
+}
+
+// publishAll publishes the complete state of the player.
+func (pub *Publisher) publishAll() {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	state, err := pub.jukebox.PlayerState(ctx, pub.playerName)
+	if err == jukebox.ErrPlayerUnavailable {
+		pub.publish("available", "offline")
+		return
+	} else if err != nil {
+		log.WithField("player", pub.playerName).Errorf("Error publishing to MQTT: %v", err)
+		return
+	}
+	vol, err := pub.jukebox.PlayerVolume(ctx, pub.playerName)
+	if err != nil {
+		log.WithField("player", pub.playerName).Errorf("Error publishing to MQTT: %v", err)
+		return
+	}
+	pub.publish("available", "online")
+	pub.publish("state", string(state))
+	pub.publish("volume", strconv.Itoa(vol))
+	pub.publishTrack()
+}
+
+func (pub *Publisher) publishTrack() {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	track, err := pub.jukebox.PlayerCurrentTrack(ctx, pub.playerName)
+	if err != nil {
+		log.WithField("player", pub.playerName).Errorf("Error publishing to MQTT: %v", err)
+		return
+	}
+	payload := ""
+	if track != nil {
+		data, err := json.Marshal(track)
+		if err != nil {
+			log.WithField("player", pub.playerName).Errorf("Error publishing to MQTT: %v", err)
+			return
+		}
+		payload = string(data)
+	}
+	pub.publish("track", payload)
+}
+
+// publish sends a retained message to the subtopic. Messages are dropped
+// while there is no connection, the complete state is published again once
+// the connection is restored.
+func (pub *Publisher) publish(subtopic, payload string) {
+	if !pub.client.IsConnectionOpen() {
+		return
+	}
+	token := pub.client.Publish(pub.conf.Topic+"/"+subtopic, 1, true, payload)
+	if token.WaitTimeout(timeout) && token.Error() != nil {
+		log.WithField("player", pub.playerName).Errorf("Error publishing to MQTT: %v", token.Error())
+	}
+}
+
+func parseState(payload string) (player.PlayState, error) {
+	switch state := player.PlayState(strings.TrimSpace(payload)); state {
+	case player.PlayStatePlaying, player.PlayStatePaused, player.PlayStateStopped:
+		return state, nil
+	}
+	return player.PlayStateInvalid, fmt.Errorf("invalid play state %q", payload)
+}
+
+// parseVolume parses a volume, which is clamped to the range 0-100.
+func parseVolume(payload string) (int, error) {
+	vol, err := strconv.Atoi(strings.TrimSpace(payload))
+	if err != nil {
+		return 0, fmt.Errorf("invalid volume %q", payload)
+	}
+	if vol < 0 {
+		vol = 0
+	} else if vol > 100 {
+		vol = 100
+	}
+	return vol, nil
+}
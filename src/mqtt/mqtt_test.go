@@ -0,0 +1,40 @@
+package mqtt
+
+import (
+	"testing"
+
+	"github.com/polyfloyd/trollibox/src/player"
+)
+
+func TestParseState(t *testing.T) {
+	if state, err := parseState("paused\n"); err != nil || state != player.PlayStatePaused {
+		t.Fatalf("Unexpected result: %q, %v", state, err)
+	}
+	if _, err := parseState("rewinding"); err == nil {
+		t.Fatal("Expected an error for an invalid state")
+	}
+}
+
+func TestParseVolume(t *testing.T) {
+	cases := []struct {
+		payload string
+		volume  int
+	}{
+		{"42", 42},
+		{" 0 ", 0},
+		{"-3", 0},
+		{"250", 100},
+	}
+	for _, c := range cases {
+		vol, err := parseVolume(c.payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if vol != c.volume {
+			t.Fatalf("Unexpected volume for %q: %d != %d", c.payload, vol, c.volume)
+		}
+	}
+	if _, err := parseVolume("loud"); err == nil {
+		t.Fatal("Expected an error for an invalid volume")
+	}
+}
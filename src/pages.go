@@ -11,6 +11,7 @@ import (
 	"github.com/polyfloyd/trollibox/src/api"
 	"github.com/polyfloyd/trollibox/src/assets"
 	"github.com/polyfloyd/trollibox/src/player"
+	"github.com/polyfloyd/trollibox/src/util"
 )
 
 var pageTemplate = mkTemplate()
@@ -49,6 +50,6 @@ func htRedirectToDefaultPlayer(config *config, players player.List) func(w http.
 			api.WriteError(w, r, fmt.Errorf("error finding a player to redirect to: %v", err))
 			return
 		}
-		http.Redirect(w, r, "/player/"+defaultPlayer, http.StatusTemporaryRedirect)
+		http.Redirect(w, r, util.URLRootPath(config.URLRoot)+"player/"+defaultPlayer, http.StatusTemporaryRedirect)
 	}
 }
@@ -0,0 +1,60 @@
+package player
+
+// The names of the capabilities reported by Capabilities.
+const (
+	// The player implements StatsReporter.
+	CapabilityStats = "stats"
+	// The player implements ReplayGainReader.
+	CapabilityReplayGain = "replaygain"
+	// The player implements ServerSearcher.
+	CapabilityServerSearch = "serversearch"
+	// The player implements URISupporter.
+	CapabilitySupportCheck = "supportcheck"
+	// The player implements PlayCounter.
+	CapabilityPlayCounts = "playcounts"
+	// The player implements SkipCounter.
+	CapabilitySkipCounts = "skipcounts"
+	// The player implements PoolReporter.
+	CapabilityConnections = "connections"
+	// The player implements QueueSaver.
+	CapabilityServerQueues = "serverqueues"
+	// The player implements Prioritizer.
+	CapabilityPriority = "priority"
+	// The playlist of the player implements SelectiveRemover.
+	CapabilitySelectiveRemove = "selectiveremove"
+	// The playlist of the player implements RangeMover.
+	CapabilityRangeMove = "rangemove"
+)
+
+// A CapabilityReporter is a player that reports its capabilities itself,
+// because they are not reflected by the interfaces it implements. Players that
+// wrap other players, like Failover, implement every optional interface and
+// delegate to the wrapped players.
+type CapabilityReporter interface {
+	Capabilities() map[string]bool
+}
+
+// Capabilities reports which of the optional interfaces of this package are
+// implemented by the player, so clients can adapt to it. All capabilities are
+// present in the result.
+//
+// Players that implement CapabilityReporter report their own capabilities.
+func Capabilities(pl Player) map[string]bool {
+	if reporter, ok := pl.(CapabilityReporter); ok {
+		return reporter.Capabilities()
+	}
+	caps := map[string]bool{}
+	_, caps[CapabilityStats] = pl.(StatsReporter)
+	_, caps[CapabilityReplayGain] = pl.(ReplayGainReader)
+	_, caps[CapabilityServerSearch] = pl.(ServerSearcher)
+	_, caps[CapabilitySupportCheck] = pl.(URISupporter)
+	_, caps[CapabilityPlayCounts] = pl.(PlayCounter)
+	_, caps[CapabilitySkipCounts] = pl.(SkipCounter)
+	_, caps[CapabilityConnections] = pl.(PoolReporter)
+	_, caps[CapabilityServerQueues] = pl.(QueueSaver)
+	_, caps[CapabilityPriority] = pl.(Prioritizer)
+	plist := pl.Playlist()
+	_, caps[CapabilitySelectiveRemove] = plist.(SelectiveRemover)
+	_, caps[CapabilityRangeMove] = plist.(RangeMover)
+	return caps
+}
@@ -0,0 +1,15 @@
+package player
+
+import (
+	"testing"
+
+	"github.com/polyfloyd/trollibox/src/library"
+)
+
+func TestDummyPlayerImplementation(t *testing.T) {
+	TestPlayerImplementation(t, NewDummyPlayer(
+		library.Track{URI: "track1"},
+		library.Track{URI: "track2"},
+		library.Track{URI: "track3"},
+	))
+}
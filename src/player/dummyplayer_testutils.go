@@ -0,0 +1,269 @@
+package player
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/polyfloyd/trollibox/src/library"
+	"github.com/polyfloyd/trollibox/src/util"
+)
+
+// DummyPlayer is an in-memory player used for testing. Its library consists
+// of the tracks it was created with.
+type DummyPlayer struct {
+	util.Emitter
+
+	tracks   []library.Track
+	playlist PlaylistMetaKeeper
+
+	lock   sync.Mutex
+	list   DummyPlaylist
+	index  int
+	state  PlayState
+	volume int
+	time   time.Duration
+}
+
+// NewDummyPlayer creates a stopped player with an empty playlist.
+func NewDummyPlayer(tracks ...library.Track) *DummyPlayer {
+	pl := &DummyPlayer{
+		tracks: tracks,
+		index:  -1,
+		state:  PlayStateStopped,
+	}
+	pl.playlist.Playlist = dummyPlayerPlaylist{player: pl}
+	return pl
+}
+
+// Events implements the util.Eventer interface.
+func (pl *DummyPlayer) Events() *util.Emitter {
+	return &pl.Emitter
+}
+
+// Library implements the player.Player interface.
+func (pl *DummyPlayer) Library() library.Library {
+	return pl
+}
+
+// Tracks implements the library.Library interface.
+func (pl *DummyPlayer) Tracks() ([]library.Track, error) {
+	return pl.tracks, nil
+}
+
+// TrackInfo implements the library.Library interface.
+func (pl *DummyPlayer) TrackInfo(uris ...string) ([]library.Track, error) {
+	tracks := make([]library.Track, len(uris))
+	for i, uri := range uris {
+		for _, track := range pl.tracks {
+			if track.URI == uri {
+				tracks[i] = track
+				break
+			}
+		}
+	}
+	return tracks, nil
+}
+
+// TrackArt implements the library.Library interface.
+func (pl *DummyPlayer) TrackArt(uri string) (io.ReadCloser, string) {
+	return nil, ""
+}
+
+// Playlist implements the player.Player interface.
+func (pl *DummyPlayer) Playlist() MetaPlaylist {
+	return &pl.playlist
+}
+
+// Time implements the player.Player interface.
+func (pl *DummyPlayer) Time() (time.Duration, error) {
+	pl.lock.Lock()
+	defer pl.lock.Unlock()
+	return pl.time, nil
+}
+
+// SetTime implements the player.Player interface.
+func (pl *DummyPlayer) SetTime(offset time.Duration) error {
+	pl.lock.Lock()
+	defer pl.lock.Unlock()
+	if pl.state == PlayStateStopped {
+		return nil
+	}
+	pl.time = offset
+	pl.Emit(TimeEvent{Time: offset, At: time.Now()})
+	return nil
+}
+
+// TrackIndex implements the player.Player interface.
+func (pl *DummyPlayer) TrackIndex() (int, error) {
+	pl.lock.Lock()
+	defer pl.lock.Unlock()
+	return pl.index, nil
+}
+
+// SetTrackIndex implements the player.Player interface.
+func (pl *DummyPlayer) SetTrackIndex(trackIndex int) error {
+	pl.lock.Lock()
+	defer pl.lock.Unlock()
+	pl.time = 0
+	if trackIndex >= len(pl.list) {
+		pl.index = -1
+		pl.setState(PlayStateStopped)
+		if len(pl.list) > 0 {
+			pl.Emit(QueueExhaustedEvent{})
+		}
+	} else {
+		pl.index = trackIndex
+		pl.setState(PlayStatePlaying)
+	}
+	pl.Emit(PlaylistEvent{Index: pl.index})
+	return nil
+}
+
+// State implements the player.Player interface.
+func (pl *DummyPlayer) State() (PlayState, error) {
+	pl.lock.Lock()
+	defer pl.lock.Unlock()
+	return pl.state, nil
+}
+
+// SetState implements the player.Player interface.
+func (pl *DummyPlayer) SetState(state PlayState) error {
+	pl.lock.Lock()
+	defer pl.lock.Unlock()
+	if state == PlayStatePlaying {
+		if len(pl.list) == 0 {
+			pl.Emit(PlayStateEvent{State: state})
+			return nil
+		}
+		if pl.index == -1 {
+			pl.index = 0
+			pl.Emit(PlaylistEvent{Index: 0})
+		}
+	} else if state == PlayStateStopped {
+		pl.time = 0
+	}
+	pl.setState(state)
+	return nil
+}
+
+func (pl *DummyPlayer) setState(state PlayState) {
+	if pl.state != state {
+		pl.state = state
+		pl.Emit(PlayStateEvent{State: state})
+	}
+}
+
+// Volume implements the player.Player interface.
+func (pl *DummyPlayer) Volume() (int, error) {
+	pl.lock.Lock()
+	defer pl.lock.Unlock()
+	return pl.volume, nil
+}
+
+// SetVolume implements the player.Player interface.
+func (pl *DummyPlayer) SetVolume(vol int) error {
+	pl.lock.Lock()
+	defer pl.lock.Unlock()
+	if vol > 100 {
+		vol = 100
+	} else if vol < 0 {
+		vol = 0
+	}
+	pl.volume = vol
+	pl.Emit(VolumeEvent{Volume: vol})
+	return nil
+}
+
+// Lists implements the player.Player interface.
+func (pl *DummyPlayer) Lists() (map[string]Playlist, error) {
+	return map[string]Playlist{}, nil
+}
+
+// Available implements the player.Player interface.
+func (pl *DummyPlayer) Available() bool {
+	return true
+}
+
+// dummyPlayerPlaylist keeps the current track of a DummyPlayer in place when
+// its playlist is modified.
+type dummyPlayerPlaylist struct {
+	player *DummyPlayer
+}
+
+func (plist dummyPlayerPlaylist) Insert(pos int, tracks ...library.Track) error {
+	pl := plist.player
+	pl.lock.Lock()
+	defer pl.lock.Unlock()
+	if pos != -1 && pl.index >= pos {
+		pl.index += len(tracks)
+	}
+	defer pl.Emit(PlaylistEvent{Index: pl.index})
+	return pl.list.Insert(pos, tracks...)
+}
+
+func (plist dummyPlayerPlaylist) Move(fromPos, toPos int) error {
+	pl := plist.player
+	pl.lock.Lock()
+	defer pl.lock.Unlock()
+	switch {
+	case pl.index == fromPos:
+		pl.index = toPos
+	case fromPos < pl.index && toPos >= pl.index:
+		pl.index--
+	case fromPos > pl.index && toPos <= pl.index:
+		pl.index++
+	}
+	defer pl.Emit(PlaylistEvent{Index: pl.index})
+	return pl.list.Move(fromPos, toPos)
+}
+
+func (plist dummyPlayerPlaylist) MoveRange(fromPos, toPos, count int) error {
+	pl := plist.player
+	pl.lock.Lock()
+	defer pl.lock.Unlock()
+	if err := checkMoveRange(len(pl.list), fromPos, toPos, count); err != nil {
+		return err
+	}
+	for i, j := range moveRangePermutation(len(pl.list), fromPos, toPos, count) {
+		if j == pl.index {
+			pl.index = i
+			break
+		}
+	}
+	defer pl.Emit(PlaylistEvent{Index: pl.index})
+	return pl.list.MoveRange(fromPos, toPos, count)
+}
+
+func (plist dummyPlayerPlaylist) Remove(positions ...int) error {
+	pl := plist.player
+	pl.lock.Lock()
+	defer pl.lock.Unlock()
+	index := pl.index
+	for _, pos := range positions {
+		if pos == pl.index {
+			index = -1
+			pl.setState(PlayStateStopped)
+			break
+		} else if pos < pl.index {
+			index--
+		}
+	}
+	pl.index = index
+	defer pl.Emit(PlaylistEvent{Index: pl.index})
+	return pl.list.Remove(positions...)
+}
+
+func (plist dummyPlayerPlaylist) Tracks() ([]library.Track, error) {
+	pl := plist.player
+	pl.lock.Lock()
+	defer pl.lock.Unlock()
+	return append([]library.Track{}, pl.list...), nil
+}
+
+func (plist dummyPlayerPlaylist) Len() (int, error) {
+	pl := plist.player
+	pl.lock.Lock()
+	defer pl.lock.Unlock()
+	return len(pl.list), nil
+}
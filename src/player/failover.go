@@ -0,0 +1,491 @@
+package player
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/polyfloyd/trollibox/src/library"
+	"github.com/polyfloyd/trollibox/src/util"
+)
+
+// A Failover is a player that routes all operations to a primary player. While
+// the primary is unavailable, a backup player takes over. Control returns to
+// the primary once it recovers.
+//
+// The state of the queue, which consists of the playlist, the current track,
+// the time offset and the playstate, is carried over to the player that takes
+// over. Because an unavailable player can not be queried, the queue is
+// recorded each time the active player reports a change.
+//
+// Only events of the active player are passed on. AvailabilityEvents reflect
+// whether either of the players is available.
+//
+// The optional interfaces of this package are delegated to the active player.
+// Operations of interfaces that the active player does not implement fail
+// with ErrUnsupported.
+type Failover struct {
+	util.Emitter
+
+	primary Player
+	backup  Player
+
+	lock      sync.Mutex
+	active    Player
+	available bool
+	queue     failoverQueue
+
+	closed    chan struct{}
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// failoverQueue is a recording of the queue of a player.
+type failoverQueue struct {
+	tracks []library.Track
+	meta   []TrackMeta
+	index  int
+	state  PlayState
+	offset time.Duration
+	// The moment the offset was recorded.
+	at time.Time
+}
+
+// NewFailover creates a player that uses the backup while the primary is
+// unavailable. The players should share the same library.
+func NewFailover(primary, backup Player) *Failover {
+	fo := &Failover{
+		primary: primary,
+		backup:  backup,
+		active:  primary,
+		closed:  make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	primaryEvents := primary.Events().Listen()
+	backupEvents := backup.Events().Listen()
+	if primary.Available() {
+		if err := fo.record(); err != nil {
+			log.Errorf("%v: Could not record the queue: %v", fo, err)
+		}
+	}
+	fo.check()
+	go fo.run(primaryEvents, backupEvents)
+	return fo
+}
+
+func (fo *Failover) run(primaryEvents, backupEvents <-chan interface{}) {
+	defer close(fo.done)
+	defer fo.primary.Events().Unlisten(primaryEvents)
+	defer fo.backup.Events().Unlisten(backupEvents)
+	for {
+		select {
+		case event, ok := <-primaryEvents:
+			if !ok {
+				return
+			}
+			fo.handleEvent(fo.primary, event)
+		case event, ok := <-backupEvents:
+			if !ok {
+				return
+			}
+			fo.handleEvent(fo.backup, event)
+		case <-fo.closed:
+			return
+		}
+	}
+}
+
+func (fo *Failover) handleEvent(source Player, event interface{}) {
+	if _, ok := event.(AvailabilityEvent); ok {
+		fo.check()
+		return
+	}
+	if source != fo.current() {
+		return
+	}
+	fo.Emit(event)
+	switch event.(type) {
+	case PlaylistEvent:
+		if err := fo.record(); err != nil {
+			log.Errorf("%v: Could not record the queue: %v", fo, err)
+		}
+	case PlayStateEvent, TimeEvent:
+		// Listing the tracks of a long playlist is expensive, so only the
+		// position is recorded when the playlist itself did not change.
+		if err := fo.recordPosition(); err != nil {
+			log.Errorf("%v: Could not record the queue: %v", fo, err)
+		}
+	}
+}
+
+// check selects the player that should be active and hands over the queue if
+// it changed.
+func (fo *Failover) check() {
+	primaryAvailable := fo.primary.Available()
+	backupAvailable := fo.backup.Available()
+
+	fo.lock.Lock()
+	prev := fo.active
+	next := prev
+	if primaryAvailable {
+		next = fo.primary
+	} else if backupAvailable {
+		next = fo.backup
+	}
+	available := primaryAvailable || backupAvailable
+	availabilityChanged := available != fo.available
+	fo.available = available
+	fo.lock.Unlock()
+
+	if next != prev {
+		// The queue of a player that is still reachable is more recent than
+		// the last recording.
+		if prev.Available() {
+			if err := fo.recordFrom(prev); err != nil {
+				log.Errorf("%v: Could not record the queue: %v", fo, err)
+			}
+		}
+		fo.lock.Lock()
+		queue := fo.queue
+		fo.active = next
+		fo.lock.Unlock()
+
+		log.Warnf("%v: Switching to %v", fo, next)
+		// Nothing is restored if the queue was never recorded, which is the
+		// case if the primary was unavailable from the start.
+		if !queue.at.IsZero() {
+			if err := queue.restore(next); err != nil {
+				log.Errorf("%v: Could not restore the queue: %v", fo, err)
+			}
+		}
+		if prev.Available() {
+			if err := prev.SetState(PlayStateStopped); err != nil {
+				log.Errorf("%v: Could not stop %v: %v", fo, prev, err)
+			}
+		}
+	}
+	if availabilityChanged {
+		fo.Emit(AvailabilityEvent{Available: available})
+	}
+}
+
+// record stores the queue of the active player.
+func (fo *Failover) record() error {
+	return fo.recordFrom(fo.current())
+}
+
+func (fo *Failover) recordFrom(pl Player) error {
+	plist := pl.Playlist()
+	tracks, err := plist.Tracks()
+	if err != nil {
+		return err
+	}
+	meta, err := plist.Meta()
+	if err != nil {
+		return err
+	}
+	index, state, offset, err := playerPosition(pl)
+	if err != nil {
+		return err
+	}
+	fo.lock.Lock()
+	defer fo.lock.Unlock()
+	fo.queue = failoverQueue{
+		tracks: tracks,
+		meta:   meta,
+		index:  index,
+		state:  state,
+		offset: offset,
+		at:     time.Now(),
+	}
+	return nil
+}
+
+// recordPosition stores the position in the queue of the active player,
+// keeping the recorded tracks.
+func (fo *Failover) recordPosition() error {
+	index, state, offset, err := playerPosition(fo.current())
+	if err != nil {
+		return err
+	}
+	fo.lock.Lock()
+	defer fo.lock.Unlock()
+	fo.queue.index = index
+	fo.queue.state = state
+	fo.queue.offset = offset
+	fo.queue.at = time.Now()
+	return nil
+}
+
+func playerPosition(pl Player) (int, PlayState, time.Duration, error) {
+	index, err := pl.TrackIndex()
+	if err != nil {
+		return -1, PlayStateInvalid, 0, err
+	}
+	state, err := pl.State()
+	if err != nil {
+		return -1, PlayStateInvalid, 0, err
+	}
+	offset, err := pl.Time()
+	if err != nil {
+		return -1, PlayStateInvalid, 0, err
+	}
+	return index, state, offset, nil
+}
+
+// restore replaces the queue of the player with the recorded one.
+func (queue failoverQueue) restore(pl Player) error {
+	plist := pl.Playlist()
+	length, err := plist.Len()
+	if err != nil {
+		return err
+	}
+	if length > 0 {
+		positions := make([]int, length)
+		for i := range positions {
+			positions[i] = i
+		}
+		if err := plist.Remove(positions...); err != nil {
+			return err
+		}
+	}
+	if len(queue.tracks) > 0 {
+		meta := queue.meta
+		if len(meta) != len(queue.tracks) {
+			meta = make([]TrackMeta, len(queue.tracks))
+		}
+		if err := plist.InsertWithMeta(0, queue.tracks, meta); err != nil {
+			return err
+		}
+	}
+	if queue.index < 0 || queue.index >= len(queue.tracks) {
+		return pl.SetState(PlayStateStopped)
+	}
+	if err := pl.SetTrackIndex(queue.index); err != nil {
+		return err
+	}
+	offset := queue.offset
+	if queue.state == PlayStatePlaying {
+		offset += time.Since(queue.at)
+	}
+	if d := queue.tracks[queue.index].Duration; d == 0 || offset < d {
+		if err := pl.SetTime(offset); err != nil && !errors.Is(err, ErrUnseekable) {
+			return err
+		}
+	}
+	return pl.SetState(queue.state)
+}
+
+func (fo *Failover) current() Player {
+	fo.lock.Lock()
+	defer fo.lock.Unlock()
+	return fo.active
+}
+
+// Close stops watching the players and closes them if they implement
+// io.Closer.
+func (fo *Failover) Close() error {
+	fo.closeOnce.Do(func() { close(fo.closed) })
+	<-fo.done
+	var firstErr error
+	for _, pl := range []Player{fo.primary, fo.backup} {
+		if closer, ok := pl.(io.Closer); ok {
+			if err := closer.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// Events implements the util.Eventer interface.
+func (fo *Failover) Events() *util.Emitter {
+	return &fo.Emitter
+}
+
+// Library implements the player.Player interface.
+func (fo *Failover) Library() library.Library {
+	return fo.current().Library()
+}
+
+// Playlist implements the player.Player interface.
+func (fo *Failover) Playlist() MetaPlaylist {
+	return fo.current().Playlist()
+}
+
+// Time implements the player.Player interface.
+func (fo *Failover) Time() (time.Duration, error) {
+	return fo.current().Time()
+}
+
+// SetTime implements the player.Player interface.
+func (fo *Failover) SetTime(offset time.Duration) error {
+	return fo.current().SetTime(offset)
+}
+
+// TrackIndex implements the player.Player interface.
+func (fo *Failover) TrackIndex() (int, error) {
+	return fo.current().TrackIndex()
+}
+
+// SetTrackIndex implements the player.Player interface.
+func (fo *Failover) SetTrackIndex(trackIndex int) error {
+	return fo.current().SetTrackIndex(trackIndex)
+}
+
+// State implements the player.Player interface.
+func (fo *Failover) State() (PlayState, error) {
+	return fo.current().State()
+}
+
+// SetState implements the player.Player interface.
+func (fo *Failover) SetState(state PlayState) error {
+	return fo.current().SetState(state)
+}
+
+// Volume implements the player.Player interface.
+func (fo *Failover) Volume() (int, error) {
+	return fo.current().Volume()
+}
+
+// SetVolume implements the player.Player interface.
+func (fo *Failover) SetVolume(vol int) error {
+	return fo.current().SetVolume(vol)
+}
+
+// Lists implements the player.Player interface.
+func (fo *Failover) Lists() (map[string]Playlist, error) {
+	return fo.current().Lists()
+}
+
+// Supports implements the player.URISupporter interface.
+func (fo *Failover) Supports(uri string) bool {
+	if supporter, ok := fo.current().(URISupporter); ok {
+		return supporter.Supports(uri)
+	}
+	return true
+}
+
+// Capabilities implements the player.CapabilityReporter interface. The
+// capabilities of the active player are reported, as calls to the optional
+// interfaces are delegated to it.
+func (fo *Failover) Capabilities() map[string]bool {
+	return Capabilities(fo.current())
+}
+
+// WithContext implements the player.ContextBinder interface. The active
+// player is bound to the context, so the view does not switch players.
+func (fo *Failover) WithContext(ctx context.Context) Player {
+	return WithContext(ctx, fo.current())
+}
+
+// ServerStats implements the player.StatsReporter interface.
+func (fo *Failover) ServerStats() (ServerStats, error) {
+	if reporter, ok := fo.current().(StatsReporter); ok {
+		return reporter.ServerStats()
+	}
+	return ServerStats{}, ErrUnsupported
+}
+
+// ReadReplayGain implements the player.ReplayGainReader interface.
+func (fo *Failover) ReadReplayGain(tracks []library.Track) error {
+	if reader, ok := fo.current().(ReplayGainReader); ok {
+		return reader.ReadReplayGain(tracks)
+	}
+	return ErrUnsupported
+}
+
+// SearchServerSide implements the player.ServerSearcher interface.
+func (fo *Failover) SearchServerSide(tags map[string]string) ([]library.Track, error) {
+	if searcher, ok := fo.current().(ServerSearcher); ok {
+		return searcher.SearchServerSide(tags)
+	}
+	return nil, ErrUnsupported
+}
+
+// TrackPriorities implements the player.Prioritizer interface.
+func (fo *Failover) TrackPriorities() ([]int, error) {
+	if prioritizer, ok := fo.current().(Prioritizer); ok {
+		return prioritizer.TrackPriorities()
+	}
+	return nil, ErrUnsupported
+}
+
+// SetTrackPriority implements the player.Prioritizer interface.
+func (fo *Failover) SetTrackPriority(pos, prio int) error {
+	if prioritizer, ok := fo.current().(Prioritizer); ok {
+		return prioritizer.SetTrackPriority(pos, prio)
+	}
+	return ErrUnsupported
+}
+
+// PlayCounts implements the player.PlayCounter interface.
+func (fo *Failover) PlayCounts() (map[string]int, error) {
+	if counter, ok := fo.current().(PlayCounter); ok {
+		return counter.PlayCounts()
+	}
+	return nil, ErrUnsupported
+}
+
+// SkipCounts implements the player.SkipCounter interface.
+func (fo *Failover) SkipCounts() (map[string]int, error) {
+	if counter, ok := fo.current().(SkipCounter); ok {
+		return counter.SkipCounts()
+	}
+	return nil, ErrUnsupported
+}
+
+// PoolStats implements the player.PoolReporter interface. The statistics are
+// empty if the active player does not report them.
+func (fo *Failover) PoolStats() PoolStats {
+	if reporter, ok := fo.current().(PoolReporter); ok {
+		return reporter.PoolStats()
+	}
+	return PoolStats{}
+}
+
+// SavedQueues implements the player.QueueSaver interface.
+func (fo *Failover) SavedQueues() ([]string, error) {
+	if saver, ok := fo.current().(QueueSaver); ok {
+		return saver.SavedQueues()
+	}
+	return nil, ErrUnsupported
+}
+
+// SaveQueue implements the player.QueueSaver interface.
+func (fo *Failover) SaveQueue(name string) error {
+	if saver, ok := fo.current().(QueueSaver); ok {
+		return saver.SaveQueue(name)
+	}
+	return ErrUnsupported
+}
+
+// LoadQueue implements the player.QueueSaver interface.
+func (fo *Failover) LoadQueue(name string) error {
+	if saver, ok := fo.current().(QueueSaver); ok {
+		return saver.LoadQueue(name)
+	}
+	return ErrUnsupported
+}
+
+// RemoveSavedQueue implements the player.QueueSaver interface.
+func (fo *Failover) RemoveSavedQueue(name string) error {
+	if saver, ok := fo.current().(QueueSaver); ok {
+		return saver.RemoveSavedQueue(name)
+	}
+	return ErrUnsupported
+}
+
+// Available implements the player.Player interface.
+func (fo *Failover) Available() bool {
+	return fo.primary.Available() || fo.backup.Available()
+}
+
+func (fo *Failover) String() string {
+	return fmt.Sprintf("Failover{%v, %v}", fo.primary, fo.backup)
+}
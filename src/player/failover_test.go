@@ -0,0 +1,157 @@
+package player
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/polyfloyd/trollibox/src/library"
+)
+
+// flakyPlayer is a DummyPlayer that can be taken offline.
+type flakyPlayer struct {
+	*DummyPlayer
+	down int32
+}
+
+func (pl *flakyPlayer) Available() bool {
+	return atomic.LoadInt32(&pl.down) == 0
+}
+
+func (pl *flakyPlayer) setAvailable(available bool) {
+	if available {
+		atomic.StoreInt32(&pl.down, 0)
+	} else {
+		atomic.StoreInt32(&pl.down, 1)
+	}
+	pl.Emit(AvailabilityEvent{Available: available})
+}
+
+func TestFailover(t *testing.T) {
+	tracks := []library.Track{{URI: "a"}, {URI: "b"}, {URI: "c"}}
+	primary := &flakyPlayer{DummyPlayer: NewDummyPlayer(tracks...)}
+	backup := &flakyPlayer{DummyPlayer: NewDummyPlayer(tracks...)}
+	defer primary.Events().Close()
+	defer backup.Events().Close()
+	fo := NewFailover(primary, backup)
+	defer fo.Close()
+	events := fo.Listen()
+	defer fo.Unlisten(events)
+
+	expectAvailability := func(expected bool) {
+		t.Helper()
+		timeout := time.After(time.Second)
+		for {
+			select {
+			case event := <-events:
+				if ev, ok := event.(AvailabilityEvent); ok {
+					if ev.Available != expected {
+						t.Fatalf("Unexpected availability: %v", ev.Available)
+					}
+					return
+				}
+			case <-timeout:
+				t.Fatalf("No availability event")
+			}
+		}
+	}
+	expectQueue := func(pl *flakyPlayer, uris ...string) {
+		t.Helper()
+		plTracks, _ := pl.Playlist().Tracks()
+		meta, _ := pl.Playlist().Meta()
+		if len(plTracks) != len(uris) || len(meta) != len(uris) {
+			t.Fatalf("Unexpected playlist: %v", plTracks)
+		}
+		for i, uri := range uris {
+			if plTracks[i].URI != uri || meta[i].QueuedBy != "test" {
+				t.Fatalf("Unexpected playlist: %v %v", plTracks, meta)
+			}
+		}
+	}
+
+	meta := []TrackMeta{{QueuedBy: "test"}, {QueuedBy: "test"}}
+	if err := fo.Playlist().InsertWithMeta(-1, tracks[:2], meta); err != nil {
+		t.Fatal(err)
+	}
+	if err := fo.SetTrackIndex(1); err != nil {
+		t.Fatal(err)
+	}
+	// Let the failover record the queue.
+	time.Sleep(time.Millisecond * 20)
+
+	primary.setAvailable(false)
+	time.Sleep(time.Millisecond * 20)
+	if !fo.Available() {
+		t.Fatalf("The failover should be available while the backup is")
+	}
+	expectQueue(backup, "a", "b")
+	if index, _ := backup.TrackIndex(); index != 1 {
+		t.Fatalf("Unexpected index of the backup: %d", index)
+	}
+	if state, _ := fo.State(); state != PlayStatePlaying {
+		t.Fatalf("Unexpected state: %v", state)
+	}
+
+	// Changes made while the primary is down are carried over when it
+	// recovers.
+	if err := fo.Playlist().InsertWithMeta(-1, tracks[2:], meta[:1]); err != nil {
+		t.Fatal(err)
+	}
+	primary.setAvailable(true)
+	time.Sleep(time.Millisecond * 20)
+	expectQueue(primary, "a", "b", "c")
+	if state, _ := backup.State(); state != PlayStateStopped {
+		t.Fatalf("The backup should be stopped after the primary took over")
+	}
+
+	backup.setAvailable(false)
+	primary.setAvailable(false)
+	expectAvailability(false)
+	if fo.Available() {
+		t.Fatalf("The failover should be unavailable")
+	}
+	primary.setAvailable(true)
+	expectAvailability(true)
+}
+
+// countingPlayer is a flakyPlayer that reports play counts.
+type countingPlayer struct {
+	*flakyPlayer
+}
+
+func (pl countingPlayer) PlayCounts() (map[string]int, error) {
+	return map[string]int{"a": 1}, nil
+}
+
+func TestFailoverDelegation(t *testing.T) {
+	primary := countingPlayer{&flakyPlayer{DummyPlayer: NewDummyPlayer()}}
+	backup := &flakyPlayer{DummyPlayer: NewDummyPlayer()}
+	defer primary.Events().Close()
+	defer backup.Events().Close()
+	fo := NewFailover(primary, backup)
+	defer fo.Close()
+
+	if counts, err := fo.PlayCounts(); err != nil || counts["a"] != 1 {
+		t.Fatalf("Unexpected play counts: %v, %v", counts, err)
+	}
+	if bound := WithContext(context.Background(), fo); bound != Player(primary) {
+		t.Fatalf("The active player should be bound, got %v", bound)
+	}
+
+	if caps := Capabilities(fo); !caps[CapabilityPlayCounts] || caps[CapabilityStats] {
+		t.Fatalf("Unexpected capabilities: %v", caps)
+	}
+
+	primary.setAvailable(false)
+	time.Sleep(time.Millisecond * 20)
+	if caps := Capabilities(fo); caps[CapabilityPlayCounts] {
+		t.Fatalf("Unexpected capabilities after failing over: %v", caps)
+	}
+	if _, err := fo.PlayCounts(); err != ErrUnsupported {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := fo.ServerStats(); err != ErrUnsupported {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
@@ -11,8 +11,16 @@ import (
 // TrackMeta contains metadata for a track in a playlist.
 type TrackMeta struct {
 	// QueuedBy indicates by what entity a track was added.
-	// Can be either "user" or "system".
+	// This is "system" for tracks added automatically and the anonymous ID
+	// of the client for tracks added by users. Tracks added by unidentified
+	// users have "user".
 	QueuedBy string
+	// QueuedByName is the nickname of the client that added the track, if
+	// any.
+	QueuedByName string
+	// Title is displayed for the track as long as the track itself does not
+	// have a title, e.g. a stream that has yet to report its metadata.
+	Title string
 }
 
 // The PlaylistMetaKeeper wraps a Playlist which does not track the meta
@@ -22,15 +30,43 @@ type TrackMeta struct {
 //
 // Any operation performed on this playlist is propagated to the wrapped
 // playlist and are safe for concurrent use.
+//
+// The wrapped playlist may also be changed by others, e.g. another MPD client.
+// The kept state is therefore reconciled with the wrapped playlist before each
+// operation so positions are never applied to an outdated view.
 type PlaylistMetaKeeper struct {
 	Playlist
 
-	tracks   []library.Track
-	meta     []TrackMeta
-	metaLock sync.Mutex
+	// The kept state, which is shared with the keepers returned by
+	// WithPlaylist. It is allocated on first use so the zero value is ready
+	// for use.
+	state     *keeperState
+	stateOnce sync.Once
+}
+
+type keeperState struct {
+	tracks []library.Track
+	meta   []TrackMeta
+	lock   sync.Mutex
+}
+
+func (kpr *PlaylistMetaKeeper) kept() *keeperState {
+	kpr.stateOnce.Do(func() {
+		if kpr.state == nil {
+			kpr.state = &keeperState{}
+		}
+	})
+	return kpr.state
+}
+
+// WithPlaylist returns a keeper that shares its metadata with this one, but
+// performs its operations on plist. The playlist must be a view of the same
+// playlist, like one of which the operations are bound to a context.
+func (kpr *PlaylistMetaKeeper) WithPlaylist(plist Playlist) *PlaylistMetaKeeper {
+	return &PlaylistMetaKeeper{Playlist: plist, state: kpr.kept()}
 }
 
-func (kpr *PlaylistMetaKeeper) update() error {
+func (kpr *PlaylistMetaKeeper) update(st *keeperState) error {
 	tracks, err := kpr.Playlist.Tracks()
 	if err != nil {
 		return err
@@ -39,7 +75,7 @@ func (kpr *PlaylistMetaKeeper) update() error {
 	inferDefault := func(target, source *TrackMeta) {
 		if target.QueuedBy == "" {
 			if source != nil && source.QueuedBy != "" {
-				target.QueuedBy = source.QueuedBy
+				*target = *source
 			} else {
 				target.QueuedBy = "user"
 			}
@@ -53,12 +89,12 @@ outer:
 	for i, track := range tracks {
 		needIndex := found[track.URI] + 1
 		duplicateIndex := 0
-		for j, keptTrack := range kpr.tracks {
+		for j, keptTrack := range st.tracks {
 			if keptTrack.URI == track.URI {
 				if duplicateIndex++; duplicateIndex == needIndex {
 					newPlist[i] = keptTrack
 					found[track.URI] = needIndex
-					inferDefault(&newMeta[i], &kpr.meta[j])
+					inferDefault(&newMeta[i], &st.meta[j])
 					continue outer
 				}
 			}
@@ -67,8 +103,8 @@ outer:
 		newPlist[i] = track
 		inferDefault(&newMeta[i], nil)
 	}
-	kpr.meta = newMeta
-	kpr.tracks = newPlist
+	st.meta = newMeta
+	st.tracks = newPlist
 	return nil
 }
 
@@ -83,66 +119,154 @@ func (kpr *PlaylistMetaKeeper) Insert(pos int, tracks ...library.Track) error {
 
 // Move implements the player.Playlist interface.
 func (kpr *PlaylistMetaKeeper) Move(fromPos, toPos int) error {
-	kpr.metaLock.Lock()
-	defer kpr.metaLock.Unlock()
-	if kpr.meta == nil {
-		if err := kpr.update(); err != nil {
-			return err
-		}
+	st := kpr.kept()
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	if err := kpr.update(st); err != nil {
+		return err
+	}
+	if fromPos >= len(st.meta) || toPos >= len(st.meta) {
+		return fmt.Errorf("move positions out of range: (%v -> %v) len=%v", fromPos, toPos, len(st.meta))
 	}
 	if err := kpr.Playlist.Move(fromPos, toPos); err != nil {
 		return err
 	}
+	st.applyMove(fromPos, toPos, 1)
+	return nil
+}
 
-	if fromPos >= len(kpr.meta) || toPos >= len(kpr.meta) {
-		return fmt.Errorf("move positions out of range: (%v -> %v) len=%v", fromPos, toPos, len(kpr.meta))
+// MoveRange implements the player.RangeMover interface.
+func (kpr *PlaylistMetaKeeper) MoveRange(fromPos, toPos, count int) error {
+	st := kpr.kept()
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	if err := kpr.update(st); err != nil {
+		return err
 	}
-	delta := 0
-	if fromPos > toPos {
-		delta = 1
+	if err := checkMoveRange(len(st.meta), fromPos, toPos, count); err != nil {
+		return err
+	}
+	if err := MoveRange(kpr.Playlist, fromPos, toPos, count); err != nil {
+		return err
 	}
-	track := kpr.tracks[fromPos]
-	kpr.tracks = append(kpr.tracks[:fromPos], kpr.tracks[fromPos+1:]...)
-	kpr.tracks = append(kpr.tracks[:toPos+delta], append([]library.Track{track}, kpr.tracks[toPos+delta:]...)...)
-	meta := kpr.meta[fromPos]
-	kpr.meta = append(kpr.meta[:fromPos], kpr.meta[fromPos+1:]...)
-	kpr.meta = append(kpr.meta[:toPos+delta], append([]TrackMeta{meta}, kpr.meta[toPos+delta:]...)...)
+	st.applyMove(fromPos, toPos, count)
 	return nil
 }
 
+func (st *keeperState) applyMove(fromPos, toPos, count int) {
+	perm := moveRangePermutation(len(st.tracks), fromPos, toPos, count)
+	tracks := make([]library.Track, len(perm))
+	meta := make([]TrackMeta, len(perm))
+	for i, j := range perm {
+		tracks[i], meta[i] = st.tracks[j], st.meta[j]
+	}
+	st.tracks, st.meta = tracks, meta
+}
+
 // Remove implements the player.Playlist interface.
 func (kpr *PlaylistMetaKeeper) Remove(positions ...int) error {
-	kpr.metaLock.Lock()
-	defer kpr.metaLock.Unlock()
-	if kpr.meta == nil {
-		if err := kpr.update(); err != nil {
-			return err
-		}
+	st := kpr.kept()
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	if err := kpr.update(st); err != nil {
+		return err
 	}
 	sort.Ints(positions)
 	if err := kpr.Playlist.Remove(positions...); err != nil {
 		return err
 	}
+	st.forget(positions)
+	return nil
+}
+
+// RemoveURIs implements the player.SelectiveRemover interface. If the wrapped
+// playlist implements the player.IDPlaylist interface, the tracks are removed
+// by their identifiers rather than by their positions in the kept state.
+func (kpr *PlaylistMetaKeeper) RemoveURIs(uris ...string) ([]RemovedTrack, error) {
+	st := kpr.kept()
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	if err := kpr.update(st); err != nil {
+		return nil, err
+	}
+	if idPlist, ok := kpr.Playlist.(IDPlaylist); ok {
+		positions, err := idPlist.RemoveURIs(uris...)
+		if err != nil {
+			return nil, err
+		}
+		sort.Ints(positions)
+		return st.forget(positions), nil
+	}
+	remove := map[string]bool{}
+	for _, uri := range uris {
+		remove[uri] = true
+	}
+	var positions []int
+	for i, track := range st.tracks {
+		if remove[track.URI] {
+			positions = append(positions, i)
+		}
+	}
+	if len(positions) == 0 {
+		return []RemovedTrack{}, nil
+	}
+	if err := kpr.Playlist.Remove(positions...); err != nil {
+		return nil, err
+	}
+	return st.forget(positions), nil
+}
+
+// RemoveIDs implements the player.SelectiveRemover interface. An error is
+// returned if the wrapped playlist does not implement the player.IDPlaylist
+// interface.
+func (kpr *PlaylistMetaKeeper) RemoveIDs(ids ...string) ([]RemovedTrack, error) {
+	idPlist, ok := kpr.Playlist.(IDPlaylist)
+	if !ok {
+		return nil, fmt.Errorf("the playlist does not assign identifiers to tracks")
+	}
+	st := kpr.kept()
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	if err := kpr.update(st); err != nil {
+		return nil, err
+	}
+	positions, err := idPlist.RemoveIDs(ids...)
+	if err != nil {
+		return nil, err
+	}
+	sort.Ints(positions)
+	return st.forget(positions), nil
+}
 
+// forget removes the tracks at the sorted positions from the kept state after
+// they have been removed from the wrapped playlist.
+func (st *keeperState) forget(positions []int) []RemovedTrack {
+	removed := make([]RemovedTrack, 0, len(positions))
 	for i := len(positions) - 1; i >= 0; i-- {
 		pos := positions[i]
-		if pos >= len(kpr.meta) {
+		if pos >= len(st.meta) || i > 0 && positions[i-1] == pos {
 			continue
 		}
-		kpr.tracks = append(kpr.tracks[:pos], kpr.tracks[pos+1:]...)
-		kpr.meta = append(kpr.meta[:pos], kpr.meta[pos+1:]...)
+		removed = append(removed, RemovedTrack{Position: pos, Track: st.tracks[pos]})
+		st.tracks = append(st.tracks[:pos], st.tracks[pos+1:]...)
+		st.meta = append(st.meta[:pos], st.meta[pos+1:]...)
 	}
-	return nil
+	// Report the tracks in playlist order.
+	for i, j := 0, len(removed)-1; i < j; i, j = i+1, j-1 {
+		removed[i], removed[j] = removed[j], removed[i]
+	}
+	return removed
 }
 
 // Tracks implements the player.Playlist interface.
 func (kpr *PlaylistMetaKeeper) Tracks() ([]library.Track, error) {
-	kpr.metaLock.Lock()
-	defer kpr.metaLock.Unlock()
-	if err := kpr.update(); err != nil {
+	st := kpr.kept()
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	if err := kpr.update(st); err != nil {
 		return nil, err
 	}
-	return kpr.tracks, nil
+	return st.tracks, nil
 }
 
 // InsertWithMeta performs a regular playlist insertion but records the
@@ -150,37 +274,58 @@ func (kpr *PlaylistMetaKeeper) Tracks() ([]library.Track, error) {
 //
 // The tracks and meta slices should have the same length.
 func (kpr *PlaylistMetaKeeper) InsertWithMeta(pos int, tracks []library.Track, meta []TrackMeta) error {
+	_, err := kpr.InsertWithMetaResult(pos, tracks, meta)
+	return err
+}
+
+// InsertWithMetaResult implements the player.InsertReporter interface. The
+// identifiers of the tracks are set if the wrapped playlist implements the
+// player.IDPlaylist interface.
+func (kpr *PlaylistMetaKeeper) InsertWithMetaResult(pos int, tracks []library.Track, meta []TrackMeta) (InsertResult, error) {
 	if len(tracks) != len(meta) {
-		return fmt.Errorf("the number of tracks to insert, %v, mismatches that of the metadata: %v", len(tracks), len(meta))
+		return InsertResult{}, fmt.Errorf("the number of tracks to insert, %v, mismatches that of the metadata: %v", len(tracks), len(meta))
 	}
 
-	kpr.metaLock.Lock()
-	defer kpr.metaLock.Unlock()
-	if kpr.meta == nil {
-		if err := kpr.update(); err != nil {
-			return err
-		}
+	st := kpr.kept()
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	if err := kpr.update(st); err != nil {
+		return InsertResult{}, err
 	}
-	if err := kpr.Playlist.Insert(pos, tracks...); err != nil {
-		return err
+	var result InsertResult
+	if idPlist, ok := kpr.Playlist.(IDPlaylist); ok {
+		ids, err := idPlist.InsertIDs(pos, tracks...)
+		if err != nil {
+			return InsertResult{}, err
+		}
+		result.IDs = ids
+	} else if err := kpr.Playlist.Insert(pos, tracks...); err != nil {
+		return InsertResult{}, err
 	}
 
+	start := pos
 	if pos == -1 {
-		kpr.tracks = append(kpr.tracks, tracks...)
-		kpr.meta = append(kpr.meta, meta...)
+		start = len(st.tracks)
+		st.tracks = append(st.tracks, tracks...)
+		st.meta = append(st.meta, meta...)
 	} else {
-		kpr.tracks = append(kpr.tracks[:pos], append(tracks, kpr.tracks[pos:]...)...)
-		kpr.meta = append(kpr.meta[:pos], append(meta, kpr.meta[pos:]...)...)
+		st.tracks = append(st.tracks[:pos], append(tracks, st.tracks[pos:]...)...)
+		st.meta = append(st.meta[:pos], append(meta, st.meta[pos:]...)...)
 	}
-	return nil
+	result.Positions = make([]int, len(tracks))
+	for i := range tracks {
+		result.Positions[i] = start + i
+	}
+	return result, nil
 }
 
 // Meta loads the metadata associated with each track in the playlist.
 func (kpr *PlaylistMetaKeeper) Meta() ([]TrackMeta, error) {
-	kpr.metaLock.Lock()
-	defer kpr.metaLock.Unlock()
-	if err := kpr.update(); err != nil {
+	st := kpr.kept()
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	if err := kpr.update(st); err != nil {
 		return nil, err
 	}
-	return kpr.meta, nil
+	return st.meta, nil
 }
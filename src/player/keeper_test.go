@@ -1,6 +1,9 @@
 package player
 
 import (
+	"fmt"
+	"reflect"
+	"sort"
 	"testing"
 
 	"github.com/polyfloyd/trollibox/src/library"
@@ -48,3 +51,163 @@ func TestMetaKeeperInsert(t *testing.T) {
 		t.Fatalf("Unexpected QueuedBy: %v", meta[0].QueuedBy)
 	}
 }
+
+func TestMetaKeeperExternalChange(t *testing.T) {
+	backend := &DummyPlaylist{}
+	metapl := PlaylistMetaKeeper{Playlist: backend}
+	tracks := []library.Track{{URI: "a"}, {URI: "b"}}
+	meta := []TrackMeta{{QueuedBy: "system"}, {QueuedBy: "system"}}
+	if err := metapl.InsertWithMeta(0, tracks, meta); err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate a change made by another client of the backend.
+	if err := backend.Insert(-1, library.Track{URI: "c"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The position refers to the track appended by the other client.
+	if err := metapl.Move(0, 2); err != nil {
+		t.Fatal(err)
+	}
+	plTracks, err := metapl.Tracks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	plMeta, err := metapl.Meta()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plTracks) != 3 || plTracks[0].URI != "b" || plTracks[1].URI != "c" || plTracks[2].URI != "a" {
+		t.Fatalf("Unexpected tracks: %v", plTracks)
+	}
+	if plMeta[0].QueuedBy != "system" || plMeta[1].QueuedBy != "user" || plMeta[2].QueuedBy != "system" {
+		t.Fatalf("Metadata is out of sync with the tracks: %v", plMeta)
+	}
+}
+
+type idDummyPlaylist struct {
+	DummyPlaylist
+	ids    []string
+	nextID int
+}
+
+func (plist *idDummyPlaylist) InsertIDs(pos int, tracks ...library.Track) ([]string, error) {
+	if pos == -1 {
+		pos = len(plist.ids)
+	}
+	if err := plist.Insert(pos, tracks...); err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(tracks))
+	for i := range tracks {
+		plist.nextID++
+		ids[i] = fmt.Sprintf("%d", plist.nextID)
+	}
+	plist.ids = append(plist.ids[:pos], append(ids, plist.ids[pos:]...)...)
+	return ids, nil
+}
+
+func (plist *idDummyPlaylist) Remove(positions ...int) error {
+	sort.Ints(positions)
+	for i := len(positions) - 1; i >= 0; i-- {
+		plist.ids = append(plist.ids[:positions[i]], plist.ids[positions[i]+1:]...)
+	}
+	return plist.DummyPlaylist.Remove(positions...)
+}
+
+func (plist *idDummyPlaylist) RemoveIDs(ids ...string) ([]int, error) {
+	var positions []int
+	for _, id := range ids {
+		for pos, plID := range plist.ids {
+			if plID == id {
+				plist.DummyPlaylist.Remove(pos)
+				plist.ids = append(plist.ids[:pos], plist.ids[pos+1:]...)
+				positions = append(positions, pos)
+				break
+			}
+		}
+	}
+	return positions, nil
+}
+
+func (plist *idDummyPlaylist) RemoveURIs(uris ...string) ([]int, error) {
+	remove := map[string]bool{}
+	for _, uri := range uris {
+		remove[uri] = true
+	}
+	var positions []int
+	for pos := len(plist.ids) - 1; pos >= 0; pos-- {
+		if remove[plist.DummyPlaylist[pos].URI] {
+			plist.DummyPlaylist.Remove(pos)
+			plist.ids = append(plist.ids[:pos], plist.ids[pos+1:]...)
+			positions = append(positions, pos)
+		}
+	}
+	return positions, nil
+}
+
+func TestMetaKeeperInsertResult(t *testing.T) {
+	metapl := PlaylistMetaKeeper{Playlist: &idDummyPlaylist{}}
+	tracks := []library.Track{{URI: "a"}, {URI: "b"}}
+	meta := []TrackMeta{{}, {}}
+
+	result, err := metapl.InsertWithMetaResult(-1, tracks, meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Positions, []int{0, 1}) || !reflect.DeepEqual(result.IDs, []string{"1", "2"}) {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+	result, err = metapl.InsertWithMetaResult(1, tracks, meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Positions, []int{1, 2}) || !reflect.DeepEqual(result.IDs, []string{"3", "4"}) {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+
+	plain := PlaylistMetaKeeper{Playlist: &DummyPlaylist{}}
+	if result, err := plain.InsertWithMetaResult(-1, tracks, meta); err != nil {
+		t.Fatal(err)
+	} else if result.IDs != nil {
+		t.Fatalf("Unexpected IDs: %v", result.IDs)
+	}
+}
+
+func TestMetaKeeperSelectiveRemove(t *testing.T) {
+	metapl := PlaylistMetaKeeper{Playlist: &idDummyPlaylist{}}
+	tracks := []library.Track{{URI: "a"}, {URI: "b"}, {URI: "a"}, {URI: "c"}}
+	meta := []TrackMeta{{QueuedBy: "1"}, {QueuedBy: "2"}, {QueuedBy: "3"}, {QueuedBy: "4"}}
+	result, err := metapl.InsertWithMetaResult(-1, tracks, meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := metapl.RemoveURIs("a", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 2 || removed[0].Position != 0 || removed[1].Position != 2 || removed[1].Track.URI != "a" {
+		t.Fatalf("Unexpected removed tracks: %v", removed)
+	}
+
+	// The ID of "c" remains valid even though its position changed.
+	removed, err = metapl.RemoveIDs(result.IDs[3], result.IDs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 1 || removed[0].Position != 1 || removed[0].Track.URI != "c" {
+		t.Fatalf("Unexpected removed tracks: %v", removed)
+	}
+	plTracks, _ := metapl.Tracks()
+	plMeta, _ := metapl.Meta()
+	if len(plTracks) != 1 || plTracks[0].URI != "b" || plMeta[0].QueuedBy != "2" {
+		t.Fatalf("Unexpected playlist: %v %v", plTracks, plMeta)
+	}
+
+	plain := PlaylistMetaKeeper{Playlist: &DummyPlaylist{}}
+	if _, err := plain.RemoveIDs("1"); err == nil {
+		t.Fatalf("Removing by ID should fail for playlists without IDs")
+	}
+}
@@ -0,0 +1,39 @@
+package mpd
+
+import (
+	"errors"
+	"io"
+	"net"
+	"syscall"
+
+	"github.com/polyfloyd/trollibox/src/player"
+)
+
+// unavailableReason classifies an error that occurred while connecting to or
+// communicating with MPD as one of the player.Unavailable* reasons.
+func unavailableReason(err error) string {
+	if err == nil {
+		return ""
+	}
+	switch ackCode(err.Error()) {
+	case ackErrorPassword, ackErrorPermission:
+		return player.UnavailableAuthFailed
+	}
+
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return player.UnavailableConnectionRefused
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE):
+		return player.UnavailableDisconnected
+	case errors.As(err, &dnsErr),
+		errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH),
+		errors.Is(err, syscall.ENOENT):
+		return player.UnavailableUnreachable
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return player.UnavailableTimeout
+	}
+	return player.UnavailableUnknown
+}
@@ -0,0 +1,81 @@
+package mpd
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/polyfloyd/trollibox/src/player"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestUnavailableReason(t *testing.T) {
+	refused, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refused.Close()
+	_, refusedErr := net.Dial("tcp", refused.Addr().String())
+
+	cases := []struct {
+		err    error
+		reason string
+	}{
+		{nil, ""},
+		{refusedErr, player.UnavailableConnectionRefused},
+		{fmt.Errorf("ACK [3@0] {password} incorrect password"), player.UnavailableAuthFailed},
+		{fmt.Errorf("ACK [4@0] {play} you don't have permission for \"play\""), player.UnavailableAuthFailed},
+		{io.EOF, player.UnavailableDisconnected},
+		{&net.OpError{Op: "read", Net: "tcp", Err: timeoutError{}}, player.UnavailableTimeout},
+		{&net.DNSError{Err: "no such host", Name: "mpd.invalid"}, player.UnavailableUnreachable},
+		{fmt.Errorf("ACK [50@0] {partition} No such partition"), player.UnavailableUnknown},
+	}
+	for _, c := range cases {
+		if reason := unavailableReason(c.err); reason != c.reason {
+			t.Errorf("Unexpected reason for %v: %q, expected %q", c.err, reason, c.reason)
+		}
+	}
+}
+
+func TestEventLoopAuthFailed(t *testing.T) {
+	lis := fakeMPD(t, func(cmd string) ([]string, error) {
+		if strings.HasPrefix(cmd, "password") {
+			return nil, fmt.Errorf("ACK [3@0] {password} incorrect password")
+		}
+		return nil, nil
+	})
+	defer lis.Close()
+
+	for _, partition := range []string{"", "kitchen"} {
+		pl := &Player{playerState: &playerState{
+			network:     "tcp",
+			address:     lis.Addr().String(),
+			passwd:      "wrong",
+			partition:   partition,
+			closed:      make(chan struct{}),
+			resubscribe: make(chan struct{}, 1),
+		}}
+		events := pl.Listen()
+		go pl.eventLoop()
+
+		select {
+		case event := <-events:
+			ev, ok := event.(player.AvailabilityEvent)
+			if !ok || ev.Available || ev.Reason != player.UnavailableAuthFailed {
+				t.Fatalf("Unexpected event for partition %q: %#v", partition, event)
+			}
+		case <-time.After(time.Second * 5):
+			t.Fatalf("No availability event for partition %q", partition)
+		}
+		close(pl.closed)
+		pl.Unlisten(events)
+	}
+}
@@ -1,10 +1,12 @@
 package mpd
 
 import (
+	"context"
 	"encoding/base64"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net/textproto"
 	"reflect"
 	"sort"
 	"strconv"
@@ -23,6 +25,22 @@ import (
 
 const uriSchema = "mpd://"
 
+// DefaultCommandTimeout is the maximum amount of time an operation on a
+// connection to MPD may take. The connection is discarded if it is exceeded.
+const DefaultCommandTimeout = time.Second * 10
+
+// The name of the sticker in which the number of times a track was played is
+// stored.
+const playCountSticker = "playcount"
+
+// The name of the sticker in which other MPD clients commonly store the rating
+// of a track. It is only read if it is configured as a sticker tag.
+const ratingSticker = "rating"
+
+// The name of the sticker in which the number of times a track was skipped
+// before it counted as played is stored.
+const skipCountSticker = "skip-count"
+
 // Event is an event which signals a change in one of MPD's subsystems.
 type Event string
 
@@ -63,26 +81,131 @@ const (
 	MessageEvent = Event("message")
 )
 
+// DefaultSubsystems are the subsystems of MPD that are watched for changes by
+// default. These are the ones the player acts on.
+var DefaultSubsystems = []Event{
+	PlayerEvent,
+	PlaylistEvent,
+	MixerEvent,
+	UpdateEvent,
+	OutputEvent,
+	OptionsEvent,
+	StickerEvent,
+}
+
+// known reports whether the event is one of the subsystems of MPD.
+func (ev Event) known() bool {
+	switch ev {
+	case DatabaseEvent, UpdateEvent, StoredPlaylistEvent, PlaylistEvent, PlayerEvent,
+		MixerEvent, OutputEvent, OptionsEvent, PartitionEvent, StickerEvent,
+		SubscriptionEvent, MessageEvent:
+		return true
+	}
+	return false
+}
+
 // Player handles the connection to a single MPD instance.
 type Player struct {
+	*playerState
+
+	// The context the operations of the player are bound to, nil for none.
+	// See WithContext.
+	ctx context.Context
+}
+
+// playerState holds the state of a Player, which is shared with the views
+// returned by WithContext.
+type playerState struct {
 	util.Emitter
 
-	clientPool chan *mpd.Client
+	clientPool *clientPool
+	closed     chan struct{}
+	closeOnce  sync.Once
+
+	pool     player.PoolStats
+	poolLock sync.Mutex
+	// See DefaultCommandTimeout.
+	commandTimeout time.Duration
+
+	// The subsystems watched for changes, all if empty. Changing them
+	// restarts the watcher by signalling resubscribe.
+	subsystems     []Event
+	subsystemsLock sync.RWMutex
+	resubscribe    chan struct{}
 
 	network, address string
 	passwd           string
+	// The name of the partition to operate on, empty for the default.
+	partition            string
+	partitionUnsupported int32
+	// The directory of the MPD database containing the tracks of the library,
+	// empty for the entire database.
+	libraryRoot string
+
+	// The names of the stickers that are loaded into the tags of tracks.
+	stickerTags []string
+
+	// Rules to fill in missing metadata of tracks.
+	fallbackRules     []library.FallbackRule
+	fallbackRulesLock sync.RWMutex
 
 	cachedLibrary *cache.Cache
 	playlist      player.PlaylistMetaKeeper
 
+	// The metadata of the queues saved during the lifetime of the player.
+	savedMeta     map[string]savedQueueMeta
+	savedMetaLock sync.Mutex
+
+	plays      playTracker
+	playsTimer *time.Timer
+	playsLock  sync.Mutex
+
+	// The interval at which the title of a playing stream is refreshed, see
+	// DefaultStreamRefresh.
+	streamRefresh time.Duration
+	stream        streamTitleTracker
+	streamTimer   *time.Timer
+	streamLock    sync.Mutex
+
+	// Plays a stream again if it stops because of an error, see
+	// SetStreamReconnect.
+	reconnect      streamReconnector
+	reconnectDelay time.Duration
+	reconnectTimer *time.Timer
+	reconnectLock  sync.Mutex
+
+	// The values of the sticker tags as they were last loaded into the
+	// library, see stickerTagsChanged.
+	stickerSnapshot     stickerTagValues
+	stickerSnapshotLock sync.Mutex
+
 	// Sometimes, the volume returned by MPD is invalid, so we have to take
 	// care of that ourselves.
 	lastVolumeLock sync.Mutex
 	lastVolume     int
+	// The volume to set when MPD (re)starts, 0 to leave the volume as is.
+	defaultVolume int
+	// The moment the MPD server was started, used to tell a restart of MPD
+	// apart from a reconnect.
+	serverStarted time.Time
+}
+
+// Options holds the optional settings of a connection to MPD. The zero value
+// controls the default partition and includes the entire database.
+type Options struct {
+	// If not empty, the player controls the named partition, which is
+	// created if it does not exist. This allows a single MPD server to back
+	// multiple players.
+	Partition string
+	// The library is limited to the tracks below this directory of the MPD
+	// database, an empty root or "/" includes all tracks.
+	LibraryRoot string
+	// The stickers with these names are loaded into the Tags of each track.
+	StickerTags []string
 }
 
 // Connect connects to MPD with an optional username and password.
-func Connect(network, address string, mpdPassword *string) (*Player, error) {
+func Connect(network, address string, mpdPassword *string, opts Options) (*Player, error) {
 	var passwd string
 	if mpdPassword != nil {
 		passwd = *mpdPassword
@@ -90,28 +213,44 @@ func Connect(network, address string, mpdPassword *string) (*Player, error) {
 		passwd = ""
 	}
 
-	player := &Player{
+	player := &Player{playerState: &playerState{
 		Emitter: util.Emitter{Release: time.Millisecond * 100},
 		network: network,
 		address: address,
 		passwd:  passwd,
 
+		partition:   opts.Partition,
+		libraryRoot: strings.Trim(opts.LibraryRoot, "/"),
+		stickerTags: opts.StickerTags,
+		savedMeta:   map[string]savedQueueMeta{},
+
+		fallbackRules: library.DefaultFallbackRules,
+
 		// NOTE: MPD supports up to 10 concurrent connections by default. When
 		// this number is reached and ANYTHING tries to connect, the connection
 		// rudely closed.
-		clientPool: make(chan *mpd.Client, 6),
-	}
+		clientPool:     newClientPool(6),
+		closed:         make(chan struct{}),
+		commandTimeout: DefaultCommandTimeout,
+		streamRefresh:  DefaultStreamRefresh,
+		subsystems:     DefaultSubsystems,
+		resubscribe:    make(chan struct{}, 1),
+		plays: playTracker{
+			ratio: DefaultPlayCountRatio,
+			max:   DefaultPlayCountMax,
+		},
+	}}
 	player.playlist.Playlist = mpdPlaylist{player: player}
 	player.cachedLibrary = cache.NewCache(player)
 
 	// Test the connection.
-	client, err := mpd.DialAuthenticated(player.network, player.address, player.passwd)
+	client, err := player.dial()
 	if err != nil {
 		return nil, err
 	}
 	client.Close()
-	for i := 0; i < cap(player.clientPool); i++ {
-		player.clientPool <- nil
+	if len(opts.StickerTags) > 0 {
+		log.Infof("%v: Loading stickers as track tags: %s", player, strings.Join(opts.StickerTags, ", "))
 	}
 
 	go player.eventLoop()
@@ -119,43 +258,286 @@ func Connect(network, address string, mpdPassword *string) (*Player, error) {
 	return player, nil
 }
 
+// Close stops all background processing and closes the connections to MPD.
+// All listeners to the player's events are closed.
+func (pl *Player) Close() error {
+	pl.closeOnce.Do(func() {
+		close(pl.closed)
+		pl.playsLock.Lock()
+		if pl.playsTimer != nil {
+			pl.playsTimer.Stop()
+		}
+		pl.playsLock.Unlock()
+		pl.streamLock.Lock()
+		if pl.streamTimer != nil {
+			pl.streamTimer.Stop()
+		}
+		pl.streamLock.Unlock()
+		pl.reconnectLock.Lock()
+		if pl.reconnectTimer != nil {
+			pl.reconnectTimer.Stop()
+		}
+		pl.reconnectLock.Unlock()
+		// Wait for all clients to be returned to the pool.
+		for _, client := range pl.clientPool.Drain() {
+			client.Close()
+		}
+		pl.updatePoolStats(func(stats *player.PoolStats) {
+			stats.Alive = 0
+			stats.EventsConnected = false
+		})
+		pl.Emitter.Close()
+	})
+	return nil
+}
+
 func (pl *Player) withMpd(fn func(*mpd.Client) error) error {
-	client := <-pl.clientPool
-	if client == nil || client.Ping() != nil {
+	return pl.withMpdTimeout(pl.commandTimeout, fn)
+}
+
+// withMpdProgress is like withMpd, but for operations that issue many
+// commands, like inserting thousands of tracks. The operation reports each
+// step it completes through the progress function, which restarts the
+// timeout. So the timeout limits the time between steps instead of the
+// duration of the whole operation.
+func (pl *Player) withMpdProgress(fn func(mpdc *mpd.Client, progress func()) error) error {
+	return pl.withMpdDeadline(pl.commandTimeout, fn)
+}
+
+// withMpdTimeout runs fn with a pooled connection to MPD. If fn does not
+// complete within the timeout, the connection is discarded and an error is
+// returned. A zero timeout waits indefinitely, which is meant for operations
+// whose duration depends on the size of the library.
+func (pl *Player) withMpdTimeout(timeout time.Duration, fn func(*mpd.Client) error) error {
+	return pl.withMpdDeadline(timeout, func(mpdc *mpd.Client, _ func()) error {
+		return fn(mpdc)
+	})
+}
+
+// withMpdDeadline runs fn with a pooled connection to MPD. The timeout is
+// restarted each time fn reports progress.
+//
+// If the player is bound to a context, the operation is aborted once the
+// context is done.
+func (pl *Player) withMpdDeadline(timeout time.Duration, fn func(mpdc *mpd.Client, progress func()) error) error {
+	select {
+	case <-pl.closed:
+		return fmt.Errorf("%v is closed", pl)
+	default:
+	}
+	ctx := pl.context()
+	pl.updatePoolStats(func(stats *player.PoolStats) { stats.Waiting++ })
+	client, err := pl.clientPool.GetContext(ctx)
+	pl.updatePoolStats(func(stats *player.PoolStats) { stats.Waiting-- })
+	if err != nil {
+		return err
+	}
+	pl.updatePoolStats(func(stats *player.PoolStats) { stats.CheckedOut++ })
+	defer pl.updatePoolStats(func(stats *player.PoolStats) { stats.CheckedOut-- })
+
+	type result struct {
+		client *mpd.Client
+		err    error
+	}
+	resc := make(chan result, 1)
+	progressc := make(chan struct{}, 1)
+	progress := func() {
+		select {
+		case progressc <- struct{}{}:
+		default:
+		}
+	}
+	var co checkout
+	go func() {
+		client, err := pl.healthyClient(client)
+		if err != nil {
+			resc <- result{err: err}
+			return
+		}
+		if !co.set(client) {
+			// The operation was aborted while connecting.
+			client.Close()
+			pl.updatePoolStats(func(stats *player.PoolStats) { stats.Alive-- })
+			return
+		}
+		resc <- result{client: client, err: fn(client, progress)}
+	}()
+
+	var timer *time.Timer
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		timer = time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+	var abortErr error
+	for abortErr == nil {
+		select {
+		case res := <-resc:
+			pl.clientPool.Put(res.client)
+			return res.err
+		case <-progressc:
+			if timer != nil {
+				if !timer.Stop() {
+					<-timer.C
+				}
+				timer.Reset(timeout)
+			}
+		case <-deadline:
+			pl.updatePoolStats(func(stats *player.PoolStats) { stats.Timeouts++ })
+			log.Warnf("%v: Operation did not complete within %v, discarding the connection", pl, timeout)
+			abortErr = fmt.Errorf("MPD did not respond within %v", timeout)
+		case <-ctx.Done():
+			abortErr = ctx.Err()
+		}
+	}
+
+	// Closing the connection makes the command that is in progress return,
+	// so fn does not linger. This also takes care of connections that are
+	// half-open. A replacement is dialed by the next operation so the pool
+	// does not shrink.
+	if co.abort() {
+		pl.updatePoolStats(func(stats *player.PoolStats) { stats.Alive-- })
+	}
+	pl.clientPool.Put(nil)
+	return abortErr
+}
+
+// context returns the context the player is bound to.
+func (pl *Player) context() context.Context {
+	if pl.ctx == nil {
+		return context.Background()
+	}
+	return pl.ctx
+}
+
+// WithContext implements the player.ContextBinder interface. Commands that are
+// in progress once the context is done are aborted by closing their
+// connection to MPD.
+func (pl *Player) WithContext(ctx context.Context) player.Player {
+	return &Player{playerState: pl.playerState, ctx: ctx}
+}
+
+// A checkout is the connection used by an operation, which may be aborted by
+// another goroutine.
+type checkout struct {
+	lock    sync.Mutex
+	client  *mpd.Client
+	aborted bool
+}
+
+// set records the client the operation is using. False is returned if the
+// operation has already been aborted.
+func (co *checkout) set(client *mpd.Client) bool {
+	co.lock.Lock()
+	defer co.lock.Unlock()
+	co.client = client
+	return !co.aborted
+}
+
+// abort closes the client of the operation, if any, which is reported.
+func (co *checkout) abort() bool {
+	co.lock.Lock()
+	defer co.lock.Unlock()
+	co.aborted = true
+	if co.client == nil {
+		return false
+	}
+	co.client.Close()
+	return true
+}
+
+// healthyClient checks whether a pooled client is still connected and dials a
+// new connection if it is not or if the client is nil.
+func (pl *Player) healthyClient(client *mpd.Client) (*mpd.Client, error) {
+	if client != nil {
+		if err := client.Ping(); err != nil {
+			client.Close()
+			client = nil
+			pl.updatePoolStats(func(stats *player.PoolStats) {
+				stats.Alive--
+				stats.LastPingError = err.Error()
+				stats.LastPingErrorTime = time.Now()
+			})
+		}
+	}
+	if client == nil {
 		var err error
-		client, err = mpd.DialAuthenticated(pl.network, pl.address, pl.passwd)
+		client, err = pl.dial()
 		if err != nil {
-			pl.clientPool <- nil
-			return fmt.Errorf("error connecting to MPD: %v", err)
+			return nil, fmt.Errorf("error connecting to MPD: %v", err)
 		}
+		pl.updatePoolStats(func(stats *player.PoolStats) { stats.Alive++ })
 	}
+	return client, nil
+}
 
-	defer func() { pl.clientPool <- client }()
-	return fn(client)
+func (pl *Player) updatePoolStats(fn func(stats *player.PoolStats)) {
+	pl.poolLock.Lock()
+	defer pl.poolLock.Unlock()
+	fn(&pl.pool)
+}
+
+// PoolStats implements the player.PoolReporter interface.
+//
+// It does not communicate with MPD, so it remains responsive when all
+// connections are stuck.
+func (pl *Player) PoolStats() player.PoolStats {
+	pl.poolLock.Lock()
+	defer pl.poolLock.Unlock()
+	stats := pl.pool
+	stats.Size = pl.clientPool.Size()
+	return stats
 }
 
 func (pl *Player) eventLoop() {
+	// The reason MPD is unavailable, so repeated failures to reconnect for
+	// the same reason are only reported once.
+	reason := ""
 	for {
-		watcher, err := mpd.NewWatcher(pl.network, pl.address, pl.passwd)
+		watcher, err := pl.newWatcher()
 		if err != nil {
 			log.Debugf("Could not start watcher: %v", err)
+			if r := unavailableReason(err); r != reason {
+				reason = r
+				pl.Emit(player.AvailabilityEvent{Available: false, Reason: reason})
+			}
 			// Limit the number of reconnection attempts to one per second.
-			time.Sleep(time.Second)
-			continue
+			select {
+			case <-time.After(time.Second):
+				continue
+			case <-pl.closed:
+				return
+			}
 		}
-		defer watcher.Close()
+		pl.updatePoolStats(func(stats *player.PoolStats) {
+			stats.EventsConnected = true
+			stats.EventsConnectedTime = time.Now()
+		})
+		reason = ""
 		pl.Emit(player.AvailabilityEvent{Available: true})
+		if err := pl.applyDefaultVolume(false); err != nil {
+			log.Errorf("%v: Could not apply the default volume: %v", pl, err)
+		}
 
 	loop:
 		for {
 			select {
 			case event := <-watcher.Event:
 				pl.Emit(Event(event))
-			case <-watcher.Error:
-				pl.Emit(player.AvailabilityEvent{Available: false})
+			case err := <-watcher.Error:
+				pl.updatePoolStats(func(stats *player.PoolStats) { stats.EventsConnected = false })
+				reason = unavailableReason(err)
+				pl.Emit(player.AvailabilityEvent{Available: false, Reason: reason})
+				break loop
+			case <-pl.resubscribe:
 				break loop
+			case <-pl.closed:
+				watcher.Close()
+				return
 			}
 		}
+		watcher.Close()
 	}
 }
 
@@ -175,6 +557,7 @@ func (pl *Player) mainLoop() {
 		}
 	}
 
+	lastState := player.PlayStateInvalid
 	for event := range listener {
 		mpdEvent, ok := event.(Event)
 		if !ok {
@@ -185,12 +568,20 @@ func (pl *Player) mainLoop() {
 			if state, err := pl.State(); err != nil {
 				log.Error(err)
 			} else {
+				if lastState == player.PlayStatePlaying && state == player.PlayStateStopped {
+					if exhausted, err := pl.queueExhausted(); err != nil {
+						log.Error(err)
+					} else if exhausted {
+						pl.Emit(player.QueueExhaustedEvent{})
+					}
+				}
+				lastState = state
 				dedupEmit(player.PlayStateEvent{State: state}, state)
 			}
-			if time, err := pl.Time(); err != nil {
+			if offset, err := pl.Time(); err != nil {
 				log.Error(err)
 			} else {
-				dedupEmit(player.TimeEvent{Time: time}, time)
+				dedupEmit(player.TimeEvent{Time: offset, At: time.Now()}, offset)
 			}
 			fallthrough
 
@@ -200,6 +591,9 @@ func (pl *Player) mainLoop() {
 			} else {
 				pl.Emit(player.PlaylistEvent{Index: index})
 			}
+			if err := pl.trackPlay(); err != nil {
+				log.Error(err)
+			}
 
 		case MixerEvent:
 			if volume, err := pl.Volume(); err != nil {
@@ -208,6 +602,19 @@ func (pl *Player) mainLoop() {
 				dedupEmit(player.VolumeEvent{Volume: volume}, volume)
 			}
 
+		case StickerEvent:
+			// Stickers are only part of the library if they are loaded as
+			// tags. Play counts written by the player itself must not cause
+			// the library to be reloaded every time a track is played.
+			if len(pl.stickerTags) == 0 {
+				break
+			}
+			if changed, err := pl.stickerTagsChanged(); err != nil {
+				log.Error(err)
+			} else if changed {
+				pl.Emit(library.UpdateEvent{})
+			}
+
 		case UpdateEvent:
 			err := pl.withMpd(func(mpdc *mpd.Client) error {
 				status, err := mpdc.Status()
@@ -226,6 +633,383 @@ func (pl *Player) mainLoop() {
 	}
 }
 
+// queueExhausted reports whether playback has stopped because it ran off the
+// end of the playlist.
+//
+// MPD resets the current song when this happens, while stopping playback
+// explicitly retains it.
+func (pl *Player) queueExhausted() (bool, error) {
+	var exhausted bool
+	err := pl.withMpd(func(mpdc *mpd.Client) error {
+		status, err := mpdc.Status()
+		if err != nil {
+			return err
+		}
+		_, hasSong := status["song"]
+		length, _ := statusAttrInt(status, "playlistlength")
+		exhausted = status["state"] == "stop" && !hasSong && length > 0
+		return nil
+	})
+	return exhausted, err
+}
+
+// SetPlayCountThreshold configures when a track is counted as played. This is
+// the case once it has been listened to for the specified part of its
+// duration or for max, whichever is less.
+func (pl *Player) SetPlayCountThreshold(ratio float64, max time.Duration) {
+	pl.playsLock.Lock()
+	defer pl.playsLock.Unlock()
+	pl.plays.ratio, pl.plays.max = ratio, max
+}
+
+// SetSubsystems sets the subsystems of MPD that are watched for changes. An
+// empty list watches all subsystems. See DefaultSubsystems.
+func (pl *Player) SetSubsystems(subsystems []Event) error {
+	for _, name := range subsystems {
+		if !name.known() {
+			return fmt.Errorf("unknown MPD subsystem: %q", name)
+		}
+	}
+	pl.subsystemsLock.Lock()
+	pl.subsystems = append([]Event(nil), subsystems...)
+	pl.subsystemsLock.Unlock()
+	select {
+	case pl.resubscribe <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (pl *Player) subsystemNames() []string {
+	pl.subsystemsLock.RLock()
+	defer pl.subsystemsLock.RUnlock()
+	names := make([]string, len(pl.subsystems))
+	for i, name := range pl.subsystems {
+		names[i] = string(name)
+	}
+	return names
+}
+
+// SetStreamRefresh sets the interval at which the title of a playing stream
+// is refreshed. Zero disables refreshing. See DefaultStreamRefresh.
+func (pl *Player) SetStreamRefresh(interval time.Duration) {
+	pl.streamLock.Lock()
+	defer pl.streamLock.Unlock()
+	pl.streamRefresh = interval
+}
+
+// SetLibraryDebounce sets the quiet period after a database update of MPD
+// before the library is reloaded. This prevents redundant reloads while MPD is
+// scanning a large collection. See cache.Cache.SetDebounce.
+func (pl *Player) SetLibraryDebounce(quiet time.Duration) {
+	pl.cachedLibrary.SetDebounce(quiet)
+}
+
+// SetFallbackRules configures how missing metadata of tracks is filled in. The
+// rules replace library.DefaultFallbackRules, which are used if this is never
+// called.
+func (pl *Player) SetFallbackRules(rules []library.FallbackRule) error {
+	for _, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+	}
+	pl.fallbackRulesLock.Lock()
+	defer pl.fallbackRulesLock.Unlock()
+	pl.fallbackRules = rules
+	return nil
+}
+
+// trackPlay updates the listening time of the current entry in the queue and
+// increments the play count of its track once the threshold is reached.
+//
+// Entries are identified by their song ID instead of their URI, so a track
+// that is queued twice in a row is counted twice.
+func (pl *Player) trackPlay() error {
+	return pl.withMpd(func(mpdc *mpd.Client) error {
+		status, err := mpdc.Status()
+		if err != nil {
+			return err
+		}
+		var song mpd.Attrs
+		if status["songid"] != "" {
+			if song, err = mpdc.CurrentSong(); err != nil {
+				return err
+			}
+		}
+		durationf, _ := strconv.ParseFloat(status["duration"], 64)
+		if durationf == 0 {
+			// Older versions of MPD only report the duration of songs.
+			durationf, _ = strconv.ParseFloat(song["Time"], 64)
+		}
+		duration := time.Duration(durationf * float64(time.Second))
+
+		playing := status["state"] == "play"
+		pl.playsLock.Lock()
+		counted, wait := pl.plays.update(time.Now(), playing, status["songid"], song["file"], duration)
+		begun := pl.plays.begin(playing)
+		skipped := pl.plays.skipped()
+		started := pl.plays.started
+		if pl.playsTimer != nil {
+			pl.playsTimer.Stop()
+		}
+		if wait > 0 {
+			// Revisit once the threshold is expected to be reached.
+			pl.playsTimer = time.AfterFunc(wait, func() {
+				if err := pl.trackPlay(); err != nil {
+					log.Error(err)
+				}
+			})
+		}
+		pl.playsLock.Unlock()
+
+		if begun {
+			pl.Emit(player.TrackStartedEvent{URI: mpdToURI(song["file"])})
+		}
+		pl.trackStreamTitle(playing, status["songid"], song)
+		if err := pl.trackStreamError(mpdc, status, song); err != nil {
+			return err
+		}
+		if hasStickers(skipped) {
+			if err := pl.incrementSticker(mpdc, skipped, skipCountSticker); err != nil {
+				return err
+			}
+		}
+		if !counted {
+			return nil
+		}
+		pl.Emit(player.PlayEvent{URI: mpdToURI(song["file"]), Started: started})
+		if !hasStickers(song["file"]) {
+			return nil
+		}
+		return pl.incrementSticker(mpdc, song["file"], playCountSticker)
+	})
+}
+
+// hasStickers reports whether stickers can be set on the file. This is only
+// the case for tracks in the database.
+func hasStickers(file string) bool {
+	return file != "" && !strings.Contains(file, "://")
+}
+
+// trackStreamTitle emits a TrackMetadataEvent if the title of the playing
+// stream has changed. While a stream is playing, the title is refreshed
+// periodically, as MPD does not always report these changes.
+func (pl *Player) trackStreamTitle(playing bool, songID string, song mpd.Attrs) {
+	uri := mpdToURI(song["file"])
+	stream := playing && library.IsStreamURI(uri)
+	if !stream {
+		songID = ""
+	}
+
+	pl.streamLock.Lock()
+	changed := pl.stream.update(songID, song["Title"])
+	if pl.streamTimer != nil {
+		pl.streamTimer.Stop()
+		pl.streamTimer = nil
+	}
+	if stream && pl.streamRefresh > 0 {
+		pl.streamTimer = time.AfterFunc(pl.streamRefresh, func() {
+			if err := pl.trackPlay(); err != nil {
+				log.Error(err)
+			}
+		})
+	}
+	pl.streamLock.Unlock()
+
+	if changed {
+		pl.Emit(player.TrackMetadataEvent{URI: uri, Title: song["Title"]})
+	}
+}
+
+// incrementSticker increments the named numeric sticker of the file.
+func (pl *Player) incrementSticker(mpdc *mpd.Client, file, name string) error {
+	count := 0
+	if sticker, err := mpdc.StickerGet(file, name); err == nil && sticker != nil {
+		count, _ = strconv.Atoi(sticker.Value)
+	}
+	value := strconv.Itoa(count + 1)
+	if err := mpdc.StickerSet(file, name, value); err != nil {
+		return err
+	}
+	// The write is recorded in the snapshot, so the change it causes is not
+	// mistaken for one made by someone else.
+	pl.stickerSnapshotLock.Lock()
+	if values, ok := pl.stickerSnapshot[name]; ok {
+		values[file] = value
+	}
+	pl.stickerSnapshotLock.Unlock()
+	return nil
+}
+
+// setStickerSnapshot records the values of the sticker tags that have been
+// loaded into the library.
+func (pl *Player) setStickerSnapshot(tags stickerTagValues) {
+	pl.stickerSnapshotLock.Lock()
+	defer pl.stickerSnapshotLock.Unlock()
+	pl.stickerSnapshot = make(stickerTagValues, len(tags))
+	for name, values := range tags {
+		snapshot := make(map[string]string, len(values))
+		for file, value := range values {
+			snapshot[file] = value
+		}
+		pl.stickerSnapshot[name] = snapshot
+	}
+}
+
+// stickerTagsChanged looks up the sticker tags and reports whether they differ
+// from the ones that were last seen.
+func (pl *Player) stickerTagsChanged() (bool, error) {
+	var tags stickerTagValues
+	err := pl.withMpd(func(mpdc *mpd.Client) error {
+		var err error
+		tags, err = pl.findStickerTags(mpdc)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	pl.stickerSnapshotLock.Lock()
+	defer pl.stickerSnapshotLock.Unlock()
+	if reflect.DeepEqual(pl.stickerSnapshot, tags) {
+		return false, nil
+	}
+	pl.stickerSnapshot = tags
+	return true, nil
+}
+
+// Supports implements the player.URISupporter interface. MPD plays tracks from
+// its database and network streams.
+func (pl *Player) Supports(uri string) bool {
+	return strings.HasPrefix(uri, uriSchema) || library.IsStreamURI(uri)
+}
+
+// SearchServerSide implements the player.ServerSearcher interface.
+//
+// Only tags that are known to MPD are searched. Fields that Trollibox fills in
+// itself, like the artist and title interpolated from the file name, are not
+// taken into account.
+func (pl *Player) SearchServerSide(tags map[string]string) ([]library.Track, error) {
+	args := make([]string, 0, len(tags)*2+2)
+	for tag, value := range tags {
+		args = append(args, tag, value)
+	}
+	if pl.libraryRoot != "" {
+		args = append(args, "base", pl.libraryRoot)
+	}
+	var uris []string
+	err := pl.withMpd(func(mpdc *mpd.Client) error {
+		songs, err := mpdc.Search(args...)
+		if err != nil {
+			return err
+		}
+		uris = make([]string, 0, len(songs))
+		for _, song := range songs {
+			if file, ok := song["file"]; ok {
+				uris = append(uris, mpdToURI(file))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// The full track information is taken from the cache to keep it
+	// consistent with the rest of the library.
+	return pl.cachedLibrary.TrackInfo(uris...)
+}
+
+// StickerFind returns the values of the named sticker of all tracks in the
+// library that have it, by track URI. This takes a single command, which is
+// much cheaper than getting the sticker of each track.
+func (pl *Player) StickerFind(name string) (map[string]string, error) {
+	var values map[string]string
+	err := pl.withMpd(func(mpdc *mpd.Client) error {
+		files, stickers, err := mpdc.StickerFind(pl.libraryRoot, name)
+		if err != nil {
+			return err
+		}
+		values = make(map[string]string, len(files))
+		for i, file := range files {
+			values[mpdToURI(file)] = stickers[i].Value
+		}
+		return nil
+	})
+	return values, err
+}
+
+// PlayCounts implements the player.PlayCounter interface.
+func (pl *Player) PlayCounts() (map[string]int, error) {
+	return pl.stickerCounts(playCountSticker)
+}
+
+// SkipCounts implements the player.SkipCounter interface.
+func (pl *Player) SkipCounts() (map[string]int, error) {
+	return pl.stickerCounts(skipCountSticker)
+}
+
+// TrackPriorities implements the player.Prioritizer interface.
+func (pl *Player) TrackPriorities() ([]int, error) {
+	var priorities []int
+	err := pl.withMpd(func(mpdc *mpd.Client) error {
+		songs, err := mpdc.PlaylistInfo(-1, -1)
+		if err != nil {
+			return err
+		}
+		priorities = make([]int, len(songs))
+		for i, song := range songs {
+			// MPD omits the priority of entries that have the default.
+			priorities[i], _ = statusAttrInt(song, "Prio")
+		}
+		return nil
+	})
+	return priorities, err
+}
+
+// SetTrackPriority implements the player.Prioritizer interface. The priority
+// only affects the order of playback while random mode of MPD is enabled.
+//
+// The priority is set on the ID of the entry, so it sticks to the entry even if
+// the playlist changes while the command is sent.
+func (pl *Player) SetTrackPriority(pos, prio int) error {
+	if prio < 0 || prio > player.MaxTrackPriority {
+		return fmt.Errorf("the priority must be between 0 and %d, got %d", player.MaxTrackPriority, prio)
+	}
+	if pos < 0 {
+		return fmt.Errorf("invalid playlist position: %d", pos)
+	}
+	return pl.withMpd(func(mpdc *mpd.Client) error {
+		songs, err := mpdc.PlaylistInfo(pos, -1)
+		if err != nil {
+			return err
+		}
+		if len(songs) == 0 {
+			return fmt.Errorf("no entry at playlist position %d", pos)
+		}
+		songID, err := strconv.Atoi(songs[0]["Id"])
+		if err != nil {
+			return err
+		}
+		return mpdc.Command("prioid %d %d", prio, songID).OK()
+	})
+}
+
+// stickerCounts returns the positive values of the named numeric sticker by
+// track URI.
+func (pl *Player) stickerCounts(name string) (map[string]int, error) {
+	values, err := pl.StickerFind(name)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int, len(values))
+	for uri, value := range values {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			counts[uri] = n
+		}
+	}
+	return counts, nil
+}
+
 // Library implements the player.Player interface.
 func (pl *Player) Library() library.Library {
 	return pl.cachedLibrary
@@ -234,15 +1018,49 @@ func (pl *Player) Library() library.Library {
 // Tracks implements the library.Library interface.
 func (pl *Player) Tracks() ([]library.Track, error) {
 	var tracks []library.Track
-	err := pl.withMpd(func(mpdc *mpd.Client) error {
+	// Loading large libraries may take a long time, so no timeout is set.
+	err := pl.withMpdTimeout(0, func(mpdc *mpd.Client) error {
 		// The MPD listallinfo command breaks for large libraries. So we'll run
 		// individual queries for each file in the root to try to get around
 		// this weird limitiation.
-		filesInRoot, err := mpdc.ListInfo("/")
+		root := pl.libraryRoot
+		if root == "" {
+			root = "/"
+		}
+		filesInRoot, err := mpdc.ListInfo(root)
 		if err != nil {
 			return fmt.Errorf("error root MPD songs: %v", err)
 		}
-		var songs []mpd.Attrs
+		// The number of songs in the database is an upper bound of the
+		// number of tracks in the library.
+		if stats, err := mpdc.Stats(); err == nil {
+			if n, ok := statusAttrInt(stats, "songs"); ok {
+				tracks = make([]library.Track, 0, n)
+			}
+		}
+
+		// The sticker tags are fetched up front, so they can be used by the
+		// fallback rules and no commands are sent while listing the songs.
+		tags, err := pl.findStickerTags(mpdc)
+		if err != nil {
+			return err
+		}
+		pl.setStickerSnapshot(tags)
+		art, err := findArt(mpdc, pl.libraryRoot)
+		if err != nil {
+			return err
+		}
+
+		// gompd only retains the last value of tags that occur multiple
+		// times, so a raw connection is used to list the songs.
+		text, err := pl.dialText()
+		if err != nil {
+			return err
+		}
+		defer text.Close()
+		// Songs are converted as they are read, so the listing does not have
+		// to be held in memory as a whole.
+		attrs := mpd.Attrs{}
 		for _, rootFile := range filesInRoot {
 			var filename string
 			if f, ok := rootFile["file"]; ok {
@@ -252,28 +1070,156 @@ func (pl *Player) Tracks() ([]library.Track, error) {
 			} else {
 				continue
 			}
-			ls, err := mpdc.ListAllInfo(filename)
+			err := listAllInfo(text, filename, func(song songAttrs) error {
+				song.first(attrs)
+				tracks = append(tracks, library.Track{})
+				track := &tracks[len(tracks)-1]
+				if err := pl.trackFromMpdSong(mpdc, &attrs, track, tags, art); err != nil {
+					return fmt.Errorf("error mapping MPD song to track: %v", err)
+				}
+				track.SetArtists(song["Artist"]...)
+				track.SetGenres(song["Genre"]...)
+				track.SetAlbumArtists(song["AlbumArtist"]...)
+				return nil
+			})
 			if err != nil {
 				return fmt.Errorf("error getting MPD songs: %v", err)
 			}
-			songs = append(songs, ls...)
 		}
-
-		numDirs := 0
-		tracks = make([]library.Track, len(songs))
-		for i, song := range songs {
-			if _, ok := song["directory"]; ok {
-				numDirs++
-			} else if err := trackFromMpdSong(mpdc, &song, &tracks[i-numDirs]); err != nil {
-				return fmt.Errorf("error mapping MPD song to track: %v", err)
-			}
-		}
-		tracks = tracks[:len(tracks)-numDirs]
 		return nil
 	})
 	return tracks, err
 }
 
+// songAttrs holds all values of the attributes of a song.
+type songAttrs map[string][]string
+
+// first stores the first value of each attribute in attrs, replacing its
+// previous contents.
+func (song songAttrs) first(attrs mpd.Attrs) {
+	for k := range attrs {
+		delete(attrs, k)
+	}
+	for k, v := range song {
+		attrs[k] = v[0]
+	}
+}
+
+// stickerTagValues holds the values of the stickers that are loaded as tags by
+// sticker name and file.
+type stickerTagValues map[string]map[string]string
+
+// findStickerTags looks up the sticker tags of all files in the library. This
+// takes one command per sticker name.
+func (pl *Player) findStickerTags(mpdc *mpd.Client) (stickerTagValues, error) {
+	tags := make(stickerTagValues, len(pl.stickerTags))
+	for _, name := range pl.stickerTags {
+		files, stickers, err := mpdc.StickerFind(pl.libraryRoot, name)
+		if err != nil {
+			return nil, fmt.Errorf("error finding sticker %q: %v", name, err)
+		}
+		values := make(map[string]string, len(files))
+		for i, file := range files {
+			values[file] = stickers[i].Value
+		}
+		tags[name] = values
+	}
+	return tags, nil
+}
+
+// findArt looks up which files in the library have art stored in stickers.
+// This takes a single command instead of one per file. If MPD has no sticker
+// database, no file has art.
+func findArt(mpdc *mpd.Client, root string) (map[string]bool, error) {
+	files, stickers, err := mpdc.StickerFind(root, "image-nchunks")
+	if err != nil {
+		if ackCode(err.Error()) != -1 {
+			return map[string]bool{}, nil
+		}
+		return nil, fmt.Errorf("error finding art: %v", err)
+	}
+	art := make(map[string]bool, len(files))
+	for i, file := range files {
+		if _, err := strconv.ParseInt(stickers[i].Value, 10, 32); err == nil {
+			art[file] = true
+		}
+	}
+	return art, nil
+}
+
+// stickerTagsOf looks up the sticker tags of the specified files one by one,
+// which is cheaper than findStickerTags for a handful of files.
+func (pl *Player) stickerTagsOf(mpdc *mpd.Client, files []string) stickerTagValues {
+	tags := make(stickerTagValues, len(pl.stickerTags))
+	for _, name := range pl.stickerTags {
+		values := map[string]string{}
+		for _, file := range files {
+			if !hasStickers(file) {
+				continue
+			}
+			if stk, err := mpdc.StickerGet(file, name); err == nil && stk != nil {
+				values[file] = stk.Value
+			}
+		}
+		tags[name] = values
+	}
+	return tags
+}
+
+// listAllInfo is like mpd.Client.ListAllInfo, but retains all values of tags
+// that occur multiple times. Directories and playlists are omitted.
+//
+// Songs are passed to fn as soon as they have been read. The songAttrs are
+// reused for the next song after fn returns, so fn must not retain them,
+// although retaining the value slices is safe.
+func listAllInfo(text *textproto.Conn, uri string, fn func(songAttrs) error) error {
+	if err := text.PrintfLine("listallinfo %s", quoteArg(uri)); err != nil {
+		return err
+	}
+	song := songAttrs{}
+	inEntry := false
+	var fnErr error
+	flush := func() {
+		if inEntry && fnErr == nil {
+			fnErr = fn(song)
+		}
+		for k := range song {
+			delete(song, k)
+		}
+	}
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return err
+		}
+		if line == "OK" {
+			break
+		} else if strings.HasPrefix(line, "ACK ") {
+			return fmt.Errorf("%s", line)
+		}
+		i := strings.Index(line, ": ")
+		if i < 0 {
+			return textproto.ProtocolError("can't parse line: " + line)
+		}
+		key, value := line[:i], line[i+2:]
+		switch key {
+		case "file":
+			flush()
+			inEntry = true
+		case "directory", "playlist":
+			flush()
+			inEntry = false
+		}
+		if inEntry {
+			song[key] = append(song[key], value)
+		}
+	}
+	flush()
+	// The response is read completely even if fn fails so the connection
+	// remains usable.
+	return fnErr
+}
+
 // TrackInfo implements the library.Library interface.
 func (pl *Player) TrackInfo(identities ...string) ([]library.Track, error) {
 	currentTrackURI := ""
@@ -307,8 +1253,7 @@ func (pl *Player) TrackInfo(identities ...string) ([]library.Track, error) {
 				continue
 			}
 
-			isHTTP := strings.HasPrefix(uri, "https://") || strings.HasPrefix(uri, "http://")
-			if currentTrackURI == uri && isHTTP {
+			if currentTrackURI == uri && library.IsStreamURI(uri) {
 				song, err := mpdc.CurrentSong()
 				if err != nil {
 					return fmt.Errorf("unable to get info about %v: %v", uri, err)
@@ -318,13 +1263,21 @@ func (pl *Player) TrackInfo(identities ...string) ([]library.Track, error) {
 			}
 		}
 
+		files := make([]string, 0, len(songs))
+		for _, song := range songs {
+			if file, ok := song["file"]; ok {
+				files = append(files, file)
+			}
+		}
+		tags := pl.stickerTagsOf(mpdc, files)
+
 		numDirs := 0
 		tracks = make([]library.Track, len(songs))
 		for i, song := range songs {
 			if _, ok := song["directory"]; ok {
 				numDirs++
 			} else if song != nil {
-				if err := trackFromMpdSong(mpdc, &song, &tracks[i-numDirs]); err != nil {
+				if err := pl.trackFromMpdSong(mpdc, &song, &tracks[i-numDirs], tags, nil); err != nil {
 					return err
 				}
 			}
@@ -335,23 +1288,67 @@ func (pl *Player) TrackInfo(identities ...string) ([]library.Track, error) {
 	return tracks, err
 }
 
-// Lists implements the player.Player interface.
-func (pl *Player) Lists() (map[string]player.Playlist, error) {
-	playlists := map[string]player.Playlist{}
+// ReadReplayGain implements the player.ReplayGainReader interface.
+//
+// MPD does not report ReplayGain tags along with the other tags of songs, so
+// they are read from the files themselves with one command per track.
+func (pl *Player) ReadReplayGain(tracks []library.Track) error {
+	return pl.withMpdProgress(func(mpdc *mpd.Client, progress func()) error {
+		for i := range tracks {
+			if !strings.HasPrefix(tracks[i].URI, uriSchema) {
+				continue
+			}
+			comments, err := mpdc.ReadComments(uriToMpd(tracks[i].URI))
+			if err != nil {
+				// MPD may be unable to read the file, which should not
+				// prevent reading the others.
+				if ackCode(err.Error()) != -1 {
+					continue
+				}
+				return err
+			}
+			tracks[i].SetReplayGain(songAttr(comments, "replaygain_track_gain"), songAttr(comments, "replaygain_track_peak"))
+			progress()
+		}
+		return nil
+	})
+}
+
+// StoredPlaylists lists the names of the playlists stored by MPD.
+func (pl *Player) StoredPlaylists() ([]string, error) {
+	var names []string
 	err := pl.withMpd(func(mpdc *mpd.Client) error {
-		plAttrs, err := mpdc.ListPlaylists()
+		lists, err := mpdc.ListPlaylists()
 		if err != nil {
 			return err
 		}
-		for _, attr := range plAttrs {
-			playlists[attr["playlist"]] = userPlaylist{
-				player: pl,
-				name:   attr["playlist"],
-			}
+		names = make([]string, len(lists))
+		for i, attrs := range lists {
+			names[i] = attrs["playlist"]
 		}
 		return nil
 	})
-	return playlists, err
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Lists implements the player.Player interface.
+func (pl *Player) Lists() (map[string]player.Playlist, error) {
+	names, err := pl.StoredPlaylists()
+	if err != nil {
+		return nil, err
+	}
+	playlists := make(map[string]player.Playlist, len(names))
+	for _, name := range names {
+		playlists[name] = userPlaylist{
+			player: pl,
+			name:   name,
+		}
+	}
+	return playlists, nil
 }
 
 // Time implements the player.Player interface.
@@ -362,8 +1359,8 @@ func (pl *Player) Time() (time.Duration, error) {
 		if err != nil {
 			return err
 		}
-		timef, _ := strconv.ParseFloat(status["elapsed"], 32)
-		offset = time.Duration(timef) * time.Second
+		timef, _ := strconv.ParseFloat(status["elapsed"], 64)
+		offset = time.Duration(timef * float64(time.Second)).Round(time.Millisecond)
 		return
 	})
 	return offset, err
@@ -380,7 +1377,13 @@ func (pl *Player) setTimeWith(mpdc *mpd.Client, offset time.Duration) error {
 	if index < 0 {
 		return fmt.Errorf("error setting time: negative track index (is any playback happening?)")
 	}
-	if err := mpdc.Seek(index, int(offset/time.Second)); err != nil {
+	// MPD fails with an obscure error when seeking in streams.
+	if song, err := mpdc.CurrentSong(); err != nil {
+		return fmt.Errorf("error getting the current track for setting time: %v", err)
+	} else if library.IsStreamURI(mpdToURI(song["file"])) {
+		return player.ErrUnseekable
+	}
+	if err := mpdc.SeekPos(index, offset); err != nil {
 		return fmt.Errorf("error setting time: %v", err)
 	}
 	return nil
@@ -399,7 +1402,13 @@ func (pl *Player) SetTrackIndex(trackIndex int) error {
 		if plistLen, err := pl.Playlist().Len(); err != nil {
 			return err
 		} else if trackIndex >= plistLen {
-			return pl.setStateWith(mpdc, player.PlayStateStopped)
+			if err := pl.setStateWith(mpdc, player.PlayStateStopped); err != nil {
+				return err
+			}
+			if plistLen > 0 {
+				pl.Emit(player.QueueExhaustedEvent{})
+			}
+			return nil
 		}
 		return mpdc.Play(trackIndex)
 	})
@@ -516,18 +1525,78 @@ func (pl *Player) Volume() (int, error) {
 }
 
 // SetVolume implements the player.Player interface.
+//
+// The volume is clamped to 0-100, as MPD rejects volumes above 100 regardless
+// of the mixer in use.
 func (pl *Player) SetVolume(vol int) error {
 	return pl.withMpd(func(mpdc *mpd.Client) error {
+		pl.lastVolumeLock.Lock()
+		defer pl.lastVolumeLock.Unlock()
 		if vol > 100 {
 			vol = 100
 		} else if vol < 0 {
 			vol = 0
 		}
+		return pl.setVolumeLocked(mpdc, vol)
+	})
+}
+
+// setVolumeLocked sets the volume of MPD and records it as the last volume.
+// The lastVolumeLock must be held.
+func (pl *Player) setVolumeLocked(mpdc *mpd.Client, vol int) error {
+	if err := mpdc.SetVolume(vol); err != nil {
+		return err
+	}
+	pl.lastVolume = vol
+	return nil
+}
+
+// SetDefaultVolume configures a volume between 1 and 100 that is set right away
+// and every time MPD is restarted. Reconnecting to an MPD server that kept
+// running leaves the volume alone, so a level that was set deliberately is
+// retained. A volume of 0 disables the default.
+func (pl *Player) SetDefaultVolume(vol int) error {
+	pl.lastVolumeLock.Lock()
+	if vol < 0 || vol > 100 {
+		pl.lastVolumeLock.Unlock()
+		return fmt.Errorf("default volume out of range: %d", vol)
+	}
+	pl.defaultVolume = vol
+	pl.lastVolumeLock.Unlock()
+	return pl.applyDefaultVolume(true)
+}
+
+// applyDefaultVolume sets the default volume if MPD was restarted since it was
+// last seen, or unconditionally if force is set.
+func (pl *Player) applyDefaultVolume(force bool) error {
+	return pl.withMpd(func(mpdc *mpd.Client) error {
+		stats, err := mpdc.Stats()
+		if err != nil {
+			return err
+		}
+		uptime, _ := statusAttrInt(stats, "uptime")
+		started := time.Now().Add(-time.Duration(uptime) * time.Second)
 
 		pl.lastVolumeLock.Lock()
 		defer pl.lastVolumeLock.Unlock()
-		pl.lastVolume = vol
-		return mpdc.SetVolume(vol)
+		// The uptime has a resolution of seconds, allow for some slack.
+		restarted := !pl.serverStarted.IsZero() && started.Sub(pl.serverStarted) > time.Second*5
+		pl.serverStarted = started
+		if pl.defaultVolume == 0 || !(force || restarted) {
+			if pl.lastVolume == 0 {
+				// Seed the volume reported while playback is stopped.
+				if status, err := mpdc.Status(); err == nil {
+					if vol, ok := statusAttrInt(status, "volume"); ok && vol >= 0 {
+						pl.lastVolume = vol
+					}
+				}
+			}
+			return nil
+		}
+		if restarted {
+			log.Infof("%v: MPD was restarted, setting the volume to %d", pl, pl.defaultVolume)
+		}
+		return pl.setVolumeLocked(mpdc, pl.defaultVolume)
 	})
 }
 
@@ -538,6 +1607,9 @@ func (pl *Player) Available() bool {
 
 // Playlist implements the player.Player interface.
 func (pl *Player) Playlist() player.MetaPlaylist {
+	if pl.ctx != nil {
+		return pl.playlist.WithPlaylist(mpdPlaylist{player: pl})
+	}
 	return &pl.playlist
 }
 
@@ -560,13 +1632,38 @@ func (pl *Player) TrackArt(track string) (image io.ReadCloser, mime string) {
 			}
 			chunks = append(chunks, strings.NewReader(stkB64Data.Value))
 		}
-		image = ioutil.NopCloser(base64.NewDecoder(base64.StdEncoding, io.MultiReader(chunks...)))
-		mime = "image/jpeg"
+		image, mime = library.SniffArt(ioutil.NopCloser(base64.NewDecoder(base64.StdEncoding, io.MultiReader(chunks...))))
 		return nil
 	})
 	return
 }
 
+// ServerStats implements the player.StatsReporter interface.
+func (pl *Player) ServerStats() (player.ServerStats, error) {
+	var stats player.ServerStats
+	err := pl.withMpd(func(mpdc *mpd.Client) error {
+		attrs, err := mpdc.Stats()
+		if err != nil {
+			return err
+		}
+		seconds := func(attr string) time.Duration {
+			n, _ := statusAttrInt(attrs, attr)
+			return time.Duration(n) * time.Second
+		}
+		stats.Uptime = seconds("uptime")
+		stats.Playtime = seconds("playtime")
+		stats.DBPlaytime = seconds("db_playtime")
+		if dbUpdate, err := strconv.ParseInt(attrs["db_update"], 10, 64); err == nil {
+			stats.DBUpdate = time.Unix(dbUpdate, 0)
+		}
+		stats.Artists, _ = statusAttrInt(attrs, "artists")
+		stats.Albums, _ = statusAttrInt(attrs, "albums")
+		stats.Tracks, _ = statusAttrInt(attrs, "songs")
+		return nil
+	})
+	return stats, err
+}
+
 // Events implements the player.Player interface.
 func (pl *Player) Events() *util.Emitter {
 	return &pl.Emitter
@@ -581,22 +1678,94 @@ type mpdPlaylist struct {
 }
 
 func (plist mpdPlaylist) Insert(pos int, tracks ...library.Track) error {
-	return plist.player.withMpd(func(mpdc *mpd.Client) error {
+	_, err := plist.InsertIDs(pos, tracks...)
+	return err
+}
+
+// InsertIDs implements the player.IDPlaylist interface. The identifiers are
+// the song IDs assigned by MPD.
+func (plist mpdPlaylist) InsertIDs(pos int, tracks ...library.Track) ([]string, error) {
+	ids := make([]string, 0, len(tracks))
+	err := plist.player.withMpdProgress(func(mpdc *mpd.Client, progress func()) error {
 		if pos == -1 {
 			for _, track := range tracks {
-				if _, err := mpdc.AddID(uriToMpd(track.URI), -1); err != nil {
+				id, err := mpdc.AddID(uriToMpd(track.URI), -1)
+				if err != nil {
 					return fmt.Errorf("error appending %q: %v", track.URI, err)
 				}
+				ids = append(ids, strconv.Itoa(id))
+				progress()
 			}
 		} else {
 			for i, track := range tracks {
-				if _, err := mpdc.AddID(uriToMpd(track.URI), pos+i); err != nil {
+				id, err := mpdc.AddID(uriToMpd(track.URI), pos+i)
+				if err != nil {
 					return fmt.Errorf("error inserting %q: %v", track.URI, err)
 				}
+				ids = append(ids, strconv.Itoa(id))
+				progress()
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// RemoveIDs implements the player.IDPlaylist interface.
+func (plist mpdPlaylist) RemoveIDs(ids ...string) ([]int, error) {
+	remove := map[string]bool{}
+	for _, id := range ids {
+		remove[id] = true
+	}
+	return plist.removeSongs(func(song mpd.Attrs) bool {
+		return remove[song["Id"]]
+	})
+}
+
+// RemoveURIs implements the player.IDPlaylist interface.
+func (plist mpdPlaylist) RemoveURIs(uris ...string) ([]int, error) {
+	remove := map[string]bool{}
+	for _, uri := range uris {
+		remove[uri] = true
+	}
+	return plist.removeSongs(func(song mpd.Attrs) bool {
+		return remove[mpdToURI(song["file"])]
+	})
+}
+
+// removeSongs removes the songs in the playlist that match by their song ID.
+// The positions of the songs before any were removed are returned.
+func (plist mpdPlaylist) removeSongs(match func(song mpd.Attrs) bool) ([]int, error) {
+	var positions []int
+	err := plist.player.withMpdProgress(func(mpdc *mpd.Client, progress func()) error {
+		songs, err := mpdc.PlaylistInfo(-1, -1)
+		if err != nil {
+			return err
+		}
+		for _, song := range songs {
+			if !match(song) {
+				continue
+			}
+			pos, ok := statusAttrInt(song, "Pos")
+			if !ok {
+				continue
+			}
+			songID, err := strconv.Atoi(song["Id"])
+			if err != nil {
+				continue
+			}
+			if err := mpdc.DeleteID(songID); err != nil {
+				return fmt.Errorf("error removing song %d: %v", songID, err)
 			}
+			positions = append(positions, pos)
+			progress()
 		}
 		return nil
 	})
+	return positions, err
 }
 
 func (plist mpdPlaylist) Move(fromPos, toPos int) error {
@@ -605,8 +1774,15 @@ func (plist mpdPlaylist) Move(fromPos, toPos int) error {
 	})
 }
 
-func (plist mpdPlaylist) Remove(positions ...int) error {
+// MoveRange implements the player.RangeMover interface.
+func (plist mpdPlaylist) MoveRange(fromPos, toPos, count int) error {
 	return plist.player.withMpd(func(mpdc *mpd.Client) error {
+		return mpdc.Move(fromPos, fromPos+count, toPos)
+	})
+}
+
+func (plist mpdPlaylist) Remove(positions ...int) error {
+	return plist.player.withMpdProgress(func(mpdc *mpd.Client, progress func()) error {
 		length, ok := playlistLength(mpdc)
 		if !ok {
 			return fmt.Errorf("unable to determine playlist length")
@@ -618,11 +1794,14 @@ func (plist mpdPlaylist) Remove(positions ...int) error {
 			} else if err := mpdc.Delete(positions[i], positions[i]+1); err != nil {
 				return err
 			}
+			progress()
 		}
 		return nil
 	})
 }
 
+// Tracks implements the player.Playlist interface. Sticker tags are not
+// loaded for the entries, the library holds the full information of tracks.
 func (plist mpdPlaylist) Tracks() ([]library.Track, error) {
 	var tracks []library.Track
 	err := plist.player.withMpd(func(mpdc *mpd.Client) error {
@@ -632,7 +1811,7 @@ func (plist mpdPlaylist) Tracks() ([]library.Track, error) {
 		}
 		tracks = make([]library.Track, len(songs))
 		for i, song := range songs {
-			if err := trackFromMpdSong(mpdc, &song, &tracks[i]); err != nil {
+			if err := plist.player.trackFromMpdSong(mpdc, &song, &tracks[i], nil, nil); err != nil {
 				return err
 			}
 		}
@@ -666,27 +1845,58 @@ func playlistLength(mpdc *mpd.Client) (int, bool) {
 // ListAllInfo() and ListInfo() look very much the same but they don't return
 // the same thing. Who the fuck thought it was a good idea to mix capitals and
 // lowercase?!
-func trackFromMpdSong(mpdc *mpd.Client, song *mpd.Attrs, track *library.Track) error {
+//
+// The values of the stickers that are loaded as tags are looked up in tags,
+// which may be nil to leave the tags empty. Whether the song has art is looked
+// up in art, which may be nil to query the sticker of the song instead.
+func (pl *Player) trackFromMpdSong(mpdc *mpd.Client, song *mpd.Attrs, track *library.Track, tags stickerTagValues, art map[string]bool) error {
 	if _, ok := (*song)["directory"]; ok {
 		return fmt.Errorf("tried to read a directory as local file")
 	}
 
 	track.URI = mpdToURI((*song)["file"])
+	track.IsStream = library.IsStreamURI(track.URI)
 	track.Artist = (*song)["Artist"]
 	track.Title = (*song)["Title"]
 	track.Genre = (*song)["Genre"]
 	track.Album = (*song)["Album"]
 	track.AlbumArtist = (*song)["AlbumArtist"]
-	track.AlbumDisc = (*song)["Disc"]
-	track.AlbumTrack = (*song)["Track"]
+	track.SetAlbumDisc((*song)["Disc"])
+	track.SetAlbumTrack((*song)["Track"])
 
-	stkNum, _ := mpdc.StickerGet((*song)["file"], "image-nchunks")
-	if stkNum != nil {
+	if art != nil {
+		track.HasArt = art[(*song)["file"]]
+	} else if stkNum, _ := mpdc.StickerGet((*song)["file"], "image-nchunks"); stkNum != nil {
 		_, err := strconv.ParseInt(stkNum.Value, 10, 32)
 		track.HasArt = err == nil
 	}
 
-	if timeStr := (*song)["Time"]; timeStr != "" {
+	for name, values := range tags {
+		if value, ok := values[(*song)["file"]]; ok {
+			if track.Tags == nil {
+				track.Tags = map[string]string{}
+			}
+			track.Tags[name] = value
+		}
+	}
+	// Stickers that are loaded as tags also fill the attributes they
+	// correspond to.
+	if rating, err := strconv.Atoi(track.Tags[ratingSticker]); err == nil {
+		track.Rating = rating
+	}
+	if count, err := strconv.Atoi(track.Tags[playCountSticker]); err == nil {
+		track.PlayCount = count
+	}
+
+	// The duration has millisecond precision, older versions of MPD only
+	// report whole seconds as Time.
+	if durationStr := (*song)["duration"]; durationStr != "" {
+		duration, err := strconv.ParseFloat(durationStr, 64)
+		if err != nil {
+			return err
+		}
+		track.Duration = time.Duration(duration * float64(time.Second))
+	} else if timeStr := (*song)["Time"]; timeStr != "" {
 		duration, err := strconv.ParseInt(timeStr, 10, 32)
 		if err != nil {
 			return err
@@ -694,10 +1904,26 @@ func trackFromMpdSong(mpdc *mpd.Client, song *mpd.Attrs, track *library.Track) e
 		track.Duration = time.Duration(duration) * time.Second
 	}
 
-	library.InterpolateMissingFields(track)
+	pl.fallbackRulesLock.RLock()
+	library.ApplyFallbackRules(track, pl.fallbackRules)
+	pl.fallbackRulesLock.RUnlock()
 	return nil
 }
 
+// songAttr looks up an attribute of a song ignoring case. Tags that are not
+// known to MPD are reported in whatever case the file uses.
+func songAttr(song mpd.Attrs, name string) string {
+	if value, ok := song[name]; ok {
+		return value
+	}
+	for key, value := range song {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
+
 // Helper to get an attribute as an integer from an MPD status.
 func statusAttrInt(status mpd.Attrs, attr string) (int, bool) {
 	if str, ok := status[attr]; ok {
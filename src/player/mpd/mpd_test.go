@@ -1,6 +1,12 @@
 package mpd
 
 import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -11,7 +17,7 @@ import (
 )
 
 func connectForTesting() (*Player, error) {
-	return Connect("tcp", "127.0.0.1:6600", nil)
+	return Connect("tcp", "127.0.0.1:6600", nil, Options{})
 }
 
 func TestPlayerImplementation(t *testing.T) {
@@ -62,3 +68,733 @@ func TestUpdateEvent(t *testing.T) {
 		}
 	}
 }
+
+func TestDefaultVolume(t *testing.T) {
+	var uptime int32 = 100
+	volumes := make(chan string, 16)
+	lis := fakeMPD(t, func(cmd string) ([]string, error) {
+		switch {
+		case cmd == "stats":
+			return []string{fmt.Sprintf("uptime: %d", atomic.LoadInt32(&uptime))}, nil
+		case strings.HasPrefix(cmd, "setvol "):
+			volumes <- strings.TrimPrefix(cmd, "setvol ")
+		}
+		return nil, nil
+	})
+	defer lis.Close()
+
+	pl := &Player{playerState: &playerState{
+		network:        "tcp",
+		address:        lis.Addr().String(),
+		clientPool:     newClientPool(1),
+		closed:         make(chan struct{}),
+		commandTimeout: DefaultCommandTimeout,
+	}}
+	expectVolume := func(expected string) {
+		t.Helper()
+		select {
+		case vol := <-volumes:
+			if vol != expected {
+				t.Fatalf("Unexpected volume: %q", vol)
+			}
+		default:
+			if expected != "" {
+				t.Fatalf("The volume was not set")
+			}
+		}
+	}
+
+	if err := pl.SetDefaultVolume(30); err != nil {
+		t.Fatal(err)
+	}
+	expectVolume("30")
+
+	// A reconnect to the same MPD instance should leave the volume alone.
+	atomic.StoreInt32(&uptime, 160)
+	if err := pl.applyDefaultVolume(false); err != nil {
+		t.Fatal(err)
+	}
+	expectVolume("")
+
+	atomic.StoreInt32(&uptime, 2)
+	if err := pl.applyDefaultVolume(false); err != nil {
+		t.Fatal(err)
+	}
+	expectVolume("30")
+}
+
+func TestVolumeLimit(t *testing.T) {
+	volumes := make(chan string, 16)
+	lis := fakeMPD(t, func(cmd string) ([]string, error) {
+		if strings.HasPrefix(cmd, "setvol ") {
+			volumes <- strings.TrimPrefix(cmd, "setvol ")
+		}
+		return nil, nil
+	})
+	defer lis.Close()
+
+	pl := &Player{playerState: &playerState{
+		network:        "tcp",
+		address:        lis.Addr().String(),
+		clientPool:     newClientPool(1),
+		closed:         make(chan struct{}),
+		commandTimeout: DefaultCommandTimeout,
+	}}
+	if err := pl.SetDefaultVolume(120); err == nil {
+		t.Fatal("A default volume above 100 should be rejected")
+	}
+	for _, c := range []struct {
+		volume   int
+		expected string
+	}{
+		{volume: 120, expected: "100"},
+		{volume: -5, expected: "0"},
+		{volume: 40, expected: "40"},
+	} {
+		if err := pl.SetVolume(c.volume); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case v := <-volumes:
+			if v != c.expected {
+				t.Fatalf("Unexpected volume: %q, expected %q", v, c.expected)
+			}
+		default:
+			t.Fatalf("The volume was not set")
+		}
+	}
+}
+
+func TestSetTimeStream(t *testing.T) {
+	var current atomic.Value
+	current.Store("file: http://radio.example.com/stream")
+	var seeks int32
+	lis := fakeMPD(t, func(cmd string) ([]string, error) {
+		switch {
+		case cmd == "status":
+			return []string{"state: play", "song: 0"}, nil
+		case cmd == "currentsong":
+			return []string{current.Load().(string)}, nil
+		case strings.HasPrefix(cmd, "seek"):
+			atomic.AddInt32(&seeks, 1)
+		}
+		return nil, nil
+	})
+	defer lis.Close()
+
+	pl := &Player{playerState: &playerState{
+		network:        "tcp",
+		address:        lis.Addr().String(),
+		clientPool:     newClientPool(1),
+		closed:         make(chan struct{}),
+		commandTimeout: DefaultCommandTimeout,
+	}}
+	if err := pl.SetTime(time.Second); err != player.ErrUnseekable {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n := atomic.LoadInt32(&seeks); n != 0 {
+		t.Fatalf("Streams should not be seeked")
+	}
+
+	current.Store("file: music/song.mp3")
+	if err := pl.SetTime(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&seeks); n != 1 {
+		t.Fatalf("The track was not seeked")
+	}
+}
+
+func TestPoolStats(t *testing.T) {
+	var failPing int32
+	lis := fakeMPD(t, func(cmd string) ([]string, error) {
+		if cmd == "ping" && atomic.CompareAndSwapInt32(&failPing, 1, 0) {
+			return nil, fmt.Errorf("ACK [5@0] {ping} broken")
+		}
+		return nil, nil
+	})
+	defer lis.Close()
+
+	pl := &Player{playerState: &playerState{
+		network:        "tcp",
+		address:        lis.Addr().String(),
+		clientPool:     newClientPool(2),
+		closed:         make(chan struct{}),
+		commandTimeout: DefaultCommandTimeout,
+	}}
+
+	err := pl.withMpd(func(mpdc *mpd.Client) error {
+		if stats := pl.PoolStats(); stats.CheckedOut != 1 || stats.Alive != 1 {
+			t.Fatalf("Unexpected stats: %+v", stats)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats := pl.PoolStats(); stats.CheckedOut != 0 || stats.Alive != 1 || stats.Size != 2 {
+		t.Fatalf("Unexpected stats: %+v", stats)
+	}
+
+	atomic.StoreInt32(&failPing, 1)
+	for i := 0; i < 2; i++ {
+		if err := pl.withMpd(func(mpdc *mpd.Client) error { return nil }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Idle clients are reused before new connections are dialed.
+	if stats := pl.PoolStats(); stats.Alive != 1 || stats.LastPingError == "" {
+		t.Fatalf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestCommandTimeout(t *testing.T) {
+	stall := make(chan struct{})
+	lis := fakeMPD(t, func(cmd string) ([]string, error) {
+		if cmd == "status" {
+			<-stall
+		}
+		return nil, nil
+	})
+	defer lis.Close()
+	defer close(stall)
+
+	pl := &Player{playerState: &playerState{
+		network:        "tcp",
+		address:        lis.Addr().String(),
+		clientPool:     newClientPool(1),
+		closed:         make(chan struct{}),
+		commandTimeout: time.Millisecond * 50,
+	}}
+
+	err := pl.withMpd(func(mpdc *mpd.Client) error {
+		_, err := mpdc.Status()
+		return err
+	})
+	if err == nil {
+		t.Fatal("The stalled operation should time out")
+	}
+	// The stalled connection should have been closed right away.
+	if stats := pl.PoolStats(); stats.Timeouts != 1 || stats.CheckedOut != 0 || stats.Alive != 0 {
+		t.Fatalf("Unexpected stats: %+v", stats)
+	}
+	// The pool should have recovered.
+	if err := pl.withMpd(func(mpdc *mpd.Client) error { return mpdc.Ping() }); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWithContext(t *testing.T) {
+	stall := make(chan struct{})
+	lis := fakeMPD(t, func(cmd string) ([]string, error) {
+		if cmd == "status" {
+			<-stall
+		}
+		return nil, nil
+	})
+	defer lis.Close()
+	defer close(stall)
+
+	pl := &Player{playerState: &playerState{
+		network:        "tcp",
+		address:        lis.Addr().String(),
+		clientPool:     newClientPool(1),
+		closed:         make(chan struct{}),
+		commandTimeout: DefaultCommandTimeout,
+	}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	bound := pl.WithContext(ctx)
+	start := time.Now()
+	if _, err := bound.State(); err != context.DeadlineExceeded {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatal("The operation was not aborted once the context was done")
+	}
+	if stats := pl.PoolStats(); stats.CheckedOut != 0 || stats.Alive != 0 {
+		t.Fatalf("Unexpected stats: %+v", stats)
+	}
+	// The connection was replaced, the unbound player is still usable.
+	if err := pl.withMpd(func(mpdc *mpd.Client) error { return mpdc.Ping() }); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bound.Volume(); err != context.DeadlineExceeded {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+func TestCommandTimeoutProgress(t *testing.T) {
+	lis := fakeMPD(t, func(cmd string) ([]string, error) {
+		if strings.HasPrefix(cmd, "addid") {
+			time.Sleep(time.Millisecond * 20)
+			return []string{"Id: 1"}, nil
+		}
+		return nil, nil
+	})
+	defer lis.Close()
+
+	pl := &Player{playerState: &playerState{
+		network:        "tcp",
+		address:        lis.Addr().String(),
+		clientPool:     newClientPool(1),
+		closed:         make(chan struct{}),
+		commandTimeout: time.Millisecond * 50,
+	}}
+	// Inserting takes longer than the timeout, but each command completes
+	// well within it.
+	tracks := make([]library.Track, 8)
+	for i := range tracks {
+		tracks[i].URI = fmt.Sprintf("mpd://%d.mp3", i)
+	}
+	if _, err := (mpdPlaylist{player: pl}).InsertIDs(-1, tracks...); err != nil {
+		t.Fatal(err)
+	}
+	if stats := pl.PoolStats(); stats.Timeouts != 0 {
+		t.Fatalf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestLibraryRoot(t *testing.T) {
+	lis := fakeMPD(t, func(cmd string) ([]string, error) {
+		// Some commands are sent with trailing whitespace.
+		switch strings.TrimSpace(cmd) {
+		case `lsinfo "music"`:
+			return []string{"directory: music/album", "file: music/single.mp3"}, nil
+		case `listallinfo "music/album"`:
+			return []string{"directory: music/album", "file: music/album/01.mp3", "Title: One"}, nil
+		case `listallinfo "music/single.mp3"`:
+			return []string{"file: music/single.mp3", "Title: Single", "Artist: A", "Artist: B", "Genre: Pop"}, nil
+		case "ping", "status":
+			return nil, nil
+		}
+		if strings.HasPrefix(cmd, "sticker get") {
+			return nil, fmt.Errorf("ACK [50@0] {sticker} no such sticker")
+		}
+		return nil, fmt.Errorf("ACK [5@0] {} unexpected command %q", cmd)
+	})
+	defer lis.Close()
+
+	pl := &Player{playerState: &playerState{
+		network:        "tcp",
+		address:        lis.Addr().String(),
+		libraryRoot:    "music",
+		clientPool:     newClientPool(1),
+		closed:         make(chan struct{}),
+		commandTimeout: DefaultCommandTimeout,
+	}}
+
+	tracks, err := pl.Tracks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tracks) != 2 {
+		t.Fatalf("Unexpected tracks: %v", tracks)
+	}
+	if tracks[0].URI != "mpd://music/album/01.mp3" || tracks[1].URI != "mpd://music/single.mp3" {
+		t.Fatalf("Unexpected URIs: %q, %q", tracks[0].URI, tracks[1].URI)
+	}
+	if tracks[1].Artist != "A" || !reflect.DeepEqual(tracks[1].Artists, []string{"A", "B"}) {
+		t.Fatalf("Unexpected artists: %q, %q", tracks[1].Artist, tracks[1].Artists)
+	}
+	if tracks[1].Genre != "Pop" || tracks[1].Genres != nil {
+		t.Fatalf("Unexpected genres: %q, %q", tracks[1].Genre, tracks[1].Genres)
+	}
+}
+
+func TestReadReplayGain(t *testing.T) {
+	lis := fakeMPD(t, func(cmd string) ([]string, error) {
+		switch cmd {
+		case `readcomments "a.flac"`:
+			return []string{"REPLAYGAIN_TRACK_GAIN: -3.5 dB", "REPLAYGAIN_TRACK_PEAK: 0.9"}, nil
+		case `readcomments "b.flac"`:
+			return nil, fmt.Errorf("ACK [50@0] {readcomments} No such file")
+		case "ping":
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ACK [5@0] {} unexpected command %q", cmd)
+	})
+	defer lis.Close()
+
+	pl := &Player{playerState: &playerState{
+		network:        "tcp",
+		address:        lis.Addr().String(),
+		clientPool:     newClientPool(1),
+		closed:         make(chan struct{}),
+		commandTimeout: DefaultCommandTimeout,
+	}}
+
+	tracks := []library.Track{{URI: "mpd://a.flac"}, {URI: "mpd://b.flac"}, {URI: "http://stream"}}
+	if err := pl.ReadReplayGain(tracks); err != nil {
+		t.Fatal(err)
+	}
+	if tracks[0].ReplayGain == nil || *tracks[0].ReplayGain != -3.5 || tracks[0].ReplayGainPeak == nil || *tracks[0].ReplayGainPeak != 0.9 {
+		t.Fatalf("Unexpected ReplayGain: %v, %v", tracks[0].ReplayGain, tracks[0].ReplayGainPeak)
+	}
+	if tracks[1].ReplayGain != nil || tracks[2].ReplayGain != nil {
+		t.Fatalf("Unexpected ReplayGain: %v, %v", tracks[1].ReplayGain, tracks[2].ReplayGain)
+	}
+}
+
+func TestStickerTags(t *testing.T) {
+	lis := fakeMPD(t, func(cmd string) ([]string, error) {
+		switch strings.TrimSpace(cmd) {
+		case `lsinfo "/"`:
+			return []string{"file: a.mp3", "file: b.mp3"}, nil
+		case `listallinfo "a.mp3"`:
+			return []string{"file: a.mp3", "Title: A"}, nil
+		case `listallinfo "b.mp3"`:
+			return []string{"file: b.mp3", "Title: B"}, nil
+		case `sticker find song "" "rating"`:
+			return []string{"file: b.mp3", "sticker: rating=5"}, nil
+		case `sticker find song "" "image-nchunks"`:
+			return []string{"file: a.mp3", "sticker: image-nchunks=3"}, nil
+		case "ping", "status":
+			return nil, nil
+		}
+		if strings.HasPrefix(cmd, "sticker get song") {
+			t.Errorf("Stickers should not be looked up per song: %q", cmd)
+		}
+		return nil, fmt.Errorf("ACK [5@0] {} unexpected command %q", cmd)
+	})
+	defer lis.Close()
+
+	pl := &Player{playerState: &playerState{
+		network:        "tcp",
+		address:        lis.Addr().String(),
+		stickerTags:    []string{"rating"},
+		clientPool:     newClientPool(1),
+		closed:         make(chan struct{}),
+		commandTimeout: DefaultCommandTimeout,
+	}}
+	tracks, err := pl.Tracks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tracks) != 2 {
+		t.Fatalf("Unexpected tracks: %v", tracks)
+	}
+	if tracks[0].Tags != nil {
+		t.Fatalf("Unexpected tags: %v", tracks[0].Tags)
+	}
+	if !tracks[0].HasArt || tracks[1].HasArt {
+		t.Fatalf("Unexpected art: %v, %v", tracks[0].HasArt, tracks[1].HasArt)
+	}
+	if !reflect.DeepEqual(tracks[1].Tags, map[string]string{"rating": "5"}) {
+		t.Fatalf("Unexpected tags: %v", tracks[1].Tags)
+	}
+}
+
+func TestPlayCounts(t *testing.T) {
+	lis := fakeMPD(t, func(cmd string) ([]string, error) {
+		switch cmd {
+		case `sticker find song "music" "playcount"`:
+			return []string{
+				"file: music/a.mp3", "sticker: playcount=3",
+				"file: music/b.mp3", "sticker: playcount=12",
+				"file: music/c.mp3", "sticker: playcount=garbage",
+			}, nil
+		case `sticker find song "music" "skip-count"`:
+			return []string{"file: music/b.mp3", "sticker: skip-count=2"}, nil
+		case "ping":
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ACK [5@0] {} unexpected command %q", cmd)
+	})
+	defer lis.Close()
+
+	pl := &Player{playerState: &playerState{
+		network:        "tcp",
+		address:        lis.Addr().String(),
+		libraryRoot:    "music",
+		clientPool:     newClientPool(1),
+		closed:         make(chan struct{}),
+		commandTimeout: DefaultCommandTimeout,
+	}}
+	counts, err := pl.PlayCounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]int{"mpd://music/a.mp3": 3, "mpd://music/b.mp3": 12}
+	if !reflect.DeepEqual(counts, expected) {
+		t.Fatalf("Unexpected play counts: %v", counts)
+	}
+
+	skips, err := pl.SkipCounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = map[string]int{"mpd://music/b.mp3": 2}
+	if !reflect.DeepEqual(skips, expected) {
+		t.Fatalf("Unexpected skip counts: %v", skips)
+	}
+}
+
+func TestTrackPriorities(t *testing.T) {
+	var set string
+	lis := fakeMPD(t, func(cmd string) ([]string, error) {
+		switch {
+		case cmd == "playlistinfo":
+			return []string{
+				"file: music/a.mp3", "Pos: 0", "Id: 1",
+				"file: music/b.mp3", "Pos: 1", "Id: 2", "Prio: 12",
+			}, nil
+		case cmd == "playlistinfo 1":
+			return []string{"file: music/b.mp3", "Pos: 1", "Id: 2", "Prio: 12"}, nil
+		case strings.HasPrefix(cmd, "prioid "):
+			set = cmd
+			return nil, nil
+		case cmd == "ping":
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ACK [5@0] {} unexpected command %q", cmd)
+	})
+	defer lis.Close()
+
+	pl := &Player{playerState: &playerState{
+		network:        "tcp",
+		address:        lis.Addr().String(),
+		clientPool:     newClientPool(1),
+		closed:         make(chan struct{}),
+		commandTimeout: DefaultCommandTimeout,
+	}}
+	priorities, err := pl.TrackPriorities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(priorities, []int{0, 12}) {
+		t.Fatalf("Unexpected priorities: %v", priorities)
+	}
+
+	if err := pl.SetTrackPriority(1, 200); err != nil {
+		t.Fatal(err)
+	}
+	if set != "prioid 200 2" {
+		t.Fatalf("Unexpected command: %q", set)
+	}
+	if err := pl.SetTrackPriority(1, player.MaxTrackPriority+1); err == nil {
+		t.Fatal("A priority out of range should be rejected")
+	}
+}
+
+func TestRemoveURIs(t *testing.T) {
+	var deleted []string
+	lis := fakeMPD(t, func(cmd string) ([]string, error) {
+		switch {
+		case cmd == "playlistinfo":
+			return []string{
+				"file: music/a.mp3", "Pos: 0", "Id: 7",
+				"file: music/b.mp3", "Pos: 1", "Id: 8",
+				"file: music/a.mp3", "Pos: 2", "Id: 9",
+			}, nil
+		case strings.HasPrefix(cmd, "deleteid "):
+			deleted = append(deleted, cmd)
+			return nil, nil
+		case cmd == "ping":
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ACK [5@0] {} unexpected command %q", cmd)
+	})
+	defer lis.Close()
+
+	pl := &Player{playerState: &playerState{
+		network:        "tcp",
+		address:        lis.Addr().String(),
+		clientPool:     newClientPool(1),
+		closed:         make(chan struct{}),
+		commandTimeout: DefaultCommandTimeout,
+	}}
+	positions, err := (mpdPlaylist{player: pl}).RemoveURIs("mpd://music/a.mp3", "mpd://music/x.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(positions, []int{0, 2}) {
+		t.Fatalf("Unexpected positions: %v", positions)
+	}
+	if !reflect.DeepEqual(deleted, []string{"deleteid 7", "deleteid 9"}) {
+		t.Fatalf("Unexpected commands: %q", deleted)
+	}
+}
+
+func TestPlayCountStickerEcho(t *testing.T) {
+	var countLock sync.Mutex
+	count := "3"
+	lis := fakeMPD(t, func(cmd string) ([]string, error) {
+		countLock.Lock()
+		defer countLock.Unlock()
+		switch cmd {
+		case `sticker get song "music/a.mp3" "playcount"`:
+			return []string{"sticker: playcount=" + count}, nil
+		case `sticker set song "music/a.mp3" "playcount" "4"`:
+			count = "4"
+			return nil, nil
+		case `sticker find song "" "playcount"`:
+			return []string{"file: music/a.mp3", "sticker: playcount=" + count}, nil
+		case "ping":
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ACK [5@0] {} unexpected command %q", cmd)
+	})
+	defer lis.Close()
+
+	pl := &Player{playerState: &playerState{
+		network:        "tcp",
+		address:        lis.Addr().String(),
+		stickerTags:    []string{playCountSticker},
+		clientPool:     newClientPool(1),
+		closed:         make(chan struct{}),
+		commandTimeout: DefaultCommandTimeout,
+	}}
+	pl.setStickerSnapshot(stickerTagValues{
+		playCountSticker: {"music/a.mp3": "3"},
+	})
+	l := pl.Listen()
+	defer pl.Unlisten(l)
+	go pl.mainLoop()
+	// Wait for the main loop to listen to events.
+	time.Sleep(time.Millisecond * 50)
+
+	err := pl.withMpd(func(mpdc *mpd.Client) error {
+		return pl.incrementSticker(mpdc, "music/a.mp3", playCountSticker)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pl.Emit(StickerEvent)
+	timeout := time.After(time.Millisecond * 200)
+	for done := false; !done; {
+		select {
+		case event := <-l:
+			if _, ok := event.(library.UpdateEvent); ok {
+				t.Fatal("Incrementing a play count should not reload the library")
+			}
+		case <-timeout:
+			done = true
+		}
+	}
+
+	// Changes made by others are not ignored.
+	countLock.Lock()
+	count = "9"
+	countLock.Unlock()
+	pl.Emit(StickerEvent)
+	for {
+		select {
+		case event := <-l:
+			if _, ok := event.(library.UpdateEvent); ok {
+				return
+			}
+		case <-time.After(time.Second):
+			t.Fatal("A changed sticker should reload the library")
+		}
+	}
+}
+
+func BenchmarkTracks(b *testing.B) {
+	const numSongs = 2000
+	var listing []string
+	for i := 0; i < numSongs; i++ {
+		album := i / 10
+		if i%10 == 0 {
+			listing = append(listing, fmt.Sprintf("directory: album%d", album))
+		}
+		listing = append(listing,
+			fmt.Sprintf("file: album%d/%02d.flac", album, i%10),
+			"Last-Modified: 2020-01-01T00:00:00Z",
+			fmt.Sprintf("Title: Track %d", i),
+			fmt.Sprintf("Artist: Artist %d", album),
+			fmt.Sprintf("Artist: Featured %d", i),
+			fmt.Sprintf("Album: Album %d", album),
+			"Genre: Rock",
+			fmt.Sprintf("Track: %d", i%10+1),
+			fmt.Sprintf("duration: %d.123", 180+i%60),
+		)
+	}
+	lis := fakeMPD(b, func(cmd string) ([]string, error) {
+		switch strings.TrimSpace(cmd) {
+		case `lsinfo "/"`:
+			return []string{"directory: music"}, nil
+		case `listallinfo "music"`:
+			return listing, nil
+		case "stats":
+			return []string{fmt.Sprintf("songs: %d", numSongs)}, nil
+		case "ping", "status":
+			return nil, nil
+		}
+		if strings.HasPrefix(cmd, "sticker get") {
+			return nil, fmt.Errorf("ACK [50@0] {sticker} no such sticker")
+		}
+		return nil, fmt.Errorf("ACK [5@0] {} unexpected command %q", cmd)
+	})
+	defer lis.Close()
+
+	pl := &Player{playerState: &playerState{
+		network:        "tcp",
+		address:        lis.Addr().String(),
+		clientPool:     newClientPool(1),
+		closed:         make(chan struct{}),
+		commandTimeout: DefaultCommandTimeout,
+	}}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tracks, err := pl.Tracks()
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(tracks) != numSongs {
+			b.Fatalf("Unexpected number of tracks: %d", len(tracks))
+		}
+	}
+}
+
+func TestStickerAttributes(t *testing.T) {
+	pl := &Player{playerState: &playerState{}}
+	song := mpd.Attrs{"file": "music/a.mp3", "Time": "60"}
+	tags := stickerTagValues{
+		playCountSticker: {"music/a.mp3": "7"},
+		ratingSticker:    {"music/a.mp3": "8"},
+	}
+	var track library.Track
+	if err := pl.trackFromMpdSong(nil, &song, &track, tags, map[string]bool{}); err != nil {
+		t.Fatal(err)
+	}
+	if track.Duration != time.Minute {
+		t.Fatalf("Unexpected duration: %v", track.Duration)
+	}
+	if track.PlayCount != 7 || track.Rating != 8 {
+		t.Fatalf("Unexpected play count and rating: %d, %d", track.PlayCount, track.Rating)
+	}
+	if track.Tags[ratingSticker] != "8" {
+		t.Fatalf("The rating is no longer a tag: %v", track.Tags)
+	}
+
+	track = library.Track{}
+	if err := pl.trackFromMpdSong(nil, &song, &track, nil, map[string]bool{}); err != nil {
+		t.Fatal(err)
+	}
+	if track.Attr("playcount") != int64(0) || track.Attr("rating") != int64(0) {
+		t.Fatalf("Stickers that are not loaded should yield zero: %v, %v", track.Attr("playcount"), track.Attr("rating"))
+	}
+}
+
+func TestTrackDuration(t *testing.T) {
+	pl := &Player{playerState: &playerState{}}
+	for _, c := range []struct {
+		song     mpd.Attrs
+		expected time.Duration
+	}{
+		{mpd.Attrs{"file": "a.mp3", "Time": "61", "duration": "60.512"}, time.Millisecond * 60512},
+		{mpd.Attrs{"file": "a.mp3", "Time": "61"}, time.Second * 61},
+		{mpd.Attrs{"file": "a.mp3"}, 0},
+	} {
+		var track library.Track
+		if err := pl.trackFromMpdSong(nil, &c.song, &track, nil, map[string]bool{}); err != nil {
+			t.Fatal(err)
+		}
+		if track.Duration != c.expected {
+			t.Fatalf("Unexpected duration of %v: %v", c.song, track.Duration)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package mpd
+
+import (
+	"fmt"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/fhs/gompd/mpd"
+	log "github.com/sirupsen/logrus"
+)
+
+// MPD protocol error codes, see
+// https://www.musicpd.org/doc/html/protocol.html#failure-responses
+const (
+	ackErrorPassword   = 3
+	ackErrorPermission = 4
+	ackErrorUnknown    = 5
+	ackErrorNoExist    = 50
+)
+
+// ackCode extracts the error code from an MPD error response. -1 is returned
+// if the message is not an error response.
+func ackCode(msg string) int {
+	i := strings.Index(msg, "ACK [")
+	if i == -1 {
+		return -1
+	}
+	msg = msg[i+len("ACK ["):]
+	end := strings.IndexByte(msg, '@')
+	if end == -1 {
+		return -1
+	}
+	code, err := strconv.Atoi(msg[:end])
+	if err != nil {
+		return -1
+	}
+	return code
+}
+
+// quoteArg quotes an argument of an MPD command.
+func quoteArg(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// dial opens a new connection to MPD which operates on the partition of the
+// player.
+func (pl *Player) dial() (*mpd.Client, error) {
+	client, err := mpd.DialAuthenticated(pl.network, pl.address, pl.passwd)
+	if err != nil {
+		return nil, err
+	}
+	err = pl.selectPartition(func(cmd string) error {
+		return client.Command(cmd).OK()
+	})
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// selectPartition switches a connection to the partition of the player using
+// the specified function to execute commands. The partition is created if it
+// does not exist yet.
+//
+// Older versions of MPD do not support partitions, in which case the default
+// partition is used.
+func (pl *Player) selectPartition(exec func(cmd string) error) error {
+	if pl.partition == "" || atomic.LoadInt32(&pl.partitionUnsupported) != 0 {
+		return nil
+	}
+	err := exec("partition " + quoteArg(pl.partition))
+	switch {
+	case err == nil:
+		return nil
+	case ackCode(err.Error()) == ackErrorUnknown:
+		if atomic.CompareAndSwapInt32(&pl.partitionUnsupported, 0, 1) {
+			log.Warnf("%v: MPD does not support partitions, using the default partition", pl)
+		}
+		return nil
+	case ackCode(err.Error()) == ackErrorNoExist:
+		if err := exec("newpartition " + quoteArg(pl.partition)); err != nil && ackCode(err.Error()) != ackErrorNoExist {
+			return fmt.Errorf("error creating partition %q: %v", pl.partition, err)
+		}
+		log.Infof("%v: Created partition %q", pl, pl.partition)
+		return exec("partition " + quoteArg(pl.partition))
+	}
+	return fmt.Errorf("error selecting partition %q: %v", pl.partition, err)
+}
+
+// textExec executes a command that does not produce output on a raw
+// connection.
+func textExec(text *textproto.Conn, cmd string) error {
+	if err := text.PrintfLine("%s", cmd); err != nil {
+		return err
+	}
+	line, err := text.ReadLine()
+	if err != nil {
+		return err
+	} else if line != "OK" {
+		return fmt.Errorf("%s", line)
+	}
+	return nil
+}
+
+// dialText opens a raw connection to MPD which operates on the partition of
+// the player. This is used for commands of which gompd does not expose all
+// of the output.
+func (pl *Player) dialText() (*textproto.Conn, error) {
+	conn, err := net.Dial(pl.network, pl.address)
+	if err != nil {
+		return nil, err
+	}
+	text := textproto.NewConn(conn)
+	exec := func(cmd string) error {
+		return textExec(text, cmd)
+	}
+	setup := func() error {
+		if line, err := text.ReadLine(); err != nil {
+			return err
+		} else if !strings.HasPrefix(line, "OK MPD ") {
+			return fmt.Errorf("unexpected greeting: %q", line)
+		}
+		if pl.passwd != "" {
+			if err := exec("password " + quoteArg(pl.passwd)); err != nil {
+				return err
+			}
+		}
+		return pl.selectPartition(exec)
+	}
+	if err := setup(); err != nil {
+		text.Close()
+		return nil, err
+	}
+	return text, nil
+}
+
+// A watcher reports the names of the MPD subsystems that have changed.
+type watcher struct {
+	Event chan string
+	Error chan error
+	close func() error
+}
+
+func (w *watcher) Close() error {
+	return w.close()
+}
+
+// newWatcher creates a watcher for the partition of the player.
+func (pl *Player) newWatcher() (*watcher, error) {
+	subsystems := pl.subsystemNames()
+	if pl.partition == "" {
+		w, err := mpd.NewWatcher(pl.network, pl.address, pl.passwd, subsystems...)
+		if err != nil {
+			return nil, err
+		}
+		return &watcher{Event: w.Event, Error: w.Error, close: w.Close}, nil
+	}
+
+	// The watcher of gompd can not be moved to another partition, so the
+	// idle loop is implemented here.
+	text, err := pl.dialText()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &watcher{
+		Event: make(chan string),
+		Error: make(chan error),
+	}
+	closed := make(chan struct{})
+	w.close = func() error {
+		close(closed)
+		return text.Close()
+	}
+	idle := strings.TrimSpace("idle " + strings.Join(subsystems, " "))
+	go func() {
+		for {
+			var changed []string
+			err := text.PrintfLine("%s", idle)
+			for err == nil {
+				var line string
+				if line, err = text.ReadLine(); err != nil {
+					break
+				} else if line == "OK" {
+					break
+				} else if strings.HasPrefix(line, "changed: ") {
+					changed = append(changed, strings.TrimPrefix(line, "changed: "))
+				} else {
+					err = fmt.Errorf("%s", line)
+				}
+			}
+			if err != nil {
+				select {
+				case w.Error <- err:
+				case <-closed:
+				}
+				return
+			}
+			for _, name := range changed {
+				select {
+				case w.Event <- name:
+				case <-closed:
+					return
+				}
+			}
+		}
+	}()
+	return w, nil
+}
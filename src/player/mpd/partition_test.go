@@ -0,0 +1,169 @@
+package mpd
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeMPD serves a minimal subset of the MPD protocol. The handler is called
+// for each command and returns the response lines, excluding the final OK.
+func fakeMPD(t testing.TB, handle func(cmd string) ([]string, error)) net.Listener {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				w := bufio.NewWriter(conn)
+				fmt.Fprintf(w, "OK MPD 0.22.0\n")
+				w.Flush()
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					lines, err := handle(scanner.Text())
+					for _, line := range lines {
+						fmt.Fprintf(w, "%s\n", line)
+					}
+					if err != nil {
+						fmt.Fprintf(w, "%v\n", err)
+					} else {
+						fmt.Fprintf(w, "OK\n")
+					}
+					w.Flush()
+				}
+			}()
+		}
+	}()
+	return lis
+}
+
+func TestAckCode(t *testing.T) {
+	if code := ackCode(`unexpected response: ACK [50@0] {partition} No such partition`); code != ackErrorNoExist {
+		t.Fatalf("Unexpected code: %d", code)
+	}
+	if code := ackCode("OK"); code != -1 {
+		t.Fatalf("Unexpected code: %d", code)
+	}
+}
+
+func TestPartitionCreate(t *testing.T) {
+	partitions := map[string]bool{"default": true}
+	commands := make(chan string, 16)
+	lis := fakeMPD(t, func(cmd string) ([]string, error) {
+		commands <- cmd
+		switch {
+		case cmd == `partition "kitchen"`:
+			if !partitions["kitchen"] {
+				return nil, fmt.Errorf("ACK [50@0] {partition} partition does not exist")
+			}
+		case cmd == `newpartition "kitchen"`:
+			partitions["kitchen"] = true
+		case cmd == "idle":
+			time.Sleep(time.Millisecond * 10)
+			return []string{"changed: player"}, nil
+		}
+		return nil, nil
+	})
+	defer lis.Close()
+
+	pl := &Player{playerState: &playerState{network: "tcp", address: lis.Addr().String(), partition: "kitchen"}}
+	client, err := pl.dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Close()
+	var sent []string
+	for len(commands) > 0 {
+		sent = append(sent, <-commands)
+	}
+	expected := `partition "kitchen",newpartition "kitchen",partition "kitchen"`
+	if s := strings.Join(sent, ","); !strings.HasPrefix(s, expected) {
+		t.Fatalf("Unexpected commands: %s", s)
+	}
+
+	w, err := pl.newWatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	select {
+	case ev := <-w.Event:
+		if ev != "player" {
+			t.Fatalf("Unexpected event: %q", ev)
+		}
+	case err := <-w.Error:
+		t.Fatal(err)
+	case <-time.After(time.Second * 5):
+		t.Fatal("No event received")
+	}
+}
+
+func TestPartitionUnsupported(t *testing.T) {
+	lis := fakeMPD(t, func(cmd string) ([]string, error) {
+		if strings.HasPrefix(cmd, "partition ") {
+			return nil, fmt.Errorf(`ACK [5@0] {} unknown command "partition"`)
+		}
+		return nil, nil
+	})
+	defer lis.Close()
+
+	pl := &Player{playerState: &playerState{network: "tcp", address: lis.Addr().String(), partition: "kitchen"}}
+	client, err := pl.dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Close()
+	if pl.partitionUnsupported == 0 {
+		t.Fatal("The partition should be ignored")
+	}
+}
+
+func TestWatcherSubsystems(t *testing.T) {
+	idle := make(chan string, 16)
+	lis := fakeMPD(t, func(cmd string) ([]string, error) {
+		if strings.HasPrefix(cmd, "idle") {
+			idle <- cmd
+			time.Sleep(time.Millisecond * 10)
+			return []string{"changed: player"}, nil
+		}
+		return nil, nil
+	})
+	defer lis.Close()
+
+	for _, partition := range []string{"", "kitchen"} {
+		pl := &Player{playerState: &playerState{network: "tcp", address: lis.Addr().String(), partition: partition}}
+		if err := pl.SetSubsystems([]Event{PlayerEvent, MixerEvent}); err != nil {
+			t.Fatal(err)
+		}
+		w, err := pl.newWatcher()
+		if err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case cmd := <-idle:
+			if cmd != "idle player mixer" {
+				t.Fatalf("Unexpected idle command for partition %q: %q", partition, cmd)
+			}
+		case <-time.After(time.Second * 5):
+			t.Fatal("No idle command received")
+		}
+		w.Close()
+		for len(idle) > 0 {
+			<-idle
+		}
+	}
+
+	pl := &Player{playerState: &playerState{}}
+	if err := pl.SetSubsystems([]Event{"bogus"}); err == nil {
+		t.Fatal("An unknown subsystem should be rejected")
+	}
+}
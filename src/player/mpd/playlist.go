@@ -34,7 +34,7 @@ func (plist userPlaylist) Tracks() ([]library.Track, error) {
 		}
 		tracks = make([]library.Track, len(songs))
 		for i, song := range songs {
-			if err := trackFromMpdSong(mpdc, &song, &tracks[i]); err != nil {
+			if err := plist.player.trackFromMpdSong(mpdc, &song, &tracks[i], nil, nil); err != nil {
 				return err
 			}
 		}
@@ -46,8 +46,8 @@ func (plist userPlaylist) Tracks() ([]library.Track, error) {
 func (plist userPlaylist) Len() (int, error) {
 	var length int
 	err := plist.player.withMpd(func(mpdc *mpd.Client) error {
-		info, err := mpdc.PlaylistInfo(-1, -1)
-		length = len(info)
+		songs, err := mpdc.PlaylistContents(plist.name)
+		length = len(songs)
 		return err
 	})
 	if err != nil {
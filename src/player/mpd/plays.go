@@ -0,0 +1,110 @@
+package mpd
+
+import (
+	"time"
+)
+
+const (
+	// DefaultPlayCountRatio is the default part of a track that must have
+	// been listened to for it to count as played.
+	DefaultPlayCountRatio = 0.5
+	// DefaultPlayCountMax is the default amount of listening time after which
+	// a track always counts as played, regardless of its duration.
+	DefaultPlayCountMax = time.Minute * 4
+)
+
+// A playTracker keeps track of how long the current entry in the queue has
+// been listened to, so plays can be counted once a threshold is reached.
+type playTracker struct {
+	ratio float64
+	max   time.Duration
+
+	songID   string
+	uri      string
+	duration time.Duration
+	// The moment the entry was first observed.
+	started time.Time
+	// The listening time accumulated up until since.
+	played time.Duration
+	// The moment playback was last observed to be running, zero if paused
+	// or stopped.
+	since   time.Time
+	counted bool
+	begun   bool
+	// The URI of the previous entry if it was skipped, see skipped.
+	skippedURI string
+}
+
+// threshold returns the amount of listening time after which the current
+// track counts as played.
+func (tr *playTracker) threshold() time.Duration {
+	if tr.duration <= 0 {
+		return tr.max
+	}
+	if t := time.Duration(float64(tr.duration) * tr.ratio); t < tr.max {
+		return t
+	}
+	return tr.max
+}
+
+// begin reports whether the current entry has just begun playing. It must be
+// called after update and reports each entry at most once.
+func (tr *playTracker) begin(playing bool) bool {
+	if tr.songID == "" || !playing || tr.begun {
+		return false
+	}
+	tr.begun = true
+	return true
+}
+
+// skipped returns the URI of the previous entry if playback advanced past it
+// after it had begun but before it counted as played. It must be called after
+// update and reports each entry at most once.
+func (tr *playTracker) skipped() string {
+	uri := tr.skippedURI
+	tr.skippedURI = ""
+	return uri
+}
+
+// update processes a change in the playback status.
+//
+// If the current entry has just reached the threshold, counted is set. If it
+// has not but is being played, wait is the remaining time until it will.
+func (tr *playTracker) update(now time.Time, playing bool, songID, uri string, duration time.Duration) (counted bool, wait time.Duration) {
+	if songID != tr.songID {
+		played := tr.played
+		if !tr.since.IsZero() {
+			played += now.Sub(tr.since)
+		}
+		var skippedURI string
+		if tr.songID != "" && songID != "" && tr.begun && !tr.counted && played < tr.threshold() {
+			skippedURI = tr.uri
+		}
+		*tr = playTracker{
+			ratio:    tr.ratio,
+			max:      tr.max,
+			songID:   songID,
+			uri:      uri,
+			duration: duration,
+			started:  now,
+
+			skippedURI: skippedURI,
+		}
+	} else if !tr.since.IsZero() {
+		tr.played += now.Sub(tr.since)
+	}
+	tr.since = time.Time{}
+	if songID == "" || tr.counted {
+		return false, 0
+	}
+
+	if tr.played >= tr.threshold() {
+		tr.counted = true
+		return true, 0
+	}
+	if !playing {
+		return false, 0
+	}
+	tr.since = now
+	return false, tr.threshold() - tr.played
+}
@@ -0,0 +1,104 @@
+package mpd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPlayTrackerThreshold(t *testing.T) {
+	tr := playTracker{ratio: DefaultPlayCountRatio, max: DefaultPlayCountMax}
+	start := time.Now()
+
+	if counted, wait := tr.update(start, true, "1", "a", time.Minute*2); counted || wait != time.Minute {
+		t.Fatalf("Unexpected result: counted=%v wait=%v", counted, wait)
+	}
+	// Pausing halfway stops the accumulation of listening time.
+	if counted, _ := tr.update(start.Add(time.Second*30), false, "1", "a", time.Minute*2); counted {
+		t.Fatal("Counted too early")
+	}
+	if counted, wait := tr.update(start.Add(time.Minute*5), true, "1", "a", time.Minute*2); counted || wait != time.Second*30 {
+		t.Fatalf("Unexpected result: counted=%v wait=%v", counted, wait)
+	}
+	if counted, _ := tr.update(start.Add(time.Minute*5+time.Second*30), true, "1", "a", time.Minute*2); !counted {
+		t.Fatal("Not counted after reaching the threshold")
+	}
+	// Plays are only counted once per entry.
+	if counted, _ := tr.update(start.Add(time.Minute*6), true, "1", "a", time.Minute*2); counted {
+		t.Fatal("Counted twice")
+	}
+}
+
+func TestPlayTrackerSkip(t *testing.T) {
+	tr := playTracker{ratio: DefaultPlayCountRatio, max: DefaultPlayCountMax}
+	start := time.Now()
+
+	tr.update(start, true, "1", "a", time.Minute*2)
+	// Skipping to the next entry before the threshold does not count.
+	if counted, _ := tr.update(start.Add(time.Second*10), true, "2", "a", time.Minute*2); counted {
+		t.Fatal("Skipped entry was counted")
+	}
+	// Long tracks are counted after the maximum.
+	tr.update(start, true, "3", "b", time.Hour)
+	if counted, _ := tr.update(start.Add(DefaultPlayCountMax), true, "3", "b", time.Hour); !counted {
+		t.Fatal("Not counted after the maximum")
+	}
+}
+
+func TestPlayTrackerBegin(t *testing.T) {
+	tr := playTracker{ratio: DefaultPlayCountRatio, max: DefaultPlayCountMax}
+	start := time.Now()
+
+	// Selecting an entry while paused does not start it.
+	tr.update(start, false, "1", "a", time.Minute*2)
+	if tr.begin(false) {
+		t.Fatal("Paused entry has begun")
+	}
+	tr.update(start.Add(time.Second), true, "1", "a", time.Minute*2)
+	if !tr.begin(true) {
+		t.Fatal("Entry has not begun")
+	}
+	// Pausing and resuming does not start the entry again.
+	tr.update(start.Add(time.Second*2), false, "1", "a", time.Minute*2)
+	tr.begin(false)
+	tr.update(start.Add(time.Second*3), true, "1", "a", time.Minute*2)
+	if tr.begin(true) {
+		t.Fatal("Entry has begun twice")
+	}
+	// The same track queued again is a new entry.
+	tr.update(start.Add(time.Second*4), true, "2", "a", time.Minute*2)
+	if !tr.begin(true) {
+		t.Fatal("Next entry has not begun")
+	}
+	tr.update(start.Add(time.Second*5), false, "", "", 0)
+	if tr.begin(false) || tr.begin(true) {
+		t.Fatal("Empty entry has begun")
+	}
+}
+
+func TestPlayTrackerSkipped(t *testing.T) {
+	tr := playTracker{ratio: DefaultPlayCountRatio, max: DefaultPlayCountMax}
+	start := time.Now()
+
+	tr.update(start, true, "1", "a", time.Minute*2)
+	tr.begin(true)
+	tr.update(start.Add(time.Second*10), true, "2", "b", time.Minute*2)
+	tr.begin(true)
+	if uri := tr.skipped(); uri != "a" {
+		t.Fatalf("Unexpected skipped entry: %q", uri)
+	}
+	if uri := tr.skipped(); uri != "" {
+		t.Fatalf("Skip reported twice: %q", uri)
+	}
+	// Entries that were counted are not skipped.
+	tr.update(start.Add(time.Minute*2), true, "3", "c", time.Minute*2)
+	tr.begin(true)
+	if uri := tr.skipped(); uri != "" {
+		t.Fatalf("Played entry was skipped: %q", uri)
+	}
+	// Neither are entries that were never started.
+	tr.update(start.Add(time.Minute*2), false, "4", "d", time.Minute*2)
+	tr.update(start.Add(time.Minute*2+time.Second), false, "5", "e", time.Minute*2)
+	if uri := tr.skipped(); uri != "" {
+		t.Fatalf("Unstarted entry was skipped: %q", uri)
+	}
+}
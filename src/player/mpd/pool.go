@@ -0,0 +1,88 @@
+package mpd
+
+import (
+	"context"
+	"sync"
+
+	"github.com/fhs/gompd/mpd"
+)
+
+// A clientPool limits the number of concurrent connections to MPD and keeps
+// idle connections around for reuse.
+//
+// Idle connections are handed out in least recently used order, so they are
+// all used evenly and none of them idles until MPD closes it. A new connection
+// is only needed if no idle connection is available.
+type clientPool struct {
+	// Holds one token for each connection that may be checked out.
+	slots chan struct{}
+	// Ordered from least to most recently used.
+	idle     []*mpd.Client
+	idleLock sync.Mutex
+}
+
+func newClientPool(size int) *clientPool {
+	pool := &clientPool{slots: make(chan struct{}, size)}
+	for i := 0; i < size; i++ {
+		pool.slots <- struct{}{}
+	}
+	return pool
+}
+
+// Size returns the maximum number of connections.
+func (pool *clientPool) Size() int {
+	return cap(pool.slots)
+}
+
+// Get blocks until a connection may be checked out and returns the least
+// recently used idle client. Nil is returned if there are no idle clients, in
+// which case the caller should dial a new connection.
+func (pool *clientPool) Get() *mpd.Client {
+	client, _ := pool.GetContext(context.Background())
+	return client
+}
+
+// GetContext is like Get, but gives up waiting once the context is done.
+func (pool *clientPool) GetContext(ctx context.Context) (*mpd.Client, error) {
+	select {
+	case <-pool.slots:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	pool.idleLock.Lock()
+	defer pool.idleLock.Unlock()
+	if len(pool.idle) == 0 {
+		return nil, nil
+	}
+	client := pool.idle[0]
+	pool.idle[0] = nil
+	pool.idle = pool.idle[1:]
+	return client, nil
+}
+
+// Put returns a client that was checked out. A nil client releases the slot
+// without returning a connection, for example if it was broken.
+func (pool *clientPool) Put(client *mpd.Client) {
+	if client != nil {
+		pool.idleLock.Lock()
+		pool.idle = append(pool.idle, client)
+		pool.idleLock.Unlock()
+	}
+	pool.slots <- struct{}{}
+}
+
+// Drain waits until all clients have been returned and removes all idle
+// clients from the pool, which are returned.
+func (pool *clientPool) Drain() []*mpd.Client {
+	for i := 0; i < cap(pool.slots); i++ {
+		<-pool.slots
+	}
+	pool.idleLock.Lock()
+	clients := pool.idle
+	pool.idle = nil
+	pool.idleLock.Unlock()
+	for i := 0; i < cap(pool.slots); i++ {
+		pool.slots <- struct{}{}
+	}
+	return clients
+}
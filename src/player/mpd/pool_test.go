@@ -0,0 +1,45 @@
+package mpd
+
+import (
+	"testing"
+
+	"github.com/fhs/gompd/mpd"
+)
+
+func TestClientPoolLeastRecentlyUsed(t *testing.T) {
+	pool := newClientPool(3)
+	a, b := &mpd.Client{}, &mpd.Client{}
+
+	for i := 0; i < 2; i++ {
+		if c := pool.Get(); c != nil {
+			t.Fatalf("An empty pool should not return a client")
+		}
+	}
+	pool.Put(a)
+	pool.Put(b)
+
+	// Idle clients are preferred over dialing new ones and are used in
+	// turns.
+	for i, expected := range []*mpd.Client{a, b, a, b} {
+		c := pool.Get()
+		if c != expected {
+			t.Fatalf("Unexpected client at %d", i)
+		}
+		pool.Put(c)
+	}
+
+	// Discarded clients are not returned.
+	pool.Get()
+	pool.Put(nil)
+	if c := pool.Get(); c != b {
+		t.Fatalf("Unexpected client")
+	}
+	pool.Put(b)
+
+	if clients := pool.Drain(); len(clients) != 1 || clients[0] != b {
+		t.Fatalf("Unexpected drained clients: %v", clients)
+	}
+	if pool.Size() != 3 || len(pool.slots) != 3 {
+		t.Fatalf("The pool should be usable after draining")
+	}
+}
@@ -0,0 +1,166 @@
+package mpd
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/fhs/gompd/mpd"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/polyfloyd/trollibox/src/library"
+	"github.com/polyfloyd/trollibox/src/player"
+)
+
+// DefaultStreamReconnectDelay is the default time to wait before replaying a
+// stream that stopped because of an error.
+const DefaultStreamReconnectDelay = time.Second * 2
+
+// A stream that plays for this long after it was reconnected is considered to
+// have recovered, so it gets all attempts again when it fails later on.
+const streamRecoveredAfter = time.Minute
+
+type reconnectAction int
+
+const (
+	reconnectNone reconnectAction = iota
+	// The error of MPD predates the stream that started playing and should
+	// be cleared so it is not mistaken for a failure of the stream.
+	reconnectClearError
+	// The stream failed and should be played again.
+	reconnectRetry
+	// The stream failed too often, playback should move on.
+	reconnectGiveUp
+)
+
+// A streamReconnector detects that the stream that was playing stopped because
+// of an error, like a hiccup of the server of a radio station.
+//
+// MPD does not report which entry of the queue an error belongs to. So the
+// stream that is playing is remembered, and an error is only attributed to it
+// if playback stopped while the stream is still the current song. Errors of
+// other songs are left alone.
+type streamReconnector struct {
+	// The number of times a failed stream is played again, 0 to disable.
+	attempts int
+
+	// The entry of the queue of the stream that is watched.
+	songID string
+	uri    string
+	// The number of times the stream has been played again.
+	tries   int
+	lastTry time.Time
+	// Set while the stream is about to be played again.
+	pending bool
+}
+
+// update processes the state of playback and reports what should be done
+// about it. The returned attempt is the number of the attempt to play the
+// stream again, starting at 1.
+func (rc *streamReconnector) update(now time.Time, state, songID, uri, errMsg string) (reconnectAction, int) {
+	if rc.attempts == 0 || rc.pending {
+		return reconnectNone, 0
+	}
+	if rc.songID != "" && errMsg != "" && state == "stop" && songID == rc.songID {
+		if !rc.lastTry.IsZero() && now.Sub(rc.lastTry) > streamRecoveredAfter {
+			rc.tries = 0
+		}
+		if rc.tries >= rc.attempts {
+			rc.reset()
+			return reconnectGiveUp, 0
+		}
+		rc.tries++
+		rc.lastTry = now
+		rc.pending = true
+		return reconnectRetry, rc.tries
+	}
+
+	switch state {
+	case "play":
+		if songID == rc.songID {
+			return reconnectNone, 0
+		}
+		rc.reset()
+		if !library.IsStreamURI(uri) {
+			return reconnectNone, 0
+		}
+		rc.songID, rc.uri = songID, uri
+		if errMsg != "" {
+			return reconnectClearError, 0
+		}
+	case "stop":
+		// Playback was stopped deliberately.
+		rc.reset()
+	}
+	return reconnectNone, 0
+}
+
+func (rc *streamReconnector) reset() {
+	*rc = streamReconnector{attempts: rc.attempts}
+}
+
+// SetStreamReconnect configures how often a stream that stopped because of an
+// error is played again before playback moves on. Each attempt is preceded by
+// the specified delay. Zero attempts disables reconnecting, which is the
+// default.
+func (pl *Player) SetStreamReconnect(attempts int, delay time.Duration) {
+	pl.reconnectLock.Lock()
+	defer pl.reconnectLock.Unlock()
+	pl.reconnect = streamReconnector{attempts: attempts}
+	pl.reconnectDelay = delay
+}
+
+// trackStreamError plays the stream that was playing again if it stopped
+// because of an error. A StreamReconnectEvent is emitted for each attempt.
+func (pl *Player) trackStreamError(mpdc *mpd.Client, status, song mpd.Attrs) error {
+	pl.reconnectLock.Lock()
+	action, attempt := pl.reconnect.update(time.Now(), status["state"], status["songid"], mpdToURI(song["file"]), status["error"])
+	songID, uri := pl.reconnect.songID, pl.reconnect.uri
+	attempts, delay := pl.reconnect.attempts, pl.reconnectDelay
+	pl.reconnectLock.Unlock()
+
+	switch action {
+	case reconnectClearError:
+		return mpdc.Command("clearerror").OK()
+
+	case reconnectRetry:
+		log.Warnf("%v: Stream %q failed (%s), reconnecting (%d/%d)", pl, uri, status["error"], attempt, attempts)
+		pl.Emit(player.StreamReconnectEvent{URI: uri, Attempt: attempt, MaxAttempts: attempts})
+		id, err := strconv.Atoi(songID)
+		if err != nil {
+			return err
+		}
+		pl.reconnectLock.Lock()
+		pl.reconnectTimer = time.AfterFunc(delay, func() {
+			select {
+			case <-pl.closed:
+				return
+			default:
+			}
+			err := pl.withMpd(func(mpdc *mpd.Client) error {
+				if err := mpdc.Command("clearerror").OK(); err != nil {
+					return err
+				}
+				return mpdc.PlayID(id)
+			})
+			if err != nil {
+				log.Errorf("%v: Could not reconnect to %q: %v", pl, uri, err)
+			}
+			pl.reconnectLock.Lock()
+			pl.reconnect.pending = false
+			pl.reconnectLock.Unlock()
+		})
+		pl.reconnectLock.Unlock()
+
+	case reconnectGiveUp:
+		log.Warnf("%v: Stream %q failed (%s), giving up", pl, uri, status["error"])
+		pl.Emit(player.StreamReconnectEvent{URI: uri, Attempt: attempts, MaxAttempts: attempts, GaveUp: true})
+		if err := mpdc.Command("clearerror").OK(); err != nil {
+			return err
+		}
+		// MPD moves on to the next track by itself unless it stopped.
+		if next, ok := statusAttrInt(status, "nextsong"); ok && status["state"] == "stop" {
+			return mpdc.Play(next)
+		}
+	}
+	return nil
+}
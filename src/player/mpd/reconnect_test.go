@@ -0,0 +1,69 @@
+package mpd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStreamReconnector(t *testing.T) {
+	const stream = "http://radio.example.com/stream"
+	now := time.Unix(0, 0)
+	rc := streamReconnector{attempts: 2}
+
+	if action, _ := rc.update(now, "play", "1", stream, "Failed to decode"); action != reconnectClearError {
+		t.Fatalf("A stale error was not cleared: %v", action)
+	}
+	for i := 1; i <= 2; i++ {
+		action, attempt := rc.update(now, "stop", "1", stream, "connection reset")
+		if action != reconnectRetry || attempt != i {
+			t.Fatalf("Expected attempt %d, got %v, %d", i, action, attempt)
+		}
+		if action, _ := rc.update(now, "stop", "1", stream, "connection reset"); action != reconnectNone {
+			t.Fatalf("A pending attempt was not awaited: %v", action)
+		}
+		rc.pending = false
+		if action, _ := rc.update(now, "play", "1", stream, ""); action != reconnectNone {
+			t.Fatalf("Unexpected action while playing: %v", action)
+		}
+	}
+	if action, _ := rc.update(now, "stop", "1", stream, "connection reset"); action != reconnectGiveUp {
+		t.Fatalf("Expected to give up, got %v", action)
+	}
+	if action, _ := rc.update(now, "play", "2", "local.mp3", "connection reset"); action != reconnectNone {
+		t.Fatalf("Tracks that are not streams should not be watched: %v", action)
+	}
+
+	// Errors of other songs are not attributed to the stream.
+	rc.update(now, "play", "4", stream, "")
+	if action, _ := rc.update(now, "stop", "5", "local.mp3", "Failed to decode"); action != reconnectNone {
+		t.Fatalf("The error of another song was attributed to the stream: %v", action)
+	}
+	rc.update(now, "play", "4", stream, "")
+	if action, _ := rc.update(now, "play", "4", stream, "connection reset"); action != reconnectNone {
+		t.Fatalf("A stream that is still playing should not be reconnected: %v", action)
+	}
+
+	// Attempts are granted again once the stream has recovered.
+	rc.update(now, "play", "3", stream, "")
+	rc.update(now, "stop", "3", stream, "error")
+	rc.pending = false
+	rc.update(now, "play", "3", stream, "")
+	now = now.Add(streamRecoveredAfter * 2)
+	if _, attempt := rc.update(now, "stop", "3", stream, "error"); attempt != 1 {
+		t.Fatalf("Attempts were not reset after recovering: %d", attempt)
+	}
+	rc.pending = false
+
+	// Stopping without an error is deliberate.
+	rc.update(now, "play", "3", stream, "")
+	rc.update(now, "stop", "3", stream, "")
+	if action, _ := rc.update(now, "stop", "3", stream, "error"); action != reconnectNone {
+		t.Fatalf("A stopped stream should not be reconnected: %v", action)
+	}
+
+	disabled := streamReconnector{}
+	disabled.update(now, "play", "1", stream, "")
+	if action, _ := disabled.update(now, "stop", "1", stream, "error"); action != reconnectNone {
+		t.Fatalf("Reconnecting is disabled: %v", action)
+	}
+}
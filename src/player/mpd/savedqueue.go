@@ -0,0 +1,133 @@
+package mpd
+
+import (
+	"github.com/fhs/gompd/mpd"
+
+	"github.com/polyfloyd/trollibox/src/library"
+	"github.com/polyfloyd/trollibox/src/player"
+)
+
+// savedQueueMeta holds the metadata of a queue that was saved as stored
+// playlist, which MPD has no room for.
+type savedQueueMeta struct {
+	uris []string
+	meta []player.TrackMeta
+}
+
+// SavedQueues implements the player.QueueSaver interface.
+//
+// Queues are saved as MPD stored playlists, so playlists created by other
+// clients are listed as well.
+func (pl *Player) SavedQueues() ([]string, error) {
+	return pl.StoredPlaylists()
+}
+
+// SaveQueue implements the player.QueueSaver interface.
+func (pl *Player) SaveQueue(name string) error {
+	if err := player.CheckQueueName(name); err != nil {
+		return err
+	}
+	tracks, err := pl.playlist.Tracks()
+	if err != nil {
+		return err
+	}
+	meta, err := pl.playlist.Meta()
+	if err != nil {
+		return err
+	}
+	err = pl.withMpd(func(mpdc *mpd.Client) error {
+		if err := mpdc.PlaylistRemove(name); err != nil && ackCode(err.Error()) != ackErrorNoExist {
+			return err
+		}
+		return mpdc.PlaylistSave(name)
+	})
+	if err != nil {
+		return err
+	}
+
+	saved := savedQueueMeta{
+		uris: make([]string, len(tracks)),
+		meta: append([]player.TrackMeta(nil), meta...),
+	}
+	for i, track := range tracks {
+		saved.uris[i] = track.URI
+	}
+	pl.savedMetaLock.Lock()
+	defer pl.savedMetaLock.Unlock()
+	pl.savedMeta[name] = saved
+	return nil
+}
+
+// LoadQueue implements the player.QueueSaver interface.
+//
+// The metadata of the tracks is restored if the queue was saved by this
+// player and has not been modified since. Otherwise, the tracks are marked as
+// queued by the user.
+func (pl *Player) LoadQueue(name string) error {
+	var uris []string
+	err := pl.withMpd(func(mpdc *mpd.Client) error {
+		songs, err := mpdc.PlaylistContents(name)
+		if err != nil {
+			return err
+		}
+		uris = make([]string, len(songs))
+		for i, song := range songs {
+			uris[i] = mpdToURI(song["file"])
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(uris) == 0 {
+		return nil
+	}
+
+	pl.savedMetaLock.Lock()
+	saved, ok := pl.savedMeta[name]
+	pl.savedMetaLock.Unlock()
+	if ok && !equalStrings(saved.uris, uris) {
+		ok = false
+	}
+
+	tracks := make([]library.Track, len(uris))
+	meta := make([]player.TrackMeta, len(uris))
+	for i, uri := range uris {
+		tracks[i].URI = uri
+		if ok && i < len(saved.meta) {
+			meta[i] = saved.meta[i]
+		} else {
+			meta[i].QueuedBy = "user"
+		}
+	}
+	return pl.playlist.InsertWithMeta(-1, tracks, meta)
+}
+
+// RemoveSavedQueue implements the player.QueueSaver interface.
+func (pl *Player) RemoveSavedQueue(name string) error {
+	err := pl.withMpd(func(mpdc *mpd.Client) error {
+		if err := mpdc.PlaylistRemove(name); err != nil && ackCode(err.Error()) != ackErrorNoExist {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	pl.savedMetaLock.Lock()
+	defer pl.savedMetaLock.Unlock()
+	delete(pl.savedMeta, name)
+	return nil
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,28 @@
+package mpd
+
+import (
+	"time"
+)
+
+// DefaultStreamRefresh is the default interval at which the metadata of a
+// playing stream is refreshed.
+const DefaultStreamRefresh = time.Second * 15
+
+// A streamTitleTracker keeps track of the title of the stream that is being
+// played, which changes as the station moves on to the next song.
+type streamTitleTracker struct {
+	songID string
+	title  string
+}
+
+// update processes the current entry of the queue. It reports whether the
+// title of the entry changed since it was last observed.
+func (tr *streamTitleTracker) update(songID, title string) (changed bool) {
+	if songID != tr.songID {
+		*tr = streamTitleTracker{songID: songID, title: title}
+		return false
+	}
+	changed = title != tr.title
+	tr.title = title
+	return changed
+}
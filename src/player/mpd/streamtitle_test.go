@@ -0,0 +1,24 @@
+package mpd
+
+import (
+	"testing"
+)
+
+func TestStreamTitleTracker(t *testing.T) {
+	var tr streamTitleTracker
+	if tr.update("1", "Artist - First") {
+		t.Fatal("The title of a new entry is not a change")
+	}
+	if tr.update("1", "Artist - First") {
+		t.Fatal("The title did not change")
+	}
+	if !tr.update("1", "Artist - Second") {
+		t.Fatal("The change of the title was not reported")
+	}
+	if tr.update("2", "Other - Third") {
+		t.Fatal("The title of a new entry is not a change")
+	}
+	if tr.update("", "") {
+		t.Fatal("Stopping is not a change of the title")
+	}
+}
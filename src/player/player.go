@@ -1,12 +1,22 @@
 package player
 
 import (
+	"context"
+	"fmt"
 	"time"
 
 	"github.com/polyfloyd/trollibox/src/library"
 	"github.com/polyfloyd/trollibox/src/util"
 )
 
+// ErrUnseekable is returned by SetTime if the current track is a stream,
+// which can not be seeked.
+var ErrUnseekable = fmt.Errorf("the current track is a stream and can not be seeked")
+
+// ErrUnsupported is returned by players that wrap other players if the wrapped
+// player does not implement an optional interface.
+var ErrUnsupported = fmt.Errorf("the player does not support this operation")
+
 // PlayState enumerates all 3 possible states of playback.
 type PlayState string
 
@@ -24,6 +34,27 @@ const (
 	PlayStatePaused = PlayState("paused")
 )
 
+// Reasons for a player to be unavailable, see AvailabilityEvent.
+const (
+	// UnavailableConnectionRefused indicates that nothing is accepting
+	// connections at the address of the player.
+	UnavailableConnectionRefused = "connection-refused"
+	// UnavailableUnreachable indicates that the host of the player could
+	// not be resolved or reached.
+	UnavailableUnreachable = "unreachable"
+	// UnavailableTimeout indicates that the player did not respond in time.
+	UnavailableTimeout = "timeout"
+	// UnavailableAuthFailed indicates that the player rejected the
+	// credentials or lacks the permissions required by Trollibox.
+	UnavailableAuthFailed = "auth-failed"
+	// UnavailableDisconnected indicates that an established connection was
+	// closed by the other end, for example because the player was stopped.
+	UnavailableDisconnected = "disconnected"
+	// UnavailableUnknown indicates that the player is unavailable for a
+	// reason that could not be determined.
+	UnavailableUnknown = "unknown"
+)
+
 type (
 	// Event is the type of event emitted by the player.
 	Event interface{}
@@ -40,6 +71,9 @@ type (
 	// track was changed.
 	TimeEvent struct {
 		Time time.Duration
+		// At is the moment the offset was sampled. Clients can use it to
+		// extrapolate the offset without being affected by delays.
+		At time.Time
 	}
 	// VolumeEvent is emitted after the volume was changed.
 	VolumeEvent struct {
@@ -51,9 +85,180 @@ type (
 	// offline.
 	AvailabilityEvent struct {
 		Available bool
+		// Why the player is unavailable, one of the Unavailable*
+		// constants. It may be empty if the reason is not known and is
+		// always empty if the player is available.
+		Reason string
+	}
+	// QueueExhaustedEvent is emitted when playback advances past the end of
+	// a non-empty playlist. It is not emitted when playback is stopped
+	// explicitly.
+	QueueExhaustedEvent struct{}
+	// TrackStartedEvent is emitted when a new entry of the playlist begins
+	// playing. Unlike PlaylistEvent, it is not emitted for changes to the
+	// playlist that do not affect the playing track.
+	TrackStartedEvent struct {
+		URI string
+	}
+	// TrackMetadataEvent is emitted when the metadata of the playing track
+	// changes while it is playing, like the title of a stream when the
+	// station moves on to the next song.
+	TrackMetadataEvent struct {
+		URI   string
+		Title string
+	}
+	// PlayEvent is emitted once a track has been listened to long enough to
+	// count as played.
+	PlayEvent struct {
+		URI string
+		// The moment the track started playing.
+		Started time.Time
+	}
+	// StreamReconnectEvent is emitted when a stream that stopped because of
+	// an error is played again, or when the player gives up on it.
+	StreamReconnectEvent struct {
+		URI string
+		// The number of the attempt, starting at 1.
+		Attempt     int
+		MaxAttempts int
+		// Set if the stream failed after the last attempt and playback
+		// moves on.
+		GaveUp bool
 	}
 )
 
+// ServerStats contains statistics about the server that is backing a player.
+type ServerStats struct {
+	// The time the server has been running.
+	Uptime time.Duration
+	// The time the server has been playing music.
+	Playtime time.Duration
+	// The sum of the durations of all tracks in the database.
+	DBPlaytime time.Duration
+	// The moment the database was last updated.
+	DBUpdate time.Time
+
+	Artists int
+	Albums  int
+	Tracks  int
+}
+
+// A StatsReporter is a player that is able to report statistics about the
+// server it is connected to.
+type StatsReporter interface {
+	ServerStats() (ServerStats, error)
+}
+
+// A ReplayGainReader is a player that is able to read the ReplayGain of tracks
+// from their files. This is for players that do not report the ReplayGain
+// along with the other info of tracks.
+type ReplayGainReader interface {
+	// ReadReplayGain sets the ReplayGain of the tracks in place. Tracks of
+	// which the ReplayGain can not be read are left alone.
+	ReadReplayGain(tracks []library.Track) error
+}
+
+// A ServerSearcher is a player that can search its library by tag on the
+// server, which is cheaper than filtering all tracks of large libraries.
+type ServerSearcher interface {
+	// SearchServerSide returns the tracks of which the value of each tag
+	// contains the respective value, ignoring case.
+	SearchServerSide(tags map[string]string) ([]library.Track, error)
+}
+
+// A URISupporter is a player that can tell which tracks it is able to play.
+// Players that do not implement it are assumed to play anything.
+type URISupporter interface {
+	// Supports reports whether the track with the specified URI can be
+	// added to the playlist.
+	Supports(uri string) bool
+}
+
+// A ContextBinder is a player of which the operations can be bound to a
+// context.
+type ContextBinder interface {
+	// WithContext returns a view of the player that shares all of its
+	// state, but aborts its operations with the error of the context once
+	// the context is done. The view should only be used for the lifetime of
+	// the context.
+	WithContext(ctx context.Context) Player
+}
+
+// WithContext binds the operations of the player to the context if it is a
+// ContextBinder. Other players are returned as is.
+func WithContext(ctx context.Context, pl Player) Player {
+	if binder, ok := pl.(ContextBinder); ok {
+		return binder.WithContext(ctx)
+	}
+	return pl
+}
+
+// A Prioritizer is a player of which the entries of the playlist have a
+// priority. When the player plays the playlist in random order, entries with a
+// higher priority are played first, entries of equal priority are picked
+// randomly. The priority has no effect on playback in order.
+type Prioritizer interface {
+	// TrackPriorities returns the priority of each entry of the playlist.
+	TrackPriorities() ([]int, error)
+
+	// SetTrackPriority sets the priority of the entry at the specified
+	// position of the playlist. The priority ranges from 0, the default,
+	// to MaxTrackPriority.
+	SetTrackPriority(pos, prio int) error
+}
+
+// MaxTrackPriority is the highest priority of an entry of a playlist, see
+// Prioritizer.
+const MaxTrackPriority = 255
+
+// A PlayCounter is a player that keeps track of how often tracks have been
+// played.
+type PlayCounter interface {
+	// PlayCounts returns the number of times tracks were played by their
+	// URI. Tracks that have never been played may be omitted.
+	PlayCounts() (map[string]int, error)
+}
+
+// A SkipCounter is a player that keeps track of how often tracks have been
+// skipped, that is, advanced past before they counted as played.
+type SkipCounter interface {
+	// SkipCounts returns the number of times tracks were skipped by their
+	// URI. Tracks that have never been skipped may be omitted.
+	SkipCounts() (map[string]int, error)
+}
+
+// PoolStats describes the state of the connections a player maintains to the
+// server backing it. It is intended for diagnosing connection problems.
+type PoolStats struct {
+	// The maximum number of connections in the pool.
+	Size int
+	// The number of open connections, including those in use.
+	Alive int
+	// The number of connections that are currently in use.
+	CheckedOut int
+	// The number of operations waiting for a connection to become
+	// available.
+	Waiting int
+	// The number of connections that were discarded because an operation
+	// did not complete in time.
+	Timeouts int
+	// The most recent error of a connection that failed a health check and
+	// the moment it occurred.
+	LastPingError     string
+	LastPingErrorTime time.Time
+	// Whether the connection that listens for events is established.
+	EventsConnected bool
+	// The moment the connection that listens for events was last
+	// (re)established.
+	EventsConnectedTime time.Time
+}
+
+// A PoolReporter is a player that is able to report on the state of its
+// connections.
+type PoolReporter interface {
+	PoolStats() PoolStats
+}
+
 // The Player is the heart of Trollibox. This interface provides all common
 // actions that can be performed on a mediaplayer.
 type Player interface {
@@ -69,6 +274,9 @@ type Player interface {
 	Library() library.Library
 
 	// Returns the currently playing playlist.
+	//
+	// Tracks that have been played are not removed, they remain in the
+	// playlist before the track at TrackIndex.
 	Playlist() MetaPlaylist
 
 	// Gets the time offset into the currently playing track. 0 if no track is
@@ -76,10 +284,12 @@ type Player interface {
 	Time() (time.Duration, error)
 
 	// SetTime Seeks to the absolute point in time of the current track. This is a
-	// no-op if player has been stopped.
+	// no-op if player has been stopped. ErrUnseekable is returned if the
+	// current track is a stream.
 	SetTime(offset time.Duration) error
 
-	// Returns absolute index into the players' playlist.
+	// Returns absolute index into the players' playlist. -1 is returned if
+	// the player has no current track.
 	TrackIndex() (int, error)
 
 	// Jumps to the specified track in the players' playlist. If the index is
@@ -90,8 +300,9 @@ type Player interface {
 	// Returns the current playstate of the player.
 	State() (PlayState, error)
 
-	// Signal the player to start/resume, stop or pause playback. If the
-	// playlist is empty, a playlist-end event is emitted.
+	// Signal the player to start/resume, stop or pause playback. If playback
+	// is started while the playlist is empty, only a PlayStateEvent is
+	// emitted.
 	SetState(state PlayState) error
 
 	// Gets the set volume as a value between 0 and 100.
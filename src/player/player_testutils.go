@@ -62,6 +62,9 @@ func TestPlayerImplementation(t *testing.T, pl Player) {
 	t.Run("volume_event", func(t *testing.T) {
 		testVolumeEvent(t, pl)
 	})
+	t.Run("play_queue_index", func(t *testing.T) {
+		testPlayQueueIndex(t, pl)
+	})
 }
 
 func testAvailability(t *testing.T, pl Player) {
@@ -90,14 +93,29 @@ func testTime(t *testing.T, pl Player) {
 
 func testTimeEvent(t *testing.T, pl Player) {
 	newTime := time.Second * 2
-	util.TestEventEmission(t, pl, TimeEvent{Time: newTime}, func() {
-		if err := pl.SetState(PlayStatePlaying); err != nil {
-			t.Fatal(err)
-		}
-		if err := pl.SetTime(newTime); err != nil {
-			t.Fatal(err)
+	l := pl.Events().Listen()
+	defer pl.Events().Unlisten(l)
+	if err := pl.SetState(PlayStatePlaying); err != nil {
+		t.Fatal(err)
+	}
+	if err := pl.SetTime(newTime); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		select {
+		case msg := <-l:
+			// The moment of sampling is not known in advance, so only the
+			// offset is compared.
+			if ev, ok := msg.(TimeEvent); ok && ev.Time == newTime {
+				if ev.At.IsZero() {
+					t.Fatalf("The time event was not timestamped")
+				}
+				return
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Time event for %v was not emitted", newTime)
 		}
-	})
+	}
 }
 
 func testTrackIndex(t *testing.T, pl Player) {
@@ -239,3 +257,42 @@ func testVolumeEvent(t *testing.T, pl Player) {
 		}
 	})
 }
+
+func testPlayQueueIndex(t *testing.T, pl Player) {
+	if err := fillPlaylist(pl, 3); err != nil {
+		t.Fatal(err)
+	}
+	tracks, err := pl.Playlist().Tracks()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := PlayQueueIndex(pl, 1, false); err != nil {
+		t.Fatal(err)
+	}
+	if index, err := pl.TrackIndex(); err != nil {
+		t.Fatal(err)
+	} else if index != 1 {
+		t.Fatalf("Unexpected track index: %v != %v", 1, index)
+	}
+
+	if err := PlayQueueIndex(pl, 2, true); err != nil {
+		t.Fatal(err)
+	}
+	if index, err := pl.TrackIndex(); err != nil {
+		t.Fatal(err)
+	} else if index != 0 {
+		t.Fatalf("Unexpected track index: %v != %v", 0, index)
+	}
+	remaining, err := pl.Playlist().Tracks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(remaining) != 1 || remaining[0].URI != tracks[2].URI {
+		t.Fatalf("Unexpected playlist after dropping preceding tracks: %v", remaining)
+	}
+
+	if err := PlayQueueIndex(pl, 5, false); err == nil {
+		t.Fatal("Expected an error for an out of range index")
+	}
+}
@@ -0,0 +1,116 @@
+package player
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path"
+	"sync"
+	"time"
+)
+
+// A Play is a single occurrence of a track being played.
+type Play struct {
+	URI  string    `json:"uri"`
+	Time time.Time `json:"time"`
+}
+
+// A PlayHistory records when tracks were played. Unlike a play count, this
+// allows recent plays to be told apart from old ones.
+//
+// The plays of each player are appended to a file in a directory with one
+// JSON object per line.
+type PlayHistory struct {
+	directory string
+	lock      sync.Mutex
+}
+
+// NewPlayHistory creates a history that keeps its records in the specified
+// directory.
+func NewPlayHistory(directory string) (*PlayHistory, error) {
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return nil, err
+	}
+	return &PlayHistory{directory: directory}, nil
+}
+
+// Record adds a play to the history of the named player.
+func (history *PlayHistory) Record(playerName string, play Play) error {
+	history.lock.Lock()
+	defer history.lock.Unlock()
+	fd, err := os.OpenFile(history.file(playerName), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(fd).Encode(play); err != nil {
+		fd.Close()
+		return err
+	}
+	return fd.Close()
+}
+
+// Plays returns the plays of the named player that happened after since, in
+// the order they were recorded.
+func (history *PlayHistory) Plays(playerName string, since time.Time) ([]Play, error) {
+	history.lock.Lock()
+	defer history.lock.Unlock()
+	return history.plays(playerName, since)
+}
+
+func (history *PlayHistory) plays(playerName string, since time.Time) ([]Play, error) {
+	fd, err := os.Open(history.file(playerName))
+	if os.IsNotExist(err) {
+		return []Play{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	plays := []Play{}
+	scanner := bufio.NewScanner(fd)
+	for scanner.Scan() {
+		var play Play
+		if err := json.Unmarshal(scanner.Bytes(), &play); err != nil {
+			// A line may have been left incomplete by a crash.
+			continue
+		}
+		if play.Time.After(since) {
+			plays = append(plays, play)
+		}
+	}
+	return plays, scanner.Err()
+}
+
+// Prune removes the plays of the named player that happened before the
+// specified moment to keep the history from growing indefinitely.
+func (history *PlayHistory) Prune(playerName string, before time.Time) error {
+	history.lock.Lock()
+	defer history.lock.Unlock()
+	plays, err := history.plays(playerName, before)
+	if err != nil {
+		return err
+	}
+
+	file := history.file(playerName)
+	fd, err := os.Create(file + ".tmp")
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(fd)
+	for _, play := range plays {
+		if err := enc.Encode(play); err != nil {
+			fd.Close()
+			os.Remove(file + ".tmp")
+			return err
+		}
+	}
+	if err := fd.Close(); err != nil {
+		os.Remove(file + ".tmp")
+		return err
+	}
+	return os.Rename(file+".tmp", file)
+}
+
+func (history *PlayHistory) file(playerName string) string {
+	return path.Join(history.directory, playerName+".jsonl")
+}
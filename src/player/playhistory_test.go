@@ -0,0 +1,49 @@
+package player
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestPlayHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trollibox-playhistory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	history, err := NewPlayHistory(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	for i, uri := range []string{"a", "b", "c"} {
+		play := Play{URI: uri, Time: now.Add(time.Duration(i-2) * time.Hour)}
+		if err := history.Record("kitchen", play); err != nil {
+			t.Fatal(err)
+		}
+	}
+	plays, err := history.Plays("kitchen", now.Add(-time.Minute*90))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plays) != 2 || plays[0].URI != "b" || plays[1].URI != "c" {
+		t.Fatalf("Unexpected plays: %v", plays)
+	}
+
+	if err := history.Prune("kitchen", now.Add(-time.Minute*30)); err != nil {
+		t.Fatal(err)
+	}
+	plays, err = history.Plays("kitchen", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plays) != 1 || plays[0].URI != "c" {
+		t.Fatalf("Unexpected plays after pruning: %v", plays)
+	}
+	if plays, _ := history.Plays("bedroom", time.Time{}); len(plays) != 0 {
+		t.Fatalf("Unexpected plays of another player: %v", plays)
+	}
+}
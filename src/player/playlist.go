@@ -1,6 +1,9 @@
 package player
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/polyfloyd/trollibox/src/library"
 )
 
@@ -36,6 +39,155 @@ type MetaPlaylist interface {
 	Meta() ([]TrackMeta, error)
 }
 
+// A RangeMover is a Playlist which is able to move a contiguous block of
+// tracks in a single operation.
+type RangeMover interface {
+	// MoveRange moves count tracks starting at fromPos so the first of them
+	// ends up at toPos. Like Move, toPos refers to the position in the
+	// resulting playlist.
+	MoveRange(fromPos, toPos, count int) error
+}
+
+// MoveRange moves count tracks starting at fromPos so the first of them ends
+// up at toPos. An error is returned if the block does not fit in the playlist
+// before or after moving.
+//
+// If the playlist does not implement RangeMover, the tracks are moved one by
+// one.
+func MoveRange(plist Playlist, fromPos, toPos, count int) error {
+	length, err := plist.Len()
+	if err != nil {
+		return err
+	}
+	if err := checkMoveRange(length, fromPos, toPos, count); err != nil {
+		return err
+	}
+	if mover, ok := plist.(RangeMover); ok {
+		return mover.MoveRange(fromPos, toPos, count)
+	}
+	for i := 0; i < count; i++ {
+		var err error
+		if toPos < fromPos {
+			err = plist.Move(fromPos+i, toPos+i)
+		} else {
+			err = plist.Move(fromPos, toPos+count-1)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkMoveRange(length, fromPos, toPos, count int) error {
+	if count < 1 {
+		return fmt.Errorf("invalid number of tracks to move: %d", count)
+	}
+	if fromPos < 0 || toPos < 0 || fromPos+count > length || toPos+count > length {
+		return fmt.Errorf("move range out of bounds: (%d..%d -> %d) len=%d", fromPos, fromPos+count-1, toPos, length)
+	}
+	return nil
+}
+
+// moveRangePermutation returns the old positions of the tracks in a playlist
+// after a range move, indexed by their new positions.
+func moveRangePermutation(length, fromPos, toPos, count int) []int {
+	rest := make([]int, 0, length-count)
+	for i := 0; i < length; i++ {
+		if i < fromPos || i >= fromPos+count {
+			rest = append(rest, i)
+		}
+	}
+	perm := make([]int, 0, length)
+	perm = append(perm, rest[:toPos]...)
+	for i := 0; i < count; i++ {
+		perm = append(perm, fromPos+i)
+	}
+	return append(perm, rest[toPos:]...)
+}
+
+// InsertResult describes where tracks that were inserted into a playlist ended
+// up.
+type InsertResult struct {
+	// The positions of the inserted tracks in the playlist.
+	Positions []int
+	// The identifiers assigned to the inserted tracks. Nil if the playlist
+	// does not assign identifiers.
+	IDs []string
+	// The positions of requested tracks that were not inserted because they
+	// were already queued, as they are after the insertion.
+	Existing []int
+}
+
+// An IDPlaylist is a Playlist which assigns an identifier to every inserted
+// track, like the song IDs of MPD.
+type IDPlaylist interface {
+	// InsertIDs is like Insert, but returns the identifiers of the inserted
+	// tracks.
+	InsertIDs(pos int, tracks ...library.Track) ([]string, error)
+
+	// RemoveIDs removes the tracks with the specified identifiers regardless
+	// of their position. Identifiers that are not in the playlist are ignored.
+	// The positions of the removed tracks are returned.
+	RemoveIDs(ids ...string) ([]int, error)
+
+	// RemoveURIs removes all tracks that have one of the specified URIs. The
+	// tracks are looked up and removed by their identifiers, so tracks moved
+	// by someone else in the meantime are not affected. The positions of the
+	// removed tracks are returned.
+	RemoveURIs(uris ...string) ([]int, error)
+}
+
+// RemovedTrack describes a track that was removed from a playlist.
+type RemovedTrack struct {
+	// The position of the track before it was removed.
+	Position int
+	Track    library.Track
+}
+
+// A SelectiveRemover is a Playlist which is able to remove tracks by their
+// URI or identifier rather than their position. Unlike positions, these stay
+// valid if the playlist is changed by someone else in the meantime.
+type SelectiveRemover interface {
+	// RemoveURIs removes all tracks that have one of the specified URIs.
+	RemoveURIs(uris ...string) ([]RemovedTrack, error)
+	// RemoveIDs removes the tracks with the specified identifiers. See
+	// IDPlaylist.
+	RemoveIDs(ids ...string) ([]RemovedTrack, error)
+}
+
+// An InsertReporter is a MetaPlaylist which is able to report where inserted
+// tracks ended up.
+type InsertReporter interface {
+	InsertWithMetaResult(pos int, tracks []library.Track, meta []TrackMeta) (InsertResult, error)
+}
+
+// QueueDuration computes how long it takes to play the playlist from the track
+// at index onwards, of which elapsed has already been played. An index of -1
+// counts the whole playlist.
+//
+// The duration of tracks like streams is unknown. These are counted
+// separately, in which case the returned duration is a lower bound.
+func QueueDuration(tracks []library.Track, index int, elapsed time.Duration) (remaining time.Duration, unknown int) {
+	if index < 0 {
+		index, elapsed = 0, 0
+	}
+	for i := index; i < len(tracks); i++ {
+		if tracks[i].IsStream || tracks[i].Duration <= 0 {
+			unknown++
+			continue
+		}
+		remaining += tracks[i].Duration
+		if i == index {
+			remaining -= elapsed
+			if remaining < 0 {
+				remaining = 0
+			}
+		}
+	}
+	return remaining, unknown
+}
+
 // A TrackIterator is a type that produces a finite or infinite stream of tracks.
 //
 // Used by AutoAppend.
@@ -61,7 +213,10 @@ func AutoAppend(pl Player, iter TrackIterator, cancel <-chan struct{}) <-chan er
 	outer:
 		for {
 			select {
-			case event := <-events:
+			case event, ok := <-events:
+				if !ok {
+					break outer
+				}
 				_, okA := event.(PlayStateEvent)
 				_, okB := event.(PlaylistEvent)
 				if !okA && !okB {
@@ -106,3 +261,30 @@ func AutoAppend(pl Player, iter TrackIterator, cancel <-chan struct{}) <-chan er
 	}()
 	return errc
 }
+
+// PlayQueueIndex jumps playback to the track at the specified index in the
+// playlist of the player. If dropPreceding is set, all tracks before it are
+// removed so the track ends up at the start of the playlist.
+func PlayQueueIndex(pl Player, index int, dropPreceding bool) error {
+	plist := pl.Playlist()
+	length, err := plist.Len()
+	if err != nil {
+		return err
+	}
+	if index < 0 || index >= length {
+		return fmt.Errorf("queue index out of range: %d, len=%d", index, length)
+	}
+	if err := pl.SetTrackIndex(index); err != nil {
+		return err
+	}
+	if !dropPreceding || index == 0 {
+		return nil
+	}
+	// Removing through the playlist of the player keeps the metadata of the
+	// remaining tracks aligned.
+	preceding := make([]int, index)
+	for i := range preceding {
+		preceding[i] = i
+	}
+	return plist.Remove(preceding...)
+}
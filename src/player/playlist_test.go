@@ -2,6 +2,7 @@ package player
 
 import (
 	"testing"
+	"time"
 
 	"github.com/polyfloyd/trollibox/src/library"
 )
@@ -26,3 +27,92 @@ func TestDummyPlaylistImplementation(t *testing.T) {
 	}
 	TestPlaylistImplementation(t, &DummyPlaylist{}, tracks)
 }
+
+func TestQueueDuration(t *testing.T) {
+	tracks := []library.Track{
+		{URI: "a", Duration: time.Minute},
+		{URI: "http://radio", IsStream: true},
+		{URI: "b", Duration: time.Minute * 2},
+		{URI: "c"},
+	}
+	if remaining, unknown := QueueDuration(tracks, 0, time.Second*20); remaining != time.Minute*2+time.Second*40 || unknown != 2 {
+		t.Fatalf("Unexpected duration: %v, %d unknown", remaining, unknown)
+	}
+	if remaining, unknown := QueueDuration(tracks, 2, time.Minute*3); remaining != 0 || unknown != 1 {
+		t.Fatalf("Unexpected duration: %v, %d unknown", remaining, unknown)
+	}
+	if remaining, unknown := QueueDuration(tracks, -1, time.Second*20); remaining != time.Minute*3 || unknown != 2 {
+		t.Fatalf("Unexpected duration: %v, %d unknown", remaining, unknown)
+	}
+}
+
+func TestMoveRange(t *testing.T) {
+	uris := func(plist Playlist) string {
+		tracks, _ := plist.Tracks()
+		s := ""
+		for _, track := range tracks {
+			s += track.URI
+		}
+		return s
+	}
+	tracks := []library.Track{{URI: "a"}, {URI: "b"}, {URI: "c"}, {URI: "d"}, {URI: "e"}}
+	tests := []struct {
+		from, to, count int
+		expected        string
+	}{
+		{1, 3, 2, "adebc"},
+		{3, 0, 2, "deabc"},
+		{0, 0, 5, "abcde"},
+		{2, 2, 1, "abcde"},
+	}
+	for _, test := range tests {
+		// Playlists that do not implement RangeMover should yield the
+		// same results.
+		for _, plist := range []Playlist{&DummyPlaylist{}, struct{ Playlist }{&DummyPlaylist{}}} {
+			plist.Insert(-1, tracks...)
+			if err := MoveRange(plist, test.from, test.to, test.count); err != nil {
+				t.Fatal(err)
+			}
+			if s := uris(plist); s != test.expected {
+				t.Fatalf("Unexpected result moving %d..%d to %d: %s", test.from, test.from+test.count-1, test.to, s)
+			}
+		}
+	}
+
+	plist := &DummyPlaylist{}
+	plist.Insert(-1, tracks...)
+	for _, invalid := range [][3]int{{0, 0, 0}, {-1, 0, 1}, {4, 0, 2}, {0, 4, 2}} {
+		if err := MoveRange(plist, invalid[0], invalid[1], invalid[2]); err == nil {
+			t.Fatalf("Expected an error for %v", invalid)
+		}
+	}
+}
+
+func TestMetaKeeperMoveRange(t *testing.T) {
+	metapl := PlaylistMetaKeeper{Playlist: &DummyPlaylist{}}
+	tracks := []library.Track{{URI: "a"}, {URI: "b"}, {URI: "c"}, {URI: "d"}}
+	meta := []TrackMeta{{QueuedBy: "1"}, {QueuedBy: "2"}, {QueuedBy: "3"}, {QueuedBy: "4"}}
+	if err := metapl.InsertWithMeta(-1, tracks, meta); err != nil {
+		t.Fatal(err)
+	}
+	if err := metapl.MoveRange(2, 0, 2); err != nil {
+		t.Fatal(err)
+	}
+	plTracks, _ := metapl.Tracks()
+	plMeta, _ := metapl.Meta()
+	for i, expected := range []string{"c3", "d4", "a1", "b2"} {
+		if plTracks[i].URI+plMeta[i].QueuedBy != expected {
+			t.Fatalf("Unexpected track at %d: %v %v", i, plTracks[i], plMeta[i])
+		}
+	}
+
+	pl := NewDummyPlayer()
+	pl.Playlist().Insert(-1, tracks...)
+	pl.SetTrackIndex(1)
+	if err := MoveRange(pl.Playlist(), 0, 2, 2); err != nil {
+		t.Fatal(err)
+	}
+	if index, _ := pl.TrackIndex(); index != 3 {
+		t.Fatalf("The current track was not followed: %d", index)
+	}
+}
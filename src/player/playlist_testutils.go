@@ -154,19 +154,33 @@ func (pl *DummyPlaylist) Insert(pos int, tracks ...library.Track) error {
 	if pos == -1 {
 		pos, _ = pl.Len()
 	}
-	*pl = append(append((*pl)[:pos], tracks...), (*pl)[pos:]...)
+	inserted := append(DummyPlaylist{}, (*pl)[:pos]...)
+	inserted = append(inserted, tracks...)
+	*pl = append(inserted, (*pl)[pos:]...)
 	return nil
 }
 
 // Move implements the player.Playlist interface.
 func (pl *DummyPlaylist) Move(fromPos, toPos int) error {
 	moved := (*pl)[fromPos]
-	cut := append((*pl)[:fromPos], (*pl)[fromPos+1:]...)
-	delta := 0
-	if fromPos > toPos {
-		delta = -1
+	cut := append(DummyPlaylist{}, (*pl)[:fromPos]...)
+	cut = append(cut, (*pl)[fromPos+1:]...)
+	result := append(DummyPlaylist{}, cut[:toPos]...)
+	result = append(result, moved)
+	*pl = append(result, cut[toPos:]...)
+	return nil
+}
+
+// MoveRange implements the player.RangeMover interface.
+func (pl *DummyPlaylist) MoveRange(fromPos, toPos, count int) error {
+	if err := checkMoveRange(len(*pl), fromPos, toPos, count); err != nil {
+		return err
+	}
+	moved := make(DummyPlaylist, len(*pl))
+	for i, j := range moveRangePermutation(len(*pl), fromPos, toPos, count) {
+		moved[i] = (*pl)[j]
 	}
-	*pl = append(append(cut[:toPos+delta], moved), (*pl)[toPos+1+delta:]...)
+	*pl = moved
 	return nil
 }
 
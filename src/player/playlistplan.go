@@ -0,0 +1,145 @@
+package player
+
+import (
+	"fmt"
+
+	"github.com/polyfloyd/trollibox/src/library"
+)
+
+// The kinds of operations of a PlaylistOp.
+const (
+	PlaylistOpRemove = "remove"
+	PlaylistOpMove   = "move"
+	PlaylistOpInsert = "insert"
+)
+
+// A PlaylistOp is a single change to a playlist. Operations are planned to be
+// performed in order, so positions refer to the playlist as it is after all
+// preceding operations.
+type PlaylistOp struct {
+	Kind string `json:"kind"`
+	// The position of the first affected track.
+	Pos int `json:"pos"`
+	// For removals, the number of consecutive tracks removed.
+	Count int `json:"count,omitempty"`
+	// For moves, the position the track ends up at. This is always encoded,
+	// as moving to the start of the playlist is a move to 0.
+	To int `json:"to"`
+	// For insertions, the tracks that are inserted at Pos.
+	URIs []string `json:"uris,omitempty"`
+	// For insertions, the index in the desired playlist of the first
+	// inserted track.
+	index int
+}
+
+// SetPlaylist changes the playlist so it holds the specified tracks. Instead
+// of replacing everything, tracks that are already in the playlist are kept
+// along with their metadata and the currently playing track is not
+// interrupted if it remains.
+//
+// If dryRun is set, the playlist is not changed. The planned operations are
+// returned in both cases.
+func SetPlaylist(plist MetaPlaylist, tracks []library.Track, meta []TrackMeta, dryRun bool) ([]PlaylistOp, error) {
+	if len(meta) != len(tracks) {
+		return nil, fmt.Errorf("the number of tracks and metadata differ: %d != %d", len(tracks), len(meta))
+	}
+	current, err := plist.Tracks()
+	if err != nil {
+		return nil, err
+	}
+	currentURIs := make([]string, len(current))
+	for i, track := range current {
+		currentURIs[i] = track.URI
+	}
+	desiredURIs := make([]string, len(tracks))
+	for i, track := range tracks {
+		desiredURIs[i] = track.URI
+	}
+	ops := planPlaylistChange(currentURIs, desiredURIs)
+	if dryRun {
+		return ops, nil
+	}
+
+	for _, op := range ops {
+		switch op.Kind {
+		case PlaylistOpRemove:
+			positions := make([]int, op.Count)
+			for i := range positions {
+				positions[i] = op.Pos + i
+			}
+			err = plist.Remove(positions...)
+		case PlaylistOpMove:
+			err = plist.Move(op.Pos, op.To)
+		case PlaylistOpInsert:
+			end := op.index + len(op.URIs)
+			err = plist.InsertWithMeta(op.Pos, tracks[op.index:end], meta[op.index:end])
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ops, nil
+}
+
+// planPlaylistChange computes the operations that turn the current playlist
+// into the desired one, both specified as URIs.
+//
+// Tracks that are not desired are removed first, in consecutive ranges from
+// the end so the positions of the remaining removals are not affected. The
+// remaining tracks are then moved into place and missing tracks are inserted
+// in between.
+func planPlaylistChange(current, desired []string) []PlaylistOp {
+	need := map[string]int{}
+	for _, uri := range desired {
+		need[uri]++
+	}
+	work := make([]string, 0, len(current))
+	var removed []int
+	for i, uri := range current {
+		if need[uri] > 0 {
+			need[uri]--
+			work = append(work, uri)
+		} else {
+			removed = append(removed, i)
+		}
+	}
+
+	var ops []PlaylistOp
+	for end := len(removed) - 1; end >= 0; {
+		start := end
+		for start > 0 && removed[start-1] == removed[start]-1 {
+			start--
+		}
+		ops = append(ops, PlaylistOp{Kind: PlaylistOpRemove, Pos: removed[start], Count: end - start + 1})
+		end = start - 1
+	}
+
+	for i, uri := range desired {
+		if i < len(work) && work[i] == uri {
+			continue
+		}
+		from := -1
+		for j := i + 1; j < len(work); j++ {
+			if work[j] == uri {
+				from = j
+				break
+			}
+		}
+		if from >= 0 {
+			ops = append(ops, PlaylistOp{Kind: PlaylistOpMove, Pos: from, To: i})
+			copy(work[i+1:from+1], work[i:from])
+			work[i] = uri
+			continue
+		}
+
+		if n := len(ops); n > 0 && ops[n-1].Kind == PlaylistOpInsert && ops[n-1].Pos+len(ops[n-1].URIs) == i {
+			ops[n-1].URIs = append(ops[n-1].URIs, uri)
+		} else {
+			ops = append(ops, PlaylistOp{Kind: PlaylistOpInsert, Pos: i, URIs: []string{uri}, index: i})
+		}
+		work = append(work, "")
+		copy(work[i+1:], work[i:])
+		work[i] = uri
+	}
+	return ops
+}
@@ -0,0 +1,144 @@
+package player
+
+import (
+	"encoding/json"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/polyfloyd/trollibox/src/library"
+)
+
+// applyPlaylistOps performs the operations on a list of URIs.
+func applyPlaylistOps(t *testing.T, list []string, ops []PlaylistOp) []string {
+	t.Helper()
+	list = append([]string{}, list...)
+	for _, op := range ops {
+		switch op.Kind {
+		case PlaylistOpRemove:
+			list = append(list[:op.Pos], list[op.Pos+op.Count:]...)
+		case PlaylistOpMove:
+			uri := list[op.Pos]
+			list = append(list[:op.Pos], list[op.Pos+1:]...)
+			list = append(list[:op.To], append([]string{uri}, list[op.To:]...)...)
+		case PlaylistOpInsert:
+			list = append(list[:op.Pos], append(append([]string{}, op.URIs...), list[op.Pos:]...)...)
+		default:
+			t.Fatalf("Unknown operation: %v", op.Kind)
+		}
+	}
+	return list
+}
+
+func TestPlanPlaylistChange(t *testing.T) {
+	testCases := []struct {
+		current, desired []string
+		ops              []PlaylistOp
+	}{
+		{
+			current: []string{"a", "b", "c"},
+			desired: []string{"a", "b", "c"},
+			ops:     nil,
+		},
+		{
+			current: []string{"a", "x", "y", "b", "z"},
+			desired: []string{"a", "b"},
+			ops: []PlaylistOp{
+				{Kind: PlaylistOpRemove, Pos: 4, Count: 1},
+				{Kind: PlaylistOpRemove, Pos: 1, Count: 2},
+			},
+		},
+		{
+			current: []string{"a", "b", "c"},
+			desired: []string{"c", "a", "b"},
+			ops: []PlaylistOp{
+				{Kind: PlaylistOpMove, Pos: 2, To: 0},
+			},
+		},
+		{
+			current: []string{"a"},
+			desired: []string{"x", "y", "a", "z"},
+			ops: []PlaylistOp{
+				{Kind: PlaylistOpInsert, Pos: 0, URIs: []string{"x", "y"}, index: 0},
+				{Kind: PlaylistOpInsert, Pos: 3, URIs: []string{"z"}, index: 3},
+			},
+		},
+	}
+	for _, c := range testCases {
+		ops := planPlaylistChange(c.current, c.desired)
+		if !reflect.DeepEqual(ops, c.ops) {
+			t.Errorf("Unexpected plan for %v -> %v: %+v", c.current, c.desired, ops)
+		}
+		if result := applyPlaylistOps(t, c.current, ops); !reflect.DeepEqual(result, c.desired) {
+			t.Errorf("Plan for %v -> %v results in %v", c.current, c.desired, result)
+		}
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	randomList := func() []string {
+		list := make([]string, rng.Intn(12))
+		for i := range list {
+			list[i] = string(rune('a' + rng.Intn(6)))
+		}
+		return list
+	}
+	for i := 0; i < 1000; i++ {
+		current, desired := randomList(), randomList()
+		result := applyPlaylistOps(t, current, planPlaylistChange(current, desired))
+		if len(result) != len(desired) || len(desired) > 0 && !reflect.DeepEqual(result, desired) {
+			t.Fatalf("Plan for %v -> %v results in %v", current, desired, result)
+		}
+	}
+}
+
+func TestPlaylistOpJSON(t *testing.T) {
+	buf, err := json.Marshal(PlaylistOp{Kind: PlaylistOpMove, Pos: 3, To: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(buf, &m); err != nil {
+		t.Fatal(err)
+	}
+	if to, ok := m["to"]; !ok || to != 0.0 {
+		t.Fatalf("move to the start lost its target: %s", buf)
+	}
+}
+
+func TestSetPlaylist(t *testing.T) {
+	pl := NewDummyPlayer()
+	defer pl.Events().Close()
+	plist := pl.Playlist()
+	initial := []library.Track{{URI: "a"}, {URI: "b"}, {URI: "c"}}
+	if err := plist.InsertWithMeta(-1, initial, []TrackMeta{{QueuedBy: "a"}, {QueuedBy: "b"}, {QueuedBy: "c"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	desired := []library.Track{{URI: "c"}, {URI: "d"}, {URI: "a"}}
+	meta := []TrackMeta{{QueuedBy: "new"}, {QueuedBy: "new"}, {QueuedBy: "new"}}
+	ops, err := SetPlaylist(plist, desired, meta, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tracks, _ := plist.Tracks(); len(ops) == 0 || len(tracks) != 3 || tracks[1].URI != "b" {
+		t.Fatalf("A dry run changed the playlist: %v", tracks)
+	}
+
+	if _, err := SetPlaylist(plist, desired, meta, false); err != nil {
+		t.Fatal(err)
+	}
+	tracks, _ := plist.Tracks()
+	plMeta, _ := plist.Meta()
+	var uris, queuedBy []string
+	for i := range tracks {
+		uris = append(uris, tracks[i].URI)
+		queuedBy = append(queuedBy, plMeta[i].QueuedBy)
+	}
+	if !reflect.DeepEqual(uris, []string{"c", "d", "a"}) {
+		t.Fatalf("Unexpected playlist: %v", uris)
+	}
+	// The metadata of tracks that were kept is retained.
+	if !reflect.DeepEqual(queuedBy, []string{"c", "new", "a"}) {
+		t.Fatalf("Unexpected metadata: %v", queuedBy)
+	}
+}
@@ -0,0 +1,251 @@
+package player
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/polyfloyd/trollibox/src/library"
+)
+
+// A QueueSaver is a player that is able to store its playlist under a name so
+// it can be restored later.
+type QueueSaver interface {
+	// SavedQueues lists the names of all saved queues.
+	SavedQueues() ([]string, error)
+
+	// SaveQueue stores the current playlist under the specified name,
+	// overwriting any queue that was previously saved with that name.
+	SaveQueue(name string) error
+
+	// LoadQueue appends the tracks of the named queue to the playlist.
+	LoadQueue(name string) error
+
+	// RemoveSavedQueue deletes the named queue. Removing a queue that does
+	// not exist is a no-op.
+	RemoveSavedQueue(name string) error
+}
+
+// ShutdownQueueName is the name under which queues are saved when Trollibox is
+// shut down. It is reserved, so clients can not replace or remove the saved
+// queue before it is restored.
+const ShutdownQueueName = "shutdown"
+
+// ValidateQueueName checks whether clients may use the specified name to save
+// a queue. Reserved names, like ShutdownQueueName, are rejected.
+func ValidateQueueName(name string) error {
+	if name == ShutdownQueueName {
+		return fmt.Errorf("the queue name %q is reserved", name)
+	}
+	return CheckQueueName(name)
+}
+
+// CheckQueueName checks whether a queue can be stored under the specified
+// name. Unlike ValidateQueueName, reserved names are accepted, so this is the
+// check implementations of QueueSaver perform.
+func CheckQueueName(name string) error {
+	if name == "" || strings.HasPrefix(name, ".") || strings.ContainsAny(name, "/\n\r") {
+		return fmt.Errorf("invalid queue name: %q", name)
+	}
+	return nil
+}
+
+// A QueuePosition is the point in a saved queue at which playback continues
+// when the queue is loaded.
+type QueuePosition struct {
+	// The index of the track relative to the start of the queue.
+	Index  int           `json:"index"`
+	Offset time.Duration `json:"offset"`
+}
+
+type savedQueue struct {
+	Tracks []string    `json:"tracks"`
+	Meta   []TrackMeta `json:"meta"`
+}
+
+// A QueueStore saves the playlists of players that are not able to do so
+// themselves. The queues of each player are stored as JSON files in a
+// directory, so the metadata of the tracks is retained.
+type QueueStore struct {
+	directory string
+}
+
+// NewQueueStore creates a store that keeps its queues in the specified
+// directory.
+func NewQueueStore(directory string) (*QueueStore, error) {
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return nil, err
+	}
+	return &QueueStore{directory: directory}, nil
+}
+
+// Names lists the names of the queues saved for the named player.
+func (store *QueueStore) Names(playerName string) ([]string, error) {
+	fd, err := os.Open(path.Join(store.directory, playerName))
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	files, err := fd.Readdir(0)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		if path.Ext(file.Name()) == ".json" {
+			names = append(names, strings.TrimSuffix(file.Name(), ".json"))
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Save stores the playlist of the named player under the specified name.
+func (store *QueueStore) Save(playerName, name string, plist MetaPlaylist) error {
+	if err := CheckQueueName(name); err != nil {
+		return err
+	}
+	tracks, err := plist.Tracks()
+	if err != nil {
+		return err
+	}
+	meta, err := plist.Meta()
+	if err != nil {
+		return err
+	}
+	if len(meta) != len(tracks) {
+		return fmt.Errorf("the playlist changed while it was being saved")
+	}
+	queue := savedQueue{
+		Tracks: make([]string, len(tracks)),
+		Meta:   meta,
+	}
+	for i, track := range tracks {
+		queue.Tracks[i] = track.URI
+	}
+
+	if err := os.MkdirAll(path.Join(store.directory, playerName), 0755); err != nil {
+		return err
+	}
+	// Write to a temporary file first so an existing queue is not lost if
+	// writing fails.
+	file := store.queueFile(playerName, name)
+	fd, err := os.Create(file + ".tmp")
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(fd).Encode(queue); err != nil {
+		fd.Close()
+		os.Remove(file + ".tmp")
+		return err
+	}
+	if err := fd.Close(); err != nil {
+		os.Remove(file + ".tmp")
+		return err
+	}
+	return os.Rename(file+".tmp", file)
+}
+
+// Load appends the tracks of the named queue to the playlist of the named
+// player along with their original metadata.
+func (store *QueueStore) Load(playerName, name string, plist MetaPlaylist) error {
+	if err := CheckQueueName(name); err != nil {
+		return err
+	}
+	fd, err := os.Open(store.queueFile(playerName, name))
+	if os.IsNotExist(err) {
+		return fmt.Errorf("no saved queue with name %q", name)
+	} else if err != nil {
+		return err
+	}
+	defer fd.Close()
+	var queue savedQueue
+	if err := json.NewDecoder(fd).Decode(&queue); err != nil {
+		return err
+	}
+	if len(queue.Tracks) == 0 {
+		return nil
+	}
+
+	tracks := make([]library.Track, len(queue.Tracks))
+	meta := make([]TrackMeta, len(queue.Tracks))
+	for i, uri := range queue.Tracks {
+		tracks[i].URI = uri
+		if i < len(queue.Meta) {
+			meta[i] = queue.Meta[i]
+		}
+	}
+	return plist.InsertWithMeta(-1, tracks, meta)
+}
+
+// Remove deletes the named queue of the player.
+func (store *QueueStore) Remove(playerName, name string) error {
+	if err := CheckQueueName(name); err != nil {
+		return err
+	}
+	if err := os.Remove(store.queueFile(playerName, name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return store.SavePosition(playerName, name, nil)
+}
+
+// SavePosition stores the position at which playback of the named queue
+// continues. A nil position removes it.
+//
+// Positions are stored separately from the tracks, so they can also be kept
+// for queues that players save themselves.
+func (store *QueueStore) SavePosition(playerName, name string, pos *QueuePosition) error {
+	if err := CheckQueueName(name); err != nil {
+		return err
+	}
+	file := store.positionFile(playerName, name)
+	if pos == nil {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.Marshal(pos)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(path.Join(store.directory, playerName), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, data, 0644)
+}
+
+// Position returns the position at which playback of the named queue
+// continues, nil if the queue was saved without one.
+func (store *QueueStore) Position(playerName, name string) (*QueuePosition, error) {
+	if err := CheckQueueName(name); err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(store.positionFile(playerName, name))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var pos QueuePosition
+	if err := json.Unmarshal(data, &pos); err != nil {
+		return nil, err
+	}
+	return &pos, nil
+}
+
+func (store *QueueStore) queueFile(playerName, name string) string {
+	return path.Join(store.directory, playerName, name+".json")
+}
+
+func (store *QueueStore) positionFile(playerName, name string) string {
+	return path.Join(store.directory, playerName, name+".position")
+}
@@ -0,0 +1,67 @@
+package player
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/polyfloyd/trollibox/src/library"
+)
+
+func TestQueueStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trollibox-queuestore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := NewQueueStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plist := &PlaylistMetaKeeper{Playlist: &DummyPlaylist{}}
+	err = plist.InsertWithMeta(-1, []library.Track{{URI: "track1"}, {URI: "track2"}}, []TrackMeta{
+		{QueuedBy: "system"},
+		{QueuedBy: "user"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save("kitchen", "evening", plist); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save("kitchen", "../escape", plist); err == nil {
+		t.Fatal("Names containing slashes should be rejected")
+	}
+	if names, err := store.Names("kitchen"); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(names, []string{"evening"}) {
+		t.Fatalf("Unexpected names: %v", names)
+	}
+	if names, err := store.Names("garden"); err != nil {
+		t.Fatal(err)
+	} else if len(names) != 0 {
+		t.Fatalf("Queues should be stored per player: %v", names)
+	}
+
+	restored := &PlaylistMetaKeeper{Playlist: &DummyPlaylist{}}
+	if err := store.Load("kitchen", "evening", restored); err != nil {
+		t.Fatal(err)
+	}
+	tracks, _ := restored.Tracks()
+	meta, _ := restored.Meta()
+	if len(tracks) != 2 || tracks[0].URI != "track1" || tracks[1].URI != "track2" {
+		t.Fatalf("Unexpected tracks: %v", tracks)
+	}
+	if meta[0].QueuedBy != "system" || meta[1].QueuedBy != "user" {
+		t.Fatalf("Metadata was not restored: %v", meta)
+	}
+
+	if err := store.Remove("kitchen", "evening"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Load("kitchen", "evening", restored); err == nil {
+		t.Fatal("Loading a removed queue should fail")
+	}
+}
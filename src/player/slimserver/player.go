@@ -104,13 +104,16 @@ var eventTranslations = []struct {
 		Exp: regexp.MustCompile(`^\S+ time (\d+)`),
 		Event: func(pl *Player, m []string) (player.Event, error) {
 			secs, _ := strconv.Atoi(m[1])
-			return player.TimeEvent{Time: time.Second * time.Duration(secs)}, nil
+			return player.TimeEvent{Time: time.Second * time.Duration(secs), At: time.Now()}, nil
 		},
 	},
 	{
-		Exp: regexp.MustCompile(`^\S+ client (?:reconnect|disconnect)`),
+		Exp: regexp.MustCompile(`^\S+ client (reconnect|disconnect)`),
 		Event: func(pl *Player, m []string) (player.Event, error) {
-			return player.AvailabilityEvent{Available: m[1] == "reconnect"}, nil
+			if m[1] == "reconnect" {
+				return player.AvailabilityEvent{Available: true}, nil
+			}
+			return player.AvailabilityEvent{Available: false, Reason: player.UnavailableDisconnected}, nil
 		},
 	},
 }
@@ -199,10 +202,11 @@ func (pl *Player) TrackInfo(uris ...string) ([]library.Track, error) {
 
 	tracks := make([]library.Track, len(uris))
 	for i, uri := range uris {
-		isHTTP := strings.HasPrefix(uri, "https://") || strings.HasPrefix(uri, "http://")
-		if isHTTP && currentTrackURI == uri {
+		isStream := library.IsStreamURI(uri)
+		if isStream && currentTrackURI == uri {
 			tr := &tracks[i]
 			tr.URI = uri
+			tr.IsStream = true
 			tr.Album = uri
 			artistRes, err := pl.Serv.request(pl.ID, "artist", "?")
 			if err == nil && len(artistRes) >= 3 {
@@ -216,7 +220,7 @@ func (pl *Player) TrackInfo(uris ...string) ([]library.Track, error) {
 			continue
 		}
 
-		if !isHTTP {
+		if !isStream {
 			attrs, err := pl.Serv.requestAttrs("songinfo", "0", "100", "tags:"+trackTags, "url:"+encodeURI(uri))
 			if err != nil {
 				return nil, err
@@ -245,12 +249,19 @@ func (pl *Player) Time() (time.Duration, error) {
 	if err != nil {
 		return 0, err
 	}
-	return time.Duration(d) * time.Second, nil
+	return time.Duration(d * float64(time.Second)).Round(time.Millisecond), nil
 }
 
 // SetTime implements the player.Player interface.
 func (pl *Player) SetTime(offset time.Duration) error {
-	_, err := pl.Serv.request(pl.ID, "time", strconv.Itoa(int(offset/time.Second)))
+	if res, err := pl.Serv.request(pl.ID, "path", "?"); err != nil {
+		return err
+	} else if len(res) >= 3 {
+		if uri, _ := url.QueryUnescape(res[2]); library.IsStreamURI(uri) {
+			return player.ErrUnseekable
+		}
+	}
+	_, err := pl.Serv.request(pl.ID, "time", strconv.FormatFloat(offset.Seconds(), 'f', 3, 64))
 	return err
 }
 
@@ -428,7 +439,10 @@ func (pl *Player) TrackArt(track string) (image io.ReadCloser, mime string) {
 	if res.StatusCode >= 400 {
 		return nil, ""
 	}
-	return res.Body, res.Header.Get("Content-Type")
+	if mime := res.Header.Get("Content-Type"); strings.HasPrefix(mime, "image/") {
+		return res.Body, mime
+	}
+	return library.SniffArt(res.Body)
 }
 
 // Events implements the player.Player interface.
@@ -308,9 +308,9 @@ func setSlimAttr(serv *Server, track *library.Track, key, value string) {
 	case "albumartist":
 		track.AlbumArtist = value
 	case "tracknum":
-		track.AlbumTrack = value
+		track.SetAlbumTrack(value)
 	case "disc":
-		track.AlbumDisc = value
+		track.SetAlbumDisc(value)
 	case "duration":
 		d, _ := strconv.ParseFloat(value, 64)
 		track.Duration = time.Duration(d) * time.Second
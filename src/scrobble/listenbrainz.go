@@ -0,0 +1,138 @@
+package scrobble
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/polyfloyd/trollibox/src/library"
+)
+
+// DefaultListenBrainzURL is the root of the public ListenBrainz API.
+const DefaultListenBrainzURL = "https://api.listenbrainz.org"
+
+// The maximum number of listens ListenBrainz accepts in a single submission.
+const lbMaxListensPerRequest = 1000
+
+// ListenBrainz submits listens to ListenBrainz.
+type ListenBrainz struct {
+	// The root URL of the API, DefaultListenBrainzURL is used if empty.
+	URL string
+	// The user token used for authentication.
+	Token string
+
+	Client *http.Client
+}
+
+type lbTrackMetadata struct {
+	ArtistName     string                 `json:"artist_name"`
+	TrackName      string                 `json:"track_name"`
+	ReleaseName    string                 `json:"release_name,omitempty"`
+	AdditionalInfo map[string]interface{} `json:"additional_info,omitempty"`
+}
+
+type lbListen struct {
+	ListenedAt    int64           `json:"listened_at,omitempty"`
+	TrackMetadata lbTrackMetadata `json:"track_metadata"`
+}
+
+// NowPlaying implements the Scrobbler interface.
+func (lb *ListenBrainz) NowPlaying(track library.Track) error {
+	return lb.submit("playing_now", []lbListen{lbListenFromTrack(track, time.Time{})})
+}
+
+// Scrobble implements the Scrobbler interface.
+func (lb *ListenBrainz) Scrobble(track library.Track, playedAt time.Time) error {
+	return lb.submit("single", []lbListen{lbListenFromTrack(track, playedAt)})
+}
+
+// ScrobbleBatch implements the BatchScrobbler interface.
+func (lb *ListenBrainz) ScrobbleBatch(listens []Listen) error {
+	payload := make([]lbListen, len(listens))
+	for i, listen := range listens {
+		payload[i] = lbListenFromTrack(listen.Track, listen.PlayedAt)
+	}
+	return lb.submit("import", payload)
+}
+
+// MaxBatchSize implements the BatchScrobbler interface.
+func (lb *ListenBrainz) MaxBatchSize() int {
+	return lbMaxListensPerRequest
+}
+
+func (lb *ListenBrainz) submit(listenType string, payload []lbListen) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"listen_type": listenType,
+		"payload":     payload,
+	})
+	if err != nil {
+		return err
+	}
+	url := lb.URL
+	if url == "" {
+		url = DefaultListenBrainzURL
+	}
+	req, err := http.NewRequest("POST", strings.TrimSuffix(url, "/")+"/1/submit-listens", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Token "+lb.Token)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := lb.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error submitting to ListenBrainz: %v", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(res.Body)
+		err := fmt.Errorf("error submitting to ListenBrainz: %s: %s", res.Status, msg)
+		if lbRejected(res.StatusCode) {
+			return RejectedError{Err: err}
+		}
+		return err
+	}
+	return nil
+}
+
+// lbRejected reports whether the status code returned by ListenBrainz means
+// that the submitted listens themselves were refused. Problems with the
+// token or the rate of requests are resolved by trying again later.
+func lbRejected(status int) bool {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return false
+	}
+	return status >= 400 && status < 500
+}
+
+func lbListenFromTrack(track library.Track, playedAt time.Time) lbListen {
+	listen := lbListen{
+		TrackMetadata: lbTrackMetadata{
+			ArtistName:  track.Artist,
+			TrackName:   track.Title,
+			ReleaseName: track.Album,
+		},
+	}
+	if !playedAt.IsZero() {
+		listen.ListenedAt = playedAt.Unix()
+	}
+	if track.Duration > 0 {
+		listen.TrackMetadata.AdditionalInfo = map[string]interface{}{
+			"duration_ms": int64(track.Duration / time.Millisecond),
+		}
+	}
+	return listen
+}
+
+func (lb *ListenBrainz) String() string {
+	return "ListenBrainz"
+}
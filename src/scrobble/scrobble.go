@@ -0,0 +1,156 @@
+package scrobble
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/polyfloyd/trollibox/src/library"
+)
+
+// A Scrobbler submits the tracks that are listened to to an external service.
+type Scrobbler interface {
+	// NowPlaying announces the track that has just started playing.
+	NowPlaying(track library.Track) error
+	// Scrobble submits a track that has been listened to long enough to be
+	// counted as played.
+	Scrobble(track library.Track, playedAt time.Time) error
+}
+
+// A Listen is a single play of a track.
+type Listen struct {
+	Track    library.Track `json:"track"`
+	PlayedAt time.Time     `json:"played_at"`
+}
+
+// A BatchScrobbler is a Scrobbler that is able to submit multiple listens at
+// once.
+type BatchScrobbler interface {
+	Scrobbler
+	ScrobbleBatch(listens []Listen) error
+	// MaxBatchSize returns the maximum number of listens that may be passed
+	// to ScrobbleBatch at once, 0 for no limit.
+	MaxBatchSize() int
+}
+
+// A RejectedError is returned by scrobblers if the service refused the
+// submitted listens, as opposed to being unreachable. Submitting the same
+// listens again will not succeed.
+type RejectedError struct {
+	Err error
+}
+
+func (err RejectedError) Error() string {
+	return err.Err.Error()
+}
+
+// A Queue wraps a Scrobbler and stores the listens that could not be
+// submitted, for example because the service was unreachable. Stored listens
+// are submitted along with the next listen and survive restarts.
+//
+// Listens that are rejected by the service are dropped, retrying them would
+// hold up all listens after them.
+type Queue struct {
+	Scrobbler
+
+	file    string
+	lock    sync.Mutex
+	pending []Listen
+}
+
+// NewQueue creates a queue for the specified scrobbler which persists pending
+// listens in the specified file.
+func NewQueue(scrobbler Scrobbler, file string) (*Queue, error) {
+	queue := &Queue{Scrobbler: scrobbler, file: file}
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return queue, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &queue.pending); err != nil {
+		return nil, fmt.Errorf("unable to load scrobble queue %q: %v", file, err)
+	}
+	return queue, nil
+}
+
+// Scrobble implements the Scrobbler interface.
+//
+// The listen is stored if it can not be submitted.
+func (queue *Queue) Scrobble(track library.Track, playedAt time.Time) error {
+	queue.lock.Lock()
+	defer queue.lock.Unlock()
+	queue.pending = append(queue.pending, Listen{Track: track, PlayedAt: playedAt})
+	err := queue.flush()
+	if saveErr := queue.save(); saveErr != nil {
+		return saveErr
+	}
+	return err
+}
+
+// Flush attempts to submit all pending listens.
+func (queue *Queue) Flush() error {
+	queue.lock.Lock()
+	defer queue.lock.Unlock()
+	err := queue.flush()
+	if saveErr := queue.save(); saveErr != nil {
+		return saveErr
+	}
+	return err
+}
+
+// Pending returns the number of listens that have not been submitted yet.
+func (queue *Queue) Pending() int {
+	queue.lock.Lock()
+	defer queue.lock.Unlock()
+	return len(queue.pending)
+}
+
+func (queue *Queue) flush() error {
+	batch, isBatch := queue.Scrobbler.(BatchScrobbler)
+	var rejected error
+	for len(queue.pending) > 0 {
+		n := 1
+		var err error
+		if isBatch {
+			n = len(queue.pending)
+			if max := batch.MaxBatchSize(); max > 0 && n > max {
+				n = max
+			}
+			err = batch.ScrobbleBatch(queue.pending[:n])
+			if _, ok := err.(RejectedError); ok && n > 1 {
+				// It is not known which of the listens were rejected, so
+				// the remaining listens are submitted one by one.
+				isBatch = false
+				continue
+			}
+		} else {
+			listen := queue.pending[0]
+			err = queue.Scrobbler.Scrobble(listen.Track, listen.PlayedAt)
+		}
+		if _, ok := err.(RejectedError); ok {
+			rejected = err
+		} else if err != nil {
+			return err
+		}
+		queue.pending = queue.pending[n:]
+	}
+	return rejected
+}
+
+func (queue *Queue) save() error {
+	if len(queue.pending) == 0 {
+		if err := os.Remove(queue.file); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.Marshal(queue.pending)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(queue.file, data, 0644)
+}
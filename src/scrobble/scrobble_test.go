@@ -0,0 +1,191 @@
+package scrobble
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/polyfloyd/trollibox/src/library"
+)
+
+type testScrobbler struct {
+	fail     bool
+	listens  []Listen
+	announce []library.Track
+}
+
+func (ts *testScrobbler) NowPlaying(track library.Track) error {
+	ts.announce = append(ts.announce, track)
+	return nil
+}
+
+func (ts *testScrobbler) Scrobble(track library.Track, playedAt time.Time) error {
+	if ts.fail {
+		return fmt.Errorf("unreachable")
+	}
+	ts.listens = append(ts.listens, Listen{Track: track, PlayedAt: playedAt})
+	return nil
+}
+
+func TestQueuePersistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trollibox-scrobble")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := path.Join(dir, "queue.json")
+
+	failing := &testScrobbler{fail: true}
+	queue, err := NewQueue(failing, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	playedAt := time.Unix(1500000000, 0)
+	if err := queue.Scrobble(library.Track{URI: "a"}, playedAt); err == nil {
+		t.Fatal("Expected an error")
+	}
+	if queue.Pending() != 1 {
+		t.Fatalf("Unexpected number of pending listens: %d", queue.Pending())
+	}
+
+	// Pending listens should be loaded and submitted after a restart.
+	working := &testScrobbler{}
+	queue, err = NewQueue(working, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := queue.Scrobble(library.Track{URI: "b"}, playedAt.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if len(working.listens) != 2 || working.listens[0].Track.URI != "a" || !working.listens[0].PlayedAt.Equal(playedAt) {
+		t.Fatalf("Unexpected listens: %v", working.listens)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Fatal("The queue file was not removed after flushing")
+	}
+}
+
+type testBatchScrobbler struct {
+	testScrobbler
+	reject  string
+	batches [][]Listen
+}
+
+func (ts *testBatchScrobbler) Scrobble(track library.Track, playedAt time.Time) error {
+	if track.URI == ts.reject {
+		return RejectedError{Err: fmt.Errorf("invalid listen")}
+	}
+	return ts.testScrobbler.Scrobble(track, playedAt)
+}
+
+func (ts *testBatchScrobbler) ScrobbleBatch(listens []Listen) error {
+	for _, listen := range listens {
+		if listen.Track.URI == ts.reject {
+			return RejectedError{Err: fmt.Errorf("invalid listen")}
+		}
+	}
+	ts.batches = append(ts.batches, listens)
+	ts.listens = append(ts.listens, listens...)
+	return nil
+}
+
+func (ts *testBatchScrobbler) MaxBatchSize() int {
+	return 2
+}
+
+func TestQueueBatches(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trollibox-scrobble")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	scrobbler := &testBatchScrobbler{}
+	queue, err := NewQueue(scrobbler, path.Join(dir, "queue.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, uri := range []string{"a", "b", "c", "d"} {
+		queue.pending = append(queue.pending, Listen{Track: library.Track{URI: uri}})
+	}
+	if err := queue.Scrobble(library.Track{URI: "e"}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if len(scrobbler.batches) != 3 || len(scrobbler.batches[0]) != 2 || len(scrobbler.batches[2]) != 1 {
+		t.Fatalf("Unexpected batches: %v", scrobbler.batches)
+	}
+}
+
+func TestQueueDropsRejected(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trollibox-scrobble")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	scrobbler := &testBatchScrobbler{reject: "b"}
+	queue, err := NewQueue(scrobbler, path.Join(dir, "queue.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	queue.pending = []Listen{{Track: library.Track{URI: "a"}}, {Track: library.Track{URI: "b"}}}
+	if err := queue.Scrobble(library.Track{URI: "c"}, time.Now()); err == nil {
+		t.Fatal("Expected the rejection to be reported")
+	}
+	if queue.Pending() != 0 {
+		t.Fatalf("Unexpected number of pending listens: %d", queue.Pending())
+	}
+	if len(scrobbler.listens) != 2 || scrobbler.listens[0].Track.URI != "a" || scrobbler.listens[1].Track.URI != "c" {
+		t.Fatalf("Unexpected listens: %v", scrobbler.listens)
+	}
+}
+
+func TestListenBrainzScrobble(t *testing.T) {
+	var received struct {
+		ListenType string `json:"listen_type"`
+		Payload    []struct {
+			ListenedAt    int64 `json:"listened_at"`
+			TrackMetadata struct {
+				ArtistName string `json:"artist_name"`
+				TrackName  string `json:"track_name"`
+			} `json:"track_metadata"`
+		} `json:"payload"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/1/submit-listens" {
+			http.NotFound(w, r)
+			return
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Token secret" {
+			http.Error(w, "bad token: "+auth, http.StatusUnauthorized)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&received)
+	}))
+	defer server.Close()
+
+	lb := &ListenBrainz{URL: server.URL, Token: "secret"}
+	track := library.Track{Artist: "Artist", Title: "Title", Duration: time.Minute}
+	if err := lb.Scrobble(track, time.Unix(1500000000, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if received.ListenType != "single" || len(received.Payload) != 1 {
+		t.Fatalf("Unexpected submission: %+v", received)
+	}
+	if p := received.Payload[0]; p.ListenedAt != 1500000000 || p.TrackMetadata.ArtistName != "Artist" || p.TrackMetadata.TrackName != "Title" {
+		t.Fatalf("Unexpected listen: %+v", p)
+	}
+
+	lb.Token = "wrong"
+	if err := lb.NowPlaying(track); err == nil {
+		t.Fatal("Expected an error for a bad token")
+	} else if _, ok := err.(RejectedError); ok {
+		t.Fatal("A bad token should not reject the listens")
+	}
+}
@@ -1,8 +1,12 @@
 package util
 
 import (
+	"reflect"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	log "github.com/sirupsen/logrus"
 )
 
 const chanBufferSize = 128
@@ -22,8 +26,13 @@ type Emitter struct {
 
 	listeners map[<-chan interface{}]chan interface{}
 	lock      sync.RWMutex
+	closed    bool
 
 	release map[interface{}]struct{}
+
+	// The number of events that were discarded because a listener was not
+	// keeping up.
+	dropped uint64
 }
 
 func (emitter *Emitter) init() {
@@ -43,24 +52,50 @@ func (emitter *Emitter) init() {
 func (emitter *Emitter) broadcast(event interface{}) {
 	emitter.lock.RLock()
 	defer emitter.lock.RUnlock()
+	if emitter.closed {
+		return
+	}
 	for _, listener := range emitter.listeners {
 		select {
 		case listener <- event:
+			continue
 		default:
 		}
+		// The listener is falling behind. Make room for the new event by
+		// discarding the oldest one so the listener will eventually catch
+		// up with the most recent state.
+		select {
+		case <-listener:
+		default:
+		}
+		select {
+		case listener <- event:
+		default:
+		}
+		n := atomic.AddUint64(&emitter.dropped, 1)
+		log.Debugf("Listener is not keeping up, dropped an event (%d dropped in total)", n)
 	}
 }
 
+// Dropped returns the number of events that were discarded because a listener
+// did not read its channel fast enough.
+func (emitter *Emitter) Dropped() uint64 {
+	return atomic.LoadUint64(&emitter.dropped)
+}
+
 // Emit emits an event to all current consumers.
 //
-// Listening channels are buffered, but whether the event is delivered
-// dependending on the whether the receiving channel is being actively read by
-// some goroutine.
+// Emitting never blocks. Listening channels are buffered, if a listener falls
+// behind, its oldest pending event is discarded to make room for the new
+// one.
+//
+// Events of which the type is not comparable, like those holding slices or
+// maps, are never deduplicated.
 func (emitter *Emitter) Emit(event interface{}) {
 	emitter.init()
 
 	emitter.lock.RLock()
-	if emitter.Release == 0 {
+	if emitter.Release == 0 || event == nil || !reflect.TypeOf(event).Comparable() {
 		emitter.lock.RUnlock()
 		emitter.broadcast(event)
 		return
@@ -96,11 +131,50 @@ func (emitter *Emitter) Listen() <-chan interface{} {
 	defer emitter.lock.Unlock()
 
 	ch := make(chan interface{}, chanBufferSize)
+	if emitter.closed {
+		close(ch)
+		return ch
+	}
 	emitter.listeners[ch] = ch
 	return ch
 }
 
+// A Listener is a handle to a channel receiving events from an Emitter.
+type Listener struct {
+	// C receives the events. It is closed when the listener is closed.
+	C <-chan interface{}
+
+	emitter *Emitter
+	once    sync.Once
+}
+
+// Close unregisters the listener from its emitter. It is safe to call Close
+// multiple times.
+func (l *Listener) Close() {
+	l.once.Do(func() {
+		l.emitter.Unlisten(l.C)
+	})
+}
+
+// Subscribe registers a new listener at this emitter.
+//
+// The listener is closed automatically when done is closed, this makes it
+// possible to tie the lifetime of a listener to that of, for example, a
+// request context. A nil done channel never closes the listener.
+func (emitter *Emitter) Subscribe(done <-chan struct{}) *Listener {
+	l := &Listener{C: emitter.Listen(), emitter: emitter}
+	if done != nil {
+		go func() {
+			<-done
+			l.Close()
+		}()
+	}
+	return l
+}
+
 // Unlisten unregisters a channel previously obtained by Listen and closes it.
+//
+// Unlistening a channel that was already closed is a no-op.
 func (emitter *Emitter) Unlisten(ch <-chan interface{}) {
 	emitter.init()
 
@@ -108,6 +182,23 @@ func (emitter *Emitter) Unlisten(ch <-chan interface{}) {
 	defer emitter.lock.Unlock()
 
 	// Ok, now clean up everything.
-	close(emitter.listeners[ch])
-	delete(emitter.listeners, ch)
+	if listener, ok := emitter.listeners[ch]; ok {
+		close(listener)
+		delete(emitter.listeners, ch)
+	}
+}
+
+// Close closes all listening channels. Events emitted after closing are
+// discarded and channels obtained from Listen are closed immediately.
+func (emitter *Emitter) Close() {
+	emitter.init()
+
+	emitter.lock.Lock()
+	defer emitter.lock.Unlock()
+
+	emitter.closed = true
+	for ch, listener := range emitter.listeners {
+		close(listener)
+		delete(emitter.listeners, ch)
+	}
 }
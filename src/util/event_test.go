@@ -58,3 +58,90 @@ outer:
 		return
 	}
 }
+
+func TestBufferedEmissionUncomparable(t *testing.T) {
+	var em Emitter
+	em.Release = time.Millisecond * 10
+
+	l := em.Listen()
+	defer em.Unlisten(l)
+	em.Emit([]string{"test"})
+
+	select {
+	case event := <-l:
+		if s, ok := event.([]string); !ok || len(s) != 1 {
+			t.Fatalf("Event malformed: %v", event)
+		}
+	case <-time.After(time.Millisecond * 500):
+		t.Fatalf("Event was not emitted")
+	}
+}
+
+func TestClose(t *testing.T) {
+	var em Emitter
+
+	l := em.Listen()
+	em.Close()
+	if _, ok := <-l; ok {
+		t.Fatal("The listener was not closed")
+	}
+	em.Unlisten(l)
+
+	em.Emit("test")
+	if _, ok := <-em.Listen(); ok {
+		t.Fatal("Listening to a closed emitter should yield a closed channel")
+	}
+}
+
+func TestSlowListener(t *testing.T) {
+	var em Emitter
+
+	slow := em.Listen()
+	defer em.Unlisten(slow)
+	fast := em.Listen()
+	defer em.Unlisten(fast)
+
+	const numEvents = chanBufferSize * 4
+	for i := 0; i < numEvents; i++ {
+		em.Emit(i)
+		select {
+		case event := <-fast:
+			if event != i {
+				t.Fatalf("Fast listener received %v, expected %v", event, i)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Fast listener was stalled")
+		}
+	}
+
+	// The slow listener should hold the most recent events.
+	if first := <-slow; first != numEvents-chanBufferSize {
+		t.Fatalf("Unexpected oldest event in slow listener: %v", first)
+	}
+	if em.Dropped() != numEvents-chanBufferSize {
+		t.Fatalf("Unexpected number of dropped events: %d", em.Dropped())
+	}
+}
+
+func TestSubscribeDone(t *testing.T) {
+	var em Emitter
+
+	done := make(chan struct{})
+	l := em.Subscribe(done)
+	close(done)
+	select {
+	case _, ok := <-l.C:
+		if ok {
+			t.Fatal("Unexpected event")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Listener was not closed")
+	}
+	l.Close()
+
+	em.lock.RLock()
+	defer em.lock.RUnlock()
+	if len(em.listeners) != 0 {
+		t.Fatalf("Listeners were not reclaimed: %d left", len(em.listeners))
+	}
+}
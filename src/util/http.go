@@ -2,6 +2,7 @@ package util
 
 import (
 	"bufio"
+	"context"
 	"net"
 	"net/http"
 
@@ -26,6 +27,30 @@ func LogHandler(next http.Handler) http.Handler {
 	})
 }
 
+type responseControllerKey struct{}
+
+// ControlHandler provides middleware that keeps the controller of the
+// response writer it is passed, which can then be looked up with
+// ResponseController. This allows handlers to control the response even if
+// middleware further down the chain wraps the response writer without
+// providing access to the original.
+func ControlHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), responseControllerKey{}, http.NewResponseController(w))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// ResponseController returns the controller of the response to the request
+// as kept by ControlHandler. If no controller was kept, one for w is
+// returned.
+func ResponseController(w http.ResponseWriter, r *http.Request) *http.ResponseController {
+	if rc, ok := r.Context().Value(responseControllerKey{}).(*http.ResponseController); ok {
+		return rc
+	}
+	return http.NewResponseController(w)
+}
+
 type rwInterceptor struct {
 	http.ResponseWriter
 	statusCode int
@@ -43,6 +68,10 @@ func (rwi *rwInterceptor) Write(b []byte) (int, error) {
 	return rwi.ResponseWriter.Write(b)
 }
 
+func (rwi *rwInterceptor) Unwrap() http.ResponseWriter {
+	return rwi.ResponseWriter
+}
+
 func (rwi *rwInterceptor) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	return rwi.ResponseWriter.(http.Hijacker).Hijack()
 }
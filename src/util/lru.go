@@ -0,0 +1,70 @@
+package util
+
+import (
+	"container/list"
+	"sync"
+)
+
+// An LRU is a cache of byte slices which is bounded by the total size of the
+// values it holds. When the cache is full, the least recently used values are
+// evicted first.
+//
+// An LRU is safe for concurrent use.
+type LRU struct {
+	maxSize int
+	size    int
+
+	lock    sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type lruEntry struct {
+	key   string
+	value []byte
+}
+
+// NewLRU creates an LRU cache that holds at most maxSize bytes.
+func NewLRU(maxSize int) *LRU {
+	return &LRU{
+		maxSize: maxSize,
+		order:   list.New(),
+		entries: map[string]*list.Element{},
+	}
+}
+
+// Get looks up the value stored under the specified key.
+func (lru *LRU) Get(key string) ([]byte, bool) {
+	lru.lock.Lock()
+	defer lru.lock.Unlock()
+	elem, ok := lru.entries[key]
+	if !ok {
+		return nil, false
+	}
+	lru.order.MoveToFront(elem)
+	return elem.Value.(*lruEntry).value, true
+}
+
+// Put stores a value under the specified key. Values larger than the maximum
+// size of the cache are not stored.
+func (lru *LRU) Put(key string, value []byte) {
+	lru.lock.Lock()
+	defer lru.lock.Unlock()
+	if elem, ok := lru.entries[key]; ok {
+		lru.remove(elem)
+	}
+	if len(value) > lru.maxSize {
+		return
+	}
+	lru.entries[key] = lru.order.PushFront(&lruEntry{key: key, value: value})
+	lru.size += len(value)
+	for lru.size > lru.maxSize {
+		lru.remove(lru.order.Back())
+	}
+}
+
+func (lru *LRU) remove(elem *list.Element) {
+	entry := lru.order.Remove(elem).(*lruEntry)
+	delete(lru.entries, entry.key)
+	lru.size -= len(entry.value)
+}
@@ -0,0 +1,37 @@
+package util
+
+import (
+	"testing"
+)
+
+func TestLRUEviction(t *testing.T) {
+	lru := NewLRU(10)
+	lru.Put("a", make([]byte, 4))
+	lru.Put("b", make([]byte, 4))
+	// Use a so b becomes the least recently used entry.
+	if _, ok := lru.Get("a"); !ok {
+		t.Fatal("a is missing")
+	}
+	lru.Put("c", make([]byte, 4))
+
+	if _, ok := lru.Get("b"); ok {
+		t.Fatal("b should have been evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := lru.Get(key); !ok {
+			t.Fatalf("%s is missing", key)
+		}
+	}
+}
+
+func TestLRUTooLarge(t *testing.T) {
+	lru := NewLRU(10)
+	lru.Put("a", make([]byte, 4))
+	lru.Put("big", make([]byte, 11))
+	if _, ok := lru.Get("big"); ok {
+		t.Fatal("Values larger than the cache should not be stored")
+	}
+	if _, ok := lru.Get("a"); !ok {
+		t.Fatal("a should not have been evicted")
+	}
+}
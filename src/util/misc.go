@@ -1,9 +1,11 @@
 package util
 
 import (
+	"context"
 	"crypto/rand"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
 	"path"
 	"regexp"
@@ -23,8 +25,8 @@ func DetermineFullURLRoot(root, address string) (string, error) {
 		// also smart enough to configure the URLRoot.
 		return "http:" + root, nil
 	}
-	// Handle "/"
-	if root == "/" {
+	// Handle "/" and "/path/"
+	if strings.HasPrefix(root, "/") {
 		i := strings.LastIndex(address, ":")
 		host, port := address[:i], address[i+1:]
 		if host == "" || host == "0.0.0.0" {
@@ -32,12 +34,26 @@ func DetermineFullURLRoot(root, address string) (string, error) {
 		} else if host == "[::]" {
 			host = "[::1]"
 		}
-		return fmt.Sprintf("http://%s:%s/", host, port), nil
+		return fmt.Sprintf("http://%s:%s%s", host, port, URLRootPath(root)), nil
 	}
 	// Give up
 	return "", fmt.Errorf("unsupported URL Root format: %q", root)
 }
 
+// URLRootPath returns the path component of the URL root. The returned path
+// always starts and ends with a '/'.
+func URLRootPath(root string) string {
+	u, err := url.Parse(root)
+	if err != nil {
+		return "/"
+	}
+	p := path.Clean("/" + u.Path)
+	if p != "/" {
+		p += "/"
+	}
+	return p
+}
+
 // TempName returns a path which may be used to create a temporary file at.
 func TempName(prefix string) string {
 	for {
@@ -60,3 +76,38 @@ func ErrorAsChannel(err error) <-chan error {
 	close(errs)
 	return errs
 }
+
+// WithContext runs fn and waits for it to complete or for the context to be
+// done, whichever comes first.
+//
+// If the context is done first, its error is returned and fn is left running
+// in the background. WithContext can not abort fn itself, so fn should watch
+// the same context when it can, like the operations of a player bound with
+// player.WithContext do. Because fn may still complete after its caller has
+// been told it failed, this should only be used for queries. Operations that
+// change state should use RunUnlessDone.
+func WithContext(ctx context.Context, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- fn() }()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RunUnlessDone runs fn unless the context is already done, in which case its
+// error is returned.
+//
+// Unlike WithContext, fn always runs to completion. An operation that changes
+// state is therefore never applied after its caller has been told it failed.
+func RunUnlessDone(ctx context.Context, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return fn()
+}
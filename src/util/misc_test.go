@@ -0,0 +1,77 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestWithContext(t *testing.T) {
+	errTest := fmt.Errorf("test")
+	if err := WithContext(context.Background(), func() error { return errTest }); err != errTest {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	release := make(chan struct{})
+	defer close(release)
+	err := WithContext(ctx, func() error {
+		<-release
+		return nil
+	})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Expected the deadline to be exceeded, got %v", err)
+	}
+}
+
+func TestRunUnlessDone(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	completed := false
+	err := RunUnlessDone(ctx, func() error {
+		<-ctx.Done()
+		completed = true
+		return nil
+	})
+	if err != nil || !completed {
+		t.Fatalf("The function was not run to completion: %v", err)
+	}
+
+	ran := false
+	err = RunUnlessDone(ctx, func() error {
+		ran = true
+		return nil
+	})
+	if err != context.DeadlineExceeded || ran {
+		t.Fatalf("The function should not run once the context is done: %v", err)
+	}
+}
+
+func TestURLRootPath(t *testing.T) {
+	cases := map[string]string{
+		"/":                         "/",
+		"":                          "/",
+		"/music/":                   "/music/",
+		"/music":                    "/music/",
+		"http://localhost:3000/":    "/",
+		"http://example.com/music/": "/music/",
+		"//example.com/a/b/":        "/a/b/",
+	}
+	for root, expected := range cases {
+		if p := URLRootPath(root); p != expected {
+			t.Errorf("Unexpected path for %q: %q != %q", root, expected, p)
+		}
+	}
+}
+
+func TestDetermineFullURLRootPath(t *testing.T) {
+	root, err := DetermineFullURLRoot("/music/", ":3000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "http://127.0.0.1:3000/music/"; root != expected {
+		t.Fatalf("Unexpected root: %q != %q", expected, root)
+	}
+}
@@ -0,0 +1,244 @@
+// Package webhook notifies external services of player events by POSTing JSON
+// payloads to configured URLs.
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/polyfloyd/trollibox/src/player"
+	"github.com/polyfloyd/trollibox/src/util"
+)
+
+const (
+	// The number of times delivery of a payload is attempted.
+	maxAttempts = 5
+	// The number of payloads that may wait for delivery to a single webhook.
+	// Payloads are dropped when this is exceeded.
+	queueSize = 64
+)
+
+// SignatureHeader is the header holding the HMAC-SHA256 signature of the
+// payload for webhooks that have a secret.
+const SignatureHeader = "X-Trollibox-Signature"
+
+// A Webhook is an URL that is notified of events.
+type Webhook struct {
+	URL string
+	// The names of the events to send, all events are sent if empty.
+	Events []string
+	// If set, only events of this player are sent.
+	Player string
+	// If set, payloads are signed with this secret.
+	Secret string
+}
+
+// A Payload is the JSON document that is POSTed to webhooks.
+type Payload struct {
+	Event  string      `json:"event"`
+	Player string      `json:"player"`
+	Time   time.Time   `json:"time"`
+	Data   interface{} `json:"data"`
+}
+
+type hook struct {
+	Webhook
+	queue chan []byte
+}
+
+// A Dispatcher delivers events to webhooks.
+//
+// Each webhook receives its payloads in order. Delivery is retried with
+// exponential backoff if the receiver can not be reached or responds with a
+// server error.
+type Dispatcher struct {
+	hooks  []*hook
+	client *http.Client
+	// The delay before the first retry, doubled for each subsequent retry.
+	backoff time.Duration
+
+	closed    chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewDispatcher starts delivering to the specified webhooks.
+func NewDispatcher(webhooks []Webhook) *Dispatcher {
+	disp := &Dispatcher{
+		client:  &http.Client{Timeout: time.Second * 10},
+		backoff: time.Second,
+		closed:  make(chan struct{}),
+	}
+	for _, wh := range webhooks {
+		h := &hook{Webhook: wh, queue: make(chan []byte, queueSize)}
+		disp.hooks = append(disp.hooks, h)
+		disp.wg.Add(1)
+		go disp.deliverLoop(h)
+	}
+	return disp
+}
+
+// Attach sends the events emitted by the named player to the webhooks.
+func (disp *Dispatcher) Attach(playerName string, events *util.Emitter) {
+	listener := events.Subscribe(disp.closed)
+	go func() {
+		for event := range listener.C {
+			name, data, ok := mapEvent(event)
+			if !ok {
+				continue
+			}
+			disp.dispatch(Payload{
+				Event:  name,
+				Player: playerName,
+				Time:   time.Now(),
+				Data:   data,
+			})
+		}
+	}()
+}
+
+// Close stops delivering payloads. Payloads that have not been delivered yet
+// are discarded.
+func (disp *Dispatcher) Close() {
+	disp.closeOnce.Do(func() {
+		close(disp.closed)
+	})
+	disp.wg.Wait()
+}
+
+func (disp *Dispatcher) dispatch(payload Payload) {
+	var body []byte
+	for _, h := range disp.hooks {
+		if !h.wants(payload) {
+			continue
+		}
+		if body == nil {
+			var err error
+			if body, err = json.Marshal(payload); err != nil {
+				log.Errorf("Could not encode webhook payload: %v", err)
+				return
+			}
+		}
+		select {
+		case h.queue <- body:
+		default:
+			log.Warnf("Webhook %s is not keeping up, dropping %q event", h.URL, payload.Event)
+		}
+	}
+}
+
+func (disp *Dispatcher) deliverLoop(h *hook) {
+	defer disp.wg.Done()
+	for {
+		select {
+		case body := <-h.queue:
+			disp.deliver(h, body)
+		case <-disp.closed:
+			return
+		}
+	}
+}
+
+// deliver POSTs the body to the webhook, retrying on failure.
+func (disp *Dispatcher) deliver(h *hook, body []byte) {
+	backoff := disp.backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := disp.post(h, body)
+		if err == nil {
+			return
+		}
+		if !retry || attempt == maxAttempts {
+			log.Errorf("Could not deliver webhook to %s: %v", h.URL, err)
+			return
+		}
+		log.Debugf("Delivering webhook to %s failed, retrying in %v: %v", h.URL, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-disp.closed:
+			return
+		}
+		backoff *= 2
+	}
+}
+
+// post performs a single delivery attempt. The returned bool indicates
+// whether the attempt may be retried.
+func (disp *Dispatcher) post(h *hook, body []byte) (bool, error) {
+	req, err := http.NewRequest("POST", h.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if h.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(h.Secret, body))
+	}
+	res, err := disp.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	io.Copy(ioutil.Discard, res.Body)
+	res.Body.Close()
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return false, nil
+	}
+	retry := res.StatusCode >= 500 || res.StatusCode == http.StatusTooManyRequests
+	return retry, fmt.Errorf("unexpected response: %s", res.Status)
+}
+
+// Sign computes the signature of a payload as it is sent in the
+// SignatureHeader.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (h *hook) wants(payload Payload) bool {
+	if h.Player != "" && h.Player != payload.Player {
+		return false
+	}
+	if len(h.Events) == 0 {
+		return true
+	}
+	for _, name := range h.Events {
+		if name == payload.Event {
+			return true
+		}
+	}
+	return false
+}
+
+// mapEvent names an event and describes its data. False is returned for
+// events that are not sent to webhooks.
+func mapEvent(ev interface{}) (string, interface{}, bool) {
+	switch t := ev.(type) {
+	case player.PlaylistEvent:
+		return "playlist", map[string]interface{}{"index": t.Index}, true
+	case player.PlayStateEvent:
+		return "playstate", map[string]interface{}{"state": t.State}, true
+	case player.VolumeEvent:
+		return "volume", map[string]interface{}{"volume": float32(t.Volume) / 100.0}, true
+	case player.AvailabilityEvent:
+		data := map[string]interface{}{"available": t.Available}
+		if !t.Available && t.Reason != "" {
+			data["reason"] = t.Reason
+		}
+		return "availability", data, true
+	case player.QueueExhaustedEvent:
+		return "queue-exhausted", struct{}{}, true
+	case player.PlayEvent:
+		return "play", map[string]interface{}{"uri": t.URI, "started": t.Started}, true
+	}
+	return "", nil, false
+}
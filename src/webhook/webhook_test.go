@@ -0,0 +1,87 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/polyfloyd/trollibox/src/player"
+	"github.com/polyfloyd/trollibox/src/util"
+)
+
+func TestDispatcher(t *testing.T) {
+	received := make(chan Payload, 16)
+	failures := 2
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failures > 0 {
+			failures--
+			http.Error(w, "try again", http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		var payload Payload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Error(err)
+		}
+		if sig := r.Header.Get(SignatureHeader); sig != Sign("secret", body) {
+			t.Errorf("Invalid signature: %q", sig)
+		}
+		received <- payload
+	}))
+	defer server.Close()
+
+	disp := NewDispatcher([]Webhook{
+		{URL: server.URL, Events: []string{"playstate"}, Player: "kitchen", Secret: "secret"},
+	})
+	disp.backoff = time.Millisecond
+	defer disp.Close()
+
+	var kitchen, garden util.Emitter
+	disp.Attach("kitchen", &kitchen)
+	disp.Attach("garden", &garden)
+
+	garden.Emit(player.PlayStateEvent{State: player.PlayStatePlaying})
+	kitchen.Emit(player.VolumeEvent{Volume: 20})
+	kitchen.Emit(player.PlayStateEvent{State: player.PlayStatePlaying})
+
+	select {
+	case payload := <-received:
+		if payload.Event != "playstate" || payload.Player != "kitchen" {
+			t.Fatalf("Unexpected payload: %+v", payload)
+		}
+		if state := payload.Data.(map[string]interface{})["state"]; state != "playing" {
+			t.Fatalf("Unexpected state: %v", state)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("Webhook was not delivered")
+	}
+	select {
+	case payload := <-received:
+		t.Fatalf("Unexpected delivery: %+v", payload)
+	case <-time.After(time.Millisecond * 50):
+	}
+}
+
+func TestDispatcherGivesUpOnClientErrors(t *testing.T) {
+	attempts := make(chan struct{}, 16)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts <- struct{}{}
+		http.Error(w, "nope", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	disp := NewDispatcher([]Webhook{{URL: server.URL}})
+	disp.backoff = time.Millisecond
+	defer disp.Close()
+	disp.dispatch(Payload{Event: "volume"})
+
+	<-attempts
+	select {
+	case <-attempts:
+		t.Fatal("Delivery should not be retried after a client error")
+	case <-time.After(time.Millisecond * 50):
+	}
+}